@@ -0,0 +1,151 @@
+// Code generated by protoc-gen-grpc-gateway. DO NOT EDIT.
+// source: v1/pipeline.proto
+
+/*
+Package v1 is a reverse proxy.
+
+It translates gRPC into RESTful JSON APIs.
+*/
+package v1
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Suppress "imported and not used" errors
+var _ codes.Code
+var _ io.Reader
+var _ status.Status
+var _ = runtime.String
+var _ = utilities.NewDoubleArray
+var _ = metadata.Join
+
+func request_PipelineTemplateService_ListPipelineTemplate_0(ctx context.Context, marshaler runtime.Marshaler, client PipelineTemplateServiceClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
+	var protoReq ListPipelineTemplateRequest
+	var metadata runtime.ServerMetadata
+
+	msg, err := client.ListPipelineTemplate(ctx, &protoReq, grpc.Header(&metadata.HeaderMD), grpc.Trailer(&metadata.TrailerMD))
+	return msg, metadata, err
+
+}
+
+func local_request_PipelineTemplateService_ListPipelineTemplate_0(ctx context.Context, marshaler runtime.Marshaler, server PipelineTemplateServiceServer, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
+	var protoReq ListPipelineTemplateRequest
+	var metadata runtime.ServerMetadata
+
+	msg, err := server.ListPipelineTemplate(ctx, &protoReq)
+	return msg, metadata, err
+
+}
+
+// RegisterPipelineTemplateServiceHandlerServer registers the http handlers for service PipelineTemplateService to "mux".
+// UnaryRPC     :call PipelineTemplateServiceServer directly.
+// StreamingRPC :currently unsupported pending https://github.com/grpc/grpc-go/issues/906.
+// Note that using this registration option will cause many gRPC library features to stop working. Consider using RegisterPipelineTemplateServiceHandlerFromEndpoint instead.
+func RegisterPipelineTemplateServiceHandlerServer(ctx context.Context, mux *runtime.ServeMux, server PipelineTemplateServiceServer) error {
+
+	mux.Handle("GET", pattern_PipelineTemplateService_ListPipelineTemplate_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		var stream runtime.ServerTransportStream
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &stream)
+		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		rctx, err := runtime.AnnotateIncomingContext(ctx, mux, req, "/workstation.v1.PipelineTemplateService/ListPipelineTemplate", runtime.WithHTTPPathPattern("/v1/pipeline/templates"))
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		resp, md, err := local_request_PipelineTemplateService_ListPipelineTemplate_0(rctx, inboundMarshaler, server, req, pathParams)
+		md.HeaderMD, md.TrailerMD = metadata.Join(md.HeaderMD, stream.Header()), metadata.Join(md.TrailerMD, stream.Trailer())
+		ctx = runtime.NewServerMetadataContext(ctx, md)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		forward_PipelineTemplateService_ListPipelineTemplate_0(ctx, mux, outboundMarshaler, w, req, resp, mux.GetForwardResponseOptions()...)
+
+	})
+
+	return nil
+}
+
+// RegisterPipelineTemplateServiceHandlerFromEndpoint is same as RegisterPipelineTemplateServiceHandler but
+// automatically dials to "endpoint" and closes the connection when "ctx" gets done.
+func RegisterPipelineTemplateServiceHandlerFromEndpoint(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) (err error) {
+	conn, err := grpc.Dial(endpoint, opts...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if cerr := conn.Close(); cerr != nil {
+				grpclog.Infof("Failed to close conn to %s: %v", endpoint, cerr)
+			}
+			return
+		}
+		go func() {
+			<-ctx.Done()
+			if cerr := conn.Close(); cerr != nil {
+				grpclog.Infof("Failed to close conn to %s: %v", endpoint, cerr)
+			}
+		}()
+	}()
+
+	return RegisterPipelineTemplateServiceHandler(ctx, mux, conn)
+}
+
+// RegisterPipelineTemplateServiceHandler registers the http handlers for service PipelineTemplateService to "mux".
+// The handlers forward requests to the grpc endpoint over "conn".
+func RegisterPipelineTemplateServiceHandler(ctx context.Context, mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	return RegisterPipelineTemplateServiceHandlerClient(ctx, mux, NewPipelineTemplateServiceClient(conn))
+}
+
+// RegisterPipelineTemplateServiceHandlerClient registers the http handlers for service PipelineTemplateService
+// to "mux". The handlers forward requests to the grpc endpoint over the given implementation of "PipelineTemplateServiceClient".
+// Note: the gRPC framework executes interceptors within the gRPC handler. If the passed in "PipelineTemplateServiceClient"
+// doesn't go through the normal gRPC flow (creating a gRPC client etc.) then it will be up to the passed in
+// "PipelineTemplateServiceClient" to call the correct interceptors.
+func RegisterPipelineTemplateServiceHandlerClient(ctx context.Context, mux *runtime.ServeMux, client PipelineTemplateServiceClient) error {
+
+	mux.Handle("GET", pattern_PipelineTemplateService_ListPipelineTemplate_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		rctx, err := runtime.AnnotateContext(ctx, mux, req, "/workstation.v1.PipelineTemplateService/ListPipelineTemplate", runtime.WithHTTPPathPattern("/v1/pipeline/templates"))
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		resp, md, err := request_PipelineTemplateService_ListPipelineTemplate_0(rctx, inboundMarshaler, client, req, pathParams)
+		ctx = runtime.NewServerMetadataContext(ctx, md)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		forward_PipelineTemplateService_ListPipelineTemplate_0(ctx, mux, outboundMarshaler, w, req, resp, mux.GetForwardResponseOptions()...)
+
+	})
+
+	return nil
+}
+
+var (
+	pattern_PipelineTemplateService_ListPipelineTemplate_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2}, []string{"v1", "pipeline", "templates"}, ""))
+)
+
+var (
+	forward_PipelineTemplateService_ListPipelineTemplate_0 = runtime.ForwardResponseMessage
+)
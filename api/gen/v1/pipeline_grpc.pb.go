@@ -0,0 +1,105 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.17.3
+// source: v1/pipeline.proto
+
+package v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// PipelineTemplateServiceClient is the client API for PipelineTemplateService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PipelineTemplateServiceClient interface {
+	// ListPipelineTemplate lists pipeline templates.
+	ListPipelineTemplate(ctx context.Context, in *ListPipelineTemplateRequest, opts ...grpc.CallOption) (*ListPipelineTemplateResponse, error)
+}
+
+type pipelineTemplateServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPipelineTemplateServiceClient(cc grpc.ClientConnInterface) PipelineTemplateServiceClient {
+	return &pipelineTemplateServiceClient{cc}
+}
+
+func (c *pipelineTemplateServiceClient) ListPipelineTemplate(ctx context.Context, in *ListPipelineTemplateRequest, opts ...grpc.CallOption) (*ListPipelineTemplateResponse, error) {
+	out := new(ListPipelineTemplateResponse)
+	err := c.cc.Invoke(ctx, "/workstation.v1.PipelineTemplateService/ListPipelineTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PipelineTemplateServiceServer is the server API for PipelineTemplateService service.
+// All implementations should embed UnimplementedPipelineTemplateServiceServer
+// for forward compatibility
+type PipelineTemplateServiceServer interface {
+	// ListPipelineTemplate lists pipeline templates.
+	ListPipelineTemplate(context.Context, *ListPipelineTemplateRequest) (*ListPipelineTemplateResponse, error)
+}
+
+// UnimplementedPipelineTemplateServiceServer should be embedded to have forward compatible implementations.
+type UnimplementedPipelineTemplateServiceServer struct {
+}
+
+func (UnimplementedPipelineTemplateServiceServer) ListPipelineTemplate(context.Context, *ListPipelineTemplateRequest) (*ListPipelineTemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPipelineTemplate not implemented")
+}
+
+// UnsafePipelineTemplateServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PipelineTemplateServiceServer will
+// result in compilation errors.
+type UnsafePipelineTemplateServiceServer interface {
+	mustEmbedUnimplementedPipelineTemplateServiceServer()
+}
+
+func RegisterPipelineTemplateServiceServer(s grpc.ServiceRegistrar, srv PipelineTemplateServiceServer) {
+	s.RegisterService(&PipelineTemplateService_ServiceDesc, srv)
+}
+
+func _PipelineTemplateService_ListPipelineTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPipelineTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PipelineTemplateServiceServer).ListPipelineTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/workstation.v1.PipelineTemplateService/ListPipelineTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PipelineTemplateServiceServer).ListPipelineTemplate(ctx, req.(*ListPipelineTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PipelineTemplateService_ServiceDesc is the grpc.ServiceDesc for PipelineTemplateService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PipelineTemplateService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "workstation.v1.PipelineTemplateService",
+	HandlerType: (*PipelineTemplateServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListPipelineTemplate",
+			Handler:    _PipelineTemplateService_ListPipelineTemplate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "v1/pipeline.proto",
+}
@@ -0,0 +1,258 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.17.3
+// source: v1/proj.proto
+
+package v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ProjServiceClient is the client API for ProjService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ProjServiceClient interface {
+	// ListProj lists projects in organization, all projects would be listed if org_id is missing.
+	ListProj(ctx context.Context, in *ListProjRequest, opts ...grpc.CallOption) (*ListProjResponse, error)
+	// GetProj returns project with source.
+	GetProj(ctx context.Context, in *GetProjRequest, opts ...grpc.CallOption) (*Proj, error)
+	// CreateProj creates project in organization.
+	CreateProj(ctx context.Context, in *CreateProjRequest, opts ...grpc.CallOption) (*Proj, error)
+	// UpdateProj updates project.
+	UpdateProj(ctx context.Context, in *UpdateProjRequest, opts ...grpc.CallOption) (*Proj, error)
+	// DeleteProj deletes project.
+	DeleteProj(ctx context.Context, in *DeleteProjRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type projServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProjServiceClient(cc grpc.ClientConnInterface) ProjServiceClient {
+	return &projServiceClient{cc}
+}
+
+func (c *projServiceClient) ListProj(ctx context.Context, in *ListProjRequest, opts ...grpc.CallOption) (*ListProjResponse, error) {
+	out := new(ListProjResponse)
+	err := c.cc.Invoke(ctx, "/workstation.v1.ProjService/ListProj", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projServiceClient) GetProj(ctx context.Context, in *GetProjRequest, opts ...grpc.CallOption) (*Proj, error) {
+	out := new(Proj)
+	err := c.cc.Invoke(ctx, "/workstation.v1.ProjService/GetProj", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projServiceClient) CreateProj(ctx context.Context, in *CreateProjRequest, opts ...grpc.CallOption) (*Proj, error) {
+	out := new(Proj)
+	err := c.cc.Invoke(ctx, "/workstation.v1.ProjService/CreateProj", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projServiceClient) UpdateProj(ctx context.Context, in *UpdateProjRequest, opts ...grpc.CallOption) (*Proj, error) {
+	out := new(Proj)
+	err := c.cc.Invoke(ctx, "/workstation.v1.ProjService/UpdateProj", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projServiceClient) DeleteProj(ctx context.Context, in *DeleteProjRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/workstation.v1.ProjService/DeleteProj", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProjServiceServer is the server API for ProjService service.
+// All implementations should embed UnimplementedProjServiceServer
+// for forward compatibility
+type ProjServiceServer interface {
+	// ListProj lists projects in organization, all projects would be listed if org_id is missing.
+	ListProj(context.Context, *ListProjRequest) (*ListProjResponse, error)
+	// GetProj returns project with source.
+	GetProj(context.Context, *GetProjRequest) (*Proj, error)
+	// CreateProj creates project in organization.
+	CreateProj(context.Context, *CreateProjRequest) (*Proj, error)
+	// UpdateProj updates project.
+	UpdateProj(context.Context, *UpdateProjRequest) (*Proj, error)
+	// DeleteProj deletes project.
+	DeleteProj(context.Context, *DeleteProjRequest) (*emptypb.Empty, error)
+}
+
+// UnimplementedProjServiceServer should be embedded to have forward compatible implementations.
+type UnimplementedProjServiceServer struct {
+}
+
+func (UnimplementedProjServiceServer) ListProj(context.Context, *ListProjRequest) (*ListProjResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListProj not implemented")
+}
+func (UnimplementedProjServiceServer) GetProj(context.Context, *GetProjRequest) (*Proj, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProj not implemented")
+}
+func (UnimplementedProjServiceServer) CreateProj(context.Context, *CreateProjRequest) (*Proj, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateProj not implemented")
+}
+func (UnimplementedProjServiceServer) UpdateProj(context.Context, *UpdateProjRequest) (*Proj, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateProj not implemented")
+}
+func (UnimplementedProjServiceServer) DeleteProj(context.Context, *DeleteProjRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteProj not implemented")
+}
+
+// UnsafeProjServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProjServiceServer will
+// result in compilation errors.
+type UnsafeProjServiceServer interface {
+	mustEmbedUnimplementedProjServiceServer()
+}
+
+func RegisterProjServiceServer(s grpc.ServiceRegistrar, srv ProjServiceServer) {
+	s.RegisterService(&ProjService_ServiceDesc, srv)
+}
+
+func _ProjService_ListProj_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProjRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjServiceServer).ListProj(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/workstation.v1.ProjService/ListProj",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjServiceServer).ListProj(ctx, req.(*ListProjRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjService_GetProj_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProjRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjServiceServer).GetProj(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/workstation.v1.ProjService/GetProj",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjServiceServer).GetProj(ctx, req.(*GetProjRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjService_CreateProj_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateProjRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjServiceServer).CreateProj(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/workstation.v1.ProjService/CreateProj",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjServiceServer).CreateProj(ctx, req.(*CreateProjRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjService_UpdateProj_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateProjRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjServiceServer).UpdateProj(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/workstation.v1.ProjService/UpdateProj",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjServiceServer).UpdateProj(ctx, req.(*UpdateProjRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjService_DeleteProj_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteProjRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjServiceServer).DeleteProj(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/workstation.v1.ProjService/DeleteProj",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjServiceServer).DeleteProj(ctx, req.(*DeleteProjRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ProjService_ServiceDesc is the grpc.ServiceDesc for ProjService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ProjService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "workstation.v1.ProjService",
+	HandlerType: (*ProjServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListProj",
+			Handler:    _ProjService_ListProj_Handler,
+		},
+		{
+			MethodName: "GetProj",
+			Handler:    _ProjService_GetProj_Handler,
+		},
+		{
+			MethodName: "CreateProj",
+			Handler:    _ProjService_CreateProj_Handler,
+		},
+		{
+			MethodName: "UpdateProj",
+			Handler:    _ProjService_UpdateProj_Handler,
+		},
+		{
+			MethodName: "DeleteProj",
+			Handler:    _ProjService_DeleteProj_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "v1/proj.proto",
+}
@@ -32,157 +32,90 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/v1/oauth/callback/github": {
-            "get": {
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "oauth"
-                ],
-                "summary": "Oauth callback",
-                "operationId": "40",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Code",
-                        "name": "code",
-                        "in": "query",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": ""
-                    }
-                }
-            }
-        },
-        "/v1/org": {
+        "/v1/me": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "organization"
+                    "user"
                 ],
-                "summary": "List organizations",
-                "operationId": "1",
+                "summary": "Get profile and linked identities of current user",
+                "operationId": "37",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controller.ListOrgResponse"
+                            "$ref": "#/definitions/controller.GetUserResponse"
                         }
                     }
                 }
             },
-            "put": {
+            "delete": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "organization"
-                ],
-                "summary": "Create organization",
-                "operationId": "3",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Organization name",
-                        "name": "orgName",
-                        "in": "query",
-                        "required": true
-                    }
+                    "user"
                 ],
+                "summary": "Delete current user together with linked identities and access tokens",
+                "operationId": "38",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controller.CreateOrgResponse"
+                            "$ref": "#/definitions/controller.DeleteUserResponse"
                         }
                     }
                 }
             }
         },
-        "/v1/org/{orgId}": {
-            "get": {
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "organization"
-                ],
-                "summary": "Get organization",
-                "operationId": "2",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Organization Id",
-                        "name": "orgId",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/controller.GetOrgResponse"
-                        }
-                    }
-                }
-            },
-            "post": {
+        "/v1/me/identities/local": {
+            "put": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "organization"
+                    "user"
                 ],
-                "summary": "Update organization",
-                "operationId": "5",
+                "summary": "Link local identity to current user or update password of it",
+                "operationId": "39",
                 "parameters": [
                     {
-                        "description": "Organization",
-                        "name": "org",
+                        "description": "Login, password and current password if linked already",
+                        "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/controller.UpdateOrgRequest"
+                            "$ref": "#/definitions/controller.LinkLocalIdentityRequest"
                         }
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Organization Id",
-                        "name": "orgId",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controller.UpdateOrgResponse"
+                            "$ref": "#/definitions/controller.LinkIdentityResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/v1/me/identities/{type}": {
             "delete": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "organization"
+                    "user"
                 ],
-                "summary": "Delete organization",
-                "operationId": "4",
+                "summary": "Unlink identity from current user, access token of the identity would be removed",
+                "operationId": "41",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Organization Id",
-                        "name": "orgId",
+                        "type": "string",
+                        "description": "Identity type, github, gitlab, local or name of OpenID Connect provider",
+                        "name": "type",
                         "in": "path",
                         "required": true
                     }
@@ -191,55 +124,27 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controller.DeleteOrgResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/v1/pipeline/template": {
-            "get": {
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "pipeline"
-                ],
-                "summary": "List pipeline templates",
-                "operationId": "14",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/controller.ListPipelineTemplateResponse"
+                            "$ref": "#/definitions/controller.UnlinkIdentityResponse"
                         }
                     }
                 }
             }
         },
-        "/v1/proj": {
+        "/v1/me/tokens": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "project"
-                ],
-                "summary": "List projects",
-                "operationId": "6",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Organization Id",
-                        "name": "orgId",
-                        "in": "query"
-                    }
+                    "user"
                 ],
+                "summary": "List personal tokens of current user",
+                "operationId": "48",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controller.ListProjResponse"
+                            "$ref": "#/definitions/controller.ListPersonalTokenResponse"
                         }
                     }
                 }
@@ -249,18 +154,18 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "project"
+                    "user"
                 ],
-                "summary": "create project",
-                "operationId": "8",
+                "summary": "Create personal token for CLI and automation, the token is returned only once",
+                "operationId": "49",
                 "parameters": [
                     {
-                        "description": "Project",
-                        "name": "project",
+                        "description": "Name, scopes and expiration",
+                        "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/controller.CreateProjRequest"
+                            "$ref": "#/definitions/controller.CreatePersonalTokenRequest"
                         }
                     }
                 ],
@@ -268,27 +173,27 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controller.CreateProjResponse"
+                            "$ref": "#/definitions/controller.CreatePersonalTokenResponse"
                         }
                     }
                 }
             }
         },
-        "/v1/proj/{projId}": {
-            "get": {
+        "/v1/me/tokens/{tokenId}": {
+            "delete": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "project"
+                    "user"
                 ],
-                "summary": "Get project",
-                "operationId": "7",
+                "summary": "Revoke personal token",
+                "operationId": "51",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Project Id",
-                        "name": "projId",
+                        "description": "Personal token id",
+                        "name": "tokenId",
                         "in": "path",
                         "required": true
                     }
@@ -297,409 +202,2208 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controller.GetProjResponse"
+                            "$ref": "#/definitions/controller.RevokePersonalTokenResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/v1/me/tokens/{tokenId}/rotate": {
             "post": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "project"
+                    "user"
                 ],
-                "summary": "update project",
-                "operationId": "10",
+                "summary": "Replace personal token with a new one, the old token stops working immediately",
+                "operationId": "50",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Project Id",
-                        "name": "projId",
+                        "description": "Personal token id",
+                        "name": "tokenId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Project",
-                        "name": "project",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controller.UpdateProjRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controller.UpdateProjResponse"
+                            "$ref": "#/definitions/controller.CreatePersonalTokenResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/v1/oauth/callback/gitea": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "project"
+                    "oauth"
                 ],
-                "summary": "delete project",
-                "operationId": "9",
+                "summary": "Callback of gitea or forgejo",
+                "operationId": "54",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Project Id",
-                        "name": "projId",
-                        "in": "path",
+                        "type": "string",
+                        "description": "Code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "State issued by login API",
+                        "name": "state",
+                        "in": "query",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/controller.DeleteProjResponse"
-                        }
+                    "307": {
+                        "description": ""
                     }
                 }
             }
         },
-        "/v1/source": {
-            "put": {
+        "/v1/oauth/callback/github": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "source"
+                    "oauth"
                 ],
-                "summary": "create source",
-                "operationId": "11",
+                "summary": "Oauth callback",
+                "operationId": "40",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Project Id",
-                        "name": "projId",
+                        "type": "string",
+                        "description": "Code",
+                        "name": "code",
                         "in": "query",
                         "required": true
                     },
                     {
-                        "description": "Source",
-                        "name": "source",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controller.CreateSourceRequest"
-                        }
+                        "type": "string",
+                        "description": "State issued by login API",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/controller.CreateSourceResponse"
-                        }
+                        "description": ""
                     }
                 }
             }
         },
-        "/v1/source/{sourceId}": {
-            "delete": {
+        "/v1/oauth/callback/gitlab": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "source"
+                    "oauth"
                 ],
-                "summary": "delete source",
-                "operationId": "12",
+                "summary": "Callback of gitlab",
+                "operationId": "53",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Source Id",
-                        "name": "sourceId",
-                        "in": "path",
+                        "type": "string",
+                        "description": "Code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "State issued by login API",
+                        "name": "state",
+                        "in": "query",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/controller.DeleteProjResponse"
-                        }
+                    "307": {
+                        "description": ""
                     }
                 }
             }
         },
-        "/v1/source/{sourceId}/branches": {
+        "/v1/oauth/login/{provider}": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "installation"
+                    "oauth"
                 ],
-                "summary": "List branches and tags",
-                "operationId": "16",
+                "summary": "Start oauth flow",
+                "operationId": "46",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Source Id",
-                        "name": "sourceId",
+                        "type": "string",
+                        "description": "Oauth provider",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/v1/oauth/oidc/{provider}/callback": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "Callback of OpenID Connect provider",
+                "operationId": "52",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of OpenID Connect provider",
+                        "name": "provider",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "Number of commits per page",
-                        "name": "perPage",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "Page number to fetch",
-                        "name": "page",
-                        "in": "query"
+                        "type": "string",
+                        "description": "State issued by login API",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
+                    "307": {
                         "description": ""
                     }
                 }
             }
         },
-        "/v1/source/{sourceId}/commits": {
-            "get": {
+        "/v1/oauth/{provider}": {
+            "delete": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "installation"
+                    "oauth"
                 ],
-                "summary": "List user installation commits",
-                "operationId": "15",
+                "summary": "Revoke access token of oauth provider",
+                "operationId": "47",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Source Id",
-                        "name": "sourceId",
+                        "type": "string",
+                        "description": "Oauth provider",
+                        "name": "provider",
                         "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/oauth.RevokeTokenResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/org": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organization"
+                ],
+                "summary": "List organizations",
+                "operationId": "1",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma separated related resources to embed, projects, projCount or source",
+                        "name": "expand",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Branch",
-                        "name": "branch",
+                        "description": "Comma separated fields of organization to return",
+                        "name": "fields",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ListOrgResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organization"
+                ],
+                "summary": "Create organization",
+                "operationId": "3",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization name",
+                        "name": "orgName",
                         "in": "query",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "description": "Key to replay response of retried request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.CreateOrgResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/org/{orgId}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organization"
+                ],
+                "summary": "Get organization",
+                "operationId": "2",
+                "parameters": [
                     {
                         "type": "integer",
-                        "description": "Number of commits per page",
-                        "name": "perPage",
-                        "in": "query"
+                        "description": "Organization Id",
+                        "name": "orgId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.GetOrgResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organization"
+                ],
+                "summary": "Update organization",
+                "operationId": "5",
+                "parameters": [
+                    {
+                        "description": "Organization",
+                        "name": "org",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.UpdateOrgRequest"
+                        }
                     },
                     {
                         "type": "integer",
-                        "description": "Page number to fetch",
-                        "name": "page",
-                        "in": "query"
+                        "description": "Organization Id",
+                        "name": "orgId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": ""
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.UpdateOrgResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organization"
+                ],
+                "summary": "Delete organization",
+                "operationId": "4",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organization Id",
+                        "name": "orgId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.DeleteOrgResponse"
+                        }
                     }
                 }
             }
         },
-        "/v1/user/installations": {
+        "/v1/org/{orgId}/members": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "installation"
+                    "member"
                 ],
-                "summary": "List user installations",
-                "operationId": "13",
+                "summary": "List members of organization",
+                "operationId": "42",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Source",
-                        "name": "source",
-                        "in": "query",
+                        "type": "integer",
+                        "description": "Organization Id",
+                        "name": "orgId",
+                        "in": "path",
                         "required": true
-                    },
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ListMemberResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "member"
+                ],
+                "summary": "Invite user with linked identity into organization",
+                "operationId": "43",
+                "parameters": [
                     {
-                        "type": "string",
-                        "description": "user",
-                        "name": "user",
-                        "in": "query",
+                        "type": "integer",
+                        "description": "Organization Id",
+                        "name": "orgId",
+                        "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Member",
+                        "name": "member",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.InviteMemberRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": ""
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.InviteMemberResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/org/{orgId}/members/{userId}": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "member"
+                ],
+                "summary": "Change role of member",
+                "operationId": "44",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organization Id",
+                        "name": "orgId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "User Id",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Member",
+                        "name": "member",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.UpdateMemberRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.UpdateMemberResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "member"
+                ],
+                "summary": "Remove member from organization",
+                "operationId": "45",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organization Id",
+                        "name": "orgId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "User Id",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.RemoveMemberResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/pipeline/template": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pipeline"
+                ],
+                "summary": "List pipeline templates",
+                "operationId": "14",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ListPipelineTemplateResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/proj": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "List projects",
+                "operationId": "6",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organization Id",
+                        "name": "orgId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated related resources to embed, source only",
+                        "name": "expand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields of project to return",
+                        "name": "fields",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ListProjResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "create project",
+                "operationId": "8",
+                "parameters": [
+                    {
+                        "description": "Project",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.CreateProjRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay response of retried request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.CreateProjResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/proj/{projId}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "Get project",
+                "operationId": "7",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project Id",
+                        "name": "projId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.GetProjResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "update project",
+                "operationId": "10",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project Id",
+                        "name": "projId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Project",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.UpdateProjRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.UpdateProjResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "delete project",
+                "operationId": "9",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project Id",
+                        "name": "projId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.DeleteProjResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/session": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "session"
+                ],
+                "summary": "Get current session",
+                "operationId": "34",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.GetSessionResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "session"
+                ],
+                "summary": "Logout by removing session cookie",
+                "operationId": "35",
+                "responses": {
+                    "204": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/v1/session/login": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "session"
+                ],
+                "summary": "Sign in with local identity",
+                "operationId": "36",
+                "parameters": [
+                    {
+                        "description": "Login and password",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.LoginResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/source": {
+            "put": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "source"
+                ],
+                "summary": "create source",
+                "operationId": "11",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project Id",
+                        "name": "projId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Source",
+                        "name": "source",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.CreateSourceRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay response of retried request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.CreateSourceResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/source/{sourceId}": {
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "source"
+                ],
+                "summary": "delete source",
+                "operationId": "12",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Source Id",
+                        "name": "sourceId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.DeleteProjResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/source/{sourceId}/branches": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "installation"
+                ],
+                "summary": "List branches and tags",
+                "operationId": "16",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Source Id",
+                        "name": "sourceId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of branches and tags per page",
+                        "name": "perPage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number to fetch",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number of branches to fetch, page is used if missing",
+                        "name": "branchPage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number of tags to fetch, page is used if missing",
+                        "name": "tagPage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Follow pages from page until there are no more pages or max pages reached",
+                        "name": "all",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ListBranchesAndTagsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/source/{sourceId}/commits": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "installation"
+                ],
+                "summary": "List user installation commits",
+                "operationId": "15",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Source Id",
+                        "name": "sourceId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Branch",
+                        "name": "branch",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of commits per page",
+                        "name": "perPage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number to fetch",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Follow pages from page until there are no more pages or max pages reached",
+                        "name": "all",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ListCommitsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/user/installations": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "installation"
+                ],
+                "summary": "List user installations",
+                "operationId": "13",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Source, the same as source of session by default",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Sync installations from source instead of returning stored ones",
+                        "name": "refresh",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/v1/webhook/github": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Receive event delivered by webhook of github app",
+                "operationId": "55",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of event",
+                        "name": "X-GitHub-Event",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Id of delivery",
+                        "name": "X-GitHub-Delivery",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "HMAC-SHA256 of payload signed with webhook secret",
+                        "name": "X-Hub-Signature-256",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Host of GitHub Enterprise Server",
+                        "name": "X-GitHub-Enterprise-Host",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.WebhookResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/orgs": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organization"
+                ],
+                "summary": "List organizations",
+                "operationId": "26",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma separated related resources to embed, projects, projCount or source",
+                        "name": "expand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields of organization to return",
+                        "name": "fields",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ListOrgResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organization"
+                ],
+                "summary": "Create organization",
+                "operationId": "17",
+                "parameters": [
+                    {
+                        "description": "Organization",
+                        "name": "org",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.CreateOrgV2Request"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay response of retried request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/controller.GetOrgResponse"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "/v2/orgs/{orgId}"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/orgs/{orgId}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organization"
+                ],
+                "summary": "Get organization",
+                "operationId": "27",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organization Id",
+                        "name": "orgId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.GetOrgResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "organization"
+                ],
+                "summary": "Delete organization",
+                "operationId": "19",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organization Id",
+                        "name": "orgId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "organization"
+                ],
+                "summary": "Update organization partially",
+                "operationId": "18",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organization Id",
+                        "name": "orgId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "JSON Merge Patch of organization",
+                        "name": "org",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.UpdateOrgRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.GetOrgResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/pipeline/templates": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pipeline"
+                ],
+                "summary": "List pipeline templates",
+                "operationId": "33",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ListPipelineTemplateResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/projects": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "List projects",
+                "operationId": "28",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Organization Id",
+                        "name": "orgId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated related resources to embed, source only",
+                        "name": "expand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields of project to return",
+                        "name": "fields",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ListProjResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "Create project",
+                "operationId": "20",
+                "parameters": [
+                    {
+                        "description": "Project",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.CreateProjV2Request"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay response of retried request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/controller.GetProjResponse"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "/v2/projects/{projId}"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/projects/{projId}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "Get project",
+                "operationId": "29",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project Id",
+                        "name": "projId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.GetProjResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "project"
+                ],
+                "summary": "Delete project",
+                "operationId": "22",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project Id",
+                        "name": "projId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "Update project partially",
+                "operationId": "21",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project Id",
+                        "name": "projId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "JSON Merge Patch of project",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.UpdateProjRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.GetProjResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/projects/{projId}/source": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "source"
+                ],
+                "summary": "Create source of project",
+                "operationId": "23",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project Id",
+                        "name": "projId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Source",
+                        "name": "source",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.CreateSourceRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to replay response of retried request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/controller.GetSourceResponse"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "/v2/sources/{sourceId}"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/sources/{sourceId}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "source"
+                ],
+                "summary": "Get source",
+                "operationId": "24",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Source Id",
+                        "name": "sourceId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.GetSourceResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "source"
+                ],
+                "summary": "Delete source",
+                "operationId": "25",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Source Id",
+                        "name": "sourceId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/v2/sources/{sourceId}/branches": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "source"
+                ],
+                "summary": "List branches and tags of source",
+                "operationId": "31",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Source Id",
+                        "name": "sourceId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of branches and tags per page",
+                        "name": "perPage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number to fetch",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number of branches to fetch, page is used if missing",
+                        "name": "branchPage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number of tags to fetch, page is used if missing",
+                        "name": "tagPage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Follow pages from page until there are no more pages or max pages reached",
+                        "name": "all",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ListBranchesAndTagsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/sources/{sourceId}/commits": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "source"
+                ],
+                "summary": "List commits of source",
+                "operationId": "30",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Source Id",
+                        "name": "sourceId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Branch",
+                        "name": "branch",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of commits per page",
+                        "name": "perPage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number to fetch",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Follow pages from page until there are no more pages or max pages reached",
+                        "name": "all",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controller.ListCommitsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/user/installations": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "installation"
+                ],
+                "summary": "List user installations",
+                "operationId": "32",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Source, the same as source of session by default",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Sync installations from source instead of returning stored ones",
+                        "name": "refresh",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": ""
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "controller.Artifact": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.Commit": {
+            "type": "object",
+            "properties": {
+                "artifact": {
+                    "$ref": "#/definitions/controller.Artifact"
+                },
+                "committer": {
+                    "type": "string"
+                },
+                "committerUrl": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.CreateOrgResponse": {
+            "type": "object",
+            "properties": {
+                "orgId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controller.CreateOrgV2Request": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.CreatePersonalTokenRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expiredAt": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "controller.CreatePersonalTokenResponse": {
+            "type": "object",
+            "properties": {
+                "personalToken": {
+                    "$ref": "#/definitions/controller.PersonalToken"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.CreateProjRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "orgId"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "orgId": {
+                    "type": "integer"
+                },
+                "orgName": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.CreateProjResponse": {
+            "type": "object",
+            "properties": {
+                "orgId": {
+                    "type": "integer"
+                },
+                "projId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controller.CreateProjV2Request": {
+            "type": "object",
+            "required": [
+                "name",
+                "orgId"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "orgId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controller.CreateSourceRequest": {
+            "type": "object",
+            "required": [
+                "repository",
+                "type"
+            ],
+            "properties": {
+                "host": {
+                    "type": "string"
+                },
+                "repository": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.CreateSourceResponse": {
+            "type": "object",
+            "properties": {
+                "projId": {
+                    "type": "integer"
+                },
+                "sourceId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controller.DeleteOrgResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "controller.DeleteProjResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "controller.DeleteUserResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "controller.GetOrgResponse": {
+            "type": "object",
+            "properties": {
+                "org": {
+                    "$ref": "#/definitions/controller.Org"
+                }
+            }
+        },
+        "controller.GetProjResponse": {
+            "type": "object",
+            "properties": {
+                "proj": {
+                    "$ref": "#/definitions/controller.Proj"
+                }
+            }
+        },
+        "controller.GetSessionResponse": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "type": "string"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "source": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "controller.GetSourceResponse": {
+            "type": "object",
+            "properties": {
+                "source": {
+                    "$ref": "#/definitions/controller.Source"
+                }
+            }
+        },
+        "controller.GetUserResponse": {
+            "type": "object",
+            "properties": {
+                "user": {
+                    "$ref": "#/definitions/controller.User"
+                }
+            }
+        },
+        "controller.InviteMemberRequest": {
+            "type": "object",
+            "required": [
+                "login",
+                "role",
+                "type"
+            ],
+            "properties": {
+                "login": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.InviteMemberResponse": {
+            "type": "object",
+            "properties": {
+                "member": {
+                    "$ref": "#/definitions/controller.Member"
+                }
+            }
+        },
+        "controller.LinkIdentityResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "controller.LinkLocalIdentityRequest": {
+            "type": "object",
+            "required": [
+                "login",
+                "password"
+            ],
+            "properties": {
+                "currentPassword": {
+                    "description": "CurrentPassword is required if local identity was linked already",
+                    "type": "string"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.ListBranchesAndTagsResponse": {
+            "type": "object",
+            "properties": {
+                "branchPagination": {
+                    "$ref": "#/definitions/controller.Pagination"
+                },
+                "branches": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tagPagination": {
+                    "$ref": "#/definitions/controller.Pagination"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "controller.ListCommitsResponse": {
+            "type": "object",
+            "properties": {
+                "commits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controller.Commit"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/controller.Pagination"
+                }
+            }
+        },
+        "controller.ListMemberResponse": {
+            "type": "object",
+            "properties": {
+                "memberList": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controller.Member"
+                    }
+                }
+            }
+        },
+        "controller.ListOrgResponse": {
+            "type": "object",
+            "properties": {
+                "orgList": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controller.Org"
+                    }
+                }
+            }
+        },
+        "controller.ListPersonalTokenResponse": {
+            "type": "object",
+            "properties": {
+                "tokenList": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controller.PersonalToken"
+                    }
+                }
+            }
+        },
+        "controller.ListPipelineTemplateResponse": {
+            "type": "object",
+            "properties": {
+                "templateList": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controller.PipelineTemplate"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "controller.CreateOrgResponse": {
+        },
+        "controller.ListProjResponse": {
             "type": "object",
             "properties": {
-                "orgId": {
-                    "type": "integer"
+                "projList": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controller.Proj"
+                    }
                 }
             }
         },
-        "controller.CreateProjRequest": {
+        "controller.LoginRequest": {
             "type": "object",
+            "required": [
+                "login",
+                "password"
+            ],
             "properties": {
-                "name": {
+                "login": {
                     "type": "string"
                 },
-                "orgId": {
-                    "type": "integer"
+                "password": {
+                    "type": "string"
                 }
             }
         },
-        "controller.CreateProjResponse": {
+        "controller.LoginResponse": {
             "type": "object",
             "properties": {
-                "orgId": {
-                    "type": "integer"
-                },
-                "projId": {
+                "userId": {
                     "type": "integer"
                 }
             }
         },
-        "controller.CreateSourceRequest": {
+        "controller.Member": {
             "type": "object",
             "properties": {
-                "repository": {
-                    "type": "string"
-                },
-                "type": {
-                    "type": "string"
+                "meta": {
+                    "$ref": "#/definitions/repository.Member"
                 }
             }
         },
-        "controller.CreateSourceResponse": {
+        "controller.Org": {
             "type": "object",
             "properties": {
-                "projId": {
-                    "type": "integer"
+                "meta": {
+                    "$ref": "#/definitions/repository.Org"
                 },
-                "sourceId": {
+                "projCount": {
                     "type": "integer"
+                },
+                "projIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "projList": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controller.Proj"
+                    }
                 }
             }
         },
-        "controller.DeleteOrgResponse": {
+        "controller.Pagination": {
             "type": "object",
             "properties": {
-                "status": {
-                    "type": "boolean"
+                "lastPage": {
+                    "type": "integer"
+                },
+                "nextPage": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "perPage": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
                 }
             }
         },
-        "controller.DeleteProjResponse": {
+        "controller.PersonalToken": {
             "type": "object",
             "properties": {
-                "status": {
-                    "type": "boolean"
+                "meta": {
+                    "$ref": "#/definitions/repository.PersonalToken"
                 }
             }
         },
-        "controller.GetOrgResponse": {
+        "controller.PipelineTemplate": {
             "type": "object",
             "properties": {
-                "org": {
-                    "$ref": "#/definitions/controller.Org"
+                "meta": {
+                    "$ref": "#/definitions/repository.PipelineTemplate"
                 }
             }
         },
-        "controller.GetProjResponse": {
+        "controller.Proj": {
             "type": "object",
             "properties": {
-                "proj": {
-                    "$ref": "#/definitions/controller.Proj"
+                "meta": {
+                    "$ref": "#/definitions/repository.Proj"
                 }
             }
         },
-        "controller.ListOrgResponse": {
+        "controller.RemoveMemberResponse": {
             "type": "object",
             "properties": {
-                "orgList": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/controller.Org"
-                    }
+                "status": {
+                    "type": "boolean"
                 }
             }
         },
-        "controller.ListPipelineTemplateResponse": {
+        "controller.RevokePersonalTokenResponse": {
             "type": "object",
             "properties": {
-                "templateList": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/controller.PipelineTemplate"
-                    }
+                "status": {
+                    "type": "boolean"
                 }
             }
         },
-        "controller.ListProjResponse": {
+        "controller.Source": {
             "type": "object",
             "properties": {
-                "projList": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/controller.Proj"
-                    }
+                "meta": {
+                    "$ref": "#/definitions/repository.Source"
                 }
             }
         },
-        "controller.Org": {
+        "controller.UnlinkIdentityResponse": {
             "type": "object",
             "properties": {
-                "meta": {
-                    "$ref": "#/definitions/repository.Org"
-                },
-                "projIds": {
-                    "type": "array",
-                    "items": {
-                        "type": "integer"
-                    }
+                "status": {
+                    "type": "boolean"
                 }
             }
         },
-        "controller.PipelineTemplate": {
+        "controller.UpdateMemberRequest": {
             "type": "object",
+            "required": [
+                "role"
+            ],
             "properties": {
-                "meta": {
-                    "$ref": "#/definitions/repository.PipelineTemplate"
+                "role": {
+                    "type": "string"
                 }
             }
         },
-        "controller.Proj": {
+        "controller.UpdateMemberResponse": {
             "type": "object",
             "properties": {
-                "meta": {
-                    "$ref": "#/definitions/repository.Proj"
+                "member": {
+                    "$ref": "#/definitions/controller.Member"
                 }
             }
         },
         "controller.UpdateOrgRequest": {
             "type": "object",
+            "required": [
+                "name"
+            ],
             "properties": {
                 "name": {
                     "type": "string"
@@ -716,6 +2420,9 @@ var doc = `{
         },
         "controller.UpdateProjRequest": {
             "type": "object",
+            "required": [
+                "name"
+            ],
             "properties": {
                 "name": {
                     "type": "string"
@@ -730,6 +2437,97 @@ var doc = `{
                 }
             }
         },
+        "controller.User": {
+            "type": "object",
+            "properties": {
+                "meta": {
+                    "$ref": "#/definitions/repository.User"
+                }
+            }
+        },
+        "controller.WebhookResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "delivery": {
+                    "type": "string"
+                },
+                "duplicate": {
+                    "type": "boolean"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "oauth.RevokeTokenResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "repository.Identity": {
+            "type": "object",
+            "properties": {
+                "avatarUrl": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repository.Member": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "orgId": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
         "repository.Org": {
             "type": "object",
             "properties": {
@@ -747,6 +2545,38 @@ var doc = `{
                 }
             }
         },
+        "repository.PersonalToken": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "expiredAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastUsedAt": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
         "repository.PipelineTemplate": {
             "type": "object",
             "properties": {
@@ -785,6 +2615,9 @@ var doc = `{
                 "orgId": {
                     "type": "integer"
                 },
+                "orgName": {
+                    "type": "string"
+                },
                 "source": {
                     "$ref": "#/definitions/repository.Source"
                 },
@@ -799,6 +2632,12 @@ var doc = `{
                 "createdAt": {
                     "type": "string"
                 },
+                "defaultBranch": {
+                    "type": "string"
+                },
+                "host": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -808,6 +2647,13 @@ var doc = `{
                 "repository": {
                     "type": "string"
                 },
+                "repositoryId": {
+                    "description": "id of repository in remote code repository, which is kept after repository renamed",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
                 "type": {
                     "type": "string"
                 },
@@ -818,6 +2664,35 @@ var doc = `{
                     "type": "string"
                 }
             }
+        },
+        "repository.User": {
+            "type": "object",
+            "properties": {
+                "avatarUrl": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "identities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.Identity"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
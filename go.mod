@@ -7,19 +7,28 @@ require (
 	github.com/alecthomas/template v0.0.0-20190718012654-fb15b899a751
 	github.com/bradleyfalzon/ghinstallation v1.1.1 // indirect
 	github.com/gin-gonic/gin v1.7.2
-	github.com/golang-jwt/jwt v3.2.2+incompatible // indirect
+	github.com/go-playground/validator/v10 v10.4.1
+	github.com/golang-jwt/jwt v3.2.2+incompatible
 	github.com/google/go-github/v39 v39.1.0
 	github.com/google/uuid v1.2.0
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.5.0
+	github.com/prometheus/client_golang v1.10.0
+	github.com/prometheus/client_golang v1.10.0
 	github.com/rookie-ninja/rk-boot v1.2.5
 	github.com/rookie-ninja/rk-common v1.2.1
 	github.com/rookie-ninja/rk-entry v1.0.3
 	github.com/rookie-ninja/rk-gin v1.2.4
+	github.com/rookie-ninja/rk-grpc v1.2.5
 	github.com/rookie-ninja/rk-query v1.2.4
 	github.com/stretchr/testify v1.7.0
 	github.com/swaggo/swag v1.7.0
 	github.com/webview/webview v0.0.0-20210330151455-f540d88dde4e // indirect
 	go.uber.org/zap v1.16.0
+	golang.org/x/crypto v0.0.0-20210817164053-32db794688a5
 	golang.org/x/oauth2 v0.0.0-20211005180243-6b3c2da341f1
+	google.golang.org/genproto v0.0.0-20210617175327-b9e0b3197ced
+	google.golang.org/grpc v1.38.0
+	google.golang.org/protobuf v1.26.0
 	gorm.io/driver/mysql v1.1.2
 	gorm.io/gorm v1.21.15
 )
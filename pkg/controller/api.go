@@ -7,16 +7,43 @@ package controller
 
 import (
 	"errors"
-	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/pointgoal/workstation/pkg/repository"
-	"github.com/pointgoal/workstation/pkg/utils"
 	"github.com/rookie-ninja/rk-common/common"
-	"github.com/rookie-ninja/rk-common/error"
 	"github.com/rookie-ninja/rk-gin/boot"
 	"net/http"
+	"time"
 )
 
+// Register interceptors into GinEntry, this must be called before any API was registered,
+// otherwise, interceptors won't be applied to APIs registered before.
+func initInterceptors() {
+	ginEntry := rkgin.GetGinEntry("workstation")
+	if ginEntry == nil {
+		return
+	}
+
+	// routes of prometheus are registered on the same router while bootstrapping GinEntry, scrapers carry no session
+	if controller := GetController(); controller != nil && ginEntry.PromEntry != nil &&
+		!contains(controller.AuthIgnorePrefix, ginEntry.PromEntry.Path) {
+		controller.AuthIgnorePrefix = append(controller.AuthIgnorePrefix, ginEntry.PromEntry.Path)
+	}
+
+	ginEntry.Router.Use(ErrorInterceptor(), AuthInterceptor())
+}
+
+// Register collectors into prometheus of GinEntry, so that they are exposed together with metrics of APIs.
+// Nothing will happen if prometheus of GinEntry is not enabled.
+func initMetrics() {
+	ginEntry := rkgin.GetGinEntry("workstation")
+	if ginEntry == nil || ginEntry.PromEntry == nil {
+		return
+	}
+
+	// collectors might be registered already if controller is registered again
+	ginEntry.PromEntry.RegisterCollectors(githubCollectors()...)
+}
+
 func initApi() {
 	var ginEntry *rkgin.GinEntry
 
@@ -24,22 +51,45 @@ func initApi() {
 		rkcommon.ShutdownWithError(errors.New("nil GinEntry"))
 	}
 
+	// Session
+	ginEntry.Router.GET("/v1/session", GetCurrentSession)
+	ginEntry.Router.DELETE("/v1/session", DeleteSession)
+	ginEntry.Router.POST("/v1/session/login", Login)
+
+	// User
+	ginEntry.Router.GET("/v1/me", GetMe)
+	ginEntry.Router.DELETE("/v1/me", DeleteMe)
+	ginEntry.Router.PUT("/v1/me/identities/local", LinkLocalIdentity)
+	ginEntry.Router.DELETE("/v1/me/identities/:type", UnlinkIdentity)
+
+	// Personal token
+	ginEntry.Router.GET("/v1/me/tokens", ListPersonalToken)
+	ginEntry.Router.PUT("/v1/me/tokens", CreatePersonalToken)
+	ginEntry.Router.POST("/v1/me/tokens/:tokenId/rotate", RotatePersonalToken)
+	ginEntry.Router.DELETE("/v1/me/tokens/:tokenId", RevokePersonalToken)
+
 	// Organization
 	ginEntry.Router.GET("/v1/org", ListOrg)
 	ginEntry.Router.GET("/v1/org/:orgId", GetOrg)
-	ginEntry.Router.PUT("/v1/org", CreateOrg)
+	ginEntry.Router.PUT("/v1/org", IdempotencyInterceptor(), CreateOrg)
 	ginEntry.Router.DELETE("/v1/org/:orgId", DeleteOrg)
 	ginEntry.Router.POST("/v1/org/:orgId", UpdateOrg)
 
+	// Member
+	ginEntry.Router.GET("/v1/org/:orgId/members", ListMember)
+	ginEntry.Router.PUT("/v1/org/:orgId/members", InviteMember)
+	ginEntry.Router.POST("/v1/org/:orgId/members/:userId", UpdateMember)
+	ginEntry.Router.DELETE("/v1/org/:orgId/members/:userId", RemoveMember)
+
 	// Project
 	ginEntry.Router.GET("/v1/proj", ListProj)
 	ginEntry.Router.GET("/v1/proj/:projId", GetProj)
-	ginEntry.Router.PUT("/v1/proj", CreateProj)
+	ginEntry.Router.PUT("/v1/proj", IdempotencyInterceptor(), CreateProj)
 	ginEntry.Router.DELETE("/v1/proj/:projId", DeleteProj)
 	ginEntry.Router.POST("/v1/proj/:projId", UpdateProj)
 
 	// Source
-	ginEntry.Router.PUT("/v1/source", CreateSource)
+	ginEntry.Router.PUT("/v1/source", IdempotencyInterceptor(), CreateSource)
 	ginEntry.Router.DELETE("/v1/source/:sourceId", DeleteSource)
 
 	// Installations
@@ -49,27 +99,12 @@ func initApi() {
 
 	// Pipeline templates
 	ginEntry.Router.GET("/v1/pipeline/template", ListPipelineTemplate)
-}
-
-func makeInternalError(ctx *gin.Context, message string, details ...interface{}) {
-	ctx.JSON(http.StatusInternalServerError, rkerror.New(
-		rkerror.WithHttpCode(http.StatusInternalServerError),
-		rkerror.WithMessage(message),
-		rkerror.WithDetails(details...)))
-}
 
-func makeNotFoundError(ctx *gin.Context, message string, details ...interface{}) {
-	ctx.JSON(http.StatusNotFound, rkerror.New(
-		rkerror.WithHttpCode(http.StatusNotFound),
-		rkerror.WithMessage(message),
-		rkerror.WithDetails(details...)))
-}
+	// Webhook
+	ginEntry.Router.POST("/v1/webhook/github", ReceiveGithubWebhook)
 
-func makeAlreadyExistError(ctx *gin.Context, message string, details ...interface{}) {
-	ctx.JSON(http.StatusConflict, rkerror.New(
-		rkerror.WithHttpCode(http.StatusConflict),
-		rkerror.WithMessage(message),
-		rkerror.WithDetails(details...)))
+	// v2
+	initApiV2(ginEntry)
 }
 
 func convertOrg(orgFromRepo *repository.Org, projFromRepo []*repository.Proj) *Org {
@@ -93,6 +128,12 @@ func convertProj(projFromRepo *repository.Proj) *Proj {
 	return proj
 }
 
+func convertUser(userFromRepo *repository.User) *User {
+	return &User{
+		Meta: userFromRepo,
+	}
+}
+
 func convertPipelineTemplate(templateFromRepo *repository.PipelineTemplate) *PipelineTemplate {
 	template := &PipelineTemplate{
 		Meta: templateFromRepo,
@@ -101,6 +142,337 @@ func convertPipelineTemplate(templateFromRepo *repository.PipelineTemplate) *Pip
 	return template
 }
 
+// ********************************************* //
+// ************** Session related ************** //
+// ********************************************* //
+
+// GetCurrentSession
+// @Summary Get current session
+// @Id 34
+// @version 1.0
+// @Tags session
+// @produce application/json
+// @Success 200 {object} GetSessionResponse
+// @Router /v1/session [get]
+func GetCurrentSession(ctx *gin.Context) {
+	session, ok := mustGetSession(ctx)
+	if !ok {
+		return
+	}
+
+	// personal tokens without expiration never expire
+	expiresAt := time.Time{}
+	if session.ExpiresAt > 0 {
+		expiresAt = time.Unix(session.ExpiresAt, 0)
+	}
+
+	ctx.JSON(http.StatusOK, &GetSessionResponse{
+		UserId:    session.UserId(),
+		Source:    session.Source,
+		Login:     session.Login,
+		ExpiresAt: expiresAt,
+		Scopes:    session.Scopes,
+	})
+}
+
+// DeleteSession
+// @Summary Logout by removing session cookie
+// @Id 35
+// @version 1.0
+// @Tags session
+// @produce application/json
+// @Success 204
+// @Router /v1/session [delete]
+func DeleteSession(ctx *gin.Context) {
+	clearSession(ctx)
+	ctx.Status(http.StatusNoContent)
+}
+
+// Login
+// @Summary Sign in with local identity
+// @Id 36
+// @version 1.0
+// @Tags session
+// @produce application/json
+// @Param body body LoginRequest true "Login and password"
+// @Success 200 {object} LoginResponse
+// @Router /v1/session/login [post]
+func Login(ctx *gin.Context) {
+	controller := GetController()
+
+	// 1: bind request
+	req := &LoginRequest{}
+	if !bindJson(ctx, req) {
+		return
+	}
+
+	// 2: verify password
+	identity, err := controller.verifyLocalIdentity(req.Login, req.Password)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// 3: start session
+	if _, err := controller.StartSession(ctx, identity.UserId, identity.Type, identity.Login); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &LoginResponse{
+		UserId: identity.UserId,
+	})
+}
+
+// ****************************************** //
+// ************** User related ************** //
+// ****************************************** //
+
+// GetMe
+// @Summary Get profile and linked identities of current user
+// @Id 37
+// @version 1.0
+// @Tags user
+// @produce application/json
+// @Success 200 {object} GetUserResponse
+// @Router /v1/me [get]
+func GetMe(ctx *gin.Context) {
+	controller := GetController()
+
+	session, ok := mustGetSession(ctx)
+	if !ok {
+		return
+	}
+
+	user, err := controller.getUser(session.UserId())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &GetUserResponse{
+		User: user,
+	})
+}
+
+// DeleteMe
+// @Summary Delete current user together with linked identities and access tokens
+// @Id 38
+// @version 1.0
+// @Tags user
+// @produce application/json
+// @Success 200 {object} DeleteUserResponse
+// @Router /v1/me [delete]
+func DeleteMe(ctx *gin.Context) {
+	controller := GetController()
+
+	session, ok := mustGetSession(ctx)
+	if !ok {
+		return
+	}
+
+	if err := controller.deleteUser(session.UserId()); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	clearSession(ctx)
+	ctx.JSON(http.StatusOK, &DeleteUserResponse{
+		Status: true,
+	})
+}
+
+// LinkLocalIdentity
+// @Summary Link local identity to current user or update password of it
+// @Id 39
+// @version 1.0
+// @Tags user
+// @produce application/json
+// @Param body body LinkLocalIdentityRequest true "Login, password and current password if linked already"
+// @Success 200 {object} LinkIdentityResponse
+// @Router /v1/me/identities/local [put]
+func LinkLocalIdentity(ctx *gin.Context) {
+	controller := GetController()
+
+	// 1: bind request
+	req := &LinkLocalIdentityRequest{}
+	session, ok := mustGetSession(ctx)
+	if !ok || !bindJson(ctx, req) {
+		return
+	}
+
+	// 2: personal tokens could not set password, which would sign in sessions without limits of scopes
+	if session.Scopes != nil {
+		ctx.Error(repository.NewPermissionDeniedf("local identity could not be linked with personal token"))
+		return
+	}
+
+	// 3: link identity
+	if err := controller.linkLocalIdentity(session.UserId(), req.Login, req.Password, req.CurrentPassword); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &LinkIdentityResponse{
+		Status: true,
+	})
+}
+
+// UnlinkIdentity
+// @Summary Unlink identity from current user, access token of the identity would be removed
+// @Id 41
+// @version 1.0
+// @Tags user
+// @produce application/json
+// @Param type path string true "Identity type, github, gitlab, local or name of OpenID Connect provider"
+// @Success 200 {object} UnlinkIdentityResponse
+// @Router /v1/me/identities/{type} [delete]
+func UnlinkIdentity(ctx *gin.Context) {
+	controller := GetController()
+
+	// 1: bind request
+	req := &IdentityTypeRequest{}
+	session, ok := mustGetSession(ctx)
+	if !ok || !bindUri(ctx, req) {
+		return
+	}
+
+	// 2: unlink identity
+	if err := controller.unlinkIdentity(session.UserId(), req.Type); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &UnlinkIdentityResponse{
+		Status: true,
+	})
+}
+
+// *************************************************** //
+// ************** PersonalToken related ************** //
+// *************************************************** //
+
+// ListPersonalToken
+// @Summary List personal tokens of current user
+// @Id 48
+// @version 1.0
+// @Tags user
+// @produce application/json
+// @Success 200 {object} ListPersonalTokenResponse
+// @Router /v1/me/tokens [get]
+func ListPersonalToken(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	tokenList, err := controller.listPersonalToken(userId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &ListPersonalTokenResponse{
+		TokenList: tokenList,
+	})
+}
+
+// CreatePersonalToken
+// @Summary Create personal token for CLI and automation, the token is returned only once
+// @Id 49
+// @version 1.0
+// @Tags user
+// @produce application/json
+// @Param body body CreatePersonalTokenRequest true "Name, scopes and expiration"
+// @Success 200 {object} CreatePersonalTokenResponse
+// @Router /v1/me/tokens [put]
+func CreatePersonalToken(ctx *gin.Context) {
+	controller := GetController()
+
+	// 1: bind request
+	req := &CreatePersonalTokenRequest{}
+	userId, ok := mustGetUserId(ctx)
+	if !ok || !bindJson(ctx, req) {
+		return
+	}
+
+	// 2: create token
+	token, raw, err := controller.createPersonalToken(userId, req.Name, req.Scopes, req.ExpiredAt)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &CreatePersonalTokenResponse{
+		PersonalToken: token,
+		Token:         raw,
+	})
+}
+
+// RotatePersonalToken
+// @Summary Replace personal token with a new one, the old token stops working immediately
+// @Id 50
+// @version 1.0
+// @Tags user
+// @produce application/json
+// @Param tokenId path int true "Personal token id"
+// @Success 200 {object} CreatePersonalTokenResponse
+// @Router /v1/me/tokens/{tokenId}/rotate [post]
+func RotatePersonalToken(ctx *gin.Context) {
+	controller := GetController()
+
+	// 1: bind request
+	req := &PersonalTokenIdRequest{}
+	userId, ok := mustGetUserId(ctx)
+	if !ok || !bindUri(ctx, req) {
+		return
+	}
+
+	// 2: rotate token
+	token, raw, err := controller.rotatePersonalToken(userId, req.TokenId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &CreatePersonalTokenResponse{
+		PersonalToken: token,
+		Token:         raw,
+	})
+}
+
+// RevokePersonalToken
+// @Summary Revoke personal token
+// @Id 51
+// @version 1.0
+// @Tags user
+// @produce application/json
+// @Param tokenId path int true "Personal token id"
+// @Success 200 {object} RevokePersonalTokenResponse
+// @Router /v1/me/tokens/{tokenId} [delete]
+func RevokePersonalToken(ctx *gin.Context) {
+	controller := GetController()
+
+	// 1: bind request
+	req := &PersonalTokenIdRequest{}
+	userId, ok := mustGetUserId(ctx)
+	if !ok || !bindUri(ctx, req) {
+		return
+	}
+
+	// 2: revoke token
+	if err := controller.revokePersonalToken(userId, req.TokenId); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &RevokePersonalTokenResponse{
+		Status: true,
+	})
+}
+
 // ************************************************** //
 // ************** Organization related ************** //
 // ************************************************** //
@@ -111,33 +483,34 @@ func convertPipelineTemplate(templateFromRepo *repository.PipelineTemplate) *Pip
 // @version 1.0
 // @Tags organization
 // @produce application/json
+// @Param expand query string false "Comma separated related resources to embed, projects, projCount or source"
+// @Param fields query string false "Comma separated fields of organization to return"
 // @Success 200 {object} ListOrgResponse
 // @Router /v1/org [get]
 func ListOrg(ctx *gin.Context) {
 	controller := GetController()
-	orgList := make([]*Org, 0)
 
-	// 1: list organization
-	orgListFromRepo, err := controller.Repo.ListOrg()
-	if err != nil {
-		makeInternalError(ctx, "failed to list organizations", err)
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	req := &ListOrgRequest{}
+	if !bindQuery(ctx, req) {
 		return
 	}
 
-	// 2: list projects
-	for i := range orgListFromRepo {
-		projListFromRepo, err := controller.Repo.ListProj(orgListFromRepo[i].Id)
-		if err != nil {
-			makeInternalError(ctx, fmt.Sprintf("failed to list projects with orgId:%d.", orgListFromRepo[i].Id), err)
-			return
-		}
-		// convert to API model
-		orgList = append(orgList, convertOrg(orgListFromRepo[i], projListFromRepo))
+	// 2: list organizations with projects
+	orgList, err := controller.listOrg(userId, splitCsv(req.Expand)...)
+	if err != nil {
+		ctx.Error(err)
+		return
 	}
 
-	ctx.JSON(http.StatusOK, &ListOrgResponse{
+	renderList(ctx, &ListOrgResponse{
 		OrgList: orgList,
-	})
+	}, req.Fields)
 }
 
 // GetOrg
@@ -151,23 +524,27 @@ func ListOrg(ctx *gin.Context) {
 // @Router /v1/org/{orgId} [get]
 func GetOrg(ctx *gin.Context) {
 	controller := GetController()
-	orgId := utils.ToInt(ctx.Param("orgId"))
 
-	// 1: get organization from repo
-	orgFromRepo, ok := isOrgExist(ctx, controller, orgId)
-	if !ok || orgFromRepo == nil {
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	req := &OrgIdRequest{}
+	if !bindUri(ctx, req) {
 		return
 	}
 
-	// 2: list projects from repo
-	projListFromRepo, err := controller.Repo.ListProj(orgFromRepo.Id)
+	// 2: get organization with projects
+	org, err := controller.getOrg(userId, req.OrgId)
 	if err != nil {
-		makeInternalError(ctx, fmt.Sprintf("failed to list projects from repository with orgId:%d.", orgFromRepo.Id), err)
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, &GetOrgResponse{
-		Org: convertOrg(orgFromRepo, projListFromRepo),
+		Org: org,
 	})
 }
 
@@ -178,21 +555,32 @@ func GetOrg(ctx *gin.Context) {
 // @Tags organization
 // @produce application/json
 // @Param orgName query string true "Organization name"
+// @Param Idempotency-Key header string false "Key to replay response of retried request"
 // @Success 200 {object} CreateOrgResponse
 // @Router /v1/org [put]
 func CreateOrg(ctx *gin.Context) {
 	controller := GetController()
-	name := ctx.Query("orgName")
-	orgForRepo := repository.NewOrg(name)
 
-	_, err := controller.Repo.CreateOrg(orgForRepo)
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	req := &CreateOrgRequest{}
+	if !bindQuery(ctx, req) {
+		return
+	}
+
+	// 2: create organization
+	org, err := controller.createOrg(userId, req.OrgName)
 	if err != nil {
-		makeInternalError(ctx, fmt.Sprintf("failed to create organization with name:%s", name), err)
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, &CreateOrgResponse{
-		OrgId: orgForRepo.Id,
+		OrgId: org.Meta.Id,
 	})
 }
 
@@ -207,35 +595,26 @@ func CreateOrg(ctx *gin.Context) {
 // @Router /v1/org/{orgId} [delete]
 func DeleteOrg(ctx *gin.Context) {
 	controller := GetController()
-	orgId := utils.ToInt(ctx.Param("orgId"))
 
-	// 1: get organization first
-	if _, ok := isOrgExist(ctx, controller, orgId); !ok {
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
 		return
 	}
 
-	// 2: list projects from org
-	projListFromRepo, err := controller.Repo.ListProj(orgId)
-	if err != nil {
-		makeInternalError(ctx, fmt.Sprintf("failed to list porojects with orgId:%d", orgId), err)
-		return
-	}
-	if len(projListFromRepo) > 0 {
-		ctx.JSON(http.StatusForbidden, rkerror.New(
-			rkerror.WithHttpCode(http.StatusForbidden),
-			rkerror.WithMessage("organization is not empty, please delete or migrate projects to another organization first.")))
+	// 1: bind request
+	req := &OrgIdRequest{}
+	if !bindUri(ctx, req) {
 		return
 	}
 
 	// 2: remove organization
-	succ, err := controller.Repo.RemoveOrg(orgId)
-	if err != nil {
-		makeInternalError(ctx, fmt.Sprintf("Failed to delete organization with id:%d", orgId), err)
+	if err := controller.deleteOrg(userId, req.OrgId); err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, &DeleteOrgResponse{
-		Status: succ,
+		Status: true,
 	})
 }
 
@@ -252,35 +631,175 @@ func DeleteOrg(ctx *gin.Context) {
 func UpdateOrg(ctx *gin.Context) {
 	controller := GetController()
 
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
 	// 1: bind request
+	path := &OrgIdRequest{}
 	req := &UpdateOrgRequest{}
-	if err := ctx.ShouldBind(req); err != nil {
-		ctx.JSON(http.StatusBadRequest, rkerror.New(
-			rkerror.WithHttpCode(http.StatusBadRequest),
-			rkerror.WithMessage("Invalid request"),
-			rkerror.WithDetails(err)))
+	if !bindUri(ctx, path) || !bindJson(ctx, req) {
 		return
 	}
 
-	// 2: get organization from repo
-	orgId := utils.ToInt(ctx.Param("orgId"))
-	org, ok := isOrgExist(ctx, controller, orgId)
+	// 2: update organization
+	if _, err := controller.updateOrg(userId, path.OrgId, req.Name); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &UpdateOrgResponse{
+		Status: true,
+	})
+}
+
+// ******************************************** //
+// ************** Member related ************** //
+// ******************************************** //
+
+// ListMember
+// @Summary List members of organization
+// @Id 42
+// @version 1.0
+// @Tags member
+// @produce application/json
+// @Param orgId path int true "Organization Id"
+// @Success 200 {object} ListMemberResponse
+// @Router /v1/org/{orgId}/members [get]
+func ListMember(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
 	if !ok {
 		return
 	}
 
-	// 3: replace fields
-	org.Name = req.Name
+	// 1: bind request
+	req := &OrgIdRequest{}
+	if !bindUri(ctx, req) {
+		return
+	}
 
-	// 4: update in repo
-	succ, err := controller.Repo.UpdateOrg(org)
+	// 2: list members
+	memberList, err := controller.listMember(userId, req.OrgId)
 	if err != nil {
-		makeInternalError(ctx, fmt.Sprintf("failed to update organization with orgId:%d", orgId), err)
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, &UpdateOrgResponse{
-		Status: succ,
+	ctx.JSON(http.StatusOK, &ListMemberResponse{
+		MemberList: memberList,
+	})
+}
+
+// InviteMember
+// @Summary Invite user with linked identity into organization
+// @Id 43
+// @version 1.0
+// @Tags member
+// @produce application/json
+// @Param orgId path int true "Organization Id"
+// @Param member body InviteMemberRequest true "Member"
+// @Success 200 {object} InviteMemberResponse
+// @Router /v1/org/{orgId}/members [put]
+func InviteMember(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	path := &OrgIdRequest{}
+	req := &InviteMemberRequest{}
+	if !bindUri(ctx, path) || !bindJson(ctx, req) {
+		return
+	}
+
+	// 2: invite member
+	member, err := controller.inviteMember(userId, path.OrgId, req.Type, req.Login, req.Role)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &InviteMemberResponse{
+		Member: member,
+	})
+}
+
+// UpdateMember
+// @Summary Change role of member
+// @Id 44
+// @version 1.0
+// @Tags member
+// @produce application/json
+// @Param orgId path int true "Organization Id"
+// @Param userId path int true "User Id"
+// @Param member body UpdateMemberRequest true "Member"
+// @Success 200 {object} UpdateMemberResponse
+// @Router /v1/org/{orgId}/members/{userId} [post]
+func UpdateMember(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	path := &MemberIdRequest{}
+	req := &UpdateMemberRequest{}
+	if !bindUri(ctx, path) || !bindJson(ctx, req) {
+		return
+	}
+
+	// 2: update role of member
+	member, err := controller.updateMemberRole(userId, path.OrgId, path.UserId, req.Role)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &UpdateMemberResponse{
+		Member: member,
+	})
+}
+
+// RemoveMember
+// @Summary Remove member from organization
+// @Id 45
+// @version 1.0
+// @Tags member
+// @produce application/json
+// @Param orgId path int true "Organization Id"
+// @Param userId path int true "User Id"
+// @Success 200 {object} RemoveMemberResponse
+// @Router /v1/org/{orgId}/members/{userId} [delete]
+func RemoveMember(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	req := &MemberIdRequest{}
+	if !bindUri(ctx, req) {
+		return
+	}
+
+	// 2: remove member
+	if err := controller.removeMember(userId, req.OrgId, req.UserId); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &RemoveMemberResponse{
+		Status: true,
 	})
 }
 
@@ -295,34 +814,41 @@ func UpdateOrg(ctx *gin.Context) {
 // @Tags project
 // @produce application/json
 // @Param orgId query int false "Organization Id"
+// @Param expand query string false "Comma separated related resources to embed, source only"
+// @Param fields query string false "Comma separated fields of project to return"
 // @Success 200 {object} ListProjResponse
 // @Router /v1/proj [get]
 func ListProj(ctx *gin.Context) {
-	projList := make([]*Proj, 0)
-
 	controller := GetController()
-	orgId := utils.ToInt(ctx.Query("orgId"))
 
-	// 1: list project from repo
-	projListFromRepo, err := controller.Repo.ListProj(orgId)
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	req := &ListProjRequest{}
+	if !bindQuery(ctx, req) {
+		return
+	}
+
+	// 2: list projects, all projects would be listed if orgId is missing
+	expand := splitCsv(req.Expand)
+	projList, err := controller.listProj(userId, req.OrgId, expand...)
 	if err != nil {
-		switch err.(type) {
-		case *repository.NotFound:
-			makeNotFoundError(ctx, fmt.Sprintf(repository.OrgNotFoundMsg, orgId))
-		default:
-			makeInternalError(ctx, fmt.Sprintf(repository.OrgFailedToGetMsg, orgId), err)
-		}
+		ctx.Error(err)
 		return
 	}
 
-	// 2: convert to API model
-	for i := range projListFromRepo {
-		projList = append(projList, convertProj(projListFromRepo[i]))
+	// embedded source should be returned even if not listed in fields
+	fields := req.Fields
+	if len(fields) > 0 && contains(expand, ExpandSource) {
+		fields += "," + ExpandSource
 	}
 
-	ctx.JSON(http.StatusOK, &ListProjResponse{
+	renderList(ctx, &ListProjResponse{
 		ProjList: projList,
-	})
+	}, fields)
 }
 
 // GetProj
@@ -337,16 +863,26 @@ func ListProj(ctx *gin.Context) {
 func GetProj(ctx *gin.Context) {
 	controller := GetController()
 
-	projId := utils.ToInt(ctx.Param("projId"))
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	req := &ProjIdRequest{}
+	if !bindUri(ctx, req) {
+		return
+	}
 
-	// 1: get project from repository
-	projFromRepo, ok := isProjExist(ctx, controller, projId)
-	if !ok || projFromRepo == nil {
+	// 2: get project from repository
+	proj, err := controller.getProj(userId, req.ProjId)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, &GetProjResponse{
-		Proj: convertProj(projFromRepo),
+		Proj: proj,
 	})
 }
 
@@ -357,39 +893,33 @@ func GetProj(ctx *gin.Context) {
 // @Tags project
 // @produce application/json
 // @Param project body CreateProjRequest true "Project"
+// @Param Idempotency-Key header string false "Key to replay response of retried request"
 // @Success 200 {object} CreateProjResponse
 // @Router /v1/proj [put]
 func CreateProj(ctx *gin.Context) {
 	controller := GetController()
-	// 1: bind request
-	req := &CreateProjRequest{}
-	if err := ctx.ShouldBind(req); err != nil {
-		ctx.JSON(http.StatusBadRequest, rkerror.New(
-			rkerror.WithHttpCode(http.StatusBadRequest),
-			rkerror.WithMessage(err.Error())))
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
 		return
 	}
 
-	fmt.Println(req)
-
-	// 2: get organization from repository
-	if _, ok := isOrgExist(ctx, controller, req.OrgId); !ok {
+	// 1: bind request
+	req := &CreateProjRequest{}
+	if !bindJson(ctx, req) {
 		return
 	}
 
-	// 3: create project
-	proj := repository.NewProj(req.Name)
-	proj.OrgId = req.OrgId
-	proj.OrgName = req.OrgName
-	_, err := controller.Repo.CreateProj(proj)
+	// 2: create project
+	proj, err := controller.createProj(userId, req.OrgId, req.OrgName, req.Name)
 	if err != nil {
-		makeInternalError(ctx, fmt.Sprintf("failed to create project with orgId:%d", req.OrgId), err)
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, &CreateProjResponse{
 		OrgId:  req.OrgId,
-		ProjId: proj.Id,
+		ProjId: proj.Meta.Id,
 	})
 }
 
@@ -405,22 +935,25 @@ func CreateProj(ctx *gin.Context) {
 func DeleteProj(ctx *gin.Context) {
 	controller := GetController()
 
-	projId := utils.ToInt(ctx.Param("projId"))
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
 
-	// 1: remove project
-	succ, err := controller.Repo.RemoveProj(projId)
-	if err != nil {
-		switch err.(type) {
-		case *repository.NotFound:
-			makeNotFoundError(ctx, fmt.Sprintf(repository.ProjNotFoundMsg, projId), err)
-		default:
-			makeInternalError(ctx, fmt.Sprintf(repository.ProjFailedToRemove, projId), err)
-		}
+	// 1: bind request
+	req := &ProjIdRequest{}
+	if !bindUri(ctx, req) {
+		return
+	}
+
+	// 2: remove project
+	if err := controller.deleteProj(userId, req.ProjId); err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, &DeleteProjResponse{
-		Status: succ,
+		Status: true,
 	})
 }
 
@@ -437,35 +970,26 @@ func DeleteProj(ctx *gin.Context) {
 func UpdateProj(ctx *gin.Context) {
 	controller := GetController()
 
-	projId := utils.ToInt(ctx.Param("projId"))
-
-	// 1: bind request
-	req := &UpdateProjRequest{}
-	if err := ctx.ShouldBind(req); err != nil {
-		ctx.JSON(http.StatusBadRequest, rkerror.New(
-			rkerror.WithHttpCode(http.StatusBadRequest),
-			rkerror.WithMessage(err.Error())))
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
 		return
 	}
 
-	// 3: get project from repository
-	projFromRepo, ok := isProjExist(ctx, controller, projId)
-	if !ok || projFromRepo == nil {
+	// 1: bind request
+	path := &ProjIdRequest{}
+	req := &UpdateProjRequest{}
+	if !bindUri(ctx, path) || !bindJson(ctx, req) {
 		return
 	}
 
-	// 4: update values in project
-	projFromRepo.Name = req.Name
-
-	// 5: update project to repository
-	succ, err := controller.Repo.UpdateProj(projFromRepo)
-	if err != nil {
-		makeInternalError(ctx, fmt.Sprintf("failed to update project with projId:%d", projId), err)
+	// 2: update project
+	if _, err := controller.updateProj(userId, path.ProjId, req.Name); err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, &UpdateProjResponse{
-		Status: succ,
+		Status: true,
 	})
 }
 
@@ -481,41 +1005,34 @@ func UpdateProj(ctx *gin.Context) {
 // @produce application/json
 // @Param projId query int true "Project Id"
 // @Param source body CreateSourceRequest true "Source"
+// @Param Idempotency-Key header string false "Key to replay response of retried request"
 // @Success 200 {object} CreateSourceResponse
 // @Router /v1/source [put]
 func CreateSource(ctx *gin.Context) {
 	controller := GetController()
-	projId := utils.ToInt(ctx.Query("projId"))
 
-	// 1: bind request
-	req := &CreateSourceRequest{}
-	if err := ctx.ShouldBind(req); err != nil {
-		ctx.JSON(http.StatusBadRequest, rkerror.New(
-			rkerror.WithHttpCode(http.StatusBadRequest),
-			rkerror.WithMessage(err.Error())))
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
 		return
 	}
 
-	// 2: get project from repository
-	if proj, ok := isProjExist(ctx, controller, projId); !ok {
-		return
-	} else if proj.Source != nil {
-		makeAlreadyExistError(ctx, fmt.Sprintf("source already exist in project with id:%d", proj.Source.Id))
+	// 1: bind request
+	query := &ProjIdRequest{}
+	req := &CreateSourceRequest{}
+	if !bindQuery(ctx, query) || !bindJson(ctx, req) {
 		return
 	}
 
-	// 3: create source
-	src := repository.NewSource(req.Type, req.Repository)
-	src.ProjId = projId
-	_, err := controller.Repo.CreateSource(src)
+	// 2: create source
+	src, err := controller.createSource(userId, query.ProjId, req.Type, req.Repository, req.Host)
 	if err != nil {
-		makeInternalError(ctx, fmt.Sprintf("failed to create source with projId:%d", projId), err)
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, &CreateSourceResponse{
-		ProjId:   src.ProjId,
-		SourceId: src.Id,
+		ProjId:   src.Meta.ProjId,
+		SourceId: src.Meta.Id,
 	})
 }
 
@@ -531,22 +1048,25 @@ func CreateSource(ctx *gin.Context) {
 func DeleteSource(ctx *gin.Context) {
 	controller := GetController()
 
-	sourceId := utils.ToInt(ctx.Param("sourceId"))
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
 
-	// 1: remove project
-	succ, err := controller.Repo.RemoveSource(sourceId)
-	if err != nil {
-		switch err.(type) {
-		case *repository.NotFound:
-			makeNotFoundError(ctx, fmt.Sprintf(repository.SourceNotFoundMsg, sourceId), err)
-		default:
-			makeInternalError(ctx, fmt.Sprintf(repository.SourceFailedToRemove, sourceId), err)
-		}
+	// 1: bind request
+	req := &SourceIdRequest{}
+	if !bindUri(ctx, req) {
+		return
+	}
+
+	// 2: remove source
+	if err := controller.deleteSource(userId, req.SourceId); err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, &DeleteSourceResponse{
-		Status: succ,
+		Status: true,
 	})
 }
 
@@ -556,29 +1076,29 @@ func DeleteSource(ctx *gin.Context) {
 // @version 1.0
 // @Tags installation
 // @produce application/json
-// @Param source query string true "Source"
-// @Param user query string true "user"
+// @Param source query string false "Source, the same as source of session by default"
+// @Param refresh query bool false "Sync installations from source instead of returning stored ones"
 // @Success 200
 // @Router /v1/user/installations [get]
 func ListUserInstallations(ctx *gin.Context) {
-	user := ctx.Query("user")
-	source := ctx.Query("source")
+	controller := GetController()
 
-	var res []*Installation
-	var err error
+	// 1: bind request
+	req := &ListUserInstallationsRequest{}
+	session, ok := mustGetSession(ctx)
+	if !ok || !bindQuery(ctx, req) {
+		return
+	}
 
-	switch source {
-	case "github":
-		res, err = ListUserInstallationsFromGithub(user)
-	default:
-		err = fmt.Errorf("unrecognized source:%s with user:%s", source, user)
+	// 2: list installations of source which user signed in with by default
+	if len(req.Source) < 1 {
+		req.Source = session.Source
 	}
 
+	// 3: list installations with access token of current user
+	res, err := controller.listUserInstallations(req.Source, session.UserId(), req.Refresh)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, rkerror.New(
-			rkerror.WithHttpCode(http.StatusBadRequest),
-			rkerror.WithMessage(fmt.Sprintf("failed to list user installations from %s", source)),
-			rkerror.WithDetails(err)))
+		ctx.Error(err)
 		return
 	}
 
@@ -595,18 +1115,16 @@ func ListUserInstallations(ctx *gin.Context) {
 // @Router /v1/pipeline/template [get]
 func ListPipelineTemplate(ctx *gin.Context) {
 	controller := GetController()
-	templateList := make([]*PipelineTemplate, 0)
 
-	// 1: list organization
-	templateListFromRepo, err := controller.Repo.ListPipelineTemplate()
-	if err != nil {
-		makeInternalError(ctx, "failed to list pipeline templates", err)
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
 		return
 	}
 
-	for i := range templateListFromRepo {
-		// convert to API model
-		templateList = append(templateList, convertPipelineTemplate(templateListFromRepo[i]))
+	templateList, err := controller.listPipelineTemplate(userId)
+	if err != nil {
+		ctx.Error(err)
+		return
 	}
 
 	ctx.JSON(http.StatusOK, &ListPipelineTemplateResponse{
@@ -624,39 +1142,34 @@ func ListPipelineTemplate(ctx *gin.Context) {
 // @Param branch query string true "Branch"
 // @Param perPage query int false "Number of commits per page"
 // @Param page query int false "Page number to fetch"
-// @Success 200
+// @Param all query bool false "Follow pages from page until there are no more pages or max pages reached"
+// @Success 200 {object} ListCommitsResponse
 // @Router /v1/source/{sourceId}/commits [get]
 func ListCommits(ctx *gin.Context) {
 	controller := GetController()
 
-	sourceId := utils.ToInt(ctx.Param("sourceId"))
-	branch := ctx.Query("branch")
-	perPage, page := normalizePage(utils.ToInt(ctx.Query("perPage")), utils.ToInt(ctx.Query("page")))
-
-	// 1: get source from repository
-	sourceFromRepo, ok := isSourceExist(ctx, controller, sourceId)
-	if !ok || sourceFromRepo == nil {
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
 		return
 	}
 
-	// 2: get access token from repository
-	tokenFromRepo, ok := isAccessTokenExist(ctx, controller, sourceFromRepo.Type, sourceFromRepo.User)
-	if !ok || sourceFromRepo == nil {
+	// 1: bind request
+	path := &SourceIdRequest{}
+	req := &ListCommitsRequest{}
+	if !bindUri(ctx, path) || !bindQuery(ctx, req) {
 		return
 	}
 
-	// 3: List commits
-	commits, err := ListCommitsFromGithub(sourceFromRepo, branch, tokenFromRepo.Token, perPage, page)
+	// 2: list commits
+	commits, pagination, err := controller.listCommits(userId, path.SourceId, req.Branch, req.PerPage, req.Page, req.All)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, rkerror.New(
-			rkerror.WithHttpCode(http.StatusInternalServerError),
-			rkerror.WithMessage("Failed to list commits from github"),
-			rkerror.WithDetails(err)))
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, &ListCommitsResponse{
-		Commits: commits,
+		Commits:    commits,
+		Pagination: pagination,
 	})
 }
 
@@ -667,116 +1180,84 @@ func ListCommits(ctx *gin.Context) {
 // @Tags installation
 // @produce application/json
 // @Param sourceId path int true "Source Id"
-// @Param perPage query int false "Number of commits per page"
+// @Param perPage query int false "Number of branches and tags per page"
 // @Param page query int false "Page number to fetch"
-// @Success 200
+// @Param branchPage query int false "Page number of branches to fetch, page is used if missing"
+// @Param tagPage query int false "Page number of tags to fetch, page is used if missing"
+// @Param all query bool false "Follow pages from page until there are no more pages or max pages reached"
+// @Success 200 {object} ListBranchesAndTagsResponse
 // @Router /v1/source/{sourceId}/branches [get]
 func ListBranchesAndTags(ctx *gin.Context) {
 	controller := GetController()
 
-	sourceId := utils.ToInt(ctx.Param("sourceId"))
-	perPage, page := normalizePage(utils.ToInt(ctx.Query("perPage")), utils.ToInt(ctx.Query("page")))
-
-	// 1: get source from repository
-	sourceFromRepo, ok := isSourceExist(ctx, controller, sourceId)
-	if !ok || sourceFromRepo == nil {
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
 		return
 	}
 
-	// 2: get access token from repository
-	tokenFromRepo, ok := isAccessTokenExist(ctx, controller, sourceFromRepo.Type, sourceFromRepo.User)
-	if !ok || sourceFromRepo == nil {
+	// 1: bind request
+	path := &SourceIdRequest{}
+	req := &ListBranchesAndTagsRequest{}
+	if !bindUri(ctx, path) || !bindQuery(ctx, req) {
 		return
 	}
 
-	// 3: List commits
-	branches, tags, err := ListBranchesAndTagsFromGithub(sourceFromRepo, tokenFromRepo.Token, perPage, page)
+	// 2: list branches and tags
+	res, err := controller.listBranchesAndTags(userId, path.SourceId, req.PerPage,
+		pageOr(req.BranchPage, req.Page), pageOr(req.TagPage, req.Page), req.All)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, rkerror.New(
-			rkerror.WithHttpCode(http.StatusInternalServerError),
-			rkerror.WithMessage("Failed to list commits from github"),
-			rkerror.WithDetails(err)))
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, &ListBranchesAndTagsResponse{
-		Branches: branches,
-		Tags:     tags,
-	})
+	ctx.JSON(http.StatusOK, res)
 }
 
-func isOrgExist(ctx *gin.Context, controller *Controller, orgId int) (*repository.Org, bool) {
-	org, err := controller.Repo.GetOrg(orgId)
-	if err != nil {
-		switch err.(type) {
-		case *repository.NotFound:
-			makeNotFoundError(ctx, fmt.Sprintf(repository.OrgNotFoundMsg, orgId))
-		default:
-			makeInternalError(ctx, fmt.Sprintf(repository.OrgFailedToGetMsg, orgId), err)
-		}
-		return nil, false
-	}
-	if org == nil {
-		makeNotFoundError(ctx, fmt.Sprintf(repository.OrgNotFoundMsg, orgId))
-		return nil, false
-	}
+// ********************************************* //
+// ************** Webhook related ************** //
+// ********************************************* //
 
-	return org, true
-}
+// ReceiveGithubWebhook
+// @Summary Receive event delivered by webhook of github app
+// @Id 55
+// @version 1.0
+// @Tags webhook
+// @accept application/json
+// @produce application/json
+// @Param X-GitHub-Event header string true "Name of event"
+// @Param X-GitHub-Delivery header string true "Id of delivery"
+// @Param X-Hub-Signature-256 header string true "HMAC-SHA256 of payload signed with webhook secret"
+// @Param X-GitHub-Enterprise-Host header string false "Host of GitHub Enterprise Server"
+// @Success 200 {object} WebhookResponse
+// @Router /v1/webhook/github [post]
+func ReceiveGithubWebhook(ctx *gin.Context) {
+	controller := GetController()
 
-func isProjExist(ctx *gin.Context, controller *Controller, projId int) (*repository.Proj, bool) {
-	proj, err := controller.Repo.GetProj(projId)
+	// 1: verify signature with secret of app on host
+	app, err := githubWebhookAppOf(ctx)
 	if err != nil {
-		switch err.(type) {
-		case *repository.NotFound:
-			makeNotFoundError(ctx, fmt.Sprintf(repository.ProjNotFoundMsg, projId))
-		default:
-			makeInternalError(ctx, fmt.Sprintf(repository.ProjFailedToGetMsg, projId), err)
-		}
-		return nil, false
-	}
-	if proj == nil {
-		makeNotFoundError(ctx, fmt.Sprintf(repository.ProjNotFoundMsg, projId))
-		return nil, false
+		ctx.Error(err)
+		return
 	}
 
-	return proj, true
-}
-
-func isSourceExist(ctx *gin.Context, controller *Controller, sourceId int) (*repository.Source, bool) {
-	src, err := controller.Repo.GetSource(sourceId)
+	payload, err := readGithubWebhookPayload(ctx, app.WebhookSecret)
 	if err != nil {
-		switch err.(type) {
-		case *repository.NotFound:
-			makeNotFoundError(ctx, fmt.Sprintf(repository.SourceNotFoundMsg, sourceId))
-		default:
-			makeInternalError(ctx, fmt.Sprintf(repository.SourceFailedToGetMsg, sourceId), err)
-		}
-		return nil, false
-	}
-	if src == nil {
-		makeNotFoundError(ctx, fmt.Sprintf(repository.SourceNotFoundMsg, sourceId))
-		return nil, false
+		ctx.Error(err)
+		return
 	}
 
-	return src, true
-}
+	// 2: bind headers
+	req := &GithubWebhookRequest{}
+	if !bindHeader(ctx, req) {
+		return
+	}
 
-func isAccessTokenExist(ctx *gin.Context, controller *Controller, repoType, repoUser string) (*repository.AccessToken, bool) {
-	token, err := controller.Repo.GetAccessToken(repoType, repoUser)
+	// 3: store and handle event
+	resp, err := controller.receiveGithubWebhook(app.Host, req.Delivery, req.Event, payload)
 	if err != nil {
-		switch err.(type) {
-		case *repository.NotFound:
-			makeNotFoundError(ctx, fmt.Sprintf(repository.AccessTokenNotFoundMsg, repoType, repoUser))
-		default:
-			makeInternalError(ctx, fmt.Sprintf(repository.AccessTokenFailedToGetMsg, repoType, repoUser), err)
-		}
-		return nil, false
-	}
-	if token == nil {
-		makeNotFoundError(ctx, fmt.Sprintf(repository.AccessTokenNotFoundMsg, repoType, repoUser))
-		return nil, false
+		ctx.Error(err)
+		return
 	}
 
-	return token, true
+	ctx.JSON(http.StatusOK, resp)
 }
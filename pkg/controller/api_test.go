@@ -6,18 +6,20 @@
 package controller
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
 	"github.com/pointgoal/workstation/pkg/repository"
 	"github.com/rookie-ninja/rk-entry/entry"
 	"github.com/rookie-ninja/rk-gin/boot"
 	"github.com/stretchr/testify/assert"
 	httptest "github.com/stretchr/testify/http"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestInitApi_WithNilGinEntry(t *testing.T) {
@@ -44,11 +46,26 @@ func TestListOrg(t *testing.T) {
 	repository.RegisterMemory()
 	RegisterController()
 
+	// expect 200
 	writer := &httptest.TestResponseWriter{}
 	ctx, _ := gin.CreateTestContext(writer)
-	ListOrg(ctx)
-
+	ctx.Request = newRequest("", "")
+	serve(ctx, ListOrg)
 	assert.Equal(t, 200, writer.StatusCode)
+
+	// expect 400 with unknown expand
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Request = newRequest("", "expand=projects,pipelines")
+	serve(ctx, ListOrg)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+
+	// expect 400 with unknown fields
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Request = newRequest("", "fields=id,password")
+	serve(ctx, ListOrg)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
 }
 
 func TestGetOrg(t *testing.T) {
@@ -58,10 +75,31 @@ func TestGetOrg(t *testing.T) {
 	repo := repository.RegisterMemory()
 	RegisterController()
 
-	// with invalid org id, 404 expected
+	// without org id, 400 expected
 	writer := &httptest.TestResponseWriter{}
 	ctx, _ := gin.CreateTestContext(writer)
-	GetOrg(ctx)
+	serve(ctx, GetOrg)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+
+	// with invalid org id, 400 expected
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Params = append(ctx.Params, gin.Param{
+		Key:   "orgId",
+		Value: "ut-org",
+	})
+	serve(ctx, GetOrg)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+	assert.Contains(t, writer.Output, string(repository.CodeInvalidArgument))
+
+	// with org id not exist, 404 expected
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Params = append(ctx.Params, gin.Param{
+		Key:   "orgId",
+		Value: "1",
+	})
+	serve(ctx, GetOrg)
 	assert.Equal(t, http.StatusNotFound, writer.StatusCode)
 
 	// expect 200
@@ -70,11 +108,12 @@ func TestGetOrg(t *testing.T) {
 	repo.CreateOrg(&repository.Org{
 		Name: "ut-org",
 	})
+	repo.UpsertMember(repository.NewMember(1, 1, repository.RoleOwner))
 	ctx.Params = append(ctx.Params, gin.Param{
 		Key:   "orgId",
 		Value: "1",
 	})
-	GetOrg(ctx)
+	serve(ctx, GetOrg)
 	assert.Equal(t, http.StatusOK, writer.StatusCode)
 }
 
@@ -85,11 +124,20 @@ func TestCreateOrg(t *testing.T) {
 	repository.RegisterMemory()
 	RegisterController()
 
+	// with invalid name, 400 expected
 	writer := &httptest.TestResponseWriter{}
 	ctx, _ := gin.CreateTestContext(writer)
-	CreateOrg(ctx)
+	ctx.Request = newRequest("", "orgName=ut%20org")
+	serve(ctx, CreateOrg)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+	assert.Contains(t, writer.Output, "orgName")
 
-	assert.Equal(t, 200, writer.StatusCode)
+	// expect 200
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Request = newRequest("", "orgName=ut-org")
+	serve(ctx, CreateOrg)
+	assert.Equal(t, http.StatusOK, writer.StatusCode)
 }
 
 func TestDeleteOrg(t *testing.T) {
@@ -99,10 +147,20 @@ func TestDeleteOrg(t *testing.T) {
 	repo := repository.RegisterMemory()
 	RegisterController()
 
-	// expect 404
+	// expect 400
 	writer := &httptest.TestResponseWriter{}
 	ctx, _ := gin.CreateTestContext(writer)
-	DeleteOrg(ctx)
+	serve(ctx, DeleteOrg)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+
+	// expect 404
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Params = append(ctx.Params, gin.Param{
+		Key:   "orgId",
+		Value: "1",
+	})
+	serve(ctx, DeleteOrg)
 	assert.Equal(t, http.StatusNotFound, writer.StatusCode)
 
 	// expect 200
@@ -111,11 +169,12 @@ func TestDeleteOrg(t *testing.T) {
 	repo.CreateOrg(&repository.Org{
 		Name: "ut-org",
 	})
+	repo.UpsertMember(repository.NewMember(1, 1, repository.RoleOwner))
 	ctx.Params = append(ctx.Params, gin.Param{
 		Key:   "orgId",
 		Value: "1",
 	})
-	DeleteOrg(ctx)
+	serve(ctx, DeleteOrg)
 	assert.Equal(t, http.StatusOK, writer.StatusCode)
 }
 
@@ -126,15 +185,38 @@ func TestUpdateOrg(t *testing.T) {
 	repo := repository.RegisterMemory()
 	RegisterController()
 
-	// expect 404
+	// expect 400 with malformed body
 	writer := &httptest.TestResponseWriter{}
 	ctx, _ := gin.CreateTestContext(writer)
-	stringReader := strings.NewReader(`{name:"ut-name"}`)
-	ctx.Request = &http.Request{
-		Body: ioutil.NopCloser(stringReader),
-		URL:  &url.URL{},
-	}
-	UpdateOrg(ctx)
+	ctx.Request = newRequest(`{name:"ut-name"}`, "")
+	ctx.Params = append(ctx.Params, gin.Param{
+		Key:   "orgId",
+		Value: "1",
+	})
+	serve(ctx, UpdateOrg)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+
+	// expect 400 without name
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Request = newRequest(`{}`, "")
+	ctx.Params = append(ctx.Params, gin.Param{
+		Key:   "orgId",
+		Value: "1",
+	})
+	serve(ctx, UpdateOrg)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+	assert.Contains(t, writer.Output, "name is required")
+
+	// expect 404
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Request = newRequest(`{"name":"ut-name"}`, "")
+	ctx.Params = append(ctx.Params, gin.Param{
+		Key:   "orgId",
+		Value: "1",
+	})
+	serve(ctx, UpdateOrg)
 	assert.Equal(t, http.StatusNotFound, writer.StatusCode)
 
 	// expect 200
@@ -143,16 +225,13 @@ func TestUpdateOrg(t *testing.T) {
 	repo.CreateOrg(&repository.Org{
 		Name: "ut-org",
 	})
-	stringReader = strings.NewReader(`{name:"ut-name"}`)
-	ctx.Request = &http.Request{
-		Body: ioutil.NopCloser(stringReader),
-		URL:  &url.URL{},
-	}
+	repo.UpsertMember(repository.NewMember(1, 1, repository.RoleOwner))
+	ctx.Request = newRequest(`{"name":"ut-name"}`, "")
 	ctx.Params = append(ctx.Params, gin.Param{
 		Key:   "orgId",
 		Value: "1",
 	})
-	UpdateOrg(ctx)
+	serve(ctx, UpdateOrg)
 	assert.Equal(t, http.StatusOK, writer.StatusCode)
 }
 
@@ -163,18 +242,29 @@ func TestListProj(t *testing.T) {
 	repo := repository.RegisterMemory()
 	RegisterController()
 
-	// expect 200
+	// expect 400 with invalid org id
 	writer := &httptest.TestResponseWriter{}
 	ctx, _ := gin.CreateTestContext(writer)
-	ctx.Request = &http.Request{
-		URL: &url.URL{
-			RawQuery: "orgId=1;",
-		},
-	}
+	ctx.Request = newRequest("", "orgId=-1")
+	serve(ctx, ListProj)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+
+	// expect 200
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Request = newRequest("", "orgId=1")
 	repo.CreateOrg(&repository.Org{
 		Name: "ut-org",
 	})
-	ListProj(ctx)
+	repo.UpsertMember(repository.NewMember(1, 1, repository.RoleOwner))
+	serve(ctx, ListProj)
+	assert.Equal(t, http.StatusOK, writer.StatusCode)
+
+	// expect 200 without org id
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Request = newRequest("", "")
+	serve(ctx, ListProj)
 	assert.Equal(t, http.StatusOK, writer.StatusCode)
 }
 
@@ -185,11 +275,11 @@ func TestGetProj(t *testing.T) {
 	repo := repository.RegisterMemory()
 	RegisterController()
 
-	// expect 404 without org
+	// expect 400 without project id
 	writer := &httptest.TestResponseWriter{}
 	ctx, _ := gin.CreateTestContext(writer)
-	GetProj(ctx)
-	assert.Equal(t, http.StatusNotFound, writer.StatusCode)
+	serve(ctx, GetProj)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
 
 	// expect 404 without project
 	writer = &httptest.TestResponseWriter{}
@@ -197,19 +287,18 @@ func TestGetProj(t *testing.T) {
 	repo.CreateOrg(&repository.Org{
 		Name: "ut-org",
 	})
+	repo.UpsertMember(repository.NewMember(1, 1, repository.RoleOwner))
 	ctx.Params = append(ctx.Params, gin.Param{
-		Key:   "orgId",
+		Key:   "projId",
 		Value: "1",
 	})
-	GetProj(ctx)
+	serve(ctx, GetProj)
 	assert.Equal(t, http.StatusNotFound, writer.StatusCode)
 
 	// expect 200
 	writer = &httptest.TestResponseWriter{}
 	ctx, _ = gin.CreateTestContext(writer)
-	ctx.Request = &http.Request{
-		URL: &url.URL{},
-	}
+	ctx.Request = newRequest("", "")
 	repo.CreateProj(&repository.Proj{
 		OrgId: 1,
 		Name:  "ut-org",
@@ -218,7 +307,7 @@ func TestGetProj(t *testing.T) {
 		Key:   "projId",
 		Value: "1",
 	})
-	GetProj(ctx)
+	serve(ctx, GetProj)
 	assert.Equal(t, http.StatusOK, writer.StatusCode)
 }
 
@@ -229,36 +318,30 @@ func TestCreateProj(t *testing.T) {
 	repo := repository.RegisterMemory()
 	RegisterController()
 
-	// expect 404
+	// expect 400 without org id
 	writer := &httptest.TestResponseWriter{}
 	ctx, _ := gin.CreateTestContext(writer)
+	ctx.Request = newRequest(`{"name":"ut-name"}`, "")
+	serve(ctx, CreateProj)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+	assert.Contains(t, writer.Output, "orgId is required")
 
-	stringReader := strings.NewReader(`{"name":"ut-name","orgId":1}`)
-	ctx.Request = &http.Request{
-		Body: io.NopCloser(stringReader),
-		URL:  &url.URL{},
-		Header: map[string][]string{
-			"Content-Type": {"application/json"},
-		},
-	}
-	CreateProj(ctx)
+	// expect 404
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Request = newRequest(`{"name":"ut-name","orgId":1}`, "")
+	serve(ctx, CreateProj)
 	assert.Equal(t, http.StatusNotFound, writer.StatusCode)
 
 	// expect 200
 	writer = &httptest.TestResponseWriter{}
 	ctx, _ = gin.CreateTestContext(writer)
-	stringReader = strings.NewReader(`{"name":"ut-name","orgId":1}`)
-	ctx.Request = &http.Request{
-		Body: io.NopCloser(stringReader),
-		URL:  &url.URL{},
-		Header: map[string][]string{
-			"Content-Type": {"application/json"},
-		},
-	}
+	ctx.Request = newRequest(`{"name":"ut-name","orgId":1}`, "")
 	repo.CreateOrg(&repository.Org{
 		Name: "ut-org",
 	})
-	CreateProj(ctx)
+	repo.UpsertMember(repository.NewMember(1, 1, repository.RoleOwner))
+	serve(ctx, CreateProj)
 	assert.Equal(t, http.StatusOK, writer.StatusCode)
 }
 
@@ -269,18 +352,24 @@ func TestDeleteProj(t *testing.T) {
 	repo := repository.RegisterMemory()
 	RegisterController()
 
-	// expect 404 without proj
+	// expect 400 without project id
 	writer := &httptest.TestResponseWriter{}
 	ctx, _ := gin.CreateTestContext(writer)
-	stringReader := strings.NewReader(`{name:"ut-name"}`)
-	ctx.Request = &http.Request{
-		Body: ioutil.NopCloser(stringReader),
-		URL:  &url.URL{},
-	}
+	serve(ctx, DeleteProj)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+
+	// expect 404 without proj
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
 	repo.CreateOrg(&repository.Org{
 		Name: "ut-org",
 	})
-	DeleteProj(ctx)
+	repo.UpsertMember(repository.NewMember(1, 1, repository.RoleOwner))
+	ctx.Params = append(ctx.Params, gin.Param{
+		Key:   "projId",
+		Value: "1",
+	})
+	serve(ctx, DeleteProj)
 	assert.Equal(t, http.StatusNotFound, writer.StatusCode)
 
 	// expect 200
@@ -294,7 +383,7 @@ func TestDeleteProj(t *testing.T) {
 		Key:   "projId",
 		Value: "1",
 	})
-	DeleteProj(ctx)
+	serve(ctx, DeleteProj)
 	assert.Equal(t, http.StatusOK, writer.StatusCode)
 }
 
@@ -305,43 +394,44 @@ func TestUpdateProj(t *testing.T) {
 	repo := repository.RegisterMemory()
 	RegisterController()
 
-	// expect 404 without org
+	// expect 400 without project id
 	writer := &httptest.TestResponseWriter{}
 	ctx, _ := gin.CreateTestContext(writer)
-	stringReader := strings.NewReader(`{name:"ut-name"}`)
-	ctx.Request = &http.Request{
-		Body: ioutil.NopCloser(stringReader),
-		URL:  &url.URL{},
-	}
-	UpdateProj(ctx)
-	assert.Equal(t, http.StatusNotFound, writer.StatusCode)
+	ctx.Request = newRequest(`{"name":"ut-name"}`, "")
+	serve(ctx, UpdateProj)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+
+	// expect 400 with invalid name
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Request = newRequest(`{"name":"-ut-name"}`, "")
+	ctx.Params = append(ctx.Params, gin.Param{
+		Key:   "projId",
+		Value: "1",
+	})
+	serve(ctx, UpdateProj)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+	assert.Contains(t, writer.Output, "slug")
 
 	// expect 404 without proj
 	writer = &httptest.TestResponseWriter{}
 	ctx, _ = gin.CreateTestContext(writer)
-	stringReader = strings.NewReader(`{name:"ut-name"}`)
-	ctx.Request = &http.Request{
-		Body: ioutil.NopCloser(stringReader),
-		URL:  &url.URL{},
-	}
+	ctx.Request = newRequest(`{"name":"ut-name"}`, "")
 	repo.CreateOrg(&repository.Org{
 		Name: "ut-org",
 	})
+	repo.UpsertMember(repository.NewMember(1, 1, repository.RoleOwner))
 	ctx.Params = append(ctx.Params, gin.Param{
 		Key:   "projId",
 		Value: "1",
 	})
-	UpdateProj(ctx)
+	serve(ctx, UpdateProj)
 	assert.Equal(t, http.StatusNotFound, writer.StatusCode)
 
 	// expect 200
 	writer = &httptest.TestResponseWriter{}
 	ctx, _ = gin.CreateTestContext(writer)
-	stringReader = strings.NewReader(`{name:"ut-name"}`)
-	ctx.Request = &http.Request{
-		Body: ioutil.NopCloser(stringReader),
-		URL:  &url.URL{},
-	}
+	ctx.Request = newRequest(`{"name":"ut-name"}`, "")
 	repo.CreateProj(&repository.Proj{
 		Name:  "ut-proj",
 		OrgId: 1,
@@ -350,10 +440,211 @@ func TestUpdateProj(t *testing.T) {
 		Key:   "projId",
 		Value: "1",
 	})
-	UpdateProj(ctx)
+	serve(ctx, UpdateProj)
 	assert.Equal(t, http.StatusOK, writer.StatusCode)
 }
 
+func TestCreateSource(t *testing.T) {
+	defer assertNotPanic(t)
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	rkgin.RegisterGinEntry(rkgin.WithNameGin("workstation"))
+	repository.RegisterMemory()
+	RegisterController()
+	RegisterSourceType("github")
+
+	// expect 400 without project id
+	writer := &httptest.TestResponseWriter{}
+	ctx, _ := gin.CreateTestContext(writer)
+	ctx.Request = newRequest(`{"type":"github","repository":"ut-owner/ut-repo"}`, "")
+	serve(ctx, CreateSource)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+
+	// expect 400 with unknown type and invalid repository
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Request = newRequest(`{"type":"ut-type","repository":"ut-repo"}`, "projId=1")
+	serve(ctx, CreateSource)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+	assert.Contains(t, writer.Output, "sourcetype")
+	assert.Contains(t, writer.Output, "owner/name")
+
+	// expect 404 without project
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Request = newRequest(`{"type":"github","repository":"ut-owner/ut-repo"}`, "projId=1")
+	serve(ctx, CreateSource)
+	assert.Equal(t, http.StatusNotFound, writer.StatusCode)
+}
+
+func TestListCommits_WithInvalidPage(t *testing.T) {
+	defer assertNotPanic(t)
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	rkgin.RegisterGinEntry(rkgin.WithNameGin("workstation"))
+	repository.RegisterMemory()
+	RegisterController()
+
+	writer := &httptest.TestResponseWriter{}
+	ctx, _ := gin.CreateTestContext(writer)
+	ctx.Request = newRequest("", "perPage=1000&page=-1")
+	ctx.Params = append(ctx.Params, gin.Param{
+		Key:   "sourceId",
+		Value: "1",
+	})
+	serve(ctx, ListCommits)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+	assert.Contains(t, writer.Output, "perPage")
+	assert.Contains(t, writer.Output, "page")
+}
+
+func TestUserApi(t *testing.T) {
+	defer assertNotPanic(t)
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+	repo := repository.GetRepository()
+
+	// create user with github identity as the user in session
+	user := repository.NewUser("ut-user")
+	user.Identities = append(user.Identities, repository.NewIdentity(repository.IdentityGithub, "ut-user"))
+	repo.CreateUser(user)
+	repo.UpsertAccessToken(repository.NewAccessToken(user.Id, repository.IdentityGithub, "ut-user", "ut-token"))
+
+	// get current user
+	resp := doRequest(router, http.MethodGet, "/v1/me", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"login":"ut-user"`)
+
+	// the last identity could not be unlinked
+	resp = doRequest(router, http.MethodDelete, "/v1/me/identities/github", "", "")
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	// unknown identity type
+	resp = doRequest(router, http.MethodDelete, "/v1/me/identities/svn", "", "")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// link local identity with short password
+	resp = doRequest(router, http.MethodPut, "/v1/me/identities/local", "application/json", `{"login":"ut-local","password":"short"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// link local identity
+	resp = doRequest(router, http.MethodPut, "/v1/me/identities/local", "application/json", `{"login":"ut-local","password":"ut-password"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// update password without or with wrong current password
+	resp = doRequest(router, http.MethodPut, "/v1/me/identities/local", "application/json", `{"login":"ut-local","password":"ut-new-password"}`)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	resp = doRequest(router, http.MethodPut, "/v1/me/identities/local", "application/json", `{"login":"ut-local","password":"ut-new-password","currentPassword":"wrong-password"}`)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	// update password with current password
+	resp = doRequest(router, http.MethodPut, "/v1/me/identities/local", "application/json", `{"login":"ut-local","password":"ut-password","currentPassword":"ut-password"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// session of personal token could not set password even with scope of user
+	writer := &httptest.TestResponseWriter{}
+	ctx, _ := gin.CreateTestContext(writer)
+	ctx.Request = newRequest(`{"login":"ut-local","password":"ut-new-password","currentPassword":"ut-password"}`, "")
+	ctx.Set(sessionKey, &Session{StandardClaims: jwt.StandardClaims{Subject: "1"}, Scopes: []string{ScopeWriteUser}})
+	serve(ctx, LinkLocalIdentity)
+	assert.Equal(t, http.StatusForbidden, writer.StatusCode)
+
+	// sign in with wrong password
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v1/session/login", "", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	resp = doRequest(router, http.MethodPost, "/v1/session/login", "application/json", `{"login":"ut-local","password":"wrong-password"}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	// sign in with local identity
+	resp = doRequest(router, http.MethodPost, "/v1/session/login", "application/json", `{"login":"ut-local","password":"ut-password"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Set-Cookie"), SessionCookieName)
+
+	// unlink github identity, access token should be removed too
+	resp = doRequest(router, http.MethodDelete, "/v1/me/identities/github", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	_, err := repo.GetAccessToken(user.Id, repository.IdentityGithub)
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	// delete current user
+	resp = doRequest(router, http.MethodDelete, "/v1/me", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = doRequest(router, http.MethodGet, "/v1/me", "", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestErrorInterceptor(t *testing.T) {
+	// without error
+	writer := &httptest.TestResponseWriter{}
+	ctx, _ := gin.CreateTestContext(writer)
+	ErrorInterceptor()(ctx)
+	assert.False(t, ctx.Writer.Written())
+
+	// with not found error
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Error(repository.Wrapf(repository.NewNotFoundf(repository.OrgNotFoundMsg, 1), repository.CodeInternal, "ut-message"))
+	ErrorInterceptor()(ctx)
+	assert.Equal(t, http.StatusNotFound, writer.StatusCode)
+	assert.Contains(t, writer.Output, string(repository.CodeNotFound))
+	assert.Contains(t, writer.Output, "ut-message")
+	assert.Contains(t, writer.Output, `"details":["organization not found with orgId:1"]`)
+
+	// with plain error as cause of client error, message of cause is returned instead of empty object
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Error(repository.Wrapf(errors.New("ut-error"), repository.CodeInvalidArgument, "ut-message"))
+	ErrorInterceptor()(ctx)
+	assert.Equal(t, http.StatusBadRequest, writer.StatusCode)
+	assert.Contains(t, writer.Output, `"details":["ut-error"]`)
+
+	// with unclassified error
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Error(errors.New("ut-error"))
+	ErrorInterceptor()(ctx)
+	assert.Equal(t, http.StatusInternalServerError, writer.StatusCode)
+	assert.Contains(t, writer.Output, string(repository.CodeInternal))
+	assert.NotContains(t, writer.Output, "ut-error")
+
+	// with upstream error, cause is not exposed
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Error(repository.NewUpstreamf(errors.New("ut-error"), "ut-message"))
+	ErrorInterceptor()(ctx)
+	assert.Equal(t, http.StatusBadGateway, writer.StatusCode)
+	assert.Contains(t, writer.Output, "ut-message")
+	assert.NotContains(t, writer.Output, "ut-error")
+
+	// with rate limited error
+	writer = &httptest.TestResponseWriter{}
+	ctx, _ = gin.CreateTestContext(writer)
+	ctx.Error(repository.Wrapf(repository.NewRateLimitedf(errors.New("ut-error"), 1500*time.Millisecond, "ut-message"), repository.CodeInternal, "ut-wrap"))
+	ErrorInterceptor()(ctx)
+	assert.Equal(t, http.StatusTooManyRequests, writer.StatusCode)
+	assert.Equal(t, "2", writer.Header().Get("Retry-After"))
+}
+
+// Create a http request with json body and raw query.
+func newRequest(body, rawQuery string) *http.Request {
+	return &http.Request{
+		Body: io.NopCloser(strings.NewReader(body)),
+		URL: &url.URL{
+			RawQuery: rawQuery,
+		},
+		Header: map[string][]string{
+			"Content-Type": {"application/json"},
+		},
+	}
+}
+
+// Call handler followed by ErrorInterceptor as router does, session of user 1 would be used if missing.
+func serve(ctx *gin.Context, handler gin.HandlerFunc) {
+	if GetSession(ctx) == nil {
+		ctx.Set(sessionKey, &Session{StandardClaims: jwt.StandardClaims{Subject: "1"}})
+	}
+	handler(ctx)
+	ErrorInterceptor()(ctx)
+}
+
 func assertNotPanic(t *testing.T) {
 	if r := recover(); r != nil {
 		// Expect panic to be called with non nil error
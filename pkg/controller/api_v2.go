@@ -0,0 +1,535 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-gin/boot"
+	"net/http"
+)
+
+// Register v2 APIs which follow conventional REST semantics.
+//
+// Resources are created with POST and return 201 with Location header, updated with PATCH
+// in format of JSON Merge Patch and deleted with DELETE which returns 204.
+// Read only APIs share the same handlers with v1.
+func initApiV2(ginEntry *rkgin.GinEntry) {
+	v2 := ginEntry.Router.Group("/v2")
+
+	// Organization
+	v2.GET("/orgs", ListOrgV2)
+	v2.POST("/orgs", IdempotencyInterceptor(), CreateOrgV2)
+	v2.GET("/orgs/:orgId", GetOrgV2)
+	v2.PATCH("/orgs/:orgId", PatchOrg)
+	v2.DELETE("/orgs/:orgId", DeleteOrgV2)
+
+	// Project
+	v2.GET("/projects", ListProjV2)
+	v2.POST("/projects", IdempotencyInterceptor(), CreateProjV2)
+	v2.GET("/projects/:projId", GetProjV2)
+	v2.PATCH("/projects/:projId", PatchProj)
+	v2.DELETE("/projects/:projId", DeleteProjV2)
+
+	// Source
+	v2.POST("/projects/:projId/source", IdempotencyInterceptor(), CreateSourceV2)
+	v2.GET("/sources/:sourceId", GetSource)
+	v2.DELETE("/sources/:sourceId", DeleteSourceV2)
+	v2.GET("/sources/:sourceId/commits", ListCommitsV2)
+	v2.GET("/sources/:sourceId/branches", ListBranchesAndTagsV2)
+
+	// Installations
+	v2.GET("/user/installations", ListUserInstallationsV2)
+
+	// Pipeline templates
+	v2.GET("/pipeline/templates", ListPipelineTemplateV2)
+}
+
+// Set Location header and write created resource with 201.
+func created(ctx *gin.Context, location string, obj interface{}) {
+	ctx.Header("Location", location)
+	ctx.JSON(http.StatusCreated, obj)
+}
+
+// ************************************************** //
+// ************** Organization related ************** //
+// ************************************************** //
+
+// ListOrgV2
+// @Summary List organizations
+// @Id 26
+// @version 2.0
+// @Tags organization
+// @produce application/json
+// @Param expand query string false "Comma separated related resources to embed, projects, projCount or source"
+// @Param fields query string false "Comma separated fields of organization to return"
+// @Success 200 {object} ListOrgResponse
+// @Router /v2/orgs [get]
+func ListOrgV2(ctx *gin.Context) {
+	ListOrg(ctx)
+}
+
+// GetOrgV2
+// @Summary Get organization
+// @Id 27
+// @version 2.0
+// @Tags organization
+// @produce application/json
+// @Param orgId path int true "Organization Id"
+// @Success 200 {object} GetOrgResponse
+// @Router /v2/orgs/{orgId} [get]
+func GetOrgV2(ctx *gin.Context) {
+	GetOrg(ctx)
+}
+
+// CreateOrgV2
+// @Summary Create organization
+// @Id 17
+// @version 2.0
+// @Tags organization
+// @accept application/json
+// @produce application/json
+// @Param org body CreateOrgV2Request true "Organization"
+// @Param Idempotency-Key header string false "Key to replay response of retried request"
+// @Success 201 {object} GetOrgResponse
+// @Header 201 {string} Location "/v2/orgs/{orgId}"
+// @Router /v2/orgs [post]
+func CreateOrgV2(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	req := &CreateOrgV2Request{}
+	if !bindJson(ctx, req) {
+		return
+	}
+
+	// 2: create organization
+	org, err := controller.createOrg(userId, req.Name)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	created(ctx, fmt.Sprintf("/v2/orgs/%d", org.Meta.Id), &GetOrgResponse{
+		Org: org,
+	})
+}
+
+// PatchOrg
+// @Summary Update organization partially
+// @Id 18
+// @version 2.0
+// @Tags organization
+// @accept application/merge-patch+json
+// @produce application/json
+// @Param orgId path int true "Organization Id"
+// @Param org body UpdateOrgRequest true "JSON Merge Patch of organization"
+// @Success 200 {object} GetOrgResponse
+// @Router /v2/orgs/{orgId} [patch]
+func PatchOrg(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind path
+	path := &OrgIdRequest{}
+	if !bindUri(ctx, path) {
+		return
+	}
+
+	// 2: get organization from repo
+	orgFromRepo, err := controller.findOrg(path.OrgId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// 3: apply patch
+	req := &UpdateOrgRequest{}
+	if !bindMergePatch(ctx, &UpdateOrgRequest{Name: orgFromRepo.Name}, req) {
+		return
+	}
+
+	// 4: update organization
+	org, err := controller.updateOrg(userId, path.OrgId, req.Name)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &GetOrgResponse{
+		Org: org,
+	})
+}
+
+// DeleteOrgV2
+// @Summary Delete organization
+// @Id 19
+// @version 2.0
+// @Tags organization
+// @Param orgId path int true "Organization Id"
+// @Success 204
+// @Router /v2/orgs/{orgId} [delete]
+func DeleteOrgV2(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	req := &OrgIdRequest{}
+	if !bindUri(ctx, req) {
+		return
+	}
+
+	// 2: remove organization
+	if err := controller.deleteOrg(userId, req.OrgId); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ********************************************* //
+// ************** Project related ************** //
+// ********************************************* //
+
+// ListProjV2
+// @Summary List projects
+// @Id 28
+// @version 2.0
+// @Tags project
+// @produce application/json
+// @Param orgId query int false "Organization Id"
+// @Param expand query string false "Comma separated related resources to embed, source only"
+// @Param fields query string false "Comma separated fields of project to return"
+// @Success 200 {object} ListProjResponse
+// @Router /v2/projects [get]
+func ListProjV2(ctx *gin.Context) {
+	ListProj(ctx)
+}
+
+// GetProjV2
+// @Summary Get project
+// @Id 29
+// @version 2.0
+// @Tags project
+// @produce application/json
+// @Param projId path int true "Project Id"
+// @Success 200 {object} GetProjResponse
+// @Router /v2/projects/{projId} [get]
+func GetProjV2(ctx *gin.Context) {
+	GetProj(ctx)
+}
+
+// CreateProjV2
+// @Summary Create project
+// @Id 20
+// @version 2.0
+// @Tags project
+// @accept application/json
+// @produce application/json
+// @Param project body CreateProjV2Request true "Project"
+// @Param Idempotency-Key header string false "Key to replay response of retried request"
+// @Success 201 {object} GetProjResponse
+// @Header 201 {string} Location "/v2/projects/{projId}"
+// @Router /v2/projects [post]
+func CreateProjV2(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	req := &CreateProjV2Request{}
+	if !bindJson(ctx, req) {
+		return
+	}
+
+	// 2: create project
+	proj, err := controller.createProj(userId, req.OrgId, "", req.Name)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	created(ctx, fmt.Sprintf("/v2/projects/%d", proj.Meta.Id), &GetProjResponse{
+		Proj: proj,
+	})
+}
+
+// PatchProj
+// @Summary Update project partially
+// @Id 21
+// @version 2.0
+// @Tags project
+// @accept application/merge-patch+json
+// @produce application/json
+// @Param projId path int true "Project Id"
+// @Param project body UpdateProjRequest true "JSON Merge Patch of project"
+// @Success 200 {object} GetProjResponse
+// @Router /v2/projects/{projId} [patch]
+func PatchProj(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind path
+	path := &ProjIdRequest{}
+	if !bindUri(ctx, path) {
+		return
+	}
+
+	// 2: get project from repository
+	projFromRepo, err := controller.findProj(path.ProjId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// 3: apply patch
+	req := &UpdateProjRequest{}
+	if !bindMergePatch(ctx, &UpdateProjRequest{Name: projFromRepo.Name}, req) {
+		return
+	}
+
+	// 4: update project
+	proj, err := controller.updateProj(userId, path.ProjId, req.Name)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &GetProjResponse{
+		Proj: proj,
+	})
+}
+
+// DeleteProjV2
+// @Summary Delete project
+// @Id 22
+// @version 2.0
+// @Tags project
+// @Param projId path int true "Project Id"
+// @Success 204
+// @Router /v2/projects/{projId} [delete]
+func DeleteProjV2(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	req := &ProjIdRequest{}
+	if !bindUri(ctx, req) {
+		return
+	}
+
+	// 2: remove project
+	if err := controller.deleteProj(userId, req.ProjId); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ******************************************** //
+// ************** Source related ************** //
+// ******************************************** //
+
+// CreateSourceV2
+// @Summary Create source of project
+// @Id 23
+// @version 2.0
+// @Tags source
+// @accept application/json
+// @produce application/json
+// @Param projId path int true "Project Id"
+// @Param source body CreateSourceRequest true "Source"
+// @Param Idempotency-Key header string false "Key to replay response of retried request"
+// @Success 201 {object} GetSourceResponse
+// @Header 201 {string} Location "/v2/sources/{sourceId}"
+// @Router /v2/projects/{projId}/source [post]
+func CreateSourceV2(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	path := &ProjIdRequest{}
+	req := &CreateSourceRequest{}
+	if !bindUri(ctx, path) || !bindJson(ctx, req) {
+		return
+	}
+
+	// 2: create source
+	src, err := controller.createSource(userId, path.ProjId, req.Type, req.Repository, req.Host)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	created(ctx, fmt.Sprintf("/v2/sources/%d", src.Meta.Id), &GetSourceResponse{
+		Source: src,
+	})
+}
+
+// GetSource
+// @Summary Get source
+// @Id 24
+// @version 2.0
+// @Tags source
+// @produce application/json
+// @Param sourceId path int true "Source Id"
+// @Success 200 {object} GetSourceResponse
+// @Router /v2/sources/{sourceId} [get]
+func GetSource(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	req := &SourceIdRequest{}
+	if !bindUri(ctx, req) {
+		return
+	}
+
+	// 2: get source from repository
+	src, err := controller.getSource(userId, req.SourceId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &GetSourceResponse{
+		Source: src,
+	})
+}
+
+// DeleteSourceV2
+// @Summary Delete source
+// @Id 25
+// @version 2.0
+// @Tags source
+// @Param sourceId path int true "Source Id"
+// @Success 204
+// @Router /v2/sources/{sourceId} [delete]
+func DeleteSourceV2(ctx *gin.Context) {
+	controller := GetController()
+
+	userId, ok := mustGetUserId(ctx)
+	if !ok {
+		return
+	}
+
+	// 1: bind request
+	req := &SourceIdRequest{}
+	if !bindUri(ctx, req) {
+		return
+	}
+
+	// 2: remove source
+	if err := controller.deleteSource(userId, req.SourceId); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListCommitsV2
+// @Summary List commits of source
+// @Id 30
+// @version 2.0
+// @Tags source
+// @produce application/json
+// @Param sourceId path int true "Source Id"
+// @Param branch query string false "Branch"
+// @Param perPage query int false "Number of commits per page"
+// @Param page query int false "Page number to fetch"
+// @Param all query bool false "Follow pages from page until there are no more pages or max pages reached"
+// @Success 200 {object} ListCommitsResponse
+// @Router /v2/sources/{sourceId}/commits [get]
+func ListCommitsV2(ctx *gin.Context) {
+	ListCommits(ctx)
+}
+
+// ListBranchesAndTagsV2
+// @Summary List branches and tags of source
+// @Id 31
+// @version 2.0
+// @Tags source
+// @produce application/json
+// @Param sourceId path int true "Source Id"
+// @Param perPage query int false "Number of branches and tags per page"
+// @Param page query int false "Page number to fetch"
+// @Param branchPage query int false "Page number of branches to fetch, page is used if missing"
+// @Param tagPage query int false "Page number of tags to fetch, page is used if missing"
+// @Param all query bool false "Follow pages from page until there are no more pages or max pages reached"
+// @Success 200 {object} ListBranchesAndTagsResponse
+// @Router /v2/sources/{sourceId}/branches [get]
+func ListBranchesAndTagsV2(ctx *gin.Context) {
+	ListBranchesAndTags(ctx)
+}
+
+// ************************************************** //
+// ************** Installation related ************** //
+// ************************************************** //
+
+// ListUserInstallationsV2
+// @Summary List user installations
+// @Id 32
+// @version 2.0
+// @Tags installation
+// @produce application/json
+// @Param source query string false "Source, the same as source of session by default"
+// @Param refresh query bool false "Sync installations from source instead of returning stored ones"
+// @Success 200
+// @Router /v2/user/installations [get]
+func ListUserInstallationsV2(ctx *gin.Context) {
+	ListUserInstallations(ctx)
+}
+
+// ************************************************** //
+// ************ PipelineTemplate related ************ //
+// ************************************************** //
+
+// ListPipelineTemplateV2
+// @Summary List pipeline templates
+// @Id 33
+// @version 2.0
+// @Tags pipeline
+// @produce application/json
+// @Success 200 {object} ListPipelineTemplateResponse
+// @Router /v2/pipeline/templates [get]
+func ListPipelineTemplateV2(ctx *gin.Context) {
+	ListPipelineTemplate(ctx)
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/rookie-ninja/rk-gin/boot"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApiV2_Org(t *testing.T) {
+	defer assertNotPanic(t)
+	router := newRouterV2()
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+
+	// create organization, expect 201 with location
+	resp := doRequest(router, http.MethodPost, "/v2/orgs", binding.MIMEJSON, `{"name":"ut-org"}`)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, "/v2/orgs/1", resp.Header().Get("Location"))
+	assert.Equal(t, "ut-org", decodeOrg(t, resp).Meta.Name)
+
+	// create organization with invalid name, expect 400
+	resp = doRequest(router, http.MethodPost, "/v2/orgs", binding.MIMEJSON, `{"name":"ut org"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// patch organization, expect 200
+	resp = doRequest(router, http.MethodPatch, "/v2/orgs/1", MergePatchContentType, `{"name":"ut-org-new"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "ut-org-new", decodeOrg(t, resp).Meta.Name)
+
+	// patch with empty document keeps everything, expect 200
+	resp = doRequest(router, http.MethodPatch, "/v2/orgs/1", MergePatchContentType, `{}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "ut-org-new", decodeOrg(t, resp).Meta.Name)
+
+	// remove required name with null, expect 400
+	resp = doRequest(router, http.MethodPatch, "/v2/orgs/1", MergePatchContentType, `{"name":null}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// patch with unsupported content type, expect 400
+	resp = doRequest(router, http.MethodPatch, "/v2/orgs/1", "text/plain", `{"name":"ut-org"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// patch organization not exist, expect 404
+	resp = doRequest(router, http.MethodPatch, "/v2/orgs/2", MergePatchContentType, `{"name":"ut-org"}`)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	// get organization, expect 200
+	resp = doRequest(router, http.MethodGet, "/v2/orgs/1", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// delete organization, expect 204 and 404 afterwards
+	resp = doRequest(router, http.MethodDelete, "/v2/orgs/1", "", "")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Empty(t, resp.Body.String())
+
+	resp = doRequest(router, http.MethodDelete, "/v2/orgs/1", "", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestApiV2_ProjAndSource(t *testing.T) {
+	defer assertNotPanic(t)
+	router := newRouterV2()
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	RegisterSourceType("github")
+	api := newFakeGithubApi(t)
+	RegisterGithubApp(api.newApp(t, GithubHostDefault))
+	defer unregisterGithubApp(GithubHostDefault)
+
+	// create project without organization, expect 404
+	resp := doRequest(router, http.MethodPost, "/v2/projects", binding.MIMEJSON, `{"orgId":1,"name":"ut-proj"}`)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	// create project, expect 201 with location
+	doRequest(router, http.MethodPost, "/v2/orgs", binding.MIMEJSON, `{"name":"ut-org"}`)
+	resp = doRequest(router, http.MethodPost, "/v2/projects", binding.MIMEJSON, `{"orgId":1,"name":"ut-proj"}`)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, "/v2/projects/1", resp.Header().Get("Location"))
+
+	// delete organization with projects, expect 409
+	resp = doRequest(router, http.MethodDelete, "/v2/orgs/1", "", "")
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	// patch project, expect 200
+	resp = doRequest(router, http.MethodPatch, "/v2/projects/1", MergePatchContentType, `{"name":"ut-proj-new"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "ut-proj-new")
+
+	// create source without github account connected, expect 401
+	resp = doRequest(router, http.MethodPost, "/v2/projects/1/source", binding.MIMEJSON, `{"type":"github","repository":"ut-owner/ut-repo"}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "connect your github account first")
+
+	// create source with repository which app could access but user could not, expect 400
+	api.linkUser(t, GetController().Repo, 1)
+	resp = doRequest(router, http.MethodPost, "/v2/projects/1/source", binding.MIMEJSON, `{"type":"github","repository":"ut-owner/ut-private"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// create source with type in mixed case, expect 201 with location and lower-cased type
+	resp = doRequest(router, http.MethodPost, "/v2/projects/1/source", binding.MIMEJSON, `{"type":"GitHub","repository":"ut-owner/ut-repo"}`)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"type":"github"`)
+	location := resp.Header().Get("Location")
+	assert.True(t, strings.HasPrefix(location, "/v2/sources/"))
+
+	// create source again, expect 409
+	resp = doRequest(router, http.MethodPost, "/v2/projects/1/source", binding.MIMEJSON, `{"type":"github","repository":"ut-owner/ut-repo"}`)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	// get source, expect 200
+	resp = doRequest(router, http.MethodGet, location, "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "ut-owner/ut-repo")
+
+	// delete project, expect 204
+	resp = doRequest(router, http.MethodDelete, "/v2/projects/1", "", "")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+}
+
+func TestApiV2_KeepV1(t *testing.T) {
+	defer assertNotPanic(t)
+	router := newRouterV2()
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+
+	// v1 still creates organization with PUT and returns 200
+	resp := doRequest(router, http.MethodPut, "/v1/org?orgName=ut-org", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"orgId":1`)
+}
+
+// Register GinEntry with v1 and v2 APIs and memory repository.
+func newRouterV2() *gin.Engine {
+	entry := rkgin.RegisterGinEntry(rkgin.WithNameGin("workstation"))
+	repository.RegisterMemory()
+	RegisterController()
+	initInterceptors()
+	initApi()
+
+	return entry.Router
+}
+
+// Send request with session of user 1.
+func doRequest(router *gin.Engine, method, path, contentType, body string) *httptest.ResponseRecorder {
+	headers := sessionHeadersOf(1)
+	headers["Content-Type"] = contentType
+	return doRequestWithHeaders(router, method, path, body, headers)
+}
+
+// Send request with headers, headers with empty values are not set.
+func doRequestWithHeaders(router *gin.Engine, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for key, value := range headers {
+		if len(value) > 0 {
+			req.Header.Set(key, value)
+		}
+	}
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	return resp
+}
+
+// Returns headers of json request with bearer token.
+func bearerHeadersOf(token string) map[string]string {
+	return map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": bearerPrefix + token,
+	}
+}
+
+// Returns headers of json request with session of user.
+func sessionHeadersOf(userId int) map[string]string {
+	return bearerHeadersOf(newSessionToken(userId, "github", "ut-user"))
+}
+
+func decodeOrg(t *testing.T, resp *httptest.ResponseRecorder) *Org {
+	res := &GetOrgResponse{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), res))
+	assert.NotNil(t, res.Org)
+
+	return res.Org
+}
+
+// Issue session token with controller registered.
+func newSessionToken(userId int, source, login string) string {
+	token, _, _ := GetController().IssueSession(userId, source, login)
+	return token
+}
@@ -8,11 +8,15 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/pointgoal/workstation/pkg/utils"
 	"github.com/rookie-ninja/rk-common/common"
 	"github.com/rookie-ninja/rk-entry/entry"
 	rkquery "github.com/rookie-ninja/rk-query"
 	"go.uber.org/zap"
+	"sync"
+	"time"
 )
 
 const (
@@ -36,6 +40,23 @@ type BootConfig struct {
 				Ref string `yaml:"ref" json:"ref"`
 			} `yaml:"eventLogger" json:"eventLogger"`
 		} `yaml:"logger" json:"logger"`
+		Idempotency struct {
+			Window string `yaml:"window" json:"window"`
+		} `yaml:"idempotency" json:"idempotency"`
+		Session struct {
+			Secret       string   `yaml:"secret" json:"secret"`
+			Ttl          string   `yaml:"ttl" json:"ttl"`
+			IgnorePrefix []string `yaml:"ignorePrefix" json:"ignorePrefix"`
+		} `yaml:"session" json:"session"`
+		Git struct {
+			Roots []string `yaml:"roots" json:"roots"`
+		} `yaml:"git" json:"git"`
+		Vcs struct {
+			MaxPages int `yaml:"maxPages" json:"maxPages"`
+		} `yaml:"vcs" json:"vcs"`
+		Installations struct {
+			SyncInterval string `yaml:"syncInterval" json:"syncInterval"`
+		} `yaml:"installations" json:"installations"`
 	} `yaml:"controller" json:"controller"`
 }
 
@@ -48,10 +69,56 @@ func RegisterControllerFromConfig(configFilePath string) map[string]rkentry.Entr
 	config := &BootConfig{}
 	rkcommon.UnmarshalBootConfig(configFilePath, config)
 
-	// 3: construct entry
+	// 2: parse idempotency window
+	opts := make([]ControllerOption, 0)
+	if len(config.Controller.Idempotency.Window) > 0 {
+		window, err := time.ParseDuration(config.Controller.Idempotency.Window)
+		if err != nil {
+			rkcommon.ShutdownWithError(fmt.Errorf("invalid idempotency window:%s", config.Controller.Idempotency.Window))
+		}
+		opts = append(opts, WithIdempotencyWindow(window))
+	}
+
+	// 3: parse session
+	if len(config.Controller.Session.Ttl) > 0 {
+		ttl, err := time.ParseDuration(config.Controller.Session.Ttl)
+		if err != nil {
+			rkcommon.ShutdownWithError(fmt.Errorf("invalid session ttl:%s", config.Controller.Session.Ttl))
+		}
+		opts = append(opts, WithSessionTtl(ttl))
+	}
+	if len(config.Controller.Session.Secret) > 0 {
+		secret := utils.MustResolveSecret("controller.session.secret", config.Controller.Session.Secret)
+		opts = append(opts, WithSessionSecret([]byte(secret)))
+	}
+	opts = append(opts, WithAuthIgnorePrefix(config.Controller.Session.IgnorePrefix...))
+	opts = append(opts, WithGitRoots(config.Controller.Git.Roots...))
+	if config.Controller.Vcs.MaxPages < 0 {
+		rkcommon.ShutdownWithError(fmt.Errorf("invalid vcs maxPages:%d", config.Controller.Vcs.MaxPages))
+	}
+	opts = append(opts, WithVCSMaxPages(config.Controller.Vcs.MaxPages))
+
+	// 4: parse installation sync interval
+	if len(config.Controller.Installations.SyncInterval) > 0 {
+		interval, err := time.ParseDuration(config.Controller.Installations.SyncInterval)
+		if err != nil {
+			rkcommon.ShutdownWithError(fmt.Errorf("invalid installations syncInterval:%s", config.Controller.Installations.SyncInterval))
+		}
+		opts = append(opts, WithInstallationSyncInterval(interval))
+	}
+
+	// 5: construct entry
 	if config.Controller.Enabled {
-		controller := RegisterController()
+		controller := RegisterController(opts...)
 		res[controller.GetName()] = controller
+
+		// GinEntry was registered already since rk-gin registered its EntryRegFunc before us,
+		// register interceptors here so that APIs from all entries would be covered.
+		initInterceptors()
+		initMetrics()
+
+		// The same as GrpcEntry, services must be registered before GrpcEntry bootstrapped.
+		initGrpc()
 	}
 
 	return res
@@ -60,18 +127,52 @@ func RegisterControllerFromConfig(configFilePath string) map[string]rkentry.Entr
 // RegisterController will register Entry into GlobalAppCtx
 func RegisterController(opts ...ControllerOption) *Controller {
 	controller := &Controller{
-		EntryName:        EntryName,
-		EntryType:        EntryType,
-		EntryDescription: EntryDescription,
-		ZapLoggerEntry:   rkentry.GlobalAppCtx.GetZapLoggerEntryDefault(),
-		EventLoggerEntry: rkentry.GlobalAppCtx.GetEventLoggerEntryDefault(),
-		Repo:             repository.GetRepository(),
+		EntryName:                EntryName,
+		EntryType:                EntryType,
+		EntryDescription:         EntryDescription,
+		ZapLoggerEntry:           rkentry.GlobalAppCtx.GetZapLoggerEntryDefault(),
+		EventLoggerEntry:         rkentry.GlobalAppCtx.GetEventLoggerEntryDefault(),
+		Repo:                     repository.GetRepository(),
+		IdempotencyWindow:        IdempotencyWindowDefault,
+		SessionTtl:               SessionTtlDefault,
+		AuthIgnorePrefix:         append([]string{}, AuthIgnorePrefixDefault...),
+		VCSMaxPages:              VCSMaxPagesDefault,
+		InstallationSyncInterval: InstallationSyncIntervalDefault,
+		installationSyncCh:       make(chan userOfSource, installationSyncQueueSize),
 	}
 
 	for i := range opts {
 		opts[i](controller)
 	}
 
+	if controller.IdempotencyWindow <= 0 {
+		controller.IdempotencyWindow = IdempotencyWindowDefault
+	}
+
+	if controller.SessionTtl <= 0 {
+		controller.SessionTtl = SessionTtlDefault
+	}
+
+	if controller.VCSMaxPages <= 0 {
+		controller.VCSMaxPages = VCSMaxPagesDefault
+	}
+
+	if controller.InstallationSyncInterval <= 0 {
+		controller.InstallationSyncInterval = InstallationSyncIntervalDefault
+	}
+
+	// sessions would be invalid after restart with random secret
+	if len(controller.SessionSecret) < 1 {
+		controller.ZapLoggerEntry.GetLogger().Warn("session secret is missing, use random secret instead")
+		controller.SessionSecret = randomSessionSecret()
+	}
+
+	// local git repositories could be used as sources only if they are under configured roots
+	if len(controller.GitRoots) > 0 {
+		RegisterSourceType(SourceTypeGit)
+		RegisterVCSProvider(SourceTypeGit, NewGitProvider(controller.GitRoots...))
+	}
+
 	rkentry.GlobalAppCtx.AddEntry(controller)
 
 	return controller
@@ -80,14 +181,74 @@ func RegisterController(opts ...ControllerOption) *Controller {
 // ControllerOption will be extended in future.
 type ControllerOption func(*Controller)
 
+// WithIdempotencyWindow provide duration while responses of create requests with Idempotency-Key could be replayed.
+func WithIdempotencyWindow(window time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.IdempotencyWindow = window
+	}
+}
+
+// WithSessionTtl provide duration before session expired.
+func WithSessionTtl(ttl time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.SessionTtl = ttl
+	}
+}
+
+// WithSessionSecret provide secret to sign sessions with HMAC.
+func WithSessionSecret(secret []byte) ControllerOption {
+	return func(controller *Controller) {
+		controller.SessionSecret = secret
+	}
+}
+
+// WithAuthIgnorePrefix provide extra path prefixes which could be accessed without session.
+func WithAuthIgnorePrefix(prefix ...string) ControllerOption {
+	return func(controller *Controller) {
+		controller.AuthIgnorePrefix = append(controller.AuthIgnorePrefix, prefix...)
+	}
+}
+
+// WithGitRoots provide directories of local bare git repositories which could be used as sources with type of git.
+func WithGitRoots(roots ...string) ControllerOption {
+	return func(controller *Controller) {
+		controller.GitRoots = append(controller.GitRoots, roots...)
+	}
+}
+
+// WithVCSMaxPages provide max number of pages followed while listing all commits, branches or tags of source.
+func WithVCSMaxPages(maxPages int) ControllerOption {
+	return func(controller *Controller) {
+		controller.VCSMaxPages = maxPages
+	}
+}
+
+// WithInstallationSyncInterval provide interval of syncing installations of users from remote code repositories in background.
+func WithInstallationSyncInterval(interval time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.InstallationSyncInterval = interval
+	}
+}
+
 // Controller performs as manager of project and organizations
 type Controller struct {
-	EntryName        string                    `json:"entryName" yaml:"entryName"`
-	EntryType        string                    `json:"entryType" yaml:"entryType"`
-	EntryDescription string                    `json:"entryDescription" yaml:"entryDescription"`
-	ZapLoggerEntry   *rkentry.ZapLoggerEntry   `json:"zapLoggerEntry" yaml:"zapLoggerEntry"`
-	EventLoggerEntry *rkentry.EventLoggerEntry `json:"eventLoggerEntry" yaml:"eventLoggerEntry"`
-	Repo             repository.Repository     `json:"repository" yaml:"repository"`
+	EntryName                string                    `json:"entryName" yaml:"entryName"`
+	EntryType                string                    `json:"entryType" yaml:"entryType"`
+	EntryDescription         string                    `json:"entryDescription" yaml:"entryDescription"`
+	ZapLoggerEntry           *rkentry.ZapLoggerEntry   `json:"zapLoggerEntry" yaml:"zapLoggerEntry"`
+	EventLoggerEntry         *rkentry.EventLoggerEntry `json:"eventLoggerEntry" yaml:"eventLoggerEntry"`
+	Repo                     repository.Repository     `json:"repository" yaml:"repository"`
+	IdempotencyWindow        time.Duration             `json:"idempotencyWindow" yaml:"idempotencyWindow"`
+	SessionTtl               time.Duration             `json:"sessionTtl" yaml:"sessionTtl"`
+	SessionSecret            []byte                    `json:"-" yaml:"-"`
+	AuthIgnorePrefix         []string                  `json:"authIgnorePrefix" yaml:"authIgnorePrefix"`
+	GitRoots                 []string                  `json:"gitRoots" yaml:"gitRoots"`
+	VCSMaxPages              int                       `json:"vcsMaxPages" yaml:"vcsMaxPages"`
+	InstallationSyncInterval time.Duration             `json:"installationSyncInterval" yaml:"installationSyncInterval"`
+	quitCh                   chan struct{}
+	installationSyncCh       chan userOfSource
+	// installationsSyncedAt records when installations of user with type of source were synced by current process
+	installationsSyncedAt sync.Map
 }
 
 // Bootstrap entry
@@ -105,6 +266,14 @@ func (con *Controller) Bootstrap(context.Context) {
 	// Get DB
 	con.Repo = repository.GetRepository()
 
+	// Remove expired idempotency keys in background
+	con.quitCh = make(chan struct{})
+	go con.removeExpiredIdempotencyKeys(con.quitCh)
+
+	// Sync installations of users in background
+	go con.syncInstallationsPeriodically(con.quitCh)
+	go con.syncEnqueuedInstallations(con.quitCh)
+
 	con.EventLoggerEntry.GetEventHelper().Finish(event)
 	logger.Info("Bootstrapping controller.", event.ListPayloads()...)
 }
@@ -117,12 +286,35 @@ func (con *Controller) Interrupt(context.Context) {
 		rkquery.WithEntryType(con.EntryType))
 	logger := con.ZapLoggerEntry.GetLogger().With(zap.String("eventId", event.GetEventId()))
 
-	// TODO: Interrupting anything related.
+	if con.quitCh != nil {
+		close(con.quitCh)
+		con.quitCh = nil
+	}
 
 	con.EventLoggerEntry.GetEventHelper().Finish(event)
 	logger.Info("Interrupting controller.", event.ListPayloads()...)
 }
 
+// Remove expired idempotency keys periodically until quitCh closed.
+func (con *Controller) removeExpiredIdempotencyKeys(quitCh chan struct{}) {
+	ticker := time.NewTicker(con.IdempotencyWindow)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quitCh:
+			return
+		case now := <-ticker.C:
+			if con.Repo == nil {
+				continue
+			}
+			if _, err := con.Repo.RemoveExpiredIdempotencyKey(now); err != nil {
+				con.ZapLoggerEntry.GetLogger().Warn("failed to remove expired idempotency keys", zap.Error(err))
+			}
+		}
+	}
+}
+
 // GetName returns entry name
 func (con *Controller) GetName() string {
 	return con.EntryName
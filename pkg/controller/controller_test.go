@@ -14,6 +14,7 @@ import (
 	"os"
 	"path"
 	"testing"
+	"time"
 )
 
 func TestRegisterControllerFromConfig(t *testing.T) {
@@ -27,6 +28,42 @@ controller:
 	entries := RegisterControllerFromConfig(tempDir)
 
 	assert.NotEmpty(t, entries)
+	assert.Equal(t, IdempotencyWindowDefault, GetController().IdempotencyWindow)
+}
+
+func TestRegisterControllerFromConfig_WithIdempotencyWindow(t *testing.T) {
+	bootConfigStr := `
+controller:
+  enabled: true
+  idempotency:
+    window: 1h
+`
+
+	tempDir := path.Join(t.TempDir(), "boot.yaml")
+	assert.Nil(t, ioutil.WriteFile(tempDir, []byte(bootConfigStr), os.ModePerm))
+	RegisterControllerFromConfig(tempDir)
+
+	assert.Equal(t, time.Hour, GetController().IdempotencyWindow)
+}
+
+func TestRegisterControllerFromConfig_WithSession(t *testing.T) {
+	bootConfigStr := `
+controller:
+  enabled: true
+  session:
+    secret: ut-secret
+    ttl: 1h
+    ignorePrefix: ["/v1/public"]
+`
+
+	tempDir := path.Join(t.TempDir(), "boot.yaml")
+	assert.Nil(t, ioutil.WriteFile(tempDir, []byte(bootConfigStr), os.ModePerm))
+	RegisterControllerFromConfig(tempDir)
+
+	assert.Equal(t, time.Hour, GetController().SessionTtl)
+	assert.Equal(t, []byte("ut-secret"), GetController().SessionSecret)
+	assert.Contains(t, GetController().AuthIgnorePrefix, "/v1/public")
+	assert.Contains(t, GetController().AuthIgnorePrefix, "/v1/oauth")
 }
 
 func TestRegisterController(t *testing.T) {
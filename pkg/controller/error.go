@@ -0,0 +1,98 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-common/error"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"go.uber.org/zap"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// httpCodes maps error code to http status code
+var httpCodes = map[repository.Code]int{
+	repository.CodeInternal:         http.StatusInternalServerError,
+	repository.CodeInvalidArgument:  http.StatusBadRequest,
+	repository.CodeUnauthenticated:  http.StatusUnauthorized,
+	repository.CodeNotFound:         http.StatusNotFound,
+	repository.CodeAlreadyExist:     http.StatusConflict,
+	repository.CodeConflict:         http.StatusConflict,
+	repository.CodePermissionDenied: http.StatusForbidden,
+	repository.CodeUpstream:         http.StatusBadGateway,
+	repository.CodeUnprocessable:    http.StatusUnprocessableEntity,
+	repository.CodeRateLimited:      http.StatusTooManyRequests,
+}
+
+// ErrorInterceptor renders the last error attached with ctx.Error() as rkerror response.
+//
+// Handlers should call ctx.Error() and return instead of writing error response by themselves.
+// The http status code would be mapped from repository.Code and the code itself would be
+// returned as status of rkerror response.
+func ErrorInterceptor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) < 1 || ctx.Writer.Written() {
+			return
+		}
+
+		err := ctx.Errors.Last().Err
+		if retryAfter := repository.RetryAfterOf(err); retryAfter > 0 {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		}
+
+		httpCode, resp := toErrorResp(err)
+		ctx.JSON(httpCode, resp)
+	}
+}
+
+// Convert error into http status code and rkerror response.
+// Causes of server errors are logged only, since they might carry messages of DB drivers or upstream responses.
+func toErrorResp(err error) (int, *rkerror.ErrorResp) {
+	var inner *repository.Error
+	if !errors.As(err, &inner) {
+		inner = repository.Wrapf(err, repository.CodeInternal, "internal error")
+	}
+	logServerError(inner)
+
+	httpCode, ok := httpCodes[inner.Code]
+	if !ok {
+		httpCode = http.StatusInternalServerError
+	}
+
+	// causes are appended as messages, since plain errors would be marshaled into empty objects
+	details := make([]interface{}, 0)
+	details = append(details, inner.Details...)
+	if cause := inner.Unwrap(); cause != nil && !isServerError(inner) {
+		details = append(details, cause.Error())
+	}
+
+	return httpCode, rkerror.New(
+		rkerror.WithCodeAndStatus(httpCode, string(inner.Code)),
+		rkerror.WithMessage(inner.Message),
+		rkerror.WithDetails(details...))
+}
+
+// Checks whether error is caused by server or upstream instead of request.
+func isServerError(err *repository.Error) bool {
+	httpCode, ok := httpCodes[err.Code]
+	return !ok || httpCode >= http.StatusInternalServerError
+}
+
+// Logs server error together with cause, which is not returned to clients.
+func logServerError(err *repository.Error) {
+	if !isServerError(err) {
+		return
+	}
+
+	rkentry.GlobalAppCtx.GetZapLoggerEntryDefault().GetLogger().Warn("server error",
+		zap.String("code", string(err.Code)), zap.Error(err))
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ExpandProjects embeds projects into organizations
+	ExpandProjects = "projects"
+	// ExpandProjCount returns counts of projects in organizations instead of ids
+	ExpandProjCount = "projCount"
+	// ExpandSource embeds source into projects
+	ExpandSource = "source"
+)
+
+// splitCsv splits comma separated values in query, empty values would be ignored.
+func splitCsv(s string) []string {
+	res := make([]string, 0)
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
+
+// renderList writes list response, fields of meta would be selected if fields is not empty.
+func renderList(ctx *gin.Context, resp interface{}, fields string) {
+	if len(fields) < 1 {
+		ctx.JSON(http.StatusOK, resp)
+		return
+	}
+
+	doc, err := selectMetaFields(resp, splitCsv(fields))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, doc)
+}
+
+// selectMetaFields converts response into JSON document and keeps fields listed in fields only
+// from meta of every resource in lists of response.
+func selectMetaFields(resp interface{}, fields []string) (interface{}, error) {
+	bytes, err := json.Marshal(resp)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to marshal response")
+	}
+
+	doc := make(map[string]interface{})
+	if err := json.Unmarshal(bytes, &doc); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to unmarshal response")
+	}
+
+	selected := make(map[string]bool)
+	for i := range fields {
+		selected[fields[i]] = true
+	}
+
+	for _, v := range doc {
+		list, ok := v.([]interface{})
+		if !ok {
+			continue
+		}
+
+		for i := range list {
+			obj, ok := list[i].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			meta, ok := obj["meta"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			for k := range meta {
+				if !selected[k] {
+					delete(meta, k)
+				}
+			}
+		}
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"encoding/json"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestSplitCsv(t *testing.T) {
+	assert.Empty(t, splitCsv(""))
+	assert.Equal(t, []string{"projects", "source"}, splitCsv(" projects,,source "))
+}
+
+func TestListOrg_WithExpandAndFields(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+	repo := GetController().Repo
+
+	// prepare an organization with two projects, one of them with source
+	org := repository.NewOrg("ut-org")
+	repo.CreateOrg(org)
+	repo.UpsertMember(repository.NewMember(org.Id, 1, repository.RoleOwner))
+	proj1 := repository.NewProj("ut-proj-1")
+	proj1.OrgId = org.Id
+	repo.CreateProj(proj1)
+	proj2 := repository.NewProj("ut-proj-2")
+	proj2.OrgId = org.Id
+	repo.CreateProj(proj2)
+	src := repository.NewSource("github", "ut-owner/ut-repo")
+	src.ProjId = proj2.Id
+	repo.CreateSource(src)
+
+	// 1: compact response with ids of projects
+	resp := doRequest(router, http.MethodGet, "/v1/org", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	listOrg := &ListOrgResponse{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), listOrg))
+	assert.Len(t, listOrg.OrgList, 1)
+	assert.Equal(t, []int{proj1.Id, proj2.Id}, listOrg.OrgList[0].ProjIds)
+	assert.Empty(t, listOrg.OrgList[0].ProjList)
+	assert.Nil(t, listOrg.OrgList[0].ProjCount)
+
+	// counts of projects instead of ids
+	resp = doRequest(router, http.MethodGet, "/v1/org?expand=projCount", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	listOrg = &ListOrgResponse{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), listOrg))
+	assert.Equal(t, 2, *listOrg.OrgList[0].ProjCount)
+	assert.Empty(t, listOrg.OrgList[0].ProjIds)
+
+	// 2: embedded projects with source
+	resp = doRequest(router, http.MethodGet, "/v1/org?expand=projects,source", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	listOrg = &ListOrgResponse{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), listOrg))
+	assert.Len(t, listOrg.OrgList[0].ProjList, 2)
+	assert.Nil(t, listOrg.OrgList[0].ProjList[0].Meta.Source)
+	assert.Equal(t, "ut-owner/ut-repo", listOrg.OrgList[0].ProjList[1].Meta.Source.Repository)
+
+	// 3: embedded projects without source
+	resp = doRequest(router, http.MethodGet, "/v1/org?expand=projects", "", "")
+	listOrg = &ListOrgResponse{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), listOrg))
+	assert.Nil(t, listOrg.OrgList[0].ProjList[1].Meta.Source)
+	// source should stay in repository
+	assert.NotNil(t, proj2.Source)
+
+	// 4: selected fields
+	resp = doRequest(router, http.MethodGet, "/v1/org?fields=id,name", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	doc := make(map[string][]map[string]interface{})
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &doc))
+	assert.Equal(t, map[string]interface{}{"id": float64(org.Id), "name": "ut-org"}, doc["orgList"][0]["meta"])
+	assert.NotEmpty(t, doc["orgList"][0]["projIds"])
+}
+
+func TestListProj_WithExpandAndFields(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+	repo := GetController().Repo
+
+	// prepare an organization with a project with source
+	org := repository.NewOrg("ut-org")
+	repo.CreateOrg(org)
+	repo.UpsertMember(repository.NewMember(org.Id, 1, repository.RoleOwner))
+	proj := repository.NewProj("ut-proj")
+	proj.OrgId = org.Id
+	repo.CreateProj(proj)
+	src := repository.NewSource("github", "ut-owner/ut-repo")
+	src.ProjId = proj.Id
+	repo.CreateSource(src)
+
+	// 1: compact response without source
+	resp := doRequest(router, http.MethodGet, "/v1/proj", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	listProj := &ListProjResponse{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), listProj))
+	assert.Len(t, listProj.ProjList, 1)
+	assert.Nil(t, listProj.ProjList[0].Meta.Source)
+
+	// 2: embedded source
+	resp = doRequest(router, http.MethodGet, "/v1/proj?orgId=1&expand=source", "", "")
+	listProj = &ListProjResponse{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), listProj))
+	assert.Equal(t, src.Id, listProj.ProjList[0].Meta.Source.Id)
+
+	// 3: selected fields keep embedded source
+	resp = doRequest(router, http.MethodGet, "/v1/proj?expand=source&fields=name", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	doc := make(map[string][]map[string]interface{})
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &doc))
+	meta := doc["projList"][0]["meta"].(map[string]interface{})
+	assert.Len(t, meta, 2)
+	assert.Equal(t, "ut-proj", meta["name"])
+	assert.NotNil(t, meta["source"])
+
+	// 4: projects could not be expanded in projects
+	resp = doRequest(router, http.MethodGet, "/v1/proj?expand=projects", "", "")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SourceTypeGit is type of source which is a bare git repository on local disk
+	SourceTypeGit = "git"
+	// gitFileScheme is scheme of URL of local git repository, like file:///srv/git/repo.git
+	gitFileScheme = "file://"
+)
+
+// GitProvider reads commits, branches and tags of local bare git repositories with git binary.
+// Repositories are accessed without any token, so that only repositories under roots could be read.
+type GitProvider struct {
+	Roots []string
+}
+
+// NewGitProvider creates provider which reads repositories under roots only.
+func NewGitProvider(roots ...string) *GitProvider {
+	res := &GitProvider{
+		Roots: make([]string, 0),
+	}
+
+	for i := range roots {
+		if root, err := filepath.Abs(roots[i]); err == nil {
+			res.Roots = append(res.Roots, root)
+		}
+	}
+
+	return res
+}
+
+// ListInstallations returns nothing since local repositories are not installed by users.
+func (p *GitProvider) ListInstallations(ctx context.Context, userId int) ([]*Installation, error) {
+	return make([]*Installation, 0), nil
+}
+
+// ListRepos returns nothing since local repositories are not installed by users.
+func (p *GitProvider) ListRepos(ctx context.Context, userId int, installation *Installation) ([]*Repository, error) {
+	return make([]*Repository, 0), nil
+}
+
+// GetRepository returns local repository of source with branch which HEAD points to as default branch.
+// Path of repository identifies it, so id of repository is left empty.
+func (p *GitProvider) GetRepository(ctx context.Context, src *repository.Source) (*Repository, error) {
+	gitDir, err := p.gitDirOf(src)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := p.git(ctx, gitDir, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return nil, repository.NewNotFoundf("local git repository:%s not found", src.Repository)
+	}
+
+	return &Repository{
+		FullName:      src.Repository,
+		Name:          strings.TrimSuffix(filepath.Base(gitDir), ".git"),
+		DefaultBranch: strings.TrimSpace(string(head)),
+	}, nil
+}
+
+// ListCommits returns commits of branch, commits of HEAD would be returned if branch is empty.
+// Total is counted with commits reachable from branch.
+func (p *GitProvider) ListCommits(ctx context.Context, src *repository.Source, branch string, perPage, page int) ([]*Commit, *Pagination, error) {
+	gitDir, err := p.gitDirOf(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(branch) < 1 {
+		branch = "HEAD"
+	}
+	if err := p.verifyRevision(ctx, gitDir, branch+"^{commit}"); err != nil {
+		return nil, nil, repository.NewNotFoundf("branch:%s not found in %s", branch, src.Repository)
+	}
+
+	count, err := p.git(ctx, gitDir, "rev-list", "--count", branch, "--")
+	if err != nil {
+		return nil, nil, repository.NewUpstreamf(err, "failed to count commits of %s", src.Repository)
+	}
+	total, _ := strconv.Atoi(strings.TrimSpace(string(count)))
+
+	// fields are separated with unit separator and commits are separated with record separator
+	perPage, page = normalizePage(perPage, page)
+	out, err := p.git(ctx, gitDir, "log", "--format=%H%x1f%cn%x1f%cI%x1f%B%x1e",
+		fmt.Sprintf("--max-count=%d", perPage), fmt.Sprintf("--skip=%d", (page-1)*perPage), branch, "--")
+	if err != nil {
+		return nil, nil, repository.NewUpstreamf(err, "failed to list commits of %s", src.Repository)
+	}
+
+	res := make([]*Commit, 0)
+	for _, record := range strings.Split(string(out), "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x1f", 4)
+		if len(fields) < 4 {
+			continue
+		}
+
+		date, _ := time.Parse(time.RFC3339, fields[2])
+		res = append(res, &Commit{
+			Id:        fields[0],
+			Message:   strings.TrimSpace(fields[3]),
+			Date:      date,
+			Committer: fields[1],
+		})
+	}
+
+	return res, paginationOf(total, perPage, page), nil
+}
+
+// ListBranches returns names of branches in order of names.
+func (p *GitProvider) ListBranches(ctx context.Context, src *repository.Source, perPage, page int) ([]string, *Pagination, error) {
+	return p.listRefs(ctx, src, "refs/heads", perPage, page)
+}
+
+// ListTags returns names of tags in order of names.
+func (p *GitProvider) ListTags(ctx context.Context, src *repository.Source, perPage, page int) ([]string, *Pagination, error) {
+	return p.listRefs(ctx, src, "refs/tags", perPage, page)
+}
+
+// GetFileContent returns content of file at ref, HEAD would be used if ref is empty.
+func (p *GitProvider) GetFileContent(ctx context.Context, src *repository.Source, ref, path string) ([]byte, error) {
+	gitDir, err := p.gitDirOf(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ref) < 1 {
+		ref = "HEAD"
+	}
+
+	// everything after colon is path of file, so that type of object is checked instead of peeling it
+	object := ref + ":" + strings.TrimPrefix(path, "/")
+	if err := p.verifyRevision(ctx, gitDir, object); err != nil {
+		return nil, repository.NewNotFoundf("file:%s not found in %s at ref:%s", path, src.Repository, ref)
+	}
+
+	objectType, err := p.git(ctx, gitDir, "cat-file", "-t", object)
+	if err != nil {
+		return nil, repository.NewUpstreamf(err, "failed to get file:%s of %s", path, src.Repository)
+	}
+	if strings.TrimSpace(string(objectType)) != "blob" {
+		return nil, repository.NewInvalidArgumentf("path:%s of %s is not a file", path, src.Repository)
+	}
+
+	out, err := p.git(ctx, gitDir, "cat-file", "blob", object)
+	if err != nil {
+		return nil, repository.NewUpstreamf(err, "failed to get file:%s of %s", path, src.Repository)
+	}
+
+	return out, nil
+}
+
+// List short names of refs with prefix.
+func (p *GitProvider) listRefs(ctx context.Context, src *repository.Source, prefix string, perPage, page int) ([]string, *Pagination, error) {
+	gitDir, err := p.gitDirOf(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out, err := p.git(ctx, gitDir, "for-each-ref", "--sort=refname", "--format=%(refname:short)", prefix)
+	if err != nil {
+		return nil, nil, repository.NewUpstreamf(err, "failed to list %s of %s", prefix, src.Repository)
+	}
+
+	refs := strings.Fields(string(out))
+	start, end := pageOf(len(refs), perPage, page)
+
+	return append(make([]string, 0), refs[start:end]...), paginationOf(len(refs), perPage, page), nil
+}
+
+// Returns path of bare repository of source, which must be under one of roots.
+// Symbolic links are resolved before comparing, so that links under roots could not point to repositories outside.
+func (p *GitProvider) gitDirOf(src *repository.Source) (string, error) {
+	path := src.Repository
+	if strings.HasPrefix(path, gitFileScheme) {
+		u, err := url.Parse(path)
+		if err != nil || len(u.Host) > 0 {
+			return "", repository.NewInvalidArgumentf("invalid url of local git repository:%s", src.Repository)
+		}
+		path = u.Path
+	}
+
+	if !filepath.IsAbs(path) {
+		return "", repository.NewInvalidArgumentf("path of local git repository must be absolute:%s", src.Repository)
+	}
+
+	path, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", repository.NewNotFoundf("local git repository:%s not found", src.Repository)
+	}
+
+	for i := range p.Roots {
+		root, err := filepath.EvalSymlinks(p.Roots[i])
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return path, nil
+		}
+	}
+
+	return "", repository.NewNotFoundf("local git repository:%s not found", src.Repository)
+}
+
+// Checks whether revision could be resolved, options are rejected so that refs from request are not parsed as options.
+func (p *GitProvider) verifyRevision(ctx context.Context, gitDir, rev string) error {
+	if strings.HasPrefix(rev, "-") {
+		return repository.NewInvalidArgumentf("invalid revision:%s", rev)
+	}
+
+	_, err := p.git(ctx, gitDir, "rev-parse", "--verify", "--quiet", rev)
+	return err
+}
+
+// Runs git command against bare repository and returns stdout, stderr would be included in error.
+func (p *GitProvider) git(ctx context.Context, gitDir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"--git-dir=" + gitDir}, args...)...)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v, %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// Checks whether repository is absolute path or file:// URL of local git repository.
+func isLocalGitRepository(repo string) bool {
+	if strings.HasPrefix(repo, gitFileScheme) {
+		u, err := url.Parse(repo)
+		return err == nil && len(u.Host) < 1 && filepath.IsAbs(u.Path)
+	}
+
+	return filepath.IsAbs(repo)
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// Creates bare repository ut-repo.git under root with two commits on main, branch dev and tag v1.0.0.
+func newLocalGitRepo(t *testing.T, root string) string {
+	work := t.TempDir()
+	run := func(dir string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=ut-author", "GIT_AUTHOR_EMAIL=ut@example.com",
+			"GIT_COMMITTER_NAME=ut-committer", "GIT_COMMITTER_EMAIL=ut@example.com",
+			"GIT_COMMITTER_DATE=2021-08-01T00:00:00Z")
+		out, err := cmd.CombinedOutput()
+		assert.Nil(t, err, string(out))
+	}
+
+	run(work, "init", "-q", "-b", "main")
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(work, "ws.yaml"), []byte("ut-content"), 0644))
+	run(work, "add", "ws.yaml")
+	run(work, "commit", "-q", "-m", "ut-first")
+	run(work, "tag", "v1.0.0")
+	run(work, "commit", "-q", "--allow-empty", "-m", "ut-second")
+	run(work, "branch", "dev", "HEAD~1")
+
+	path := filepath.Join(root, "ut-repo.git")
+	run(work, "clone", "-q", "--bare", work, path)
+
+	return path
+}
+
+func TestGitProvider(t *testing.T) {
+	root := t.TempDir()
+	path := newLocalGitRepo(t, root)
+	provider := NewGitProvider(root)
+	src := repository.NewSource(SourceTypeGit, "file://"+path)
+	ctx := context.Background()
+
+	// 1: commits of HEAD are paged, latest commit comes first
+	commits, pagination, err := provider.ListCommits(ctx, src, "", 1, 1)
+	assert.Nil(t, err)
+	assert.Len(t, commits, 1)
+	assert.Equal(t, &Pagination{Page: 1, PerPage: 1, NextPage: 2, LastPage: 2, Total: 2}, pagination)
+	assert.Equal(t, "ut-second", commits[0].Message)
+	assert.Equal(t, "ut-committer", commits[0].Committer)
+	assert.Equal(t, 2021, commits[0].Date.Year())
+	commits, _, err = provider.ListCommits(ctx, src, "dev", 10, 1)
+	assert.Nil(t, err)
+	assert.Len(t, commits, 1)
+	assert.Equal(t, "ut-first", commits[0].Message)
+	_, _, err = provider.ListCommits(ctx, src, "ut-missing", 10, 1)
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+	_, _, err = provider.ListCommits(ctx, src, "--all", 10, 1)
+	assert.NotNil(t, err)
+
+	// 2: branches and tags
+	branches, _, err := provider.ListBranches(ctx, src, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"dev", "main"}, branches)
+	branches, pagination, err = provider.ListBranches(ctx, src, 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"main"}, branches)
+	assert.Equal(t, &Pagination{Page: 2, PerPage: 1, LastPage: 2, Total: 2}, pagination)
+	tags, _, err := provider.ListTags(ctx, src, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v1.0.0"}, tags)
+
+	// 3: file contents
+	content, err := provider.GetFileContent(ctx, src, "v1.0.0", "/ws.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-content", string(content))
+	_, err = provider.GetFileContent(ctx, src, "", "ut-missing.yaml")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	// 4: repository with branch which HEAD points to
+	remote, err := provider.GetRepository(ctx, src)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-repo", remote.Name)
+	assert.Equal(t, "main", remote.DefaultBranch)
+
+	// 5: repositories outside of roots could not be read
+	_, _, err = provider.ListBranches(ctx, repository.NewSource(SourceTypeGit, filepath.Join(root, "..", "ut-other.git")), 0, 0)
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+	_, _, err = provider.ListBranches(ctx, repository.NewSource(SourceTypeGit, "ut-repo.git"), 0, 0)
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+	_, _, err = provider.ListBranches(ctx, repository.NewSource(SourceTypeGit, filepath.Join(root, "ut-missing.git")), 0, 0)
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+}
+
+func TestGitProvider_WithSymlinks(t *testing.T) {
+	root := t.TempDir()
+	outside := newLocalGitRepo(t, t.TempDir())
+	ctx := context.Background()
+
+	// 1: link under root could not point to repository outside of roots
+	link := filepath.Join(root, "ut-link.git")
+	assert.Nil(t, os.Symlink(outside, link))
+	_, _, err := NewGitProvider(root).ListBranches(ctx, repository.NewSource(SourceTypeGit, link), 0, 0)
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	// 2: root which is a link is resolved as well
+	path := newLocalGitRepo(t, root)
+	rootLink := filepath.Join(t.TempDir(), "ut-root")
+	assert.Nil(t, os.Symlink(root, rootLink))
+	branches, _, err := NewGitProvider(rootLink).ListBranches(ctx, repository.NewSource(SourceTypeGit, path), 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"dev", "main"}, branches)
+}
+
+func TestSourceApi_WithLocalGit(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+	root := t.TempDir()
+	path := newLocalGitRepo(t, root)
+
+	// git sources are enabled with roots
+	RegisterController(WithGitRoots(root))
+	assert.True(t, IsSourceTypeRegistered(SourceTypeGit))
+
+	repo := GetController().Repo
+	org := repository.NewOrg("ut-org")
+	repo.CreateOrg(org)
+	repo.UpsertMember(repository.NewMember(org.Id, 1, repository.RoleOwner))
+	proj := repository.NewProj("ut-proj")
+	proj.OrgId = org.Id
+	repo.CreateProj(proj)
+
+	// 1: repository of git source must be local repository
+	resp := doRequest(router, http.MethodPost, "/v2/projects/1/source", binding.MIMEJSON, `{"type":"git","repository":"ut-owner/ut-repo"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "gitrepository")
+
+	// 2: create source with path of bare repository
+	resp = doRequest(router, http.MethodPost, "/v2/projects/1/source", binding.MIMEJSON, `{"type":"git","repository":"`+path+`"}`)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	// 3: commits, branches and tags are read without any token
+	resp = doRequest(router, http.MethodGet, "/v1/source/1/commits", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "ut-second")
+	resp = doRequest(router, http.MethodGet, "/v1/source/1/branches", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"branches":["dev","main"],"tags":["v1.0.0"]`)
+}
@@ -0,0 +1,267 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// giteaReposPerPage is page size while listing repositories of user, Gitea accepts at most 50 by default
+	giteaReposPerPage = 50
+	// giteaTokenPrefix is prefix of authorization header accepted by Gitea and Forgejo
+	giteaTokenPrefix = "token "
+)
+
+// GiteaProvider accesses repositories of gitea sources with REST API v1 and access tokens of users.
+// Forgejo is compatible with Gitea, so it is supported by the same provider.
+// Repositories of user are grouped by owners, which are returned as installations.
+type GiteaProvider struct {
+	BaseUrl string
+}
+
+// NewGiteaProvider creates provider with URL of Gitea or Forgejo instance, like https://gitea.example.com.
+func NewGiteaProvider(baseUrl string) *GiteaProvider {
+	return &GiteaProvider{
+		BaseUrl: strings.TrimSuffix(baseUrl, "/"),
+	}
+}
+
+type giteaUser struct {
+	Id        int64  `json:"id"`
+	Login     string `json:"login"`
+	AvatarUrl string `json:"avatar_url"`
+	HtmlUrl   string `json:"html_url"`
+}
+
+type giteaRepo struct {
+	Id            int64     `json:"id"`
+	Name          string    `json:"name"`
+	FullName      string    `json:"full_name"`
+	DefaultBranch string    `json:"default_branch"`
+	Owner         giteaUser `json:"owner"`
+}
+
+type giteaCommit struct {
+	Sha     string `json:"sha"`
+	HtmlUrl string `json:"html_url"`
+	Commit  struct {
+		Message   string `json:"message"`
+		Committer struct {
+			Name string    `json:"name"`
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+	Committer *giteaUser `json:"committer"`
+}
+
+type giteaRef struct {
+	Name string `json:"name"`
+}
+
+// ListInstallations returns owners of repositories which user could access.
+func (p *GiteaProvider) ListInstallations(ctx context.Context, userId int) ([]*Installation, error) {
+	repos, err := p.listRepos(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*Installation, 0)
+	seen := make(map[int64]bool)
+	for i := range repos {
+		owner := repos[i].Owner
+		if seen[owner.Id] {
+			continue
+		}
+		seen[owner.Id] = true
+
+		res = append(res, &Installation{
+			Id:           owner.Id,
+			RepoSource:   repository.IdentityGitea,
+			Organization: owner.Login,
+			AvatarUrl:    owner.AvatarUrl,
+			Repos:        make([]*Repository, 0),
+		})
+	}
+
+	return res, nil
+}
+
+// ListRepos returns repositories of owner which user could access.
+func (p *GiteaProvider) ListRepos(ctx context.Context, userId int, installation *Installation) ([]*Repository, error) {
+	repos, err := p.listRepos(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*Repository, 0)
+	for i := range repos {
+		if repos[i].Owner.Id == installation.Id {
+			res = append(res, &Repository{
+				FullName: repos[i].FullName,
+				Name:     repos[i].Name,
+			})
+		}
+	}
+
+	return res, nil
+}
+
+// ListCommits returns commits of branch, commits of default branch would be returned if branch is empty.
+func (p *GiteaProvider) ListCommits(ctx context.Context, src *repository.Source, branch string, perPage, page int) ([]*Commit, *Pagination, error) {
+	perPage, page = normalizePage(perPage, page)
+	query := giteaPageQuery(perPage, page)
+	if len(branch) > 0 {
+		query.Set("sha", branch)
+	}
+
+	commits := make([]*giteaCommit, 0)
+	header, err := p.getRepoResource(ctx, src, "/commits", query, &commits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res := make([]*Commit, 0)
+	for i := range commits {
+		commit := &Commit{
+			Id:        commits[i].Sha,
+			Url:       commits[i].HtmlUrl,
+			Message:   commits[i].Commit.Message,
+			Date:      commits[i].Commit.Committer.Date,
+			Committer: commits[i].Commit.Committer.Name,
+		}
+		// committer is missing if email of commit does not belong to any user
+		if commits[i].Committer != nil {
+			commit.CommitterUrl = commits[i].Committer.HtmlUrl
+		}
+		res = append(res, commit)
+	}
+
+	return res, giteaPaginationOf(header, perPage, page, len(res)), nil
+}
+
+// ListBranches returns names of branches.
+func (p *GiteaProvider) ListBranches(ctx context.Context, src *repository.Source, perPage, page int) ([]string, *Pagination, error) {
+	return p.listRefs(ctx, src, "/branches", perPage, page)
+}
+
+// ListTags returns names of tags.
+func (p *GiteaProvider) ListTags(ctx context.Context, src *repository.Source, perPage, page int) ([]string, *Pagination, error) {
+	return p.listRefs(ctx, src, "/tags", perPage, page)
+}
+
+// GetFileContent returns raw content of file at ref, default branch would be used if ref is empty.
+func (p *GiteaProvider) GetFileContent(ctx context.Context, src *repository.Source, ref, path string) ([]byte, error) {
+	query := url.Values{}
+	if len(ref) > 0 {
+		query.Set("ref", ref)
+	}
+
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+
+	res := make([]byte, 0)
+	_, err := p.getRepoResource(ctx, src, "/raw/"+strings.Join(segments, "/"), query, &res)
+
+	return res, err
+}
+
+// GetRepository returns repository of source.
+func (p *GiteaProvider) GetRepository(ctx context.Context, src *repository.Source) (*Repository, error) {
+	repo := &giteaRepo{}
+	if _, err := p.getRepoResource(ctx, src, "", url.Values{}, repo); err != nil {
+		return nil, err
+	}
+
+	return &Repository{
+		Id:            strconv.FormatInt(repo.Id, 10),
+		FullName:      repo.FullName,
+		Name:          repo.Name,
+		DefaultBranch: repo.DefaultBranch,
+	}, nil
+}
+
+// List repositories which user could access, with access token of user.
+func (p *GiteaProvider) listRepos(ctx context.Context, userId int) ([]*giteaRepo, error) {
+	token, err := GetController().findUserAccessToken(userId, repository.IdentityGitea)
+	if err != nil {
+		return nil, err
+	}
+
+	rawUrl := p.BaseUrl + "/api/v1/user/repos?" + giteaPageQuery(giteaReposPerPage, PageDefault).Encode()
+	res := make([]*giteaRepo, 0)
+	if err := getVCSResource(ctx, rawUrl, giteaTokenPrefix+token.Token, &res); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list repositories of user:%s from gitea", token.User)
+	}
+
+	return res, nil
+}
+
+// List names of branches or tags.
+func (p *GiteaProvider) listRefs(ctx context.Context, src *repository.Source, path string, perPage, page int) ([]string, *Pagination, error) {
+	perPage, page = normalizePage(perPage, page)
+
+	refs := make([]*giteaRef, 0)
+	header, err := p.getRepoResource(ctx, src, path, giteaPageQuery(perPage, page), &refs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res := make([]string, 0)
+	for i := range refs {
+		res = append(res, refs[i].Name)
+	}
+
+	return res, giteaPaginationOf(header, perPage, page, len(res)), nil
+}
+
+// Get resource of repository with access token of user who created source.
+// Header of response would be returned, which carries pagination of list.
+func (p *GiteaProvider) getRepoResource(ctx context.Context, src *repository.Source, path string, query url.Values, out interface{}) (http.Header, error) {
+	// repo was stored with format of owner/repo
+	owner, repo, err := splitRepository(src.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := GetController().findAccessToken(src.Type, src.User)
+	if err != nil {
+		return nil, err
+	}
+
+	rawUrl := fmt.Sprintf("%s/api/v1/repos/%s/%s%s?%s", p.BaseUrl, url.PathEscape(owner), url.PathEscape(repo), path, query.Encode())
+	header, err := getVCSResourceWithHeader(ctx, rawUrl, giteaTokenPrefix+token.Token, out)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to get %s%s from gitea", src.Repository, path)
+	}
+
+	return header, nil
+}
+
+// Returns pagination from Link and X-Total-Count headers of Gitea.
+func giteaPaginationOf(header http.Header, perPage, page, size int) *Pagination {
+	next, last := pagesOfLinkHeader(header)
+	return newPagination(perPage, page, size, next, last, intOfHeader(header, "X-Total-Count"))
+}
+
+// Returns query of page, Gitea names page size as limit.
+func giteaPageQuery(perPage, page int) url.Values {
+	perPage, page = normalizePage(perPage, page)
+
+	return url.Values{
+		"limit": {fmt.Sprint(perPage)},
+		"page":  {fmt.Sprint(page)},
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Stand-in of Gitea REST API v1 which accepts access token ut-gitea-token only.
+func newFakeGiteaApi(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token ut-gitea-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.EscapedPath() {
+		case "/api/v1/user/repos":
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"name": "ut-repo", "full_name": "ut-org/ut-repo", "owner": map[string]interface{}{"id": 1, "login": "ut-org", "avatar_url": "https://ut-gitea/ut.png"}},
+				{"name": "ut-other", "full_name": "ut-org/ut-other", "owner": map[string]interface{}{"id": 1, "login": "ut-org"}},
+				{"name": "ut-mine", "full_name": "ut-user/ut-mine", "owner": map[string]interface{}{"id": 2, "login": "ut-user"}},
+			})
+		case "/api/v1/repos/ut-org/ut-repo":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id": 42, "name": "ut-repo", "full_name": "ut-org/ut-repo", "default_branch": "main",
+			})
+		case "/api/v1/repos/ut-org/ut-repo/commits":
+			assert.Equal(t, "dev", r.URL.Query().Get("sha"))
+			assert.Equal(t, "5", r.URL.Query().Get("limit"))
+			w.Header().Set("X-Total-Count", "12")
+			w.Header().Set("Link", `<https://ut-gitea/api/v1/repos/ut-org/ut-repo/commits?limit=5&page=2>; rel="next",`+
+				`<https://ut-gitea/api/v1/repos/ut-org/ut-repo/commits?limit=5&page=3>; rel="last"`)
+			json.NewEncoder(w).Encode([]map[string]interface{}{{
+				"sha":      "ut-sha",
+				"html_url": "https://ut-gitea/ut-sha",
+				"commit": map[string]interface{}{
+					"message":   "ut-message",
+					"committer": map[string]interface{}{"name": "ut-committer", "date": "2021-08-01T00:00:00Z"},
+				},
+				"committer": map[string]interface{}{"login": "ut-committer", "html_url": "https://ut-gitea/ut-committer"},
+			}})
+		case "/api/v1/repos/ut-org/ut-repo/branches":
+			json.NewEncoder(w).Encode([]map[string]string{{"name": "main"}, {"name": "dev"}})
+		case "/api/v1/repos/ut-org/ut-repo/tags":
+			json.NewEncoder(w).Encode([]map[string]string{{"name": "v1.0.0"}})
+		case "/api/v1/repos/ut-org/ut-repo/raw/ci/ws.yaml":
+			assert.Equal(t, "dev", r.URL.Query().Get("ref"))
+			w.Write([]byte("ut-content"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGiteaProvider(t *testing.T) {
+	server := newFakeGiteaApi(t)
+	repo := repository.RegisterMemory()
+	RegisterController()
+	ctx := context.Background()
+
+	// user with linked gitea identity
+	user := repository.NewUser("ut-user")
+	repo.CreateUser(user)
+	identity := repository.NewIdentity(repository.IdentityGitea, "ut-user")
+	identity.UserId = user.Id
+	repo.UpsertIdentity(identity)
+	repo.UpsertAccessToken(repository.NewAccessToken(1, repository.IdentityGitea, "ut-user", "ut-gitea-token"))
+
+	provider := NewGiteaProvider(server.URL + "/")
+	src := repository.NewSource(repository.IdentityGitea, "ut-org/ut-repo")
+	src.User = "ut-user"
+
+	// 1: repositories are grouped by owners
+	installations, err := provider.ListInstallations(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 2)
+	assert.Equal(t, "ut-org", installations[0].Organization)
+	assert.Equal(t, "https://ut-gitea/ut.png", installations[0].AvatarUrl)
+	repos, err := provider.ListRepos(ctx, 1, installations[0])
+	assert.Nil(t, err)
+	assert.Equal(t, []*Repository{
+		{FullName: "ut-org/ut-repo", Name: "ut-repo"},
+		{FullName: "ut-org/ut-other", Name: "ut-other"},
+	}, repos)
+
+	// 2: commits
+	commits, pagination, err := provider.ListCommits(ctx, src, "dev", 5, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, &Pagination{Page: 1, PerPage: 5, NextPage: 2, LastPage: 3, Total: 12}, pagination)
+	assert.Equal(t, "ut-sha", commits[0].Id)
+	assert.Equal(t, "ut-committer", commits[0].Committer)
+	assert.Equal(t, "https://ut-gitea/ut-committer", commits[0].CommitterUrl)
+	assert.Equal(t, 2021, commits[0].Date.Year())
+
+	// 3: branches and tags
+	branches, _, err := provider.ListBranches(ctx, src, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"main", "dev"}, branches)
+	tags, _, err := provider.ListTags(ctx, src, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v1.0.0"}, tags)
+
+	// 4: file contents
+	content, err := provider.GetFileContent(ctx, src, "dev", "/ci/ws.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-content", string(content))
+	_, err = provider.GetFileContent(ctx, src, "dev", "ut-missing.yaml")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	// 5: repository
+	remote, err := provider.GetRepository(ctx, src)
+	assert.Nil(t, err)
+	assert.Equal(t, &Repository{Id: "42", FullName: "ut-org/ut-repo", Name: "ut-repo", DefaultBranch: "main"}, remote)
+	_, err = provider.GetRepository(ctx, repository.NewSource(repository.IdentityGitea, "ut-org/ut-missing"))
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	// 6: token rejected by gitea
+	repo.UpsertAccessToken(repository.NewAccessToken(1, repository.IdentityGitea, "ut-user", "ut-invalid-token"))
+	_, _, err = provider.ListBranches(ctx, src, 0, 0)
+	assert.Equal(t, repository.CodeUpstream, repository.CodeOf(err))
+}
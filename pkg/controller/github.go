@@ -2,186 +2,303 @@ package controller
 
 import (
 	"context"
-	"fmt"
 	"github.com/google/go-github/v39/github"
 	"github.com/pointgoal/workstation/pkg/repository"
 	"golang.org/x/oauth2"
-	"strings"
-)
-
-const (
-	PerPageDefault = 10
-	PageDefault    = 1
+	"net/http"
+	"strconv"
 )
 
 type Installation struct {
+	Id           int64         `yaml:"id" json:"id"`
 	RepoSource   string        `yaml:"repoSource" json:"repoSource"`
+	Host         string        `yaml:"host,omitempty" json:"host,omitempty"`
 	Organization string        `yaml:"organization" json:"organization"`
 	AvatarUrl    string        `yaml:"avatarUrl" json:"avatarUrl"`
 	Repos        []*Repository `yaml:"repos" json:"repos"`
 }
 
 type Repository struct {
-	FullName string `yaml:"fullName" json:"fullName"`
-	Name     string `yaml:"name" json:"name"`
+	Id            string `yaml:"id" json:"id,omitempty"`
+	FullName      string `yaml:"fullName" json:"fullName"`
+	Name          string `yaml:"name" json:"name"`
+	DefaultBranch string `yaml:"defaultBranch" json:"defaultBranch,omitempty"`
 }
 
-// ListUserInstallations returns repositories from code repo created by user.
-// The repos would have access permission with Github app named as workstation.
-func ListUserInstallationsFromGithub(user string) ([]*Installation, error) {
-	res := make([]*Installation, 0)
+// GithubProvider accesses repositories of github sources on github.com or GitHub Enterprise Server.
+// Installation tokens of GitHub App are preferred, access tokens of users are used otherwise.
+// Installations of users are listed from primary host which users sign in with.
+type GithubProvider struct{}
 
-	// 1: Get access code from repo
-	repo := repository.GetRepository()
+// ListInstallations returns installations of Github app named as workstation which user could access.
+func (p *GithubProvider) ListInstallations(ctx context.Context, userId int) ([]*Installation, error) {
+	res := make([]*Installation, 0)
 
-	accessToken, err := repo.GetAccessToken("github", user)
+	client, host, user, err := p.userClientOf(userId)
 	if err != nil {
 		return res, err
 	}
 
-	client := getGithubClient(accessToken.Token)
-	listOpts := &github.ListOptions{}
+	// follow pages until there are no more pages or max pages of controller reached
+	installsFromGithub := make([]*github.Installation, 0)
+	opts := &github.ListOptions{PerPage: PerPageMax}
+	for i := 0; i < GetController().VCSMaxPages; i++ {
+		installs, resp, err := client.Apps.ListUserInstallations(ctx, opts)
+		if err != nil {
+			return res, githubErrorf(err, "failed to list installations of user:%s from github", user)
+		}
+		installsFromGithub = append(installsFromGithub, installs...)
 
-	installsFromGithub, _, err := client.Apps.ListUserInstallations(context.Background(), listOpts)
-	if err != nil {
-		return res, err
+		if resp.NextPage <= opts.Page {
+			break
+		}
+		opts.Page = resp.NextPage
 	}
 
 	for i := range installsFromGithub {
-		install := &Installation{
+		res = append(res, &Installation{
+			Id:           installsFromGithub[i].GetID(),
 			AvatarUrl:    installsFromGithub[i].GetAccount().GetAvatarURL(),
-			RepoSource:   "github",
+			RepoSource:   repository.IdentityGithub,
+			Host:         host.Host,
 			Organization: installsFromGithub[i].GetAccount().GetLogin(),
 			Repos:        make([]*Repository, 0),
-		}
+		})
+	}
+
+	return res, nil
+}
+
+// ListRepos returns repositories of installation which user could access.
+func (p *GithubProvider) ListRepos(ctx context.Context, userId int, installation *Installation) ([]*Repository, error) {
+	res := make([]*Repository, 0)
 
-		// list repositories from this installation
-		userReposFromRepo, _, err := client.Apps.ListUserRepos(context.Background(), installsFromGithub[i].GetID(), listOpts)
+	client, _, _, err := p.userClientOf(userId)
+	if err != nil {
+		return res, err
+	}
+
+	// follow pages until there are no more pages or max pages of controller reached
+	opts := &github.ListOptions{PerPage: PerPageMax}
+	for i := 0; i < GetController().VCSMaxPages; i++ {
+		reposFromGithub, resp, err := client.Apps.ListUserRepos(ctx, installation.Id, opts)
 		if err != nil {
-			return res, err
+			return res, githubErrorf(err, "failed to list repositories of installation:%d from github", installation.Id)
+		}
+
+		for j := range reposFromGithub.Repositories {
+			res = append(res, &Repository{
+				FullName: reposFromGithub.Repositories[j].GetFullName(),
+				Name:     reposFromGithub.Repositories[j].GetName(),
+			})
 		}
 
-		for j := range userReposFromRepo.Repositories {
-			repo := &Repository{
-				FullName: userReposFromRepo.Repositories[j].GetFullName(),
-				Name:     userReposFromRepo.Repositories[j].GetName(),
-			}
-			install.Repos = append(install.Repos, repo)
+		if resp.NextPage <= opts.Page {
+			break
 		}
-		res = append(res, install)
+		opts.Page = resp.NextPage
 	}
 
 	return res, nil
 }
 
-// ListBranchesAndTagsFromGithub returns branches and taqs from github repository.
-func ListBranchesAndTagsFromGithub(src *repository.Source, accessToken string, perPage, page int) ([]string, []string, error) {
-	branches := make([]string, 0)
-	tags := make([]string, 0)
+// GetRepository returns repository of source from remote github repository with access token of user bound to source,
+// so that sources could not be created with repositories which are accessible by app only. Sources on other hosts
+// are verified with installation tokens of apps, since tokens of users are issued by primary host.
+func (p *GithubProvider) GetRepository(ctx context.Context, src *repository.Source) (*Repository, error) {
+	owner, repo, client, err := p.userSourceClientOf(src)
+	if err != nil {
+		return nil, err
+	}
 
-	client := getGithubClient(accessToken)
+	repoFromGithub, resp, err := client.Repositories.Get(ctx, owner, repo)
+	// github answers 404 for private repositories which are not visible to token
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, repository.NewNotFoundf("repository %s not found on github", src.Repository)
+	}
+	if err != nil {
+		return nil, githubErrorf(err, "failed to get repository %s from github", src.Repository)
+	}
 
-	opts := &github.BranchListOptions{
+	return &Repository{
+		Id:            strconv.FormatInt(repoFromGithub.GetID(), 10),
+		FullName:      repoFromGithub.GetFullName(),
+		Name:          repoFromGithub.GetName(),
+		DefaultBranch: repoFromGithub.GetDefaultBranch(),
+	}, nil
+}
+
+// ListCommits returns commits from remote github repository, pagination is parsed from Link header.
+func (p *GithubProvider) ListCommits(ctx context.Context, src *repository.Source, branch string, perPage, page int) ([]*Commit, *Pagination, error) {
+	res := make([]*Commit, 0)
+
+	owner, repo, client, err := p.sourceClientOf(src)
+	if err != nil {
+		return res, nil, err
+	}
+
+	perPage, page = normalizePage(perPage, page)
+	opts := &github.CommitsListOptions{
+		SHA: branch,
 		ListOptions: github.ListOptions{
 			PerPage: perPage,
 			Page:    page,
 		},
 	}
 
-	// repo was stored with format of owner/repo
-	srcTokens := strings.Split(src.Repository, "/")
-	if len(srcTokens) < 2 {
-		return branches, tags, fmt.Errorf("invalid repository in DB repo:%s", src.Repository)
+	commits, resp, err := client.Repositories.ListCommits(ctx, owner, repo, opts)
+	if err != nil {
+		return res, nil, githubErrorf(err, "failed to list commits of %s from github", src.Repository)
+	}
+
+	for i := range commits {
+		res = append(res, &Commit{
+			Id:           commits[i].GetSHA(),
+			Url:          commits[i].GetHTMLURL(),
+			Message:      commits[i].GetCommit().GetMessage(),
+			Date:         commits[i].GetCommit().GetCommitter().GetDate(),
+			Committer:    commits[i].GetCommit().GetCommitter().GetName(),
+			CommitterUrl: commits[i].GetCommitter().GetHTMLURL(),
+		})
 	}
 
-	// 1: list branches
-	branchesFromRepo, _, err := client.Repositories.ListBranches(context.Background(), srcTokens[0], srcTokens[1], opts)
+	return res, newPagination(perPage, page, len(res), resp.NextPage, resp.LastPage, 0), nil
+}
+
+// ListBranches returns branches from remote github repository, pagination is parsed from Link header.
+func (p *GithubProvider) ListBranches(ctx context.Context, src *repository.Source, perPage, page int) ([]string, *Pagination, error) {
+	res := make([]string, 0)
+
+	owner, repo, client, err := p.sourceClientOf(src)
 	if err != nil {
-		return branches, tags, err
+		return res, nil, err
 	}
 
-	for i := range branchesFromRepo {
-		branches = append(branches, branchesFromRepo[i].GetName())
+	perPage, page = normalizePage(perPage, page)
+	opts := &github.BranchListOptions{
+		ListOptions: github.ListOptions{
+			PerPage: perPage,
+			Page:    page,
+		},
+	}
+
+	branches, resp, err := client.Repositories.ListBranches(ctx, owner, repo, opts)
+	if err != nil {
+		return res, nil, githubErrorf(err, "failed to list branches of %s from github", src.Repository)
+	}
+
+	for i := range branches {
+		res = append(res, branches[i].GetName())
 	}
 
-	// 2: list tags
-	tagsFromRepo, _, err := client.Repositories.ListTags(context.Background(), srcTokens[0], srcTokens[1], &github.ListOptions{
+	return res, newPagination(perPage, page, len(res), resp.NextPage, resp.LastPage, 0), nil
+}
+
+// ListTags returns tags from remote github repository, pagination is parsed from Link header.
+func (p *GithubProvider) ListTags(ctx context.Context, src *repository.Source, perPage, page int) ([]string, *Pagination, error) {
+	res := make([]string, 0)
+
+	owner, repo, client, err := p.sourceClientOf(src)
+	if err != nil {
+		return res, nil, err
+	}
+
+	perPage, page = normalizePage(perPage, page)
+	tags, resp, err := client.Repositories.ListTags(ctx, owner, repo, &github.ListOptions{
 		PerPage: perPage,
 		Page:    page,
 	})
 	if err != nil {
-		return branches, tags, err
+		return res, nil, githubErrorf(err, "failed to list tags of %s from github", src.Repository)
 	}
 
-	for i := range tagsFromRepo {
-		tags = append(tags, tagsFromRepo[i].GetName())
+	for i := range tags {
+		res = append(res, tags[i].GetName())
 	}
 
-	return branches, tags, nil
+	return res, newPagination(perPage, page, len(res), resp.NextPage, resp.LastPage, 0), nil
 }
 
-// ListCommitsFromGithub returns commits from remote github repository.
-// The repos would have access permission with Github app named as workstation.
-func ListCommitsFromGithub(src *repository.Source, branch, accessToken string, perPage, page int) ([]*Commit, error) {
-	res := make([]*Commit, 0)
-
-	client := getGithubClient(accessToken)
+// GetFileContent returns content of file at ref from remote github repository.
+func (p *GithubProvider) GetFileContent(ctx context.Context, src *repository.Source, ref, path string) ([]byte, error) {
+	owner, repo, client, err := p.sourceClientOf(src)
+	if err != nil {
+		return nil, err
+	}
 
-	opts := &github.CommitsListOptions{
-		SHA: branch,
-		ListOptions: github.ListOptions{
-			PerPage: perPage,
-			Page:    page,
-		},
+	file, _, resp, err := client.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, repository.NewNotFoundf("file:%s not found in %s at ref:%s", path, src.Repository, ref)
+	}
+	if err != nil {
+		return nil, githubErrorf(err, "failed to get file:%s of %s from github", path, src.Repository)
 	}
 
-	// repo was stored with format of owner/repo
-	srcTokens := strings.Split(src.Repository, "/")
-	if len(srcTokens) < 2 {
-		return res, fmt.Errorf("invalid repository in DB repo:%s", src.Repository)
+	// path of directory
+	if file == nil {
+		return nil, repository.NewInvalidArgumentf("path:%s of %s is not a file", path, src.Repository)
 	}
 
-	commits, _, err := client.Repositories.ListCommits(context.Background(), srcTokens[0], srcTokens[1], opts)
+	content, err := file.GetContent()
 	if err != nil {
-		return res, err
+		return nil, githubErrorf(err, "failed to decode file:%s of %s from github", path, src.Repository)
 	}
 
-	for i := range commits {
-		res = append(res, &Commit{
-			Id:           commits[i].GetSHA(),
-			Url:          commits[i].GetHTMLURL(),
-			Message:      commits[i].GetCommit().GetMessage(),
-			Date:         commits[i].GetCommit().GetCommitter().GetDate(),
-			Committer:    commits[i].GetCommit().GetCommitter().GetName(),
-			CommitterUrl: commits[i].GetCommitter().GetHTMLURL(),
-		})
+	return []byte(content), nil
+}
+
+// Returns client of primary host with access token of user, token would be refreshed if expired.
+func (p *GithubProvider) userClientOf(userId int) (*github.Client, *GithubHost, string, error) {
+	accessToken, err := GetController().findUserAccessToken(userId, repository.IdentityGithub)
+	if err != nil {
+		return nil, nil, "", err
 	}
 
-	return res, nil
+	host := GetGithubHost("")
+	return githubClientOfToken(host, accessToken), host, accessToken.User, nil
 }
 
-// normalize page and perPage
-func normalizePage(perPage, page int) (int, int) {
-	if perPage < 0 {
-		perPage = PerPageDefault
+// Returns owner and name of repository together with client which could access repository of source.
+func (p *GithubProvider) sourceClientOf(src *repository.Source) (string, string, *github.Client, error) {
+	// repo was stored with format of owner/repo
+	owner, repo, err := splitRepository(src.Repository)
+	if err != nil {
+		return "", "", nil, err
 	}
 
-	if page < 0 {
-		page = PageDefault
+	client, err := GetController().githubClientOf(src)
+	if err != nil {
+		return "", "", nil, err
 	}
 
-	return perPage, page
+	return owner, repo, client, nil
 }
 
-// Get github client with accessToken
-func getGithubClient(accessToken string) *github.Client {
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{
-			AccessToken: accessToken,
-			TokenType:   "token",
-		},
-	)
-	client := github.NewClient(oauth2.NewClient(context.Background(), ts))
+// Returns owner and name of repository together with client of user bound to source, client of sourceClientOf
+// would be returned if source is not on primary host or not bound to user.
+func (p *GithubProvider) userSourceClientOf(src *repository.Source) (string, string, *github.Client, error) {
+	host, err := githubHostOf(src)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if host.Host != GetGithubHost("").Host || len(src.User) < 1 {
+		return p.sourceClientOf(src)
+	}
+
+	owner, repo, err := splitRepository(src.Repository)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	token, err := GetController().findAccessToken(src.Type, src.User)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return owner, repo, githubClientOfToken(host, token), nil
+}
 
-	return client
+// Returns shared client of host with access token of user.
+func githubClientOfToken(host *GithubHost, accessToken *repository.AccessToken) *github.Client {
+	return sharedGithubClient(host, &oauth2.Token{AccessToken: accessToken.Token, TokenType: "token"}, accessToken.User)
 }
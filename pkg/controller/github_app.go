@@ -0,0 +1,248 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/go-github/v39/github"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"golang.org/x/oauth2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// GithubAppSlugDefault is slug of GitHub App used if missing in config
+	GithubAppSlugDefault = "pg-workstation-test"
+	// githubAppJwtTtl is lifetime of JWT signed by app, GitHub accepts at most 10 minutes
+	githubAppJwtTtl = 9 * time.Minute
+	// githubAppJwtClockSkew is subtracted from issued time of JWT in case clock of GitHub drifts
+	githubAppJwtClockSkew = time.Minute
+)
+
+// GithubApp authenticates as GitHub App with JWT signed by private key of app,
+// and mints installation tokens to access repositories of sources on host of app without token of any user.
+type GithubApp struct {
+	AppId      int64
+	Slug       string
+	InstallUrl string
+	Host       *GithubHost
+	// WebhookSecret verifies signatures of events delivered by webhook of app, webhook is disabled if empty
+	WebhookSecret string
+	privateKey    *rsa.PrivateKey
+	// installations of repositories with format of owner/repo in lower case
+	installations map[string]int64
+	// installation tokens cached until expired
+	tokens map[int64]*oauth2.Token
+	mutex  sync.Mutex
+}
+
+// NewGithubApp creates GitHub App on host with PEM encoded RSA private key, github.com would be used if host is nil.
+// Install URL of app is derived from slug if missing.
+func NewGithubApp(host *GithubHost, appId int64, slug, installUrl string, privateKey []byte) (*GithubApp, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key of github app:%d, %v", appId, err)
+	}
+
+	if len(slug) < 1 {
+		slug = GithubAppSlugDefault
+	}
+
+	if host == nil {
+		host = mustGithubHost(GithubHostDefault, "", "")
+	}
+
+	if len(installUrl) < 1 {
+		installUrl = host.AppInstallUrlOf(slug)
+	}
+
+	return &GithubApp{
+		AppId:         appId,
+		Slug:          slug,
+		InstallUrl:    installUrl,
+		Host:          host,
+		privateKey:    key,
+		installations: make(map[string]int64),
+		tokens:        make(map[int64]*oauth2.Token),
+	}, nil
+}
+
+// Jwt returns JWT signed by private key of app, which authenticates as app itself.
+func (app *GithubApp) Jwt() (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.StandardClaims{
+		Issuer:    strconv.FormatInt(app.AppId, 10),
+		IssuedAt:  now.Add(-githubAppJwtClockSkew).Unix(),
+		ExpiresAt: now.Add(githubAppJwtTtl).Unix(),
+	})
+
+	res, err := token.SignedString(app.privateKey)
+	if err != nil {
+		return "", repository.Wrapf(err, repository.CodeInternal, "failed to sign jwt of github app:%d", app.AppId)
+	}
+
+	return res, nil
+}
+
+// AppClient returns client which authenticates as app, it could be used to manage installations only.
+func (app *GithubApp) AppClient() (*github.Client, error) {
+	token, err := app.Jwt()
+	if err != nil {
+		return nil, err
+	}
+
+	return app.newClient(&oauth2.Token{AccessToken: token, TokenType: "Bearer"}), nil
+}
+
+// InstallationToken returns token of installation, tokens are cached until they are about to expire.
+func (app *GithubApp) InstallationToken(ctx context.Context, installationId int64) (*oauth2.Token, error) {
+	app.mutex.Lock()
+	token, ok := app.tokens[installationId]
+	app.mutex.Unlock()
+
+	if ok && token.Expiry.After(time.Now().Add(TokenRefreshSkew)) {
+		return token, nil
+	}
+
+	client, err := app.AppClient()
+	if err != nil {
+		return nil, err
+	}
+
+	res, _, err := client.Apps.CreateInstallationToken(ctx, installationId, nil)
+	if err != nil {
+		return nil, githubErrorf(err, "failed to create token of installation:%d from github", installationId)
+	}
+
+	token = &oauth2.Token{
+		AccessToken: res.GetToken(),
+		TokenType:   "token",
+		Expiry:      res.GetExpiresAt(),
+	}
+
+	app.mutex.Lock()
+	app.tokens[installationId] = token
+	app.mutex.Unlock()
+
+	return token, nil
+}
+
+// InstallationOf returns installation of app on repository with format of owner/repo.
+// NotFound would be returned if app is not installed on repository.
+func (app *GithubApp) InstallationOf(ctx context.Context, repo string) (int64, error) {
+	key := strings.ToLower(repo)
+
+	app.mutex.Lock()
+	installationId, ok := app.installations[key]
+	app.mutex.Unlock()
+
+	if ok {
+		return installationId, nil
+	}
+
+	owner, name, err := splitRepository(repo)
+	if err != nil {
+		return 0, err
+	}
+
+	client, err := app.AppClient()
+	if err != nil {
+		return 0, err
+	}
+
+	installation, resp, err := client.Apps.FindRepositoryInstallation(ctx, owner, name)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return 0, repository.NewNotFoundf("github app:%s is not installed on %s, please install it from %s", app.Slug, repo, app.InstallUrl)
+	}
+	if err != nil {
+		return 0, githubErrorf(err, "failed to find installation of %s from github", repo)
+	}
+
+	app.mutex.Lock()
+	app.installations[key] = installation.GetID()
+	app.mutex.Unlock()
+
+	return installation.GetID(), nil
+}
+
+// ClientOf returns client authenticated with token of installation which covers repository of source.
+func (app *GithubApp) ClientOf(ctx context.Context, src *repository.Source) (*github.Client, error) {
+	installationId, err := app.InstallationOf(ctx, src.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := app.InstallationToken(ctx, installationId)
+	if err != nil {
+		// installation might be removed and installed again, find it again next time
+		app.forgetInstallation(src.Repository)
+		return nil, err
+	}
+
+	return sharedGithubClient(app.Host, token, fmt.Sprintf("installation:%d", installationId)), nil
+}
+
+// Remove cached installation of repository.
+func (app *GithubApp) forgetInstallation(repo string) {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
+	delete(app.installations, strings.ToLower(repo))
+}
+
+// Create client of host with token.
+func (app *GithubApp) newClient(token *oauth2.Token) *github.Client {
+	return app.Host.NewClient(oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(token)))
+}
+
+// githubClientOf returns client to access repository of github source on host of source.
+// Installation token of GitHub App is preferred, so that sources could be accessed without any user,
+// token of user who created source is used if app is not registered or not installed on repository.
+// Tokens of users are issued by primary host, so that sources on other hosts could be accessed with apps only.
+func (con *Controller) githubClientOf(src *repository.Source) (*github.Client, error) {
+	host, err := githubHostOf(src)
+	if err != nil {
+		return nil, err
+	}
+
+	primary := host.Host == GetGithubHost("").Host
+	if app := GetGithubApp(host.Host); app != nil {
+		client, err := app.ClientOf(context.Background(), src)
+		if err == nil {
+			return client, nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) || len(src.User) < 1 || !primary {
+			return nil, err
+		}
+	} else if !primary {
+		return nil, repository.NewNotFoundf("github app is not configured on host:%s", host.Host)
+	}
+
+	token, err := con.findAccessToken(src.Type, src.User)
+	if err != nil {
+		return nil, err
+	}
+
+	return githubClientOfToken(host, token), nil
+}
+
+// Split repository with format of owner/repo.
+func splitRepository(repo string) (string, string, error) {
+	tokens := strings.Split(repo, "/")
+	if len(tokens) != 2 || len(tokens[0]) < 1 || len(tokens[1]) < 1 {
+		return "", "", repository.NewInvalidArgumentf("invalid repository:%s, expect owner/repo", repo)
+	}
+
+	return tokens[0], tokens[1], nil
+}
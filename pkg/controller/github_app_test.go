@@ -0,0 +1,320 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/golang-jwt/jwt"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGithubApi is a stand-in GitHub API which accepts JWT of app with id 42 and installation tokens minted by it.
+type fakeGithubApi struct {
+	*httptest.Server
+	key          *rsa.PrivateKey
+	tokensMinted int
+}
+
+func newFakeGithubApi(t *testing.T) *fakeGithubApi {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	api := &fakeGithubApi{key: key}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/repos/ut-owner/ut-repo/installation", func(w http.ResponseWriter, r *http.Request) {
+		if !api.isAppJwt(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 7})
+	})
+
+	mux.HandleFunc("/app/installations/7/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !api.isAppJwt(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		api.tokensMinted++
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      "ut-installation-token",
+			"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339),
+		})
+	})
+
+	// repository is accessible with installation token and token of user linked by linkUser
+	mux.HandleFunc("/repos/ut-owner/ut-repo", func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "token ut-installation-token" && auth != "token ut-user-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":             1296269,
+			"name":           "ut-repo",
+			"full_name":      "ut-owner/ut-repo",
+			"default_branch": "main",
+		})
+	})
+
+	// repository is accessible with installation token only, user could not see it
+	mux.HandleFunc("/repos/ut-owner/ut-private", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token ut-installation-token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 42, "name": "ut-private", "full_name": "ut-owner/ut-private"})
+	})
+
+	mux.HandleFunc("/repos/ut-owner/ut-repo/commits", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token ut-installation-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// commits are paged by one with Link header like github
+		if r.URL.Query().Get("page") == "2" {
+			json.NewEncoder(w).Encode([]map[string]interface{}{{"sha": "ut-sha-2"}})
+			return
+		}
+		next := "http://" + r.Host + "/repos/ut-owner/ut-repo/commits?page=2"
+		w.Header().Set("Link", `<`+next+`>; rel="next", <`+next+`>; rel="last"`)
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"sha": "ut-sha"}})
+	})
+
+	api.Server = httptest.NewServer(mux)
+	t.Cleanup(api.Close)
+
+	return api
+}
+
+// Returns true if request is authenticated with JWT signed by app.
+func (api *fakeGithubApi) isAppJwt(r *http.Request) bool {
+	raw := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	claims := &jwt.StandardClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return &api.key.PublicKey, nil
+	})
+
+	return err == nil && claims.Issuer == "42"
+}
+
+// Returns app on host with private key of api, REST API of host is pointed to api.
+func (api *fakeGithubApi) newApp(t *testing.T, host string) *GithubApp {
+	githubHost, err := NewGithubHost(host, api.URL+"/", "")
+	assert.Nil(t, err)
+
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(api.key)})
+	app, err := NewGithubApp(githubHost, 42, "ut-app", "", privateKey)
+	assert.Nil(t, err)
+
+	return app
+}
+
+// Links github identity ut-user with access token ut-user-token to user, primary host is pointed to api until test finished.
+func (api *fakeGithubApi) linkUser(t *testing.T, repo repository.Repository, userId int) {
+	primary := GetGithubHost(GithubHostDefault)
+	host, err := NewGithubHost(GithubHostDefault, api.URL+"/", "")
+	assert.Nil(t, err)
+	RegisterPrimaryGithubHost(host)
+	t.Cleanup(func() {
+		RegisterPrimaryGithubHost(primary)
+	})
+
+	if user, _ := repo.GetUser(userId); user == nil {
+		user = repository.NewUser("ut-user")
+		repo.CreateUser(user)
+		assert.Equal(t, userId, user.Id)
+	}
+	identity := repository.NewIdentity(repository.IdentityGithub, "ut-user")
+	identity.UserId = userId
+	repo.UpsertIdentity(identity)
+	repo.UpsertAccessToken(repository.NewAccessToken(userId, repository.IdentityGithub, "ut-user", "ut-user-token"))
+}
+
+// Removes registered app of host.
+func unregisterGithubApp(host string) {
+	githubHostsMutex.Lock()
+	defer githubHostsMutex.Unlock()
+
+	delete(githubApps, host)
+}
+
+func TestNewGithubApp(t *testing.T) {
+	// invalid private key
+	_, err := NewGithubApp(nil, 42, "ut-app", "", []byte("ut-key"))
+	assert.NotNil(t, err)
+
+	// install url is derived from slug
+	api := newFakeGithubApi(t)
+	app := api.newApp(t, GithubHostDefault)
+	assert.Equal(t, "https://github.com/apps/ut-app/installations/new", app.InstallUrl)
+
+	// apps of GitHub Enterprise Server are served under github-apps
+	app = api.newApp(t, "ut-ghe.example.com")
+	assert.Equal(t, "http://ut-ghe.example.com/github-apps/ut-app/installations/new", app.InstallUrl)
+}
+
+func TestGithubApp_ClientOf(t *testing.T) {
+	api := newFakeGithubApi(t)
+	app := api.newApp(t, GithubHostDefault)
+	ctx := context.Background()
+
+	// 1: installation token is minted once and cached
+	for i := 0; i < 2; i++ {
+		client, err := app.ClientOf(ctx, repository.NewSource(repository.IdentityGithub, "ut-owner/ut-repo"))
+		assert.Nil(t, err)
+		commits, _, err := client.Repositories.ListCommits(ctx, "ut-owner", "ut-repo", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "ut-sha", commits[0].GetSHA())
+	}
+	assert.Equal(t, 1, api.tokensMinted)
+
+	// 2: app is not installed on repository
+	_, err := app.ClientOf(ctx, repository.NewSource(repository.IdentityGithub, "ut-owner/ut-other"))
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	// 3: invalid repository
+	_, err = app.ClientOf(ctx, repository.NewSource(repository.IdentityGithub, "ut-repo"))
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+}
+
+func TestListCommits_WithGithubApp(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+	repo := GetController().Repo
+
+	api := newFakeGithubApi(t)
+	RegisterGithubApp(api.newApp(t, GithubHostDefault))
+	defer unregisterGithubApp(GithubHostDefault)
+
+	// source created by user without any access token
+	org := repository.NewOrg("ut-org")
+	repo.CreateOrg(org)
+	repo.UpsertMember(repository.NewMember(org.Id, 1, repository.RoleViewer))
+	proj := repository.NewProj("ut-proj")
+	proj.OrgId = org.Id
+	repo.CreateProj(proj)
+	src := repository.NewSource(repository.IdentityGithub, "ut-owner/ut-repo")
+	src.ProjId = proj.Id
+	src.User = "ut-user"
+	repo.CreateSource(src)
+
+	resp := doRequest(router, http.MethodGet, "/v1/source/1/commits?perPage=1", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "ut-sha")
+	assert.Contains(t, resp.Body.String(), `"pagination":{"page":1,"perPage":1,"nextPage":2,"lastPage":2,"total":0}`)
+
+	// all pages are followed
+	resp = doRequest(router, http.MethodGet, "/v1/source/1/commits?perPage=1&all=true", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "ut-sha-2")
+	assert.Contains(t, resp.Body.String(), `"pagination":{"page":1,"perPage":1,"nextPage":0,"lastPage":2,"total":2}`)
+}
+
+func TestGithubHost(t *testing.T) {
+	// github.com
+	host, err := NewGithubHost("", "", "")
+	assert.Nil(t, err)
+	assert.True(t, host.IsDefault())
+	assert.Equal(t, "https://api.github.com/", host.BaseUrl)
+	assert.Equal(t, "https://github.com/login/oauth/authorize", host.OauthEndpoint().AuthURL)
+
+	// GitHub Enterprise Server with URLs derived from host
+	host, err = NewGithubHost("GHE.example.com", "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "ghe.example.com", host.Host)
+	assert.Equal(t, "https://ghe.example.com/api/v3/", host.BaseUrl)
+	assert.Equal(t, "https://ghe.example.com/api/uploads/", host.UploadUrl)
+	assert.Equal(t, "https://ghe.example.com/login/oauth/access_token", host.OauthEndpoint().TokenURL)
+	client := host.NewClient(nil)
+	assert.Equal(t, "https://ghe.example.com/api/v3/", client.BaseURL.String())
+
+	// invalid base url
+	_, err = NewGithubHost("ghe.example.com", "ut-url", "")
+	assert.NotNil(t, err)
+
+	// hosts are registered together with github.com
+	RegisterGithubHost(host)
+	assert.True(t, IsGithubHostRegistered("ghe.example.com"))
+	assert.True(t, IsGithubHostRegistered(GithubHostDefault))
+	assert.False(t, IsGithubHostRegistered("ut-missing.example.com"))
+	assert.Equal(t, GithubHostDefault, GetGithubHost("").Host)
+}
+
+func TestSourceApi_WithGithubHosts(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+	repo := GetController().Repo
+
+	// source on GitHub Enterprise Server is accessed with app of the host
+	api := newFakeGithubApi(t)
+	app := api.newApp(t, "ut-ghe.example.com")
+	RegisterGithubHost(app.Host)
+	RegisterGithubApp(app)
+	defer unregisterGithubApp(app.Host.Host)
+	api.linkUser(t, repo, 1)
+
+	org := repository.NewOrg("ut-org")
+	repo.CreateOrg(org)
+	repo.UpsertMember(repository.NewMember(org.Id, 1, repository.RoleOwner))
+	for _, name := range []string{"ut-proj-1", "ut-proj-2"} {
+		proj := repository.NewProj(name)
+		proj.OrgId = org.Id
+		repo.CreateProj(proj)
+	}
+
+	// 1: host must be registered
+	resp := doRequest(router, http.MethodPost, "/v2/projects/1/source", binding.MIMEJSON,
+		`{"type":"github","repository":"ut-owner/ut-repo","host":"ut-missing.example.com"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "githubhost")
+
+	// 2: source created on host, repository is verified with app of the host
+	resp = doRequest(router, http.MethodPost, "/v2/projects/1/source", binding.MIMEJSON,
+		`{"type":"github","repository":"ut-owner/ut-missing","host":"ut-ghe.example.com"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	resp = doRequest(router, http.MethodPost, "/v2/projects/1/source", binding.MIMEJSON,
+		`{"type":"github","repository":"ut-owner/ut-repo","host":"ut-ghe.example.com"}`)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"repositoryId":"1296269","defaultBranch":"main"`)
+	resp = doRequest(router, http.MethodGet, "/v1/source/1/commits", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "ut-sha")
+
+	// 3: source without host is created on primary host, repository is verified with token of user
+	resp = doRequest(router, http.MethodPost, "/v2/projects/2/source", binding.MIMEJSON,
+		`{"type":"github","repository":"ut-owner/ut-repo"}`)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"host":"github.com"`)
+
+	// 4: tokens of users are not used on other hosts
+	src := repository.NewSource(repository.IdentityGithub, "ut-owner/ut-repo")
+	src.Host = "ut-other.example.com"
+	RegisterGithubHost(mustGithubHost(src.Host, "", ""))
+	src.User = "ut-user"
+	_, err := GetController().githubClientOf(src)
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+	src.Host = "ut-missing.example.com"
+	_, err = GetController().githubClientOf(src)
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+}
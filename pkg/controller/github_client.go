@@ -0,0 +1,373 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"github.com/google/go-github/v39/github"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/oauth2"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// githubClientsMax is max number of shared clients, the least recently used client would be dropped
+	githubClientsMax = 256
+	// githubCacheSize is max number of responses cached with ETag by each client
+	githubCacheSize = 512
+	// githubRetryMax is max number of retries of requests rejected by secondary rate limits
+	githubRetryMax = 3
+)
+
+var (
+	// githubBackoffBase is delay before the first retry if Retry-After is missing, it is doubled for each retry
+	githubBackoffBase = time.Second
+	// githubBackoffMax is the longest delay to wait before retry, requests would fail instead of waiting longer
+	githubBackoffMax = 10 * time.Second
+
+	// githubClients contains clients shared by requests of the same owner of token on the same host
+	githubClients      = make(map[string]*list.Element)
+	githubClientsLru   = list.New()
+	githubClientsMutex = sync.Mutex{}
+
+	githubRateLimitLabels = []string{"host", "client", "resource"}
+	// GithubRateLimitRemaining is number of requests remaining in current rate limit window
+	GithubRateLimitRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "workstation",
+		Subsystem: "github",
+		Name:      "rate_limit_remaining",
+		Help:      "Number of requests remaining in current rate limit window of github.",
+	}, githubRateLimitLabels)
+	// GithubRateLimitLimit is max number of requests permitted in rate limit window
+	GithubRateLimitLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "workstation",
+		Subsystem: "github",
+		Name:      "rate_limit_limit",
+		Help:      "Max number of requests permitted in rate limit window of github.",
+	}, githubRateLimitLabels)
+	// GithubRateLimitReset is time when current rate limit window resets in unix seconds
+	GithubRateLimitReset = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "workstation",
+		Subsystem: "github",
+		Name:      "rate_limit_reset_seconds",
+		Help:      "Time when current rate limit window of github resets in unix seconds.",
+	}, githubRateLimitLabels)
+)
+
+type githubClientEntry struct {
+	key       string
+	client    *github.Client
+	transport *githubTransport
+}
+
+// sharedGithubClient returns client of host with token, which is shared by all requests of the same owner of token,
+// so that responses cached with ETag and rate limits known by client are reused after token refreshed.
+// Name identifies owner of token, like login of user or installation:<id>, token itself is never exposed in metrics.
+func sharedGithubClient(host *GithubHost, token *oauth2.Token, name string) *github.Client {
+	// base URL is used since API of host might be changed
+	key := host.BaseUrl + name
+
+	githubClientsMutex.Lock()
+	defer githubClientsMutex.Unlock()
+
+	if element, ok := githubClients[key]; ok {
+		githubClientsLru.MoveToFront(element)
+		entry := element.Value.(*githubClientEntry)
+		entry.transport.setToken(token)
+		return entry.client
+	}
+
+	transport := newGithubTransport(host.Host, name, token, http.DefaultTransport)
+	client := host.NewClient(&http.Client{Transport: transport})
+
+	githubClients[key] = githubClientsLru.PushFront(&githubClientEntry{key: key, client: client, transport: transport})
+	for githubClientsLru.Len() > githubClientsMax {
+		oldest := githubClientsLru.Back()
+		githubClientsLru.Remove(oldest)
+		delete(githubClients, oldest.Value.(*githubClientEntry).key)
+	}
+
+	return client
+}
+
+// githubCollectors returns gauges of rate limits of github, which are registered into prometheus of GinEntry.
+func githubCollectors() []prometheus.Collector {
+	return []prometheus.Collector{GithubRateLimitRemaining, GithubRateLimitLimit, GithubRateLimitReset}
+}
+
+// githubTransport sends requests to REST API of github with conditional requests and backoff.
+//
+// Responses of GET requests with ETag are cached, and sent again with If-None-Match, since 304 responses
+// are not counted against rate limit. Requests rejected by secondary rate limits are retried with exponential backoff,
+// requests rejected by exhausted rate limit are returned as they are, which are converted to RateLimitError by client.
+type githubTransport struct {
+	host  string
+	name  string
+	token *oauth2.Token
+	base  http.RoundTripper
+	cache map[string]*list.Element
+	lru   *list.List
+	mutex sync.Mutex
+}
+
+type githubCachedResponse struct {
+	key    string
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+func newGithubTransport(host, name string, token *oauth2.Token, base http.RoundTripper) *githubTransport {
+	return &githubTransport{
+		host:  host,
+		name:  name,
+		token: token,
+		base:  base,
+		cache: make(map[string]*list.Element),
+		lru:   list.New(),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *githubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// only GET requests are cached and retried
+	cacheable := req.Method == http.MethodGet
+	key := req.URL.String()
+
+	// request must not be modified by transport
+	req = req.Clone(req.Context())
+	t.mutex.Lock()
+	t.token.SetAuthHeader(req)
+	t.mutex.Unlock()
+
+	var cached *githubCachedResponse
+	if cacheable {
+		if cached = t.get(key); cached != nil {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		t.observe(resp)
+
+		if cached != nil && resp.StatusCode == http.StatusNotModified {
+			resp.Body.Close()
+			return cached.responseOf(req, resp), nil
+		}
+
+		if delay, ok := githubRetryDelayOf(resp, attempt); ok && cacheable {
+			resp.Body.Close()
+			if err := sleepWithContext(req.Context(), delay); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if cacheable && resp.StatusCode == http.StatusOK && len(resp.Header.Get("ETag")) > 0 {
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+			t.put(&githubCachedResponse{key: key, etag: resp.Header.Get("ETag"), header: resp.Header.Clone(), body: body})
+		}
+
+		return resp, nil
+	}
+}
+
+// Replaces token, which might be refreshed or rotated.
+func (t *githubTransport) setToken(token *oauth2.Token) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.token = token
+}
+
+// Updates gauges of rate limits with headers of response.
+func (t *githubTransport) observe(resp *http.Response) {
+	if len(resp.Header.Get("X-RateLimit-Remaining")) < 1 {
+		return
+	}
+
+	resource := resp.Header.Get("X-RateLimit-Resource")
+	if len(resource) < 1 {
+		resource = "core"
+	}
+
+	GithubRateLimitRemaining.WithLabelValues(t.host, t.name, resource).Set(float64(intOfHeader(resp.Header, "X-RateLimit-Remaining")))
+	GithubRateLimitLimit.WithLabelValues(t.host, t.name, resource).Set(float64(intOfHeader(resp.Header, "X-RateLimit-Limit")))
+	GithubRateLimitReset.WithLabelValues(t.host, t.name, resource).Set(float64(intOfHeader(resp.Header, "X-RateLimit-Reset")))
+}
+
+// Returns cached response of key, nil would be returned if missing.
+func (t *githubTransport) get(key string) *githubCachedResponse {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	element, ok := t.cache[key]
+	if !ok {
+		return nil
+	}
+	t.lru.MoveToFront(element)
+
+	return element.Value.(*githubCachedResponse)
+}
+
+// Caches response, the least recently used response would be dropped if cache is full.
+func (t *githubTransport) put(cached *githubCachedResponse) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if element, ok := t.cache[cached.key]; ok {
+		t.lru.Remove(element)
+	}
+	t.cache[cached.key] = t.lru.PushFront(cached)
+
+	for t.lru.Len() > githubCacheSize {
+		oldest := t.lru.Back()
+		t.lru.Remove(oldest)
+		delete(t.cache, oldest.Value.(*githubCachedResponse).key)
+	}
+}
+
+// Returns cached response as response of req, rate limits are taken from notModified which are up to date.
+func (cached *githubCachedResponse) responseOf(req *http.Request, notModified *http.Response) *http.Response {
+	header := cached.header.Clone()
+	for key, values := range notModified.Header {
+		if strings.HasPrefix(http.CanonicalHeaderKey(key), "X-Ratelimit-") {
+			header[key] = values
+		}
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(cached.body)),
+		ContentLength: int64(len(cached.body)),
+		Request:       req,
+	}
+}
+
+// Returns delay before retry if response is rejected by secondary rate limit.
+// Requests are not retried if rate limit is exhausted, retried too many times or delay is too long.
+func githubRetryDelayOf(resp *http.Response, attempt int) (time.Duration, bool) {
+	if attempt >= githubRetryMax || !isGithubSecondaryRateLimit(resp) {
+		return 0, false
+	}
+
+	delay := githubBackoffBase * time.Duration(math.Pow(2, float64(attempt)))
+	if retryAfter := retryAfterOfHeader(resp.Header); retryAfter > 0 {
+		delay = retryAfter
+	}
+
+	return delay, delay <= githubBackoffMax
+}
+
+// Checks whether response is rejected by secondary rate limit, body of response would be kept.
+func isGithubSecondaryRateLimit(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+
+	// rate limit exhausted, which resets after a long time
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return false
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || len(resp.Header.Get("Retry-After")) > 0 {
+		return true
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	message := strings.ToLower(string(body))
+
+	return strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse")
+}
+
+// Returns duration in Retry-After header with seconds, 0 would be returned if missing.
+func retryAfterOfHeader(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(header.Get("Retry-After")))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// Sleeps for delay unless ctx is done.
+func sleepWithContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// githubErrorf converts error returned by github client, errors of rate limits are converted to CodeRateLimited
+// with duration before retry, CodeUpstream is used otherwise.
+func githubErrorf(err error, format string, a ...interface{}) *repository.Error {
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return repository.NewRateLimitedf(err, retryAfterOfReset(rateLimitErr.Rate.Reset.Time), format, a...)
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		retryAfter := githubBackoffMax
+		if abuseErr.RetryAfter != nil {
+			retryAfter = *abuseErr.RetryAfter
+		}
+		return repository.NewRateLimitedf(err, retryAfter, format, a...)
+	}
+
+	// secondary rate limits documented as #secondary-rate-limits are not recognized by client
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil && isGithubSecondaryRateLimit(respErr.Response) {
+		retryAfter := retryAfterOfHeader(respErr.Response.Header)
+		if retryAfter < 1 {
+			retryAfter = githubBackoffMax
+		}
+		return repository.NewRateLimitedf(err, retryAfter, format, a...)
+	}
+
+	return repository.NewUpstreamf(err, format, a...)
+}
+
+// Returns duration until reset, which is at least one second.
+func retryAfterOfReset(reset time.Time) time.Duration {
+	res := time.Until(reset).Round(time.Second)
+	if res < time.Second {
+		res = time.Second
+	}
+
+	return res
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/go-github/v39/github"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Returns shared client of host pointed to server, requests would be handled by handler.
+func newTestGithubClient(t *testing.T, name string, handler http.HandlerFunc) *github.Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	host, err := NewGithubHost("ut-github.example.com", server.URL+"/", "")
+	assert.Nil(t, err)
+
+	return sharedGithubClient(host, &oauth2.Token{AccessToken: "ut-token", TokenType: "token"}, name)
+}
+
+func TestSharedGithubClient(t *testing.T) {
+	host := mustGithubHost("ut-github.example.com", "", "")
+	client := sharedGithubClient(host, &oauth2.Token{AccessToken: "ut-token"}, "ut-user")
+
+	// clients are shared by owner of token, even if token is refreshed
+	assert.Equal(t, client, sharedGithubClient(host, &oauth2.Token{AccessToken: "ut-refreshed"}, "ut-user"))
+	assert.NotEqual(t, client, sharedGithubClient(host, &oauth2.Token{AccessToken: "ut-token"}, "ut-other"))
+}
+
+func TestGithubTransport_WithETag(t *testing.T) {
+	requests := 0
+	client := newTestGithubClient(t, "ut-etag", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "token ut-token", r.Header.Get("Authorization"))
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(5000-requests))
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+
+		if r.Header.Get("If-None-Match") == `"ut-etag"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"ut-etag"`)
+		w.Write([]byte(`[{"name":"main"}]`))
+	})
+
+	// 1: response is cached with ETag
+	branches, _, err := client.Repositories.ListBranches(context.Background(), "ut-owner", "ut-repo", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "main", branches[0].GetName())
+
+	// 2: cached response is returned if not modified, rate limits are up to date
+	branches, resp, err := client.Repositories.ListBranches(context.Background(), "ut-owner", "ut-repo", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "main", branches[0].GetName())
+	assert.Equal(t, 4998, resp.Rate.Remaining)
+	assert.Equal(t, 2, requests)
+
+	// 3: remaining quota is exposed as gauges
+	assert.Equal(t, float64(4998), testutil.ToFloat64(GithubRateLimitRemaining.WithLabelValues("ut-github.example.com", "ut-etag", "core")))
+	assert.Equal(t, float64(5000), testutil.ToFloat64(GithubRateLimitLimit.WithLabelValues("ut-github.example.com", "ut-etag", "core")))
+}
+
+func TestGithubTransport_WithSecondaryRateLimit(t *testing.T) {
+	defer func(base time.Duration) { githubBackoffBase = base }(githubBackoffBase)
+	githubBackoffBase = time.Millisecond
+
+	// 1: requests are retried with backoff
+	requests := 0
+	client := newTestGithubClient(t, "ut-secondary", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"You have exceeded a secondary rate limit.",` +
+				`"documentation_url":"https://docs.github.com/rest/overview/resources-in-the-rest-api#secondary-rate-limits"}`))
+			return
+		}
+		w.Write([]byte(`[{"name":"v1.0.0"}]`))
+	})
+	tags, _, err := client.Repositories.ListTags(context.Background(), "ut-owner", "ut-repo", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1.0.0", tags[0].GetName())
+	assert.Equal(t, 3, requests)
+
+	// 2: requests are not retried if Retry-After is too long
+	requests = 0
+	client = newTestGithubClient(t, "ut-secondary-long", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"You have exceeded a secondary rate limit."}`))
+	})
+	_, _, err = client.Repositories.ListTags(context.Background(), "ut-owner", "ut-repo", nil)
+	err = githubErrorf(err, "ut-message")
+	assert.True(t, errors.Is(err, repository.ErrRateLimited))
+	assert.Equal(t, 120*time.Second, repository.RetryAfterOf(err))
+	assert.Equal(t, 1, requests)
+}
+
+func TestGithubErrorf(t *testing.T) {
+	// rate limit exhausted
+	reset := time.Now().Add(time.Minute).Unix()
+	client := newTestGithubClient(t, "ut-exhausted", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprint(reset))
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"API rate limit exceeded"}`))
+	})
+	_, _, err := client.Repositories.ListTags(context.Background(), "ut-owner", "ut-repo", nil)
+	err = githubErrorf(err, "ut-message")
+	assert.True(t, errors.Is(err, repository.ErrRateLimited))
+	assert.InDelta(t, time.Minute.Seconds(), repository.RetryAfterOf(err).Seconds(), 2)
+
+	// other errors are upstream errors
+	err = githubErrorf(errors.New("ut-error"), "ut-message")
+	assert.True(t, errors.Is(err, repository.ErrUpstream))
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"fmt"
+	"github.com/google/go-github/v39/github"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	// GithubHostDefault is host of github.com, which is always registered
+	GithubHostDefault = "github.com"
+	// githubBaseUrlDefault is base URL of REST API of github.com
+	githubBaseUrlDefault = "https://api.github.com/"
+	// githubUploadUrlDefault is upload URL of REST API of github.com
+	githubUploadUrlDefault = "https://uploads.github.com/"
+)
+
+var (
+	// githubHosts contains github.com and hosts registered with RegisterGithubHost()
+	githubHosts = map[string]*GithubHost{
+		GithubHostDefault: mustGithubHost(GithubHostDefault, "", ""),
+	}
+	// githubHostPrimary is host which users sign in with, access tokens of github users are issued by it
+	githubHostPrimary = GithubHostDefault
+	// githubApps contains GitHub Apps registered with RegisterGithubApp() by host
+	githubApps       = make(map[string]*GithubApp)
+	githubHostsMutex = sync.RWMutex{}
+)
+
+// GithubHost describes github.com or an instance of GitHub Enterprise Server, like github.example.com.
+// Sources of github are distinguished by host, so that multiple hosts could be used together.
+type GithubHost struct {
+	Host      string `yaml:"host" json:"host"`
+	BaseUrl   string `yaml:"baseUrl" json:"baseUrl"`
+	UploadUrl string `yaml:"uploadUrl" json:"uploadUrl"`
+	WebUrl    string `yaml:"webUrl" json:"webUrl"`
+}
+
+// NewGithubHost creates host with URLs of REST API, github.com would be used if host is empty.
+// URLs of GitHub Enterprise Server are derived from host if missing, like https://github.example.com/api/v3/.
+func NewGithubHost(host, baseUrl, uploadUrl string) (*GithubHost, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "/"))
+	if len(host) < 1 {
+		host = GithubHostDefault
+	}
+
+	if len(baseUrl) < 1 {
+		baseUrl = "https://" + host + "/api/v3/"
+		if host == GithubHostDefault {
+			baseUrl = githubBaseUrlDefault
+		}
+	}
+
+	if len(uploadUrl) < 1 {
+		uploadUrl = "https://" + host + "/api/uploads/"
+		if host == GithubHostDefault {
+			uploadUrl = githubUploadUrlDefault
+		}
+	}
+
+	res := &GithubHost{
+		Host:      host,
+		BaseUrl:   strings.TrimSuffix(baseUrl, "/") + "/",
+		UploadUrl: strings.TrimSuffix(uploadUrl, "/") + "/",
+	}
+
+	// web pages are served with the same scheme as API, which might be http in private networks
+	parsed, err := url.Parse(res.BaseUrl)
+	if err != nil || len(parsed.Scheme) < 1 || len(parsed.Host) < 1 {
+		return nil, fmt.Errorf("invalid baseUrl of github host:%s", host)
+	}
+	if _, err := url.Parse(res.UploadUrl); err != nil {
+		return nil, fmt.Errorf("invalid uploadUrl of github host:%s", host)
+	}
+	res.WebUrl = parsed.Scheme + "://" + host
+	if host == GithubHostDefault {
+		res.WebUrl = "https://" + host
+	}
+
+	return res, nil
+}
+
+// Creates host which is known to be valid.
+func mustGithubHost(host, baseUrl, uploadUrl string) *GithubHost {
+	res, err := NewGithubHost(host, baseUrl, uploadUrl)
+	if err != nil {
+		panic(err)
+	}
+
+	return res
+}
+
+// IsDefault checks whether host is github.com.
+func (host *GithubHost) IsDefault() bool {
+	return host.Host == GithubHostDefault
+}
+
+// NewClient creates client of REST API of host with http client which authenticates requests.
+func (host *GithubHost) NewClient(httpClient *http.Client) *github.Client {
+	client := github.NewClient(httpClient)
+	client.BaseURL, _ = url.Parse(host.BaseUrl)
+	client.UploadURL, _ = url.Parse(host.UploadUrl)
+
+	return client
+}
+
+// OauthEndpoint returns endpoint of oauth apps and GitHub Apps on host.
+func (host *GithubHost) OauthEndpoint() oauth2.Endpoint {
+	return oauth2.Endpoint{
+		AuthURL:  host.WebUrl + "/login/oauth/authorize",
+		TokenURL: host.WebUrl + "/login/oauth/access_token",
+	}
+}
+
+// AppInstallUrlOf returns URL to install GitHub App with slug, GitHub Enterprise Server serves apps under /github-apps.
+func (host *GithubHost) AppInstallUrlOf(slug string) string {
+	if host.IsDefault() {
+		return fmt.Sprintf("%s/apps/%s/installations/new", host.WebUrl, slug)
+	}
+
+	return fmt.Sprintf("%s/github-apps/%s/installations/new", host.WebUrl, slug)
+}
+
+// RegisterGithubHost registers host, so that sources on it could be created.
+func RegisterGithubHost(host *GithubHost) {
+	githubHostsMutex.Lock()
+	defer githubHostsMutex.Unlock()
+
+	githubHosts[host.Host] = host
+}
+
+// RegisterPrimaryGithubHost registers host which users sign in with, it is used by github sources without host.
+func RegisterPrimaryGithubHost(host *GithubHost) {
+	githubHostsMutex.Lock()
+	defer githubHostsMutex.Unlock()
+
+	githubHosts[host.Host] = host
+	githubHostPrimary = host.Host
+}
+
+// GetGithubHost returns registered host, primary host would be returned if name is empty and nil if missing.
+func GetGithubHost(name string) *GithubHost {
+	githubHostsMutex.RLock()
+	defer githubHostsMutex.RUnlock()
+
+	if len(name) < 1 {
+		name = githubHostPrimary
+	}
+
+	return githubHosts[strings.ToLower(name)]
+}
+
+// IsGithubHostRegistered checks whether host is github.com or registered.
+func IsGithubHostRegistered(name string) bool {
+	return GetGithubHost(name) != nil && len(name) > 0
+}
+
+// RegisterGithubApp registers GitHub App which is used to access github sources on host of app.
+func RegisterGithubApp(app *GithubApp) {
+	githubHostsMutex.Lock()
+	defer githubHostsMutex.Unlock()
+
+	githubApps[app.Host.Host] = app
+}
+
+// GetGithubApp returns GitHub App registered on host, app of primary host would be returned if name is empty.
+// Nil would be returned if missing.
+func GetGithubApp(name string) *GithubApp {
+	githubHostsMutex.RLock()
+	defer githubHostsMutex.RUnlock()
+
+	if len(name) < 1 {
+		name = githubHostPrimary
+	}
+
+	return githubApps[strings.ToLower(name)]
+}
+
+// Returns host of github source, InvalidArgument would be returned if host is not registered.
+func githubHostOf(src *repository.Source) (*GithubHost, error) {
+	host := GetGithubHost(src.Host)
+	if host == nil {
+		return nil, repository.NewInvalidArgumentf("unrecognized github host:%s", src.Host)
+	}
+
+	return host, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGithubProvider_WithPages(t *testing.T) {
+	// stand-in of github which pages installations and repositories of user with Link header
+	mux := http.NewServeMux()
+	paginate := func(path string, pages ...interface{}) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "token ut-user-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+			if r.URL.Query().Get("page") == "2" {
+				json.NewEncoder(w).Encode(pages[1])
+				return
+			}
+			next := "http://" + r.Host + path + "?page=2"
+			w.Header().Set("Link", `<`+next+`>; rel="next", <`+next+`>; rel="last"`)
+			json.NewEncoder(w).Encode(pages[0])
+		})
+	}
+	paginate("/user/installations",
+		map[string]interface{}{"total_count": 2, "installations": []interface{}{map[string]interface{}{"id": 7, "account": map[string]string{"login": "ut-owner"}}}},
+		map[string]interface{}{"total_count": 2, "installations": []interface{}{map[string]interface{}{"id": 8, "account": map[string]string{"login": "ut-other"}}}})
+	paginate("/user/installations/7/repositories",
+		map[string]interface{}{"total_count": 2, "repositories": []interface{}{map[string]string{"name": "ut-repo", "full_name": "ut-owner/ut-repo"}}},
+		map[string]interface{}{"total_count": 2, "repositories": []interface{}{map[string]string{"name": "ut-other", "full_name": "ut-owner/ut-other"}}})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	host, err := NewGithubHost("ut-paged.example.com", server.URL+"/", "")
+	assert.Nil(t, err)
+	RegisterPrimaryGithubHost(host)
+	defer RegisterPrimaryGithubHost(GetGithubHost(GithubHostDefault))
+
+	repo := repository.RegisterMemory()
+	con := RegisterController()
+	repo.UpsertAccessToken(repository.NewAccessToken(1, repository.IdentityGithub, "ut-paged-user", "ut-user-token"))
+	provider := &GithubProvider{}
+	ctx := context.Background()
+
+	// 1: all pages are followed
+	installations, err := provider.ListInstallations(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 2)
+	assert.Equal(t, "ut-other", installations[1].Organization)
+	repos, err := provider.ListRepos(ctx, 1, installations[0])
+	assert.Nil(t, err)
+	assert.Equal(t, []*Repository{
+		{FullName: "ut-owner/ut-repo", Name: "ut-repo"},
+		{FullName: "ut-owner/ut-other", Name: "ut-other"},
+	}, repos)
+
+	// 2: pages are followed until max pages of controller reached
+	con.VCSMaxPages = 1
+	installations, err = provider.ListInstallations(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 1)
+	repos, err = provider.ListRepos(ctx, 1, installations[0])
+	assert.Nil(t, err)
+	assert.Len(t, repos, 1)
+}
@@ -0,0 +1,298 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// GitlabBaseUrlDefault is base URL of gitlab.com, self-hosted GitLab could be configured instead
+	GitlabBaseUrlDefault = "https://gitlab.com"
+	// gitlabProjectsPerPage is page size while listing projects of user, GitLab accepts at most 100
+	gitlabProjectsPerPage = 100
+)
+
+// GitlabProvider accesses projects of gitlab sources with REST API v4 and access tokens of users.
+// Projects of user are grouped by namespaces, which are returned as installations.
+type GitlabProvider struct {
+	BaseUrl string
+}
+
+// NewGitlabProvider creates provider with base URL of GitLab, like https://gitlab.example.com.
+func NewGitlabProvider(baseUrl string) *GitlabProvider {
+	baseUrl = strings.TrimSuffix(baseUrl, "/")
+	if len(baseUrl) < 1 {
+		baseUrl = GitlabBaseUrlDefault
+	}
+
+	return &GitlabProvider{
+		BaseUrl: baseUrl,
+	}
+}
+
+type gitlabNamespace struct {
+	Id        int64  `json:"id"`
+	Kind      string `json:"kind"`
+	Path      string `json:"path"`
+	FullPath  string `json:"full_path"`
+	AvatarUrl string `json:"avatar_url"`
+}
+
+type gitlabProject struct {
+	Id                int64           `json:"id"`
+	Path              string          `json:"path"`
+	PathWithNamespace string          `json:"path_with_namespace"`
+	DefaultBranch     string          `json:"default_branch"`
+	Namespace         gitlabNamespace `json:"namespace"`
+}
+
+type gitlabCommit struct {
+	Id             string    `json:"id"`
+	Message        string    `json:"message"`
+	WebUrl         string    `json:"web_url"`
+	CommitterName  string    `json:"committer_name"`
+	CommitterEmail string    `json:"committer_email"`
+	CommittedDate  time.Time `json:"committed_date"`
+}
+
+type gitlabRef struct {
+	Name string `json:"name"`
+}
+
+// ListInstallations returns namespaces of projects which user is member of.
+func (p *GitlabProvider) ListInstallations(ctx context.Context, userId int) ([]*Installation, error) {
+	token, err := GetController().findUserAccessToken(userId, repository.IdentityGitlab)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := p.listProjects(ctx, token, "/projects", url.Values{"membership": {"true"}})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*Installation, 0)
+	seen := make(map[int64]bool)
+	for i := range projects {
+		namespace := projects[i].Namespace
+		if seen[namespace.Id] {
+			continue
+		}
+		seen[namespace.Id] = true
+
+		res = append(res, &Installation{
+			Id:           namespace.Id,
+			RepoSource:   repository.IdentityGitlab,
+			Organization: namespace.FullPath,
+			AvatarUrl:    p.absoluteUrl(namespace.AvatarUrl),
+			Repos:        make([]*Repository, 0),
+		})
+	}
+
+	return res, nil
+}
+
+// ListRepos returns projects in namespace which user is member of, projects of subgroups are not included.
+func (p *GitlabProvider) ListRepos(ctx context.Context, userId int, installation *Installation) ([]*Repository, error) {
+	token, err := GetController().findUserAccessToken(userId, repository.IdentityGitlab)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1: namespace is either a group or a user, whose projects are listed with different APIs
+	namespace := &gitlabNamespace{}
+	if err := getVCSResource(ctx, fmt.Sprintf("%s/api/v4/namespaces/%d", p.BaseUrl, installation.Id), bearerPrefix+token.Token, namespace); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to get namespace:%d from gitlab", installation.Id)
+	}
+
+	path := fmt.Sprintf("/groups/%d/projects", namespace.Id)
+	if namespace.Kind == "user" {
+		path = "/users/" + url.PathEscape(namespace.Path) + "/projects"
+	}
+
+	// 2: list projects of namespace, guest access is the minimal access of members
+	projects, err := p.listProjects(ctx, token, path, url.Values{"min_access_level": {"10"}})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*Repository, 0)
+	for i := range projects {
+		res = append(res, &Repository{
+			FullName: projects[i].PathWithNamespace,
+			Name:     projects[i].Path,
+		})
+	}
+
+	return res, nil
+}
+
+// ListCommits returns commits of branch, commits of default branch would be returned if branch is empty.
+func (p *GitlabProvider) ListCommits(ctx context.Context, src *repository.Source, branch string, perPage, page int) ([]*Commit, *Pagination, error) {
+	perPage, page = normalizePage(perPage, page)
+	query := pageQuery(perPage, page)
+	if len(branch) > 0 {
+		query.Set("ref_name", branch)
+	}
+
+	commits := make([]*gitlabCommit, 0)
+	header, err := p.getProjectResource(ctx, src, "/repository/commits", query, &commits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res := make([]*Commit, 0)
+	for i := range commits {
+		res = append(res, &Commit{
+			Id:        commits[i].Id,
+			Url:       commits[i].WebUrl,
+			Message:   commits[i].Message,
+			Date:      commits[i].CommittedDate,
+			Committer: commits[i].CommitterName,
+		})
+	}
+
+	return res, gitlabPaginationOf(header, perPage, page, len(res)), nil
+}
+
+// ListBranches returns names of branches.
+func (p *GitlabProvider) ListBranches(ctx context.Context, src *repository.Source, perPage, page int) ([]string, *Pagination, error) {
+	return p.listRefs(ctx, src, "/repository/branches", perPage, page)
+}
+
+// ListTags returns names of tags.
+func (p *GitlabProvider) ListTags(ctx context.Context, src *repository.Source, perPage, page int) ([]string, *Pagination, error) {
+	return p.listRefs(ctx, src, "/repository/tags", perPage, page)
+}
+
+// GetFileContent returns raw content of file at ref, HEAD would be used if ref is empty.
+func (p *GitlabProvider) GetFileContent(ctx context.Context, src *repository.Source, ref, path string) ([]byte, error) {
+	if len(ref) < 1 {
+		ref = "HEAD"
+	}
+
+	res := make([]byte, 0)
+	_, err := p.getProjectResource(ctx, src, "/repository/files/"+url.PathEscape(strings.TrimPrefix(path, "/"))+"/raw",
+		url.Values{"ref": {ref}}, &res)
+
+	return res, err
+}
+
+// GetRepository returns project of source, which is identified by id if source was bound to project.
+func (p *GitlabProvider) GetRepository(ctx context.Context, src *repository.Source) (*Repository, error) {
+	project := &gitlabProject{}
+	if _, err := p.getProjectResource(ctx, src, "", url.Values{}, project); err != nil {
+		return nil, err
+	}
+
+	return &Repository{
+		Id:            strconv.FormatInt(project.Id, 10),
+		FullName:      project.PathWithNamespace,
+		Name:          project.Path,
+		DefaultBranch: project.DefaultBranch,
+	}, nil
+}
+
+// List projects with path of API and access token of user, pages are followed with X-Next-Page header
+// until there are no more pages or max pages of controller reached.
+func (p *GitlabProvider) listProjects(ctx context.Context, token *repository.AccessToken, path string, filter url.Values) ([]*gitlabProject, error) {
+	res := make([]*gitlabProject, 0)
+	_, err := listPages(gitlabProjectsPerPage, PageDefault, GetController().VCSMaxPages, func(perPage, page int) (*Pagination, error) {
+		query := pageQuery(perPage, page)
+		query.Set("simple", "true")
+		for k := range filter {
+			query.Set(k, filter.Get(k))
+		}
+
+		projects := make([]*gitlabProject, 0)
+		header, err := getVCSResourceWithHeader(ctx, p.BaseUrl+"/api/v4"+path+"?"+query.Encode(), bearerPrefix+token.Token, &projects)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, projects...)
+
+		return gitlabPaginationOf(header, perPage, page, len(projects)), nil
+	})
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list projects of user:%s from gitlab", token.User)
+	}
+
+	return res, nil
+}
+
+// List names of branches or tags.
+func (p *GitlabProvider) listRefs(ctx context.Context, src *repository.Source, path string, perPage, page int) ([]string, *Pagination, error) {
+	perPage, page = normalizePage(perPage, page)
+
+	refs := make([]*gitlabRef, 0)
+	header, err := p.getProjectResource(ctx, src, path, pageQuery(perPage, page), &refs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res := make([]string, 0)
+	for i := range refs {
+		res = append(res, refs[i].Name)
+	}
+
+	return res, gitlabPaginationOf(header, perPage, page, len(res)), nil
+}
+
+// Get resource of project with access token of user who created source, project is identified by id so that renamed
+// project is still accessible, or by path with namespace if source was not bound to project.
+// Header of response would be returned, which carries pagination of list.
+func (p *GitlabProvider) getProjectResource(ctx context.Context, src *repository.Source, path string, query url.Values, out interface{}) (http.Header, error) {
+	token, err := GetController().findAccessToken(src.Type, src.User)
+	if err != nil {
+		return nil, err
+	}
+
+	project := src.RepositoryId
+	if len(project) < 1 {
+		project = src.Repository
+	}
+
+	rawUrl := fmt.Sprintf("%s/api/v4/projects/%s%s?%s", p.BaseUrl, url.PathEscape(project), path, query.Encode())
+	header, err := getVCSResourceWithHeader(ctx, rawUrl, bearerPrefix+token.Token, out)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to get %s%s from gitlab", src.Repository, path)
+	}
+
+	return header, nil
+}
+
+// Avatars of self-hosted GitLab might be relative to base URL.
+func (p *GitlabProvider) absoluteUrl(rawUrl string) string {
+	if strings.HasPrefix(rawUrl, "/") {
+		return p.BaseUrl + rawUrl
+	}
+
+	return rawUrl
+}
+
+// Returns pagination from headers of GitLab, X-Total and X-Total-Pages are omitted by GitLab for large lists.
+func gitlabPaginationOf(header http.Header, perPage, page, size int) *Pagination {
+	return newPagination(perPage, page, size,
+		intOfHeader(header, "X-Next-Page"), intOfHeader(header, "X-Total-Pages"), intOfHeader(header, "X-Total"))
+}
+
+// Returns query of page.
+func pageQuery(perPage, page int) url.Values {
+	perPage, page = normalizePage(perPage, page)
+
+	return url.Values{
+		"per_page": {fmt.Sprint(perPage)},
+		"page":     {fmt.Sprint(page)},
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// Stand-in of GitLab REST API v4 which accepts access token ut-gitlab-token only.
+func newFakeGitlabApi(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	encode := func(path string, v interface{}) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer ut-gitlab-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(v)
+		})
+	}
+
+	group := map[string]interface{}{"id": 1, "kind": "group", "path": "ut-sub", "full_path": "ut-group/ut-sub", "avatar_url": "/uploads/ut.png"}
+	user := map[string]interface{}{"id": 2, "kind": "user", "path": "ut-user", "full_path": "ut-user"}
+	repo := map[string]interface{}{"path": "ut-repo", "path_with_namespace": "ut-group/ut-sub/ut-repo", "namespace": group}
+	other := map[string]interface{}{"path": "ut-other", "path_with_namespace": "ut-group/ut-sub/ut-other", "namespace": group}
+	mine := map[string]interface{}{"path": "ut-mine", "path_with_namespace": "ut-user/ut-mine", "namespace": user}
+
+	encode("/api/v4/namespaces/1", group)
+	encode("/api/v4/namespaces/2", user)
+
+	// lists of projects are paginated with X-Next-Page header
+	paginate := func(path string, pages ...[]map[string]interface{}) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer ut-gitlab-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page < len(pages) {
+				w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+			}
+			json.NewEncoder(w).Encode(pages[page-1])
+		})
+	}
+	paginate("/api/v4/projects", []map[string]interface{}{repo}, []map[string]interface{}{other, mine})
+	paginate("/api/v4/groups/1/projects", []map[string]interface{}{repo}, []map[string]interface{}{other})
+	paginate("/api/v4/users/ut-user/projects", []map[string]interface{}{mine})
+
+	// project is identified by url encoded path with namespace, or id if source was bound to project
+	mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ut-gitlab-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/ut-group%2Fut-sub%2Fut-repo":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id": 42, "path": "ut-repo", "path_with_namespace": "ut-group/ut-sub/ut-repo", "default_branch": "main",
+			})
+		case "/api/v4/projects/ut-group%2Fut-sub%2Fut-repo/repository/commits":
+			assert.Equal(t, "dev", r.URL.Query().Get("ref_name"))
+			assert.Equal(t, "5", r.URL.Query().Get("per_page"))
+			// X-Total is omitted by GitLab for large lists
+			w.Header().Set("X-Next-Page", "2")
+			w.Header().Set("X-Total-Pages", "3")
+			json.NewEncoder(w).Encode([]map[string]interface{}{{
+				"id":             "ut-sha",
+				"message":        "ut-message",
+				"web_url":        "https://ut-gitlab/ut-sha",
+				"committer_name": "ut-committer",
+				"committed_date": "2021-08-01T00:00:00Z",
+			}})
+		case "/api/v4/projects/ut-group%2Fut-sub%2Fut-repo/repository/branches":
+			w.Header().Set("X-Next-Page", "")
+			w.Header().Set("X-Total", "2")
+			json.NewEncoder(w).Encode([]map[string]string{{"name": "main"}, {"name": "dev"}})
+		case "/api/v4/projects/ut-group%2Fut-sub%2Fut-repo/repository/tags", "/api/v4/projects/42/repository/tags":
+			json.NewEncoder(w).Encode([]map[string]string{{"name": "v1.0.0"}})
+		case "/api/v4/projects/ut-group%2Fut-sub%2Fut-repo/repository/files/ci%2Fws.yaml/raw":
+			w.Write([]byte("ut-content"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGitlabProvider(t *testing.T) {
+	server := newFakeGitlabApi(t)
+	repo := repository.RegisterMemory()
+	RegisterController()
+	ctx := context.Background()
+
+	// user with linked gitlab identity
+	user := repository.NewUser("ut-user")
+	repo.CreateUser(user)
+	identity := repository.NewIdentity(repository.IdentityGitlab, "ut-user")
+	identity.UserId = user.Id
+	repo.UpsertIdentity(identity)
+	repo.UpsertAccessToken(repository.NewAccessToken(1, repository.IdentityGitlab, "ut-user", "ut-gitlab-token"))
+
+	provider := NewGitlabProvider(server.URL + "/")
+	src := repository.NewSource(repository.IdentityGitlab, "ut-group/ut-sub/ut-repo")
+	src.User = "ut-user"
+
+	// 1: projects of all pages are grouped by namespaces
+	installations, err := provider.ListInstallations(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 2)
+	assert.Equal(t, "ut-group/ut-sub", installations[0].Organization)
+	assert.Equal(t, server.URL+"/uploads/ut.png", installations[0].AvatarUrl)
+	assert.Equal(t, "ut-user", installations[1].Organization)
+
+	// 2: projects are listed with group or user of namespace
+	repos, err := provider.ListRepos(ctx, 1, installations[0])
+	assert.Nil(t, err)
+	assert.Equal(t, []*Repository{
+		{FullName: "ut-group/ut-sub/ut-repo", Name: "ut-repo"},
+		{FullName: "ut-group/ut-sub/ut-other", Name: "ut-other"},
+	}, repos)
+	repos, err = provider.ListRepos(ctx, 1, installations[1])
+	assert.Nil(t, err)
+	assert.Equal(t, []*Repository{{FullName: "ut-user/ut-mine", Name: "ut-mine"}}, repos)
+
+	// 3: pages are followed until max pages of controller reached
+	GetController().VCSMaxPages = 1
+	installations, err = provider.ListInstallations(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 1)
+	GetController().VCSMaxPages = VCSMaxPagesDefault
+
+	// 4: commits
+	commits, pagination, err := provider.ListCommits(ctx, src, "dev", 5, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, &Pagination{Page: 1, PerPage: 5, NextPage: 2, LastPage: 3}, pagination)
+	assert.Equal(t, "ut-sha", commits[0].Id)
+	assert.Equal(t, "ut-committer", commits[0].Committer)
+	assert.Equal(t, 2021, commits[0].Date.Year())
+
+	// 5: branches and tags
+	branches, pagination, err := provider.ListBranches(ctx, src, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"main", "dev"}, branches)
+	assert.Equal(t, &Pagination{Page: 1, PerPage: PerPageDefault, LastPage: 1, Total: 2}, pagination)
+	tags, _, err := provider.ListTags(ctx, src, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v1.0.0"}, tags)
+
+	// 6: file contents
+	content, err := provider.GetFileContent(ctx, src, "", "/ci/ws.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-content", string(content))
+	_, err = provider.GetFileContent(ctx, src, "", "ut-missing.yaml")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	// 7: project is accessed with id after bound, so that renamed project is still accessible
+	remote, err := provider.GetRepository(ctx, src)
+	assert.Nil(t, err)
+	assert.Equal(t, &Repository{Id: "42", FullName: "ut-group/ut-sub/ut-repo", Name: "ut-repo", DefaultBranch: "main"}, remote)
+	renamed := repository.NewSource(repository.IdentityGitlab, "ut-group/ut-sub/ut-old")
+	renamed.User = "ut-user"
+	renamed.RepositoryId = remote.Id
+	tags, _, err = provider.ListTags(ctx, renamed, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v1.0.0"}, tags)
+
+	// 8: token rejected by gitlab
+	repo.UpsertAccessToken(repository.NewAccessToken(1, repository.IdentityGitlab, "ut-user", "ut-invalid-token"))
+	_, _, err = provider.ListBranches(ctx, src, 0, 0)
+	assert.Equal(t, repository.CodeUpstream, repository.CodeOf(err))
+}
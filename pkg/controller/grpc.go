@@ -0,0 +1,659 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	wsv1 "github.com/pointgoal/workstation/api/gen/v1"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-grpc/boot"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// GrpcEntryName name of GrpcEntry which serves gRPC APIs and grpc-gateway
+	GrpcEntryName = "workstation-grpc"
+	// grpcServicePrefix is prefix of full method of workstation services
+	grpcServicePrefix = "/workstation."
+)
+
+// Key of session in context of gRPC requests.
+type sessionCtxKey struct{}
+
+// grpcCodes maps error code to gRPC status code
+var grpcCodes = map[repository.Code]codes.Code{
+	repository.CodeInternal:         codes.Internal,
+	repository.CodeInvalidArgument:  codes.InvalidArgument,
+	repository.CodeUnauthenticated:  codes.Unauthenticated,
+	repository.CodeNotFound:         codes.NotFound,
+	repository.CodeAlreadyExist:     codes.AlreadyExists,
+	repository.CodeConflict:         codes.FailedPrecondition,
+	repository.CodePermissionDenied: codes.PermissionDenied,
+	repository.CodeUpstream:         codes.Unavailable,
+	repository.CodeUnprocessable:    codes.FailedPrecondition,
+	repository.CodeRateLimited:      codes.ResourceExhausted,
+}
+
+// Register gRPC services and grpc-gateway handlers into GrpcEntry.
+// GrpcEntry is optional, nothing will happen if it was not configured in boot config.
+//
+// This must be called before GrpcEntry bootstrapped, since services are registered while bootstrapping.
+func initGrpc() {
+	grpcEntry := rkgrpc.GetGrpcEntry(GrpcEntryName)
+	if grpcEntry == nil {
+		return
+	}
+
+	grpcEntry.AddUnaryInterceptors(AuthUnaryInterceptor())
+	grpcEntry.AddRegFuncGrpc(registerGrpcServices)
+	grpcEntry.AddRegFuncGw(
+		wsv1.RegisterOrgServiceHandlerFromEndpoint,
+		wsv1.RegisterProjServiceHandlerFromEndpoint,
+		wsv1.RegisterSourceServiceHandlerFromEndpoint,
+		wsv1.RegisterPipelineTemplateServiceHandlerFromEndpoint)
+}
+
+// AuthUnaryInterceptor requires valid session on workstation services, the same as AuthInterceptor.
+//
+// Session is read from authorization metadata with Bearer scheme first, and then cookie forwarded by grpc-gateway.
+// Services of rk-grpc like health check are not affected.
+func AuthUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, grpcServicePrefix) {
+			return handler(ctx, req)
+		}
+
+		token := sessionTokenFromMetadata(ctx)
+		if len(token) < 1 {
+			return nil, toGrpcError(repository.NewUnauthenticatedf("missing session, please login with oauth first"))
+		}
+
+		session, err := GetController().sessionOf(token)
+		if err != nil {
+			return nil, toGrpcError(err)
+		}
+
+		if err := checkScope(session, grpcScopeOf(info.FullMethod)); err != nil {
+			return nil, toGrpcError(err)
+		}
+
+		return handler(context.WithValue(ctx, sessionCtxKey{}, session), req)
+	}
+}
+
+// GetGrpcSession returns session of current gRPC request set by AuthUnaryInterceptor, nil would be returned if missing.
+func GetGrpcSession(ctx context.Context) *Session {
+	if session, ok := ctx.Value(sessionCtxKey{}).(*Session); ok {
+		return session
+	}
+
+	return nil
+}
+
+// Returns id of user in session of current gRPC request or unauthenticated error.
+func grpcUserId(ctx context.Context) (int, error) {
+	session := GetGrpcSession(ctx)
+	if session == nil {
+		return 0, toGrpcError(repository.NewUnauthenticatedf("missing session, please login with oauth first"))
+	}
+
+	return session.UserId(), nil
+}
+
+// Read session token from authorization or cookie in metadata.
+func sessionTokenFromMetadata(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	for _, v := range md.Get("authorization") {
+		if strings.HasPrefix(v, bearerPrefix) {
+			return strings.TrimPrefix(v, bearerPrefix)
+		}
+	}
+
+	// cookie was forwarded by grpc-gateway with prefix
+	req := &http.Request{Header: http.Header{"Cookie": md.Get(runtime.MetadataPrefix + "cookie")}}
+	if cookie, err := req.Cookie(SessionCookieName); err == nil {
+		return cookie.Value
+	}
+
+	return ""
+}
+
+// Register implementations of gRPC services into server.
+func registerGrpcServices(server *grpc.Server) {
+	wsv1.RegisterOrgServiceServer(server, &OrgGrpcServer{})
+	wsv1.RegisterProjServiceServer(server, &ProjGrpcServer{})
+	wsv1.RegisterSourceServiceServer(server, &SourceGrpcServer{})
+	wsv1.RegisterPipelineTemplateServiceServer(server, &PipelineTemplateGrpcServer{})
+}
+
+// ************************************************** //
+// ************** Organization related ************** //
+// ************************************************** //
+
+// OrgGrpcServer implements wsv1.OrgServiceServer
+type OrgGrpcServer struct{}
+
+// ListOrg lists organizations.
+func (s *OrgGrpcServer) ListOrg(ctx context.Context, _ *wsv1.ListOrgRequest) (*wsv1.ListOrgResponse, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orgList, err := GetController().listOrg(userId)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	res := &wsv1.ListOrgResponse{}
+	for i := range orgList {
+		res.Orgs = append(res.Orgs, toOrgPb(orgList[i]))
+	}
+
+	return res, nil
+}
+
+// GetOrg returns organization with ids of projects.
+func (s *OrgGrpcServer) GetOrg(ctx context.Context, in *wsv1.GetOrgRequest) (*wsv1.Org, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &OrgIdRequest{OrgId: int(in.GetOrgId())}
+	if err := validate(req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	org, err := GetController().getOrg(userId, req.OrgId)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	return toOrgPb(org), nil
+}
+
+// CreateOrg creates organization.
+func (s *OrgGrpcServer) CreateOrg(ctx context.Context, in *wsv1.CreateOrgRequest) (*wsv1.Org, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &CreateOrgV2Request{Name: in.GetName()}
+	if err := validate(req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	org, err := GetController().createOrg(userId, req.Name)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	return toOrgPb(org), nil
+}
+
+// UpdateOrg updates organization.
+func (s *OrgGrpcServer) UpdateOrg(ctx context.Context, in *wsv1.UpdateOrgRequest) (*wsv1.Org, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	path := &OrgIdRequest{OrgId: int(in.GetOrgId())}
+	req := &UpdateOrgRequest{Name: in.GetName()}
+	if err := validateAll(path, req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	org, err := GetController().updateOrg(userId, path.OrgId, req.Name)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	return toOrgPb(org), nil
+}
+
+// DeleteOrg deletes organization without projects.
+func (s *OrgGrpcServer) DeleteOrg(ctx context.Context, in *wsv1.DeleteOrgRequest) (*emptypb.Empty, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &OrgIdRequest{OrgId: int(in.GetOrgId())}
+	if err := validate(req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	if err := GetController().deleteOrg(userId, req.OrgId); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ********************************************* //
+// ************** Project related ************** //
+// ********************************************* //
+
+// ProjGrpcServer implements wsv1.ProjServiceServer
+type ProjGrpcServer struct{}
+
+// ListProj lists projects in organization, all projects would be listed if org_id is missing.
+func (s *ProjGrpcServer) ListProj(ctx context.Context, in *wsv1.ListProjRequest) (*wsv1.ListProjResponse, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &ListProjRequest{OrgId: int(in.GetOrgId())}
+	if err := validate(req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	projList, err := GetController().listProj(userId, req.OrgId)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	res := &wsv1.ListProjResponse{}
+	for i := range projList {
+		res.Projects = append(res.Projects, toProjPb(projList[i]))
+	}
+
+	return res, nil
+}
+
+// GetProj returns project with source.
+func (s *ProjGrpcServer) GetProj(ctx context.Context, in *wsv1.GetProjRequest) (*wsv1.Proj, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &ProjIdRequest{ProjId: int(in.GetProjId())}
+	if err := validate(req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	proj, err := GetController().getProj(userId, req.ProjId)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	return toProjPb(proj), nil
+}
+
+// CreateProj creates project in organization.
+func (s *ProjGrpcServer) CreateProj(ctx context.Context, in *wsv1.CreateProjRequest) (*wsv1.Proj, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &CreateProjV2Request{OrgId: int(in.GetOrgId()), Name: in.GetName()}
+	if err := validate(req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	proj, err := GetController().createProj(userId, req.OrgId, "", req.Name)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	return toProjPb(proj), nil
+}
+
+// UpdateProj updates project.
+func (s *ProjGrpcServer) UpdateProj(ctx context.Context, in *wsv1.UpdateProjRequest) (*wsv1.Proj, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	path := &ProjIdRequest{ProjId: int(in.GetProjId())}
+	req := &UpdateProjRequest{Name: in.GetName()}
+	if err := validateAll(path, req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	proj, err := GetController().updateProj(userId, path.ProjId, req.Name)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	return toProjPb(proj), nil
+}
+
+// DeleteProj deletes project.
+func (s *ProjGrpcServer) DeleteProj(ctx context.Context, in *wsv1.DeleteProjRequest) (*emptypb.Empty, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &ProjIdRequest{ProjId: int(in.GetProjId())}
+	if err := validate(req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	if err := GetController().deleteProj(userId, req.ProjId); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ******************************************** //
+// ************** Source related ************** //
+// ******************************************** //
+
+// SourceGrpcServer implements wsv1.SourceServiceServer
+type SourceGrpcServer struct{}
+
+// CreateSource creates source in project.
+func (s *SourceGrpcServer) CreateSource(ctx context.Context, in *wsv1.CreateSourceRequest) (*wsv1.Source, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	path := &ProjIdRequest{ProjId: int(in.GetProjId())}
+	req := &CreateSourceRequest{Type: in.GetType(), Repository: in.GetRepository(), Host: in.GetHost()}
+	if err := validateAll(path, req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	src, err := GetController().createSource(userId, path.ProjId, req.Type, req.Repository, req.Host)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	return toSourcePb(src.Meta), nil
+}
+
+// GetSource returns source.
+func (s *SourceGrpcServer) GetSource(ctx context.Context, in *wsv1.GetSourceRequest) (*wsv1.Source, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &SourceIdRequest{SourceId: int(in.GetSourceId())}
+	if err := validate(req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	src, err := GetController().getSource(userId, req.SourceId)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	return toSourcePb(src.Meta), nil
+}
+
+// DeleteSource deletes source.
+func (s *SourceGrpcServer) DeleteSource(ctx context.Context, in *wsv1.DeleteSourceRequest) (*emptypb.Empty, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &SourceIdRequest{SourceId: int(in.GetSourceId())}
+	if err := validate(req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	if err := GetController().deleteSource(userId, req.SourceId); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListCommits lists commits of source from remote code repository.
+func (s *SourceGrpcServer) ListCommits(ctx context.Context, in *wsv1.ListCommitsRequest) (*wsv1.ListCommitsResponse, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	path := &SourceIdRequest{SourceId: int(in.GetSourceId())}
+	req := &ListCommitsRequest{Branch: in.GetBranch(), PerPage: int(in.GetPerPage()), Page: int(in.GetPage()), All: in.GetAll()}
+	if err := validateAll(path, req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	commits, pagination, err := GetController().listCommits(userId, path.SourceId, req.Branch, req.PerPage, req.Page, req.All)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	res := &wsv1.ListCommitsResponse{Pagination: toPaginationPb(pagination)}
+	for i := range commits {
+		res.Commits = append(res.Commits, &wsv1.Commit{
+			Id:           commits[i].Id,
+			Url:          commits[i].Url,
+			Message:      commits[i].Message,
+			Date:         toTimestampPb(commits[i].Date),
+			Committer:    commits[i].Committer,
+			CommitterUrl: commits[i].CommitterUrl,
+		})
+	}
+
+	return res, nil
+}
+
+// ListBranchesAndTags lists branches and tags of source from remote code repository.
+func (s *SourceGrpcServer) ListBranchesAndTags(ctx context.Context, in *wsv1.ListBranchesAndTagsRequest) (*wsv1.ListBranchesAndTagsResponse, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	path := &SourceIdRequest{SourceId: int(in.GetSourceId())}
+	req := &ListBranchesAndTagsRequest{
+		PerPage:    int(in.GetPerPage()),
+		Page:       int(in.GetPage()),
+		BranchPage: int(in.GetBranchPage()),
+		TagPage:    int(in.GetTagPage()),
+		All:        in.GetAll(),
+	}
+	if err := validateAll(path, req); err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	res, err := GetController().listBranchesAndTags(userId, path.SourceId, req.PerPage,
+		pageOr(req.BranchPage, req.Page), pageOr(req.TagPage, req.Page), req.All)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	return &wsv1.ListBranchesAndTagsResponse{
+		Branches:         res.Branches,
+		Tags:             res.Tags,
+		BranchPagination: toPaginationPb(res.BranchPagination),
+		TagPagination:    toPaginationPb(res.TagPagination),
+	}, nil
+}
+
+// ************************************************** //
+// ************ PipelineTemplate related ************ //
+// ************************************************** //
+
+// PipelineTemplateGrpcServer implements wsv1.PipelineTemplateServiceServer
+type PipelineTemplateGrpcServer struct{}
+
+// ListPipelineTemplate lists pipeline templates.
+func (s *PipelineTemplateGrpcServer) ListPipelineTemplate(ctx context.Context, _ *wsv1.ListPipelineTemplateRequest) (*wsv1.ListPipelineTemplateResponse, error) {
+	userId, err := grpcUserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	templateList, err := GetController().listPipelineTemplate(userId)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+
+	res := &wsv1.ListPipelineTemplateResponse{}
+	for i := range templateList {
+		meta := templateList[i].Meta
+		res.Templates = append(res.Templates, &wsv1.PipelineTemplate{
+			Id:        int64(meta.Id),
+			Name:      meta.Name,
+			Language:  meta.Language,
+			Content:   meta.Content,
+			CreatedAt: toTimestampPb(meta.CreatedAt),
+			UpdatedAt: toTimestampPb(meta.UpdatedAt),
+		})
+	}
+
+	return res, nil
+}
+
+// ******************************************** //
+// ************** Helper functions ************** //
+// ******************************************** //
+
+// Validate requests one by one and return the first error.
+func validateAll(reqs ...interface{}) error {
+	for i := range reqs {
+		if err := validate(reqs[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Convert error into gRPC status error, field violations would be attached as BadRequest details.
+func toGrpcError(err error) error {
+	var inner *repository.Error
+	if !errors.As(err, &inner) {
+		inner = repository.Wrapf(err, repository.CodeInternal, "internal error")
+	}
+
+	code, ok := grpcCodes[inner.Code]
+	if !ok {
+		code = codes.Internal
+	}
+
+	// causes of server errors are logged only, like rendering of REST APIs
+	message := inner.Error()
+	if isServerError(inner) {
+		logServerError(inner)
+		message = inner.Message
+	}
+	st := status.New(code, message)
+
+	badRequest := &errdetails.BadRequest{}
+	for i := range inner.Details {
+		if violation, ok := inner.Details[i].(*FieldViolation); ok {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       violation.Field,
+				Description: violation.Message,
+			})
+		}
+	}
+
+	if len(badRequest.FieldViolations) > 0 {
+		if withDetails, err := st.WithDetails(badRequest); err == nil {
+			st = withDetails
+		}
+	}
+
+	if retryAfter := repository.RetryAfterOf(err); retryAfter > 0 {
+		if withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+			st = withDetails
+		}
+	}
+
+	return st.Err()
+}
+
+func toTimestampPb(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
+
+func toOrgPb(org *Org) *wsv1.Org {
+	res := &wsv1.Org{
+		Id:        int64(org.Meta.Id),
+		Name:      org.Meta.Name,
+		CreatedAt: toTimestampPb(org.Meta.CreatedAt),
+		UpdatedAt: toTimestampPb(org.Meta.UpdatedAt),
+	}
+
+	for i := range org.ProjIds {
+		res.ProjIds = append(res.ProjIds, int64(org.ProjIds[i]))
+	}
+
+	return res
+}
+
+func toProjPb(proj *Proj) *wsv1.Proj {
+	return &wsv1.Proj{
+		Id:        int64(proj.Meta.Id),
+		OrgId:     int64(proj.Meta.OrgId),
+		OrgName:   proj.Meta.OrgName,
+		Name:      proj.Meta.Name,
+		CreatedAt: toTimestampPb(proj.Meta.CreatedAt),
+		UpdatedAt: toTimestampPb(proj.Meta.UpdatedAt),
+		Source:    toSourcePb(proj.Meta.Source),
+	}
+}
+
+func toSourcePb(src *repository.Source) *wsv1.Source {
+	if src == nil {
+		return nil
+	}
+
+	return &wsv1.Source{
+		Id:            int64(src.Id),
+		ProjId:        int64(src.ProjId),
+		Type:          src.Type,
+		Repository:    src.Repository,
+		Host:          src.Host,
+		User:          src.User,
+		Status:        src.Status,
+		RepositoryId:  src.RepositoryId,
+		DefaultBranch: src.DefaultBranch,
+		CreatedAt:     toTimestampPb(src.CreatedAt),
+		UpdatedAt:     toTimestampPb(src.UpdatedAt),
+	}
+}
+
+func toPaginationPb(pagination *Pagination) *wsv1.Pagination {
+	if pagination == nil {
+		return nil
+	}
+
+	return &wsv1.Pagination{
+		Page:     int32(pagination.Page),
+		PerPage:  int32(pagination.PerPage),
+		NextPage: int32(pagination.NextPage),
+		LastPage: int32(pagination.LastPage),
+		Total:    int32(pagination.Total),
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	wsv1 "github.com/pointgoal/workstation/api/gen/v1"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/rookie-ninja/rk-grpc/boot"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInitGrpc_WithoutGrpcEntry(t *testing.T) {
+	defer assertNotPanic(t)
+	initGrpc()
+}
+
+func TestInitGrpc_HappyCase(t *testing.T) {
+	defer assertNotPanic(t)
+	defer rkentry.GlobalAppCtx.RemoveEntry(GrpcEntryName)
+
+	entry := rkgrpc.RegisterGrpcEntry(rkgrpc.WithNameGrpc(GrpcEntryName))
+	initGrpc()
+
+	assert.Len(t, entry.GrpcRegF, 1)
+	assert.Len(t, entry.GwRegF, 4)
+}
+
+func TestOrgGrpcServer(t *testing.T) {
+	defer assertNotPanic(t)
+	conn := newGrpcConn(t, grpc.UnaryInterceptor(AuthUnaryInterceptor()))
+	defer conn.Close()
+	client := wsv1.NewOrgServiceClient(conn)
+	ctx := withBearerSession(1)
+
+	// create organization
+	org, err := client.CreateOrg(ctx, &wsv1.CreateOrgRequest{Name: "ut-org"})
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-org", org.GetName())
+	assert.NotNil(t, org.GetCreatedAt())
+
+	// create organization with invalid name, field violations expected
+	_, err = client.CreateOrg(ctx, &wsv1.CreateOrgRequest{Name: "ut org"})
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Len(t, st.Details(), 1)
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	assert.True(t, ok)
+	assert.Equal(t, "name", badRequest.GetFieldViolations()[0].GetField())
+
+	// update organization
+	org, err = client.UpdateOrg(ctx, &wsv1.UpdateOrgRequest{OrgId: org.GetId(), Name: "ut-org-new"})
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-org-new", org.GetName())
+
+	// list and get organization
+	orgList, err := client.ListOrg(ctx, &wsv1.ListOrgRequest{})
+	assert.Nil(t, err)
+	assert.Len(t, orgList.GetOrgs(), 1)
+
+	_, err = client.GetOrg(ctx, &wsv1.GetOrgRequest{OrgId: 2})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.GetOrg(ctx, &wsv1.GetOrgRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// delete organization
+	_, err = client.DeleteOrg(ctx, &wsv1.DeleteOrgRequest{OrgId: org.GetId()})
+	assert.Nil(t, err)
+
+	_, err = client.DeleteOrg(ctx, &wsv1.DeleteOrgRequest{OrgId: org.GetId()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestProjAndSourceGrpcServer(t *testing.T) {
+	defer assertNotPanic(t)
+	conn := newGrpcConn(t, grpc.UnaryInterceptor(AuthUnaryInterceptor()))
+	defer conn.Close()
+	RegisterSourceType("github")
+	api := newFakeGithubApi(t)
+	RegisterGithubApp(api.newApp(t, GithubHostDefault))
+	defer unregisterGithubApp(GithubHostDefault)
+	api.linkUser(t, GetController().Repo, 1)
+	orgClient := wsv1.NewOrgServiceClient(conn)
+	projClient := wsv1.NewProjServiceClient(conn)
+	srcClient := wsv1.NewSourceServiceClient(conn)
+	ctx := withBearerSession(1)
+
+	// create project without organization
+	_, err := projClient.CreateProj(ctx, &wsv1.CreateProjRequest{OrgId: 1, Name: "ut-proj"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// create project
+	org, _ := orgClient.CreateOrg(ctx, &wsv1.CreateOrgRequest{Name: "ut-org"})
+	proj, err := projClient.CreateProj(ctx, &wsv1.CreateProjRequest{OrgId: org.GetId(), Name: "ut-proj"})
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-org", proj.GetOrgName())
+
+	// delete organization with projects
+	_, err = orgClient.DeleteOrg(ctx, &wsv1.DeleteOrgRequest{OrgId: org.GetId()})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// create source
+	src, err := srcClient.CreateSource(ctx, &wsv1.CreateSourceRequest{
+		ProjId:     proj.GetId(),
+		Type:       "github",
+		Repository: "ut-owner/ut-repo",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, proj.GetId(), src.GetProjId())
+	assert.Equal(t, "1296269", src.GetRepositoryId())
+	assert.Equal(t, "main", src.GetDefaultBranch())
+
+	_, err = srcClient.CreateSource(ctx, &wsv1.CreateSourceRequest{
+		ProjId:     proj.GetId(),
+		Type:       "github",
+		Repository: "ut-owner/ut-repo",
+	})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// get project with source
+	proj, err = projClient.GetProj(ctx, &wsv1.GetProjRequest{ProjId: proj.GetId()})
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-owner/ut-repo", proj.GetSource().GetRepository())
+
+	// list projects
+	projList, err := projClient.ListProj(ctx, &wsv1.ListProjRequest{})
+	assert.Nil(t, err)
+	assert.Len(t, projList.GetProjects(), 1)
+
+	// list commits with invalid page
+	_, err = srcClient.ListCommits(ctx, &wsv1.ListCommitsRequest{SourceId: src.GetId(), PerPage: 1000})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// user who is not a member of organization
+	_, err = projClient.GetProj(withBearerSession(2), &wsv1.GetProjRequest{ProjId: proj.GetId()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	projList, err = projClient.ListProj(withBearerSession(2), &wsv1.ListProjRequest{})
+	assert.Nil(t, err)
+	assert.Empty(t, projList.GetProjects())
+}
+
+func TestGrpcGateway(t *testing.T) {
+	defer assertNotPanic(t)
+	repository.RegisterMemory()
+	RegisterController()
+
+	gwMux := runtime.NewServeMux()
+	ctx := context.Background()
+	assert.Nil(t, wsv1.RegisterOrgServiceHandlerServer(ctx, gwMux, &OrgGrpcServer{}))
+	assert.Nil(t, wsv1.RegisterProjServiceHandlerServer(ctx, gwMux, &ProjGrpcServer{}))
+
+	// services are called in process without interceptors, attach session of user 1 directly
+	mux := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := &Session{StandardClaims: jwt.StandardClaims{Subject: "1"}}
+		gwMux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionCtxKey{}, session)))
+	})
+
+	// create organization
+	resp := httptest.NewRecorder()
+	mux.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/v1/orgs", strings.NewReader(`{"name":"ut-org"}`)))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"name":"ut-org"`)
+
+	// get organization not exist
+	resp = httptest.NewRecorder()
+	mux.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1/orgs/100", nil))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	// update organization with invalid name
+	resp = httptest.NewRecorder()
+	mux.ServeHTTP(resp, httptest.NewRequest(http.MethodPatch, "/v1/orgs/1", strings.NewReader(`{"name":"ut org"}`)))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// list projects
+	resp = httptest.NewRecorder()
+	mux.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1/projects?orgId=1", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestAuthUnaryInterceptor(t *testing.T) {
+	defer assertNotPanic(t)
+	conn := newGrpcConn(t, grpc.UnaryInterceptor(AuthUnaryInterceptor()))
+	defer conn.Close()
+	client := wsv1.NewOrgServiceClient(conn)
+
+	// without session
+	_, err := client.ListOrg(context.Background(), &wsv1.ListOrgRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// with invalid session
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", bearerPrefix+"invalid")
+	_, err = client.ListOrg(ctx, &wsv1.ListOrgRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// with bearer session
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", bearerPrefix+newSessionToken(1, "github", "ut-user"))
+	_, err = client.ListOrg(ctx, &wsv1.ListOrgRequest{})
+	assert.Nil(t, err)
+
+	// with cookie forwarded by grpc-gateway
+	ctx = metadata.AppendToOutgoingContext(context.Background(),
+		runtime.MetadataPrefix+"cookie", SessionCookieName+"="+newSessionToken(1, "github", "ut-user"))
+	_, err = client.ListOrg(ctx, &wsv1.ListOrgRequest{})
+	assert.Nil(t, err)
+
+	// with personal token limited by scopes
+	GetController().Repo.CreateUser(repository.NewUser("ut-user"))
+	_, token, _ := GetController().createPersonalToken(1, "ut-cli", []string{ScopeReadOrg}, nil)
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", bearerPrefix+token)
+	_, err = client.ListOrg(ctx, &wsv1.ListOrgRequest{})
+	assert.Nil(t, err)
+	_, err = client.CreateOrg(ctx, &wsv1.CreateOrgRequest{Name: "ut-org"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// Returns context with session of user in authorization metadata.
+func withBearerSession(userId int) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(),
+		"authorization", bearerPrefix+newSessionToken(userId, "github", "ut-user"))
+}
+
+// Start gRPC server in memory with memory repository and return client connection.
+func newGrpcConn(t *testing.T, opts ...grpc.ServerOption) *grpc.ClientConn {
+	repository.RegisterMemory()
+	RegisterController()
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(opts...)
+	registerGrpcServices(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithInsecure())
+	assert.Nil(t, err)
+
+	return conn
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader is request header which identifies retries of the same create request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is response header which marks response as replayed
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// IdempotencyWindowDefault is default duration while stored responses could be replayed
+	IdempotencyWindowDefault = 24 * time.Hour
+	// idempotencyKeyMaxLength is the max length of idempotency key
+	idempotencyKeyMaxLength = 255
+)
+
+// Keys scoped by user of requests which are in progress, retries with the same key would be rejected until they finished.
+var inFlightIdempotencyKeys = sync.Map{}
+
+// IdempotencyInterceptor replays stored response of create requests sent with Idempotency-Key header.
+//
+// Response of the first successful request would be stored through repository together with
+// fingerprint of request, retries with the same key and request would be replayed with the stored
+// response within idempotency window of controller. Reusing the key with a different request
+// would be rejected with 422. Requests without the header are served as usual.
+//
+// Keys are scoped by user in session, so that users sending the same key would not replay responses of each other.
+func IdempotencyInterceptor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(IdempotencyKeyHeader)
+		if len(key) < 1 {
+			ctx.Next()
+			return
+		}
+
+		if len(key) > idempotencyKeyMaxLength {
+			abortWithError(ctx, repository.NewInvalidArgumentf("length of %s must not be greater than %d", IdempotencyKeyHeader, idempotencyKeyMaxLength))
+			return
+		}
+
+		key = scopedIdempotencyKey(ctx, key)
+
+		// 1: calculate fingerprint of request
+		fingerprint, err := fingerprintOf(ctx)
+		if err != nil {
+			abortWithError(ctx, repository.NewInvalidArgumentf("failed to read request body"))
+			return
+		}
+
+		// 2: reject concurrent retries, otherwise, both of them would be processed
+		if _, loaded := inFlightIdempotencyKeys.LoadOrStore(key, true); loaded {
+			abortWithError(ctx, repository.NewConflictf("request with the same %s is in progress", IdempotencyKeyHeader))
+			return
+		}
+		defer inFlightIdempotencyKeys.Delete(key)
+
+		// 3: replay stored response
+		controller := GetController()
+		stored, err := controller.Repo.GetIdempotencyKey(key)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			abortWithError(ctx, repository.Wrapf(err, repository.CodeInternal, "failed to get idempotency key"))
+			return
+		}
+
+		if stored != nil && !stored.IsExpired() {
+			if stored.Fingerprint != fingerprint {
+				abortWithError(ctx, repository.NewUnprocessablef("%s was used with a different request", IdempotencyKeyHeader))
+				return
+			}
+
+			replay(ctx, stored)
+			return
+		}
+
+		// 4: process request and store response
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		ctx.Next()
+
+		// failed requests could be retried with the same key
+		if len(ctx.Errors) > 0 || recorder.Status() < http.StatusOK || recorder.Status() >= http.StatusMultipleChoices {
+			return
+		}
+
+		record := repository.NewIdempotencyKey(key, fingerprint)
+		record.StatusCode = recorder.Status()
+		record.ContentType = recorder.Header().Get("Content-Type")
+		record.Location = recorder.Header().Get("Location")
+		record.Response = recorder.body.Bytes()
+		record.ExpiredAt = time.Now().Add(controller.IdempotencyWindow)
+
+		if _, err := controller.Repo.UpsertIdempotencyKey(record); err != nil {
+			controller.ZapLoggerEntry.GetLogger().Warn("failed to store idempotency key", zap.Error(err))
+		}
+	}
+}
+
+// Prefix key with id of user in session, requests without session share scope of user 0.
+func scopedIdempotencyKey(ctx *gin.Context, key string) string {
+	userId := 0
+	if session := GetSession(ctx); session != nil {
+		userId = session.UserId()
+	}
+
+	return fmt.Sprintf("%d:%s", userId, key)
+}
+
+// Attach error to context and skip the rest handlers, ErrorInterceptor would render the error.
+func abortWithError(ctx *gin.Context, err error) {
+	ctx.Error(err)
+	ctx.Abort()
+}
+
+// Write stored response with replayed header.
+func replay(ctx *gin.Context, stored *repository.IdempotencyKey) {
+	if len(stored.Location) > 0 {
+		ctx.Header("Location", stored.Location)
+	}
+	ctx.Header(IdempotentReplayedHeader, "true")
+	ctx.Data(stored.StatusCode, stored.ContentType, stored.Response)
+	ctx.Abort()
+}
+
+// Calculate fingerprint of request with method, path, query and body.
+// Body would be restored so that handlers could read it again.
+func fingerprintOf(ctx *gin.Context) (string, error) {
+	var body []byte
+	if ctx.Request.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(ctx.Request.Body); err != nil {
+			return "", err
+		}
+		ctx.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(ctx.Request.Method + "\n"))
+	hash.Write([]byte(ctx.Request.URL.Path + "\n"))
+	// query values are sorted by key while encoding
+	hash.Write([]byte(ctx.Request.URL.Query().Encode() + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// responseRecorder copies response body while writing, so that it could be stored.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write writes data into both of buffer and underlying writer.
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes string into both of buffer and underlying writer.
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyInterceptor_WithoutKey(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+
+	// every request creates a new organization
+	assert.Equal(t, http.StatusOK, doIdempotentRequest(router, http.MethodPut, "/v1/org?orgName=ut-org", "", "").Code)
+	assert.Equal(t, http.StatusOK, doIdempotentRequest(router, http.MethodPut, "/v1/org?orgName=ut-org", "", "").Code)
+
+	orgList, _ := GetController().Repo.ListOrg()
+	assert.Len(t, orgList, 2)
+}
+
+func TestIdempotencyInterceptor_WithReplay(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+
+	// 1: the first request creates organization
+	resp := doIdempotentRequest(router, http.MethodPut, "/v1/org?orgName=ut-org", "", "ut-key")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get(IdempotentReplayedHeader))
+	first := resp.Body.String()
+
+	// 2: retry would be replayed
+	resp = doIdempotentRequest(router, http.MethodPut, "/v1/org?orgName=ut-org", "", "ut-key")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "true", resp.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first, resp.Body.String())
+
+	orgList, _ := GetController().Repo.ListOrg()
+	assert.Len(t, orgList, 1)
+
+	// 3: reuse key with a different request
+	resp = doIdempotentRequest(router, http.MethodPut, "/v1/org?orgName=ut-org-2", "", "ut-key")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+}
+
+func TestIdempotencyInterceptor_WithCreated(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+
+	resp := doIdempotentRequest(router, http.MethodPost, "/v2/orgs", `{"name":"ut-org"}`, "ut-key")
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	location := resp.Header().Get("Location")
+	assert.NotEmpty(t, location)
+
+	// status and Location header should be replayed as well
+	resp = doIdempotentRequest(router, http.MethodPost, "/v2/orgs", `{"name":"ut-org"}`, "ut-key")
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, location, resp.Header().Get("Location"))
+	assert.Equal(t, "ut-org", decodeOrg(t, resp).Meta.Name)
+
+	// the same body on another endpoint is a different request
+	resp = doIdempotentRequest(router, http.MethodPost, "/v2/projects", `{"name":"ut-org"}`, "ut-key")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+}
+
+func TestIdempotencyInterceptor_WithFailedRequest(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+
+	// failed request would not be stored
+	resp := doIdempotentRequest(router, http.MethodPost, "/v2/projects", `{"orgId":1,"name":"ut-proj"}`, "ut-key")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	// create the missing organization and retry
+	assert.Equal(t, http.StatusCreated, doIdempotentRequest(router, http.MethodPost, "/v2/orgs", `{"name":"ut-org"}`, "").Code)
+	resp = doIdempotentRequest(router, http.MethodPost, "/v2/projects", `{"orgId":1,"name":"ut-proj"}`, "ut-key")
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Empty(t, resp.Header().Get(IdempotentReplayedHeader))
+}
+
+func TestIdempotencyInterceptor_WithExpiredKey(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+	GetController().IdempotencyWindow = time.Millisecond
+
+	assert.Equal(t, http.StatusOK, doIdempotentRequest(router, http.MethodPut, "/v1/org?orgName=ut-org", "", "ut-key").Code)
+	time.Sleep(2 * time.Millisecond)
+
+	// key is free to use after expired
+	resp := doIdempotentRequest(router, http.MethodPut, "/v1/org?orgName=ut-org-2", "", "ut-key")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get(IdempotentReplayedHeader))
+}
+
+func TestIdempotencyInterceptor_WithInvalidKey(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+
+	// too long
+	resp := doIdempotentRequest(router, http.MethodPut, "/v1/org?orgName=ut-org", "", strings.Repeat("k", idempotencyKeyMaxLength+1))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// in progress
+	inFlightIdempotencyKeys.Store("1:ut-key", true)
+	defer inFlightIdempotencyKeys.Delete("1:ut-key")
+	resp = doIdempotentRequest(router, http.MethodPut, "/v1/org?orgName=ut-org", "", "ut-key")
+	assert.Equal(t, http.StatusConflict, resp.Code)
+}
+
+func TestIdempotencyInterceptor_WithKeySharedByUsers(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+
+	// 1: user 1 creates organization
+	resp := doRequestWithHeaders(router, http.MethodPost, "/v2/orgs", `{"name":"ut-org"}`, idempotentHeadersOf(1, "ut-key"))
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	first := decodeOrg(t, resp)
+
+	// 2: user 2 sends the same request with the same key, which should not be replayed with response of user 1
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v2/orgs", `{"name":"ut-org"}`, idempotentHeadersOf(2, "ut-key"))
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Empty(t, resp.Header().Get(IdempotentReplayedHeader))
+	second := decodeOrg(t, resp)
+	assert.NotEqual(t, first.Meta.Id, second.Meta.Id)
+
+	// 3: retry of user 1 is still replayed with response of user 1
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v2/orgs", `{"name":"ut-org"}`, idempotentHeadersOf(1, "ut-key"))
+	assert.Equal(t, "true", resp.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first.Meta.Id, decodeOrg(t, resp).Meta.Id)
+
+	orgList, _ := GetController().Repo.ListOrg()
+	assert.Len(t, orgList, 2)
+}
+
+func doIdempotentRequest(router *gin.Engine, method, path, body, key string) *httptest.ResponseRecorder {
+	return doRequestWithHeaders(router, method, path, body, idempotentHeadersOf(1, key))
+}
+
+// Returns headers of json request with session of user and idempotency key.
+func idempotentHeadersOf(userId int, key string) map[string]string {
+	headers := sessionHeadersOf(userId)
+	headers[IdempotencyKeyHeader] = key
+	return headers
+}
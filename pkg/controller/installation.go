@@ -0,0 +1,254 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/go-github/v39/github"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	// InstallationSyncIntervalDefault is default interval of syncing installations of users in background
+	InstallationSyncIntervalDefault = time.Hour
+	// installationSyncQueueSize is the max number of syncs enqueued, syncs enqueued while queue is full are dropped
+	installationSyncQueueSize = 64
+)
+
+// userOfSource identifies installations of user with type of source.
+type userOfSource struct {
+	userId int
+	source string
+}
+
+// SyncInstallations lists installations of user together with repositories of them from remote code repository,
+// and replaces installations of user with type of source stored in repository.
+func (con *Controller) SyncInstallations(userId int, source string) ([]*Installation, error) {
+	provider, err := vcsProviderOf(source)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1: list installations
+	res, err := provider.ListInstallations(context.Background(), userId)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list user installations from %s", source)
+	}
+
+	// 2: list repositories of each installation
+	for i := range res {
+		if res[i].Repos, err = provider.ListRepos(context.Background(), userId, res[i]); err != nil {
+			return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list repositories of installation:%d from %s", res[i].Id, source)
+		}
+	}
+
+	// 3: replace stored installations
+	installations := make([]*repository.Installation, 0)
+	for i := range res {
+		installations = append(installations, toInstallationEntity(userId, source, res[i]))
+	}
+	if _, err := con.Repo.ReplaceInstallation(userId, source, installations); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to store installations of user:%d from %s", userId, source)
+	}
+	con.installationsSyncedAt.Store(userOfSource{userId: userId, source: source}, time.Now())
+
+	return res, nil
+}
+
+// ListInstallations returns installations of user with type of source stored in repository, installations are synced
+// from remote code repository first if none of them stored and they were not synced within sync interval.
+func (con *Controller) ListInstallations(userId int, source string) ([]*Installation, error) {
+	return con.listUserInstallations(source, userId, false)
+}
+
+// EnqueueInstallationSync enqueues sync of installations of user with type of source, which would be synced in background.
+// Sync would be dropped if too many syncs are waiting, installations would be synced periodically anyway.
+func (con *Controller) EnqueueInstallationSync(userId int, source string) {
+	select {
+	case con.installationSyncCh <- userOfSource{userId: userId, source: source}:
+	default:
+		con.ZapLoggerEntry.GetLogger().Warn("too many installation syncs enqueued, drop sync of user",
+			zap.Int("userId", userId), zap.String("source", source))
+	}
+}
+
+// Sync installations enqueued with EnqueueInstallationSync until quitCh closed.
+func (con *Controller) syncEnqueuedInstallations(quitCh chan struct{}) {
+	for {
+		select {
+		case <-quitCh:
+			return
+		case key := <-con.installationSyncCh:
+			if _, err := con.SyncInstallations(key.userId, key.source); err != nil {
+				con.ZapLoggerEntry.GetLogger().Warn("failed to sync installations",
+					zap.Int("userId", key.userId), zap.String("source", key.source), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Checks whether installations of user with type of source were synced by current process within sync interval.
+func (con *Controller) isInstallationSyncedRecently(userId int, source string) bool {
+	syncedAt, ok := con.installationsSyncedAt.Load(userOfSource{userId: userId, source: source})
+	return ok && time.Since(syncedAt.(time.Time)) < con.InstallationSyncInterval
+}
+
+// syncAllInstallations syncs installations of users who have installations stored, together with users who have
+// access tokens of remote code repositories, so that users whose syncs were dropped or failed would be synced too.
+// Failures are logged only, installations of other users would be synced as usual.
+func (con *Controller) syncAllInstallations() {
+	installations, err := con.Repo.ListInstallation(0, "", false)
+	if err != nil {
+		con.ZapLoggerEntry.GetLogger().Warn("failed to list installations", zap.Error(err))
+		return
+	}
+
+	tokens, err := con.Repo.ListAccessToken("")
+	if err != nil {
+		con.ZapLoggerEntry.GetLogger().Warn("failed to list access tokens", zap.Error(err))
+		return
+	}
+
+	keys := make([]userOfSource, 0)
+	for _, installation := range installations {
+		keys = append(keys, userOfSource{userId: installation.UserId, source: installation.Type})
+	}
+	for _, token := range tokens {
+		// tokens of identity providers without remote code repositories, like OpenID Connect, are skipped
+		if GetVCSProvider(token.Type) != nil {
+			keys = append(keys, userOfSource{userId: token.UserId, source: token.Type})
+		}
+	}
+
+	synced := make(map[userOfSource]bool)
+	for _, key := range keys {
+		if synced[key] {
+			continue
+		}
+		synced[key] = true
+
+		if _, err := con.SyncInstallations(key.userId, key.source); err != nil {
+			con.ZapLoggerEntry.GetLogger().Warn("failed to sync installations",
+				zap.Int("userId", key.userId), zap.String("source", key.source), zap.Error(err))
+		}
+	}
+}
+
+// Sync installations periodically until quitCh closed.
+func (con *Controller) syncInstallationsPeriodically(quitCh chan struct{}) {
+	ticker := time.NewTicker(con.InstallationSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quitCh:
+			return
+		case <-ticker.C:
+			if con.Repo == nil {
+				continue
+			}
+			con.syncAllInstallations()
+		}
+	}
+}
+
+// Syncs installations of users who could access installation in event, including sender of event.
+// Installations are listed from primary host only, events of other hosts are ignored.
+func syncGithubInstallationsOfEvent(con *Controller, host *GithubHost, payload []byte) error {
+	if host.Host != GetGithubHost("").Host {
+		return nil
+	}
+
+	// installation and installation_repositories events share action, installation and sender
+	event := &github.InstallationEvent{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return repository.NewInvalidArgumentf("invalid payload of %s event", "installation")
+	}
+	remoteId := event.GetInstallation().GetID()
+
+	// 1: installation removed, users could not access it anymore
+	if event.GetAction() == "deleted" {
+		if _, err := con.Repo.RemoveInstallationByRemoteId(repository.IdentityGithub, host.Host, remoteId); err != nil {
+			return repository.Wrapf(err, repository.CodeInternal, "failed to remove installation:%d", remoteId)
+		}
+		return nil
+	}
+
+	// 2: collect users who have installation stored, and sender if signed in with github before
+	userIds := make([]int, 0)
+	installations, err := con.Repo.ListInstallationByRemoteId(repository.IdentityGithub, host.Host, remoteId)
+	if err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to list installations with id:%d", remoteId)
+	}
+	for _, installation := range installations {
+		userIds = append(userIds, installation.UserId)
+	}
+
+	if login := event.GetSender().GetLogin(); len(login) > 0 {
+		identity, err := con.Repo.GetIdentity(repository.IdentityGithub, login)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return repository.Wrapf(err, repository.CodeInternal, "failed to get identity of %s", login)
+		}
+		if identity != nil {
+			userIds = append(userIds, identity.UserId)
+		}
+	}
+
+	// 3: sync installations of users, failures of users are logged only since tokens of them might be revoked
+	synced := make(map[int]bool)
+	for _, userId := range userIds {
+		if synced[userId] {
+			continue
+		}
+		synced[userId] = true
+
+		if _, err := con.SyncInstallations(userId, repository.IdentityGithub); err != nil {
+			con.ZapLoggerEntry.GetLogger().Warn("failed to sync installations of user",
+				zap.Int("userId", userId), zap.Int64("installationId", remoteId), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// Convert installation from remote code repository into entity of user stored in repository.
+func toInstallationEntity(userId int, source string, installation *Installation) *repository.Installation {
+	res := repository.NewInstallation(userId, source, installation.Id)
+	res.Host = installation.Host
+	res.Organization = installation.Organization
+	res.AvatarUrl = installation.AvatarUrl
+
+	for _, repo := range installation.Repos {
+		res.Repos = append(res.Repos, repository.NewInstallationRepo(repo.FullName, repo.Name))
+	}
+
+	return res
+}
+
+// Convert installation stored in repository into model for API response.
+func toInstallation(entity *repository.Installation) *Installation {
+	res := &Installation{
+		Id:           entity.RemoteId,
+		RepoSource:   entity.Type,
+		Host:         entity.Host,
+		Organization: entity.Organization,
+		AvatarUrl:    entity.AvatarUrl,
+		Repos:        make([]*Repository, 0),
+	}
+
+	for _, repo := range entity.Repos {
+		res.Repos = append(res.Repos, &Repository{
+			FullName: repo.FullName,
+			Name:     repo.Name,
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSyncAllInstallations(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	newRouterV2()
+	con := GetController()
+
+	provider := NewMemoryVCSProvider()
+	RegisterVCSProvider("ut-sync", provider)
+	RegisterSourceType("ut-sync")
+	provider.AddInstallation(1, &Installation{Id: 1, RepoSource: "ut-sync", Organization: "ut-owner"})
+
+	// 1: users without installations or access tokens stored are not synced
+	con.syncAllInstallations()
+	installations, err := con.Repo.ListInstallation(0, "", false)
+	assert.Nil(t, err)
+	assert.Empty(t, installations)
+
+	// users with access tokens are synced, even if first sync of them was dropped or failed
+	provider.AddInstallation(3, &Installation{Id: 3, RepoSource: "ut-sync", Organization: "ut-third"})
+	con.Repo.UpsertAccessToken(repository.NewAccessToken(3, "ut-sync", "ut-third", "ut-token"))
+	con.syncAllInstallations()
+	installations, err = con.Repo.ListInstallation(3, "ut-sync", false)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 1)
+
+	// 2: installations of users stored are synced
+	_, err = con.SyncInstallations(1, "ut-sync")
+	assert.Nil(t, err)
+	provider.AddInstallation(1, &Installation{
+		Id:           2,
+		RepoSource:   "ut-sync",
+		Organization: "ut-other",
+		Repos:        []*Repository{{FullName: "ut-other/ut-repo", Name: "ut-repo"}},
+	})
+	con.syncAllInstallations()
+	installations, err = con.Repo.ListInstallation(1, "ut-sync", true)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 2)
+	assert.Equal(t, int64(2), installations[1].RemoteId)
+	assert.Equal(t, "ut-other/ut-repo", installations[1].Repos[0].FullName)
+}
+
+func TestListUserInstallations_WithNothingStored(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	newRouterV2()
+	con := GetController()
+
+	provider := NewMemoryVCSProvider()
+	RegisterVCSProvider("ut-empty", provider)
+	RegisterSourceType("ut-empty")
+
+	// 1: user without installations is synced once within sync interval
+	installations, err := con.listUserInstallations("ut-empty", 1, false)
+	assert.Nil(t, err)
+	assert.Empty(t, installations)
+	provider.AddInstallation(1, &Installation{Id: 1, RepoSource: "ut-empty", Organization: "ut-owner"})
+	installations, err = con.listUserInstallations("ut-empty", 1, false)
+	assert.Nil(t, err)
+	assert.Empty(t, installations)
+
+	// 2: refresh syncs anyway
+	installations, err = con.listUserInstallations("ut-empty", 1, true)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 1)
+
+	// 3: synced again after sync interval passed
+	_, err = con.listUserInstallations("ut-empty", 2, false)
+	assert.Nil(t, err)
+	provider.AddInstallation(2, &Installation{Id: 2, RepoSource: "ut-empty", Organization: "ut-other"})
+	con.InstallationSyncInterval = time.Millisecond
+	time.Sleep(2 * time.Millisecond)
+	installations, err = con.listUserInstallations("ut-empty", 2, false)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 1)
+}
+
+func TestEnqueueInstallationSync(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	newRouterV2()
+	con := GetController()
+
+	provider := NewMemoryVCSProvider()
+	RegisterVCSProvider("ut-enqueued", provider)
+	RegisterSourceType("ut-enqueued")
+	provider.AddInstallation(1, &Installation{Id: 1, RepoSource: "ut-enqueued", Organization: "ut-owner"})
+
+	// installations are synced in background
+	quitCh := make(chan struct{})
+	defer close(quitCh)
+	go con.syncEnqueuedInstallations(quitCh)
+	con.EnqueueInstallationSync(1, "ut-enqueued")
+
+	assert.Eventually(t, func() bool {
+		return con.isInstallationSyncedRecently(1, "ut-enqueued")
+	}, time.Second, 10*time.Millisecond)
+	installations, err := con.Repo.ListInstallation(1, "ut-enqueued", false)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 1)
+}
+
+func TestReceiveGithubWebhook_WithInstallationDeleted(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+	repo := GetController().Repo
+
+	api := newFakeGithubApi(t)
+	app := api.newApp(t, GithubHostDefault)
+	app.WebhookSecret = "ut-secret"
+	RegisterGithubApp(app)
+	defer unregisterGithubApp(GithubHostDefault)
+
+	installation := repository.NewInstallation(1, repository.IdentityGithub, 10)
+	installation.Host = GithubHostDefault
+	repo.ReplaceInstallation(1, repository.IdentityGithub, []*repository.Installation{installation})
+
+	resp := doGithubWebhook(router, "ut-secret", "installation", "ut-delivery", `{"action":"deleted","installation":{"id":10}}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	installations, err := repo.ListInstallation(1, repository.IdentityGithub, false)
+	assert.Nil(t, err)
+	assert.Empty(t, installations)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"io/ioutil"
+	"mime"
+)
+
+const (
+	// MergePatchContentType is content type of JSON Merge Patch described in RFC 7386
+	MergePatchContentType = "application/merge-patch+json"
+)
+
+// bindMergePatch applies JSON Merge Patch in request body to original and decode the result into req.
+//
+// original should contain patchable fields of resource with the same json tags as req,
+// req would be validated after patch applied.
+func bindMergePatch(ctx *gin.Context, original interface{}, req interface{}) bool {
+	// 1: check content type, application/json is accepted as well
+	if contentType := ctx.GetHeader("Content-Type"); len(contentType) > 0 {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != MergePatchContentType && mediaType != binding.MIMEJSON) {
+			ctx.Error(repository.NewInvalidArgumentf("unsupported content type:%s, expect %s", contentType, MergePatchContentType))
+			return false
+		}
+	}
+
+	// 2: read patch document
+	if ctx.Request == nil || ctx.Request.Body == nil {
+		ctx.Error(repository.NewInvalidArgumentf("empty patch document"))
+		return false
+	}
+	patch, err := ioutil.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return handleBindError(ctx, err)
+	}
+
+	// 3: apply patch to original document
+	doc, err := json.Marshal(original)
+	if err != nil {
+		ctx.Error(repository.Wrapf(err, repository.CodeInternal, "failed to marshal resource"))
+		return false
+	}
+
+	patched, err := mergePatch(doc, patch)
+	if err != nil {
+		return handleBindError(ctx, err)
+	}
+
+	// 4: decode and validate patched document
+	if err := json.Unmarshal(patched, req); err != nil {
+		return handleBindError(ctx, err)
+	}
+
+	if err := validate(req); err != nil {
+		ctx.Error(err)
+		return false
+	}
+
+	return true
+}
+
+// mergePatch applies patch to doc as described in RFC 7386.
+func mergePatch(doc, patch []byte) ([]byte, error) {
+	var docVal, patchVal interface{}
+
+	if err := json.Unmarshal(doc, &docVal); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(patch, &patchVal); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergeValue(docVal, patchVal))
+}
+
+// Merge patch value into target recursively, null in patch removes the field.
+func mergeValue(target, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = make(map[string]interface{})
+	}
+
+	for k, v := range patchMap {
+		if v == nil {
+			delete(targetMap, k)
+			continue
+		}
+		targetMap[k] = mergeValue(targetMap[k], v)
+	}
+
+	return targetMap
+}
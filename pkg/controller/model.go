@@ -5,14 +5,125 @@ import (
 	"time"
 )
 
+// ********************************************* //
+// ************** Session related ************** //
+// ********************************************* //
+
+// GetSessionResponse response of get current session
+type GetSessionResponse struct {
+	UserId    int       `yaml:"userId" json:"userId"`
+	Source    string    `yaml:"source" json:"source"`
+	Login     string    `yaml:"login" json:"login"`
+	ExpiresAt time.Time `yaml:"expiresAt" json:"expiresAt"`
+	Scopes    []string  `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+}
+
+// LoginRequest request body of sign in with local identity
+type LoginRequest struct {
+	Login    string `yaml:"login" json:"login" binding:"required,max=64"`
+	Password string `yaml:"password" json:"password" binding:"required,max=72"`
+}
+
+// LoginResponse response of sign in with local identity
+type LoginResponse struct {
+	UserId int `yaml:"userId" json:"userId"`
+}
+
+// ****************************************** //
+// ************** User related ************** //
+// ****************************************** //
+
+// User is model for API response
+type User struct {
+	Meta *repository.User `yaml:"meta" json:"meta"`
+}
+
+// GetUserResponse response of get current user
+type GetUserResponse struct {
+	User *User `yaml:"user" json:"user"`
+}
+
+// DeleteUserResponse response of delete current user
+type DeleteUserResponse struct {
+	Status bool `yaml:"status" json:"status"`
+}
+
+// IdentityTypeRequest request path of identity related API
+type IdentityTypeRequest struct {
+	Type string `uri:"type" binding:"required,identitytype"`
+}
+
+// LinkLocalIdentityRequest request body of link local identity
+type LinkLocalIdentityRequest struct {
+	Login    string `yaml:"login" json:"login" binding:"required,max=64,slug"`
+	Password string `yaml:"password" json:"password" binding:"required,min=8,max=72"`
+	// CurrentPassword is required if local identity was linked already
+	CurrentPassword string `yaml:"currentPassword" json:"currentPassword" binding:"omitempty,max=72"`
+}
+
+// LinkIdentityResponse response of link identity
+type LinkIdentityResponse struct {
+	Status bool `yaml:"status" json:"status"`
+}
+
+// UnlinkIdentityResponse response of unlink identity
+type UnlinkIdentityResponse struct {
+	Status bool `yaml:"status" json:"status"`
+}
+
+// *************************************************** //
+// ************** PersonalToken related ************** //
+// *************************************************** //
+
+// PersonalToken is model for API response
+type PersonalToken struct {
+	Meta *repository.PersonalToken `yaml:"meta" json:"meta"`
+}
+
+// PersonalTokenIdRequest request path of personal token related API
+type PersonalTokenIdRequest struct {
+	TokenId int `uri:"tokenId" binding:"required,gt=0"`
+}
+
+// ListPersonalTokenResponse response of list personal tokens
+type ListPersonalTokenResponse struct {
+	TokenList []*PersonalToken `yaml:"tokenList" json:"tokenList"`
+}
+
+// CreatePersonalTokenRequest request body of create personal token, token never expires without expiredAt
+type CreatePersonalTokenRequest struct {
+	Name      string     `yaml:"name" json:"name" binding:"required,max=64"`
+	Scopes    []string   `yaml:"scopes" json:"scopes" binding:"required,min=1,dive,oneof=read:org write:org read:proj write:proj read:source write:source read:user write:user"`
+	ExpiredAt *time.Time `yaml:"expiredAt" json:"expiredAt"`
+}
+
+// CreatePersonalTokenResponse response of create or rotate personal token, plain token is returned only once
+type CreatePersonalTokenResponse struct {
+	PersonalToken *PersonalToken `yaml:"personalToken" json:"personalToken"`
+	Token         string         `yaml:"token" json:"token"`
+}
+
+// RevokePersonalTokenResponse response of revoke personal token
+type RevokePersonalTokenResponse struct {
+	Status bool `yaml:"status" json:"status"`
+}
+
 // ************************************************** //
 // ************** Organization related ************** //
 // ************************************************** //
 
 // Org is model for API response
 type Org struct {
-	Meta    *repository.Org `yaml:"meta" json:"meta"`
-	ProjIds []int           `yaml:"projIds" json:"projIds"`
+	Meta      *repository.Org `yaml:"meta" json:"meta"`
+	ProjIds   []int           `yaml:"projIds" json:"projIds"`
+	ProjCount *int            `yaml:"projCount,omitempty" json:"projCount,omitempty"`
+	ProjList  []*Proj         `yaml:"projList,omitempty" json:"projList,omitempty"`
+}
+
+// ListOrgRequest request query of list organizations
+type ListOrgRequest struct {
+	Expand string `form:"expand" binding:"omitempty,csvoneof=projects projCount source"`
+	Fields string `form:"fields" binding:"omitempty,csvoneof=id name createdAt updatedAt"`
 }
 
 // ListOrgResponse response of list organization
@@ -25,6 +136,21 @@ type GetOrgResponse struct {
 	Org *Org `yaml:"org" json:"org"`
 }
 
+// OrgIdRequest request path of organization related API
+type OrgIdRequest struct {
+	OrgId int `uri:"orgId" binding:"required,gt=0"`
+}
+
+// CreateOrgRequest request query of create organization
+type CreateOrgRequest struct {
+	OrgName string `form:"orgName" binding:"required,max=64,slug"`
+}
+
+// CreateOrgV2Request request body of create organization in v2
+type CreateOrgV2Request struct {
+	Name string `yaml:"name" json:"name" binding:"required,max=64,slug"`
+}
+
 // CreateOrgResponse response of create organization
 type CreateOrgResponse struct {
 	OrgId int `yaml:"orgId" json:"orgId"`
@@ -42,7 +168,54 @@ type UpdateOrgResponse struct {
 
 // UpdateOrgRequest request body of update organization
 type UpdateOrgRequest struct {
-	Name string `yaml:"name" json:"name"`
+	Name string `yaml:"name" json:"name" binding:"required,max=64,slug"`
+}
+
+// ******************************************** //
+// ************** Member related ************** //
+// ******************************************** //
+
+// Member is model for API response
+type Member struct {
+	Meta *repository.Member `yaml:"meta" json:"meta"`
+}
+
+// MemberIdRequest request path of member related API
+type MemberIdRequest struct {
+	OrgId  int `uri:"orgId" binding:"required,gt=0"`
+	UserId int `uri:"userId" binding:"required,gt=0"`
+}
+
+// ListMemberResponse response of list members
+type ListMemberResponse struct {
+	MemberList []*Member `yaml:"memberList" json:"memberList"`
+}
+
+// InviteMemberRequest request body of invite member, user is found by linked identity
+type InviteMemberRequest struct {
+	Type  string `yaml:"type" json:"type" binding:"required,identitytype"`
+	Login string `yaml:"login" json:"login" binding:"required,max=255"`
+	Role  string `yaml:"role" json:"role" binding:"required,oneof=owner maintainer developer viewer"`
+}
+
+// InviteMemberResponse response of invite member
+type InviteMemberResponse struct {
+	Member *Member `yaml:"member" json:"member"`
+}
+
+// UpdateMemberRequest request body of update member
+type UpdateMemberRequest struct {
+	Role string `yaml:"role" json:"role" binding:"required,oneof=owner maintainer developer viewer"`
+}
+
+// UpdateMemberResponse response of update member
+type UpdateMemberResponse struct {
+	Member *Member `yaml:"member" json:"member"`
+}
+
+// RemoveMemberResponse response of remove member
+type RemoveMemberResponse struct {
+	Status bool `yaml:"status" json:"status"`
 }
 
 // ********************************************* //
@@ -54,6 +227,18 @@ type Proj struct {
 	Meta *repository.Proj `yaml:"meta" json:"meta"`
 }
 
+// ProjIdRequest request path or query of project related API
+type ProjIdRequest struct {
+	ProjId int `uri:"projId" form:"projId" binding:"required,gt=0"`
+}
+
+// ListProjRequest request query of list projects
+type ListProjRequest struct {
+	OrgId  int    `form:"orgId" binding:"omitempty,gt=0"`
+	Expand string `form:"expand" binding:"omitempty,csvoneof=source"`
+	Fields string `form:"fields" binding:"omitempty,csvoneof=id orgId orgName name createdAt updatedAt"`
+}
+
 // ListProjResponse response of list projects
 type ListProjResponse struct {
 	ProjList []*Proj `yaml:"projList" json:"projList"`
@@ -72,9 +257,15 @@ type CreateProjResponse struct {
 
 // CreateProjRequest request body
 type CreateProjRequest struct {
-	OrgId   int    `yaml:"orgId" json:"orgId"`
-	OrgName string `yaml:"orgName" json:"orgName"`
-	Name    string `yaml:"name" json:"name"`
+	OrgId   int    `yaml:"orgId" json:"orgId" binding:"required,gt=0"`
+	OrgName string `yaml:"orgName" json:"orgName" binding:"omitempty,max=64,slug"`
+	Name    string `yaml:"name" json:"name" binding:"required,max=64,slug"`
+}
+
+// CreateProjV2Request request body of create project in v2
+type CreateProjV2Request struct {
+	OrgId int    `yaml:"orgId" json:"orgId" binding:"required,gt=0"`
+	Name  string `yaml:"name" json:"name" binding:"required,max=64,slug"`
 }
 
 // DeleteProjResponse response of delete project
@@ -89,17 +280,34 @@ type UpdateProjResponse struct {
 
 // UpdateProjRequest request body
 type UpdateProjRequest struct {
-	Name string `yaml:"name" json:"name"`
+	Name string `yaml:"name" json:"name" binding:"required,max=64,slug"`
 }
 
 // ******************************************** //
 // ************** Source related ************** //
 // ******************************************** //
 
-// CreateSourceRequest request body
+// Source is model for API response
+type Source struct {
+	Meta *repository.Source `yaml:"meta" json:"meta"`
+}
+
+// GetSourceResponse response of get source
+type GetSourceResponse struct {
+	Source *Source `yaml:"source" json:"source"`
+}
+
+// SourceIdRequest request path of source related API
+type SourceIdRequest struct {
+	SourceId int `uri:"sourceId" binding:"required,gt=0"`
+}
+
+// CreateSourceRequest request body, repository of git source is path or file:// URL of local repository.
+// Host is used by github sources only, like github.example.com, primary host would be used if missing.
 type CreateSourceRequest struct {
-	Type       string `yaml:"type" json:"type"`
-	Repository string `yaml:"repository" json:"repository"`
+	Type       string `yaml:"type" json:"type" binding:"required,sourcetype"`
+	Repository string `yaml:"repository" json:"repository" binding:"required,max=256"`
+	Host       string `yaml:"host" json:"host" binding:"omitempty,max=256"`
 }
 
 // CreateSourceResponse response of create source
@@ -127,9 +335,34 @@ type ListPipelineTemplateResponse struct {
 	TemplateList []*PipelineTemplate `yaml:"templateList" json:"templateList"`
 }
 
+// ListUserInstallationsRequest request query of list user installations
+type ListUserInstallationsRequest struct {
+	Source  string `form:"source" binding:"omitempty,sourcetype"`
+	Refresh bool   `form:"refresh"`
+}
+
+// ListCommitsRequest request query of list commits
+type ListCommitsRequest struct {
+	Branch  string `form:"branch" binding:"omitempty,max=256"`
+	PerPage int    `form:"perPage" binding:"omitempty,min=1,max=100"`
+	Page    int    `form:"page" binding:"omitempty,min=1"`
+	All     bool   `form:"all"`
+}
+
+// ListBranchesAndTagsRequest request query of list branches and tags, page is used by both of them if
+// branchPage or tagPage is missing
+type ListBranchesAndTagsRequest struct {
+	PerPage    int  `form:"perPage" binding:"omitempty,min=1,max=100"`
+	Page       int  `form:"page" binding:"omitempty,min=1"`
+	BranchPage int  `form:"branchPage" binding:"omitempty,min=1"`
+	TagPage    int  `form:"tagPage" binding:"omitempty,min=1"`
+	All        bool `form:"all"`
+}
+
 // ListCommitsResponse response of user commits of source
 type ListCommitsResponse struct {
-	Commits []*Commit `yaml:"commits" json:"commits"`
+	Commits    []*Commit   `yaml:"commits" json:"commits"`
+	Pagination *Pagination `yaml:"pagination" json:"pagination"`
 }
 
 type Commit struct {
@@ -148,6 +381,27 @@ type Artifact struct {
 }
 
 type ListBranchesAndTagsResponse struct {
-	Branches []string `yaml:"branches" json:"branches"`
-	Tags     []string `yaml:"tags" json:"tags"`
+	Branches         []string    `yaml:"branches" json:"branches"`
+	Tags             []string    `yaml:"tags" json:"tags"`
+	BranchPagination *Pagination `yaml:"branchPagination" json:"branchPagination"`
+	TagPagination    *Pagination `yaml:"tagPagination" json:"tagPagination"`
+}
+
+// ********************************************* //
+// ************** Webhook related ************** //
+// ********************************************* //
+
+// GithubWebhookRequest request headers of event delivered by github webhook
+type GithubWebhookRequest struct {
+	Event    string `header:"X-GitHub-Event" binding:"required,max=64"`
+	Delivery string `header:"X-GitHub-Delivery" binding:"required,max=255"`
+}
+
+// WebhookResponse response of event delivered by webhook, Duplicate is true if delivery was processed already
+type WebhookResponse struct {
+	Delivery  string `yaml:"delivery" json:"delivery"`
+	Event     string `yaml:"event" json:"event"`
+	Action    string `yaml:"action" json:"action"`
+	Status    string `yaml:"status" json:"status"`
+	Duplicate bool   `yaml:"duplicate" json:"duplicate"`
 }
@@ -0,0 +1,197 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// PerPageDefault is page size used if perPage is missing
+	PerPageDefault = 10
+	// PerPageMax is the largest page size accepted by remote code repositories
+	PerPageMax = 100
+	// PageDefault is the first page
+	PageDefault = 1
+	// VCSMaxPagesDefault is the max number of pages followed while listing all items of remote code repository
+	VCSMaxPagesDefault = 10
+)
+
+// Pagination describes a page listed from remote code repository.
+// NextPage is 0 if there are no more pages. LastPage and Total are 0 if they are unknown,
+// remote code repositories like github only provide them on the last page.
+type Pagination struct {
+	Page     int `yaml:"page" json:"page"`
+	PerPage  int `yaml:"perPage" json:"perPage"`
+	NextPage int `yaml:"nextPage" json:"nextPage"`
+	LastPage int `yaml:"lastPage" json:"lastPage"`
+	Total    int `yaml:"total" json:"total"`
+}
+
+// newPagination creates pagination of page with size of items from remote code repository.
+// LastPage and Total would be derived if there are no more pages and page is not beyond the last page.
+func newPagination(perPage, page, size, nextPage, lastPage, total int) *Pagination {
+	res := &Pagination{
+		Page:     page,
+		PerPage:  perPage,
+		NextPage: nextPage,
+		LastPage: lastPage,
+		Total:    total,
+	}
+
+	if nextPage > 0 {
+		return res
+	}
+
+	res.NextPage = 0
+	if size > 0 || page == PageDefault {
+		if res.LastPage < 1 {
+			res.LastPage = page
+		}
+		if res.Total < 1 {
+			res.Total = (page-1)*perPage + size
+		}
+	}
+
+	return res
+}
+
+// paginationOf creates pagination of page in list whose total size is known.
+func paginationOf(total, perPage, page int) *Pagination {
+	perPage, page = normalizePage(perPage, page)
+
+	res := &Pagination{
+		Page:     page,
+		PerPage:  perPage,
+		LastPage: (total + perPage - 1) / perPage,
+		Total:    total,
+	}
+
+	if res.LastPage < PageDefault {
+		res.LastPage = PageDefault
+	}
+
+	if page < res.LastPage {
+		res.NextPage = page + 1
+	}
+
+	return res
+}
+
+// normalize page and perPage, perPage would be capped with PerPageMax
+func normalizePage(perPage, page int) (int, int) {
+	if perPage < 1 {
+		perPage = PerPageDefault
+	}
+
+	if perPage > PerPageMax {
+		perPage = PerPageMax
+	}
+
+	if page < 1 {
+		page = PageDefault
+	}
+
+	return perPage, page
+}
+
+// Returns page if present, fallback is returned otherwise.
+func pageOr(page, fallback int) int {
+	if page > 0 {
+		return page
+	}
+
+	return fallback
+}
+
+// Returns range of page in list with size.
+func pageOf(size, perPage, page int) (int, int) {
+	perPage, page = normalizePage(perPage, page)
+
+	start := (page - 1) * perPage
+	if start > size {
+		start = size
+	}
+
+	end := start + perPage
+	if end > size {
+		end = size
+	}
+
+	return start, end
+}
+
+// Returns numbers of next and last page in Link header, like <https://host/items?page=2>; rel="next".
+// 0 would be returned if relation is missing.
+func pagesOfLinkHeader(header http.Header) (int, int) {
+	next, last := 0, 0
+
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		segments := strings.Split(strings.TrimSpace(link), ";")
+		if len(segments) < 2 {
+			continue
+		}
+
+		rawUrl := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(segments[0]), "<"), ">")
+		u, err := url.Parse(rawUrl)
+		if err != nil {
+			continue
+		}
+		page, _ := strconv.Atoi(u.Query().Get("page"))
+
+		for _, param := range segments[1:] {
+			switch strings.ReplaceAll(strings.TrimSpace(param), " ", "") {
+			case `rel="next"`:
+				next = page
+			case `rel="last"`:
+				last = page
+			}
+		}
+	}
+
+	return next, last
+}
+
+// Returns integer in header, 0 would be returned if missing or invalid.
+func intOfHeader(header http.Header, key string) int {
+	res, _ := strconv.Atoi(strings.TrimSpace(header.Get(key)))
+	return res
+}
+
+// listPages lists pages from page with list, which collects items of page and returns pagination of it.
+// Pages are followed until there are no more pages or maxPages reached, pagination of all listed pages would be returned,
+// whose NextPage is the first page not listed.
+func listPages(perPage, page, maxPages int, list func(perPage, page int) (*Pagination, error)) (*Pagination, error) {
+	perPage, page = normalizePage(perPage, page)
+	if maxPages < 1 {
+		maxPages = 1
+	}
+
+	res := &Pagination{
+		Page:    page,
+		PerPage: perPage,
+	}
+
+	for i := 0; i < maxPages; i++ {
+		pagination, err := list(perPage, page)
+		if err != nil {
+			return nil, err
+		}
+
+		res.NextPage, res.LastPage, res.Total = pagination.NextPage, pagination.LastPage, pagination.Total
+		// stop if there are no more pages, or next page from remote code repository does not move forward
+		if pagination.NextPage <= page {
+			res.NextPage = 0
+			break
+		}
+		page = pagination.NextPage
+	}
+
+	return res, nil
+}
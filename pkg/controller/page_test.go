@@ -0,0 +1,85 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestNormalizePage(t *testing.T) {
+	perPage, page := normalizePage(0, 0)
+	assert.Equal(t, PerPageDefault, perPage)
+	assert.Equal(t, PageDefault, page)
+
+	perPage, page = normalizePage(1000, 2)
+	assert.Equal(t, PerPageMax, perPage)
+	assert.Equal(t, 2, page)
+}
+
+func TestNewPagination(t *testing.T) {
+	// more pages, total is unknown
+	assert.Equal(t, &Pagination{Page: 1, PerPage: 10, NextPage: 2}, newPagination(10, 1, 10, 2, 0, 0))
+
+	// total is derived on the last page
+	assert.Equal(t, &Pagination{Page: 3, PerPage: 10, LastPage: 3, Total: 25}, newPagination(10, 3, 5, 0, 0, 0))
+
+	// nothing is derived beyond the last page
+	assert.Equal(t, &Pagination{Page: 5, PerPage: 10}, newPagination(10, 5, 0, 0, 0, 0))
+
+	// empty list
+	assert.Equal(t, &Pagination{Page: 1, PerPage: 10, LastPage: 1}, newPagination(10, 1, 0, 0, 0, 0))
+}
+
+func TestPaginationOf(t *testing.T) {
+	assert.Equal(t, &Pagination{Page: 1, PerPage: 2, NextPage: 2, LastPage: 3, Total: 5}, paginationOf(5, 2, 1))
+	assert.Equal(t, &Pagination{Page: 3, PerPage: 2, LastPage: 3, Total: 5}, paginationOf(5, 2, 3))
+	assert.Equal(t, &Pagination{Page: 1, PerPage: PerPageDefault, LastPage: 1}, paginationOf(0, 0, 0))
+}
+
+func TestPagesOfLinkHeader(t *testing.T) {
+	header := http.Header{}
+	header.Set("Link", `<https://ut-host/items?page=3&per_page=2>; rel="next", <https://ut-host/items?page=5&per_page=2>; rel="last", `+
+		`<https://ut-host/items?page=1&per_page=2>; rel="first"`)
+	next, last := pagesOfLinkHeader(header)
+	assert.Equal(t, 3, next)
+	assert.Equal(t, 5, last)
+
+	next, last = pagesOfLinkHeader(http.Header{})
+	assert.Equal(t, 0, next)
+	assert.Equal(t, 0, last)
+}
+
+func TestListPages(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	listed := make([]int, 0)
+	list := func(perPage, page int) (*Pagination, error) {
+		start, end := pageOf(len(items), perPage, page)
+		listed = append(listed, items[start:end]...)
+		return paginationOf(len(items), perPage, page), nil
+	}
+
+	// all pages are listed
+	pagination, err := listPages(2, 1, 10, list)
+	assert.Nil(t, err)
+	assert.Equal(t, items, listed)
+	assert.Equal(t, &Pagination{Page: 1, PerPage: 2, LastPage: 3, Total: 5}, pagination)
+
+	// listing stops at max pages, next page is the first page not listed
+	listed = make([]int, 0)
+	pagination, err = listPages(2, 1, 2, list)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4}, listed)
+	assert.Equal(t, 3, pagination.NextPage)
+
+	// error of any page is returned
+	_, err = listPages(2, 1, 10, func(perPage, page int) (*Pagination, error) {
+		return nil, errors.New("ut-error")
+	})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/golang-jwt/jwt"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// PersonalTokenPrefix is prefix of personal tokens, so that they could be told apart from sessions
+	PersonalTokenPrefix = "wsp_"
+	// SessionSourcePersonalToken is source of sessions authenticated with personal token
+	SessionSourcePersonalToken = "token"
+	// personalTokenPrefixLen is length of token prefix kept in repository for display
+	personalTokenPrefixLen = len(PersonalTokenPrefix) + 8
+	// personalTokenTouchInterval limits how often last used time of token would be written into repository
+	personalTokenTouchInterval = time.Minute
+)
+
+// Scopes which could be granted to personal tokens, write scope implies read scope of the same resource.
+const (
+	ScopeReadOrg     = "read:org"
+	ScopeWriteOrg    = "write:org"
+	ScopeReadProj    = "read:proj"
+	ScopeWriteProj   = "write:proj"
+	ScopeReadSource  = "read:source"
+	ScopeWriteSource = "write:source"
+	ScopeReadUser    = "read:user"
+	ScopeWriteUser   = "write:user"
+)
+
+var (
+	// resources of routes in gin, personal tokens are never granted with token and identity resources,
+	// so that they could not mint or rotate tokens, nor link identities and passwords to sign in without scopes
+	routeResources = map[string]string{
+		"org":        "org",
+		"orgs":       "org",
+		"proj":       "proj",
+		"projects":   "proj",
+		"pipeline":   "proj",
+		"source":     "source",
+		"sources":    "source",
+		"me":         "user",
+		"session":    "user",
+		"user":       "user",
+		"tokens":     "token",
+		"identities": "identity",
+	}
+
+	// resources of gRPC services
+	serviceResources = map[string]string{
+		"OrgService":              "org",
+		"ProjService":             "proj",
+		"SourceService":           "source",
+		"PipelineTemplateService": "proj",
+	}
+)
+
+// AllowScope checks whether session is granted with scope.
+// Sessions signed in with identities have nil scopes and are not limited.
+func (s *Session) AllowScope(scope string) bool {
+	if s.Scopes == nil {
+		return true
+	}
+
+	// write scope implies read scope
+	write := strings.Replace(scope, "read:", "write:", 1)
+	for _, granted := range s.Scopes {
+		if granted == scope || granted == write {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns error if session is not granted with scope.
+func checkScope(session *Session, scope string) error {
+	if session.AllowScope(scope) {
+		return nil
+	}
+
+	return repository.NewPermissionDeniedf("personal token is not granted with scope:%s", scope)
+}
+
+// Returns scope required by request of gin route, like /v1/org/:orgId/members.
+// Resource is decided by the last known segment of route, GET and HEAD requests require read scope only.
+func routeScopeOf(method, route string) string {
+	resource := ""
+	for _, segment := range strings.Split(route, "/") {
+		if v, ok := routeResources[segment]; ok {
+			resource = v
+		}
+	}
+
+	if method == http.MethodGet || method == http.MethodHead {
+		return "read:" + resource
+	}
+
+	return "write:" + resource
+}
+
+// Returns scope required by gRPC method, like /workstation.v1.OrgService/ListOrg.
+// List and Get methods require read scope only.
+func grpcScopeOf(fullMethod string) string {
+	service, method := "", ""
+	if tokens := strings.Split(strings.TrimPrefix(fullMethod, "/"), "/"); len(tokens) == 2 {
+		service, method = tokens[0], tokens[1]
+	}
+	service = service[strings.LastIndex(service, ".")+1:]
+
+	if strings.HasPrefix(method, "List") || strings.HasPrefix(method, "Get") {
+		return "read:" + serviceResources[service]
+	}
+
+	return "write:" + serviceResources[service]
+}
+
+// sessionOf returns session of bearer token, which is either personal token or signed session.
+func (con *Controller) sessionOf(token string) (*Session, error) {
+	if strings.HasPrefix(token, PersonalTokenPrefix) {
+		return con.lookupPersonalToken(token)
+	}
+
+	return con.ParseSession(token)
+}
+
+// lookupPersonalToken verifies personal token and returns session limited by scopes of it.
+// Last used time of token is recorded at most once per personalTokenTouchInterval.
+func (con *Controller) lookupPersonalToken(raw string) (*Session, error) {
+	// 1: find token with hash
+	token, err := con.Repo.GetPersonalTokenByHash(hashOf(raw))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, repository.NewUnauthenticatedf("invalid personal token")
+	}
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to get personal token")
+	}
+
+	if token.IsExpired() {
+		return nil, repository.NewUnauthenticatedf("personal token %s expired", token.Prefix)
+	}
+
+	// 2: record last used time
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > personalTokenTouchInterval {
+		token.LastUsedAt = &now
+		if _, err := con.Repo.UpdatePersonalToken(token); err != nil {
+			con.ZapLoggerEntry.GetLogger().Warn("failed to record last used time of personal token", zap.Error(err))
+		}
+	}
+
+	// 3: convert to session
+	session := &Session{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:   SessionIssuer,
+			Subject:  strconv.Itoa(token.UserId),
+			IssuedAt: token.CreatedAt.Unix(),
+		},
+		Source: SessionSourcePersonalToken,
+		Login:  token.Name,
+		Scopes: strings.Fields(token.Scopes),
+	}
+	if token.ExpiredAt != nil {
+		session.ExpiresAt = token.ExpiredAt.Unix()
+	}
+
+	return session, nil
+}
+
+// listPersonalToken returns personal tokens of user, hashes of tokens are never returned.
+func (con *Controller) listPersonalToken(userId int) ([]*PersonalToken, error) {
+	tokenList, err := con.Repo.ListPersonalToken(userId)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list personal tokens of userId:%d", userId)
+	}
+
+	res := make([]*PersonalToken, 0)
+	for i := range tokenList {
+		res = append(res, convertPersonalToken(tokenList[i]))
+	}
+
+	return res, nil
+}
+
+// createPersonalToken mints personal token for user and returns it together with plain token,
+// which would never be available again.
+func (con *Controller) createPersonalToken(userId int, name string, scopes []string, expiredAt *time.Time) (*PersonalToken, string, error) {
+	if expiredAt != nil && !expiredAt.After(time.Now()) {
+		return nil, "", repository.NewInvalidArgumentf("expiredAt of personal token must be in the future")
+	}
+
+	raw := newPersonalToken()
+	token := repository.NewPersonalToken(userId, name, raw[:personalTokenPrefixLen], hashOf(raw))
+	token.Scopes = strings.Join(scopes, " ")
+	token.ExpiredAt = expiredAt
+
+	if _, err := con.Repo.CreatePersonalToken(token); err != nil {
+		return nil, "", repository.Wrapf(err, repository.CodeInternal, "failed to create personal token with name:%s", name)
+	}
+
+	return convertPersonalToken(token), raw, nil
+}
+
+// rotatePersonalToken replaces personal token with a new one which keeps name, scopes and expiration,
+// the old token stops working immediately.
+func (con *Controller) rotatePersonalToken(userId, tokenId int) (*PersonalToken, string, error) {
+	token, err := con.findPersonalToken(userId, tokenId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if token.IsExpired() {
+		return nil, "", repository.NewConflictf("personal token with tokenId:%d expired, please create a new one", tokenId)
+	}
+
+	raw := newPersonalToken()
+	token.Prefix = raw[:personalTokenPrefixLen]
+	token.Hash = hashOf(raw)
+	token.LastUsedAt = nil
+
+	if _, err := con.Repo.UpdatePersonalToken(token); err != nil {
+		return nil, "", repository.Wrapf(err, repository.CodeInternal, "failed to rotate personal token with tokenId:%d", tokenId)
+	}
+
+	return convertPersonalToken(token), raw, nil
+}
+
+// revokePersonalToken removes personal token of user.
+func (con *Controller) revokePersonalToken(userId, tokenId int) error {
+	if _, err := con.findPersonalToken(userId, tokenId); err != nil {
+		return err
+	}
+
+	if _, err := con.Repo.RemovePersonalToken(tokenId); err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to revoke personal token with tokenId:%d", tokenId)
+	}
+
+	return nil
+}
+
+// Find personal token which belongs to user, tokens of others are reported as not found.
+func (con *Controller) findPersonalToken(userId, tokenId int) (*repository.PersonalToken, error) {
+	token, err := con.Repo.GetPersonalToken(tokenId)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && token.UserId != userId) {
+		return nil, repository.NewNotFoundf(repository.PersonalTokenNotFoundMsg, tokenId)
+	}
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.PersonalTokenFailedToGetMsg, tokenId)
+	}
+
+	return token, nil
+}
+
+func convertPersonalToken(tokenFromRepo *repository.PersonalToken) *PersonalToken {
+	return &PersonalToken{
+		Meta: tokenFromRepo,
+	}
+}
+
+// Generate personal token with 32 bytes of entropy.
+func newPersonalToken() string {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+
+	return PersonalTokenPrefix + base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// Calculate hex encoded sha256 of token, only hashes of personal tokens are stored.
+func hashOf(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"encoding/json"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSession_AllowScope(t *testing.T) {
+	// sessions signed in with identities are not limited
+	assert.True(t, (&Session{}).AllowScope(ScopeWriteOrg))
+
+	// write scope implies read scope
+	session := &Session{Scopes: []string{ScopeWriteProj, ScopeReadOrg}}
+	assert.True(t, session.AllowScope(ScopeReadProj))
+	assert.True(t, session.AllowScope(ScopeWriteProj))
+	assert.True(t, session.AllowScope(ScopeReadOrg))
+	assert.False(t, session.AllowScope(ScopeWriteOrg))
+	assert.False(t, session.AllowScope(ScopeReadSource))
+}
+
+func TestRouteScopeOf(t *testing.T) {
+	assert.Equal(t, ScopeReadOrg, routeScopeOf(http.MethodGet, "/v1/org/:orgId/members"))
+	assert.Equal(t, ScopeWriteOrg, routeScopeOf(http.MethodPatch, "/v2/orgs/:orgId"))
+	assert.Equal(t, ScopeWriteSource, routeScopeOf(http.MethodPost, "/v2/projects/:projId/source"))
+	assert.Equal(t, ScopeReadProj, routeScopeOf(http.MethodGet, "/v1/pipeline/template"))
+	assert.Equal(t, ScopeReadUser, routeScopeOf(http.MethodGet, "/v1/user/installations"))
+	assert.Equal(t, "write:token", routeScopeOf(http.MethodPut, "/v1/me/tokens"))
+	assert.Equal(t, "write:identity", routeScopeOf(http.MethodPut, "/v1/me/identities/local"))
+	assert.Equal(t, "write:identity", routeScopeOf(http.MethodDelete, "/v1/me/identities/:type"))
+}
+
+func TestGrpcScopeOf(t *testing.T) {
+	assert.Equal(t, ScopeReadOrg, grpcScopeOf("/workstation.v1.OrgService/ListOrg"))
+	assert.Equal(t, ScopeWriteProj, grpcScopeOf("/workstation.v1.ProjService/DeleteProj"))
+	assert.Equal(t, ScopeReadSource, grpcScopeOf("/workstation.v1.SourceService/ListCommits"))
+	assert.Equal(t, ScopeReadProj, grpcScopeOf("/workstation.v1.PipelineTemplateService/ListPipelineTemplate"))
+}
+
+func TestPersonalTokenApi(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+	repo := GetController().Repo
+	repo.CreateUser(repository.NewUser("ut-user"))
+
+	// 1: scopes and expiration are validated
+	resp := doRequestWithHeaders(router, http.MethodPut, "/v1/me/tokens", `{"name":"ut-cli","scopes":["admin"]}`, sessionHeadersOf(1))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodPut, "/v1/me/tokens", `{"name":"ut-cli","scopes":["read:org"],"expiredAt":"2000-01-01T00:00:00Z"}`, sessionHeadersOf(1))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// 2: create token, only hash is stored
+	resp = doRequestWithHeaders(router, http.MethodPut, "/v1/me/tokens", `{"name":"ut-cli","scopes":["read:org","write:proj"]}`, sessionHeadersOf(1))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	created := &CreatePersonalTokenResponse{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), created))
+	assert.True(t, strings.HasPrefix(created.Token, PersonalTokenPrefix))
+	assert.True(t, strings.HasPrefix(created.Token, created.PersonalToken.Meta.Prefix))
+	assert.NotContains(t, resp.Body.String(), hashOf(created.Token))
+	stored, _ := repo.GetPersonalToken(created.PersonalToken.Meta.Id)
+	assert.Equal(t, hashOf(created.Token), stored.Hash)
+
+	// 3: token is accepted as bearer token within scopes and last used time is recorded
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/org", "", bearerHeadersOf(created.Token))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NotNil(t, stored.LastUsedAt)
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/session", "", bearerHeadersOf(created.Token))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v2/orgs", `{"name":"ut-org"}`, bearerHeadersOf(created.Token))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	// personal tokens could not manage personal tokens
+	resp = doRequestWithHeaders(router, http.MethodPut, "/v1/me/tokens", `{"name":"ut-ci","scopes":["write:org"]}`, bearerHeadersOf(created.Token))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	// personal tokens with write:user could not set password, which would sign in sessions without scopes
+	resp = doRequestWithHeaders(router, http.MethodPut, "/v1/me/tokens", `{"name":"ut-user","scopes":["write:user"]}`, sessionHeadersOf(1))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	userToken := &CreatePersonalTokenResponse{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), userToken))
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/me", "", bearerHeadersOf(userToken.Token))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodPut, "/v1/me/identities/local", `{"login":"ut-local","password":"ut-password"}`, bearerHeadersOf(userToken.Token))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "write:identity")
+	resp = doRequestWithHeaders(router, http.MethodDelete, "/v1/me/identities/github", "", bearerHeadersOf(userToken.Token))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	// 4: list tokens
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/me/tokens", "", sessionHeadersOf(1))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"scopes":"read:org write:proj"`)
+	assert.Contains(t, resp.Body.String(), `"lastUsedAt"`)
+
+	// tokens of others could not be found
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v1/me/tokens/1/rotate", "", sessionHeadersOf(2))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	// 5: rotate token, the old one stops working
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v1/me/tokens/1/rotate", "", sessionHeadersOf(1))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	rotated := &CreatePersonalTokenResponse{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), rotated))
+	assert.NotEqual(t, created.Token, rotated.Token)
+	assert.Equal(t, "ut-cli", rotated.PersonalToken.Meta.Name)
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/org", "", bearerHeadersOf(created.Token))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/org", "", bearerHeadersOf(rotated.Token))
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// 6: expired tokens are rejected
+	expiredAt := time.Now().Add(-time.Minute)
+	stored.ExpiredAt = &expiredAt
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/org", "", bearerHeadersOf(rotated.Token))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	stored.ExpiredAt = nil
+
+	// 7: revoke token
+	resp = doRequestWithHeaders(router, http.MethodDelete, "/v1/me/tokens/1", "", sessionHeadersOf(1))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/org", "", bearerHeadersOf(rotated.Token))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodDelete, "/v1/me/tokens/1", "", sessionHeadersOf(1))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"errors"
+	"github.com/pointgoal/workstation/pkg/repository"
+)
+
+// Permission describes an operation on resources of organization.
+type Permission string
+
+const (
+	// PermOrgGet allows reading organization
+	PermOrgGet Permission = "org:get"
+	// PermOrgUpdate allows updating organization
+	PermOrgUpdate Permission = "org:update"
+	// PermOrgDelete allows deleting organization
+	PermOrgDelete Permission = "org:delete"
+	// PermMemberList allows listing members of organization
+	PermMemberList Permission = "member:list"
+	// PermMemberManage allows inviting, removing and changing role of members
+	PermMemberManage Permission = "member:manage"
+	// PermProjGet allows listing and reading projects
+	PermProjGet Permission = "proj:get"
+	// PermProjCreate allows creating projects
+	PermProjCreate Permission = "proj:create"
+	// PermProjUpdate allows updating projects
+	PermProjUpdate Permission = "proj:update"
+	// PermProjDelete allows deleting projects
+	PermProjDelete Permission = "proj:delete"
+	// PermSourceGet allows reading source together with commits, branches and tags of it
+	PermSourceGet Permission = "source:get"
+	// PermSourceCreate allows creating source of project
+	PermSourceCreate Permission = "source:create"
+	// PermSourceDelete allows deleting source of project
+	PermSourceDelete Permission = "source:delete"
+	// PermTemplateList allows listing pipeline templates
+	PermTemplateList Permission = "template:list"
+)
+
+var (
+	// rolePermissions is the permission matrix of roles in organization.
+	rolePermissions = map[string][]Permission{
+		repository.RoleOwner: {
+			PermOrgGet, PermOrgUpdate, PermOrgDelete,
+			PermMemberList, PermMemberManage,
+			PermProjGet, PermProjCreate, PermProjUpdate, PermProjDelete,
+			PermSourceGet, PermSourceCreate, PermSourceDelete,
+			PermTemplateList,
+		},
+		repository.RoleMaintainer: {
+			PermOrgGet, PermOrgUpdate,
+			PermMemberList, PermMemberManage,
+			PermProjGet, PermProjCreate, PermProjUpdate, PermProjDelete,
+			PermSourceGet, PermSourceCreate, PermSourceDelete,
+			PermTemplateList,
+		},
+		repository.RoleDeveloper: {
+			PermOrgGet,
+			PermMemberList,
+			PermProjGet, PermProjUpdate,
+			PermSourceGet, PermSourceCreate,
+			PermTemplateList,
+		},
+		repository.RoleViewer: {
+			PermOrgGet,
+			PermMemberList,
+			PermProjGet,
+			PermSourceGet,
+			PermTemplateList,
+		},
+	}
+
+	// roleRanks orders roles, members could only manage roles which are not higher than their own.
+	roleRanks = map[string]int{
+		repository.RoleOwner:      4,
+		repository.RoleMaintainer: 3,
+		repository.RoleDeveloper:  2,
+		repository.RoleViewer:     1,
+	}
+)
+
+// HasPermission checks whether role is granted with permission.
+func HasPermission(role string, perm Permission) bool {
+	for _, v := range rolePermissions[role] {
+		if v == perm {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authorize checks permission of user in organization, it must be called before accessing resources of organization.
+//
+// NotFound would be returned if user is not a member of organization, so that existence of organization
+// would not be leaked to strangers.
+func (con *Controller) authorize(userId, orgId int, perm Permission) (*repository.Member, error) {
+	member, err := con.Repo.GetMember(orgId, userId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, repository.NewNotFoundf(repository.OrgNotFoundMsg, orgId)
+	}
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.MemberFailedToGetMsg, orgId, userId)
+	}
+
+	if !HasPermission(member.Role, perm) {
+		return nil, repository.NewPermissionDeniedf("%s of organization:%d is not allowed to %s", member.Role, orgId, perm)
+	}
+
+	return member, nil
+}
+
+// authorizeAny checks whether user is granted with permission in any organization,
+// it is used by resources shared among organizations like pipeline templates.
+func (con *Controller) authorizeAny(userId int, perm Permission) error {
+	memberList, err := con.Repo.ListMemberByUser(userId)
+	if err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to list memberships of userId:%d", userId)
+	}
+
+	for i := range memberList {
+		if HasPermission(memberList[i].Role, perm) {
+			return nil
+		}
+	}
+
+	return repository.NewPermissionDeniedf("user is not allowed to %s, please join an organization first", perm)
+}
+
+// authorizeProj checks permission of user in organization of project and returns the project.
+func (con *Controller) authorizeProj(userId, projId int, perm Permission) (*repository.Proj, error) {
+	proj, err := con.findProj(projId)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := con.authorize(userId, proj.OrgId, perm); err != nil {
+		return nil, err
+	}
+
+	return proj, nil
+}
+
+// authorizeSource checks permission of user in organization of source and returns the source.
+func (con *Controller) authorizeSource(userId, sourceId int, perm Permission) (*repository.Source, error) {
+	src, err := con.findSource(sourceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := con.authorizeProj(userId, src.ProjId, perm); err != nil {
+		return nil, err
+	}
+
+	return src, nil
+}
+
+// Checks whether member with role could grant or manage role, roles higher than the own one could not be managed.
+func canManageRole(role, target string) bool {
+	return roleRanks[role] >= roleRanks[target]
+}
+
+// RoleRank returns rank of role, higher role has higher rank and unknown roles are ranked as 0.
+func RoleRank(role string) int {
+	return roleRanks[role]
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestHasPermission(t *testing.T) {
+	// owner could do everything
+	assert.True(t, HasPermission(repository.RoleOwner, PermOrgDelete))
+
+	// maintainer could not delete organization
+	assert.True(t, HasPermission(repository.RoleMaintainer, PermMemberManage))
+	assert.False(t, HasPermission(repository.RoleMaintainer, PermOrgDelete))
+
+	// developer could update project and create source only
+	assert.True(t, HasPermission(repository.RoleDeveloper, PermProjUpdate))
+	assert.True(t, HasPermission(repository.RoleDeveloper, PermSourceCreate))
+	assert.False(t, HasPermission(repository.RoleDeveloper, PermProjCreate))
+	assert.False(t, HasPermission(repository.RoleDeveloper, PermSourceDelete))
+
+	// viewer could read only
+	assert.True(t, HasPermission(repository.RoleViewer, PermSourceGet))
+	assert.False(t, HasPermission(repository.RoleViewer, PermProjUpdate))
+
+	// unknown role
+	assert.False(t, HasPermission("ut-role", PermOrgGet))
+}
+
+func TestCanManageRole(t *testing.T) {
+	assert.True(t, canManageRole(repository.RoleOwner, repository.RoleOwner))
+	assert.True(t, canManageRole(repository.RoleMaintainer, repository.RoleDeveloper))
+	assert.False(t, canManageRole(repository.RoleMaintainer, repository.RoleOwner))
+	assert.True(t, RoleRank(repository.RoleOwner) > RoleRank(repository.RoleViewer))
+	assert.Equal(t, 0, RoleRank("ut-role"))
+}
+
+func TestMemberApi(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+	repo := GetController().Repo
+
+	// prepare users with github identities
+	for _, login := range []string{"ut-owner", "ut-dev", "ut-viewer"} {
+		user := repository.NewUser(login)
+		repo.CreateUser(user)
+		identity := repository.NewIdentity(repository.IdentityGithub, login)
+		identity.UserId = user.Id
+		repo.UpsertIdentity(identity)
+	}
+
+	// 1: creator of organization is owner
+	resp := doRequestWithHeaders(router, http.MethodPost, "/v2/orgs", `{"name":"ut-org"}`, sessionHeadersOf(1))
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/org/1/members", "", sessionHeadersOf(1))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"role":"owner"`)
+
+	// 2: strangers could not find organization
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/org/1", "", sessionHeadersOf(2))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/org", "", sessionHeadersOf(2))
+	assert.Equal(t, `{"orgList":[]}`, resp.Body.String())
+
+	// 3: invite members
+	resp = doRequestWithHeaders(router, http.MethodPut, "/v1/org/1/members", `{"type":"github","login":"ut-dev","role":"developer"}`, sessionHeadersOf(1))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodPut, "/v1/org/1/members", `{"type":"github","login":"ut-viewer","role":"viewer"}`, sessionHeadersOf(1))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodPut, "/v1/org/1/members", `{"type":"github","login":"ut-dev","role":"viewer"}`, sessionHeadersOf(1))
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodPut, "/v1/org/1/members", `{"type":"github","login":"ut-nobody","role":"viewer"}`, sessionHeadersOf(1))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodPut, "/v1/org/1/members", `{"type":"github","login":"ut-dev","role":"admin"}`, sessionHeadersOf(1))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// 4: developer could read organization and update project, but not create project or manage members
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/org/1", "", sessionHeadersOf(2))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v2/projects", `{"orgId":1,"name":"ut-proj"}`, sessionHeadersOf(2))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v2/projects", `{"orgId":1,"name":"ut-proj"}`, sessionHeadersOf(1))
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v1/proj/1", `{"name":"ut-proj-new"}`, sessionHeadersOf(2))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v1/org/1/members/3", `{"role":"developer"}`, sessionHeadersOf(2))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	// 5: viewer could list projects but not update them
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/proj", "", sessionHeadersOf(3))
+	assert.Contains(t, resp.Body.String(), "ut-proj-new")
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v1/proj/1", `{"name":"ut-proj"}`, sessionHeadersOf(3))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	// 6: change role of member
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v1/org/1/members/2", `{"role":"maintainer"}`, sessionHeadersOf(1))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"role":"maintainer"`)
+
+	// maintainer could not grant owner
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v1/org/1/members/3", `{"role":"owner"}`, sessionHeadersOf(2))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	// 7: the last owner could not be demoted, removed or deleted
+	resp = doRequestWithHeaders(router, http.MethodPost, "/v1/org/1/members/1", `{"role":"viewer"}`, sessionHeadersOf(1))
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodDelete, "/v1/org/1/members/1", "", sessionHeadersOf(1))
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodDelete, "/v1/me", "", sessionHeadersOf(1))
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	// 8: members could leave organization by themselves
+	resp = doRequestWithHeaders(router, http.MethodDelete, "/v1/org/1/members/3", "", sessionHeadersOf(3))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/proj/1", "", sessionHeadersOf(3))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	// 9: maintainer could not delete organization
+	resp = doRequestWithHeaders(router, http.MethodDelete, "/v2/orgs/1", "", sessionHeadersOf(2))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	// 10: members are removed together with organization
+	doRequestWithHeaders(router, http.MethodDelete, "/v2/projects/1", "", sessionHeadersOf(1))
+	resp = doRequestWithHeaders(router, http.MethodDelete, "/v2/orgs/1", "", sessionHeadersOf(1))
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	memberList, _ := repo.ListMemberByUser(2)
+	assert.Empty(t, memberList)
+
+	// 11: pipeline templates are available to members of any organization only
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/pipeline/template", "", sessionHeadersOf(2))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestController_Authorize(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	newRouterV2()
+	con := GetController()
+	con.Repo.UpsertMember(repository.NewMember(1, 1, repository.RoleViewer))
+
+	member, err := con.authorize(1, 1, PermOrgGet)
+	assert.Nil(t, err)
+	assert.Equal(t, repository.RoleViewer, member.Role)
+
+	_, err = con.authorize(1, 1, PermOrgUpdate)
+	assert.Equal(t, repository.CodePermissionDenied, repository.CodeOf(err))
+
+	_, err = con.authorize(2, 1, PermOrgGet)
+	assert.Equal(t, repository.CodeNotFound, repository.CodeOf(err))
+}
+
+func TestController_GrantOrgRoles(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	newRouterV2()
+	con := GetController()
+	con.Repo.CreateOrg(repository.NewOrg("ut-org-1"))
+	con.Repo.CreateOrg(repository.NewOrg("ut-org-2"))
+	con.Repo.UpsertMember(repository.NewMember(2, 1, repository.RoleOwner))
+
+	// 1: add member and never lower roles
+	assert.Nil(t, con.GrantOrgRoles(1, map[int]string{1: repository.RoleDeveloper, 2: repository.RoleViewer}))
+	member, _ := con.Repo.GetMember(1, 1)
+	assert.Equal(t, repository.RoleDeveloper, member.Role)
+	member, _ = con.Repo.GetMember(2, 1)
+	assert.Equal(t, repository.RoleOwner, member.Role)
+
+	// 2: raise role
+	assert.Nil(t, con.GrantOrgRoles(1, map[int]string{1: repository.RoleMaintainer}))
+	member, _ = con.Repo.GetMember(1, 1)
+	assert.Equal(t, repository.RoleMaintainer, member.Role)
+
+	// 3: invalid role and missing organization
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(con.GrantOrgRoles(1, map[int]string{1: "ut-role"})))
+	assert.Equal(t, repository.CodeNotFound, repository.CodeOf(con.GrantOrgRoles(1, map[int]string{3: repository.RoleViewer})))
+}
@@ -0,0 +1,1012 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"golang.org/x/crypto/bcrypt"
+	"sort"
+	"strings"
+)
+
+// Business logic shared by REST and gRPC APIs.
+//
+// Functions in this file accept validated arguments and return *repository.Error with proper code,
+// callers only need to convert arguments and errors with protocol of their own.
+
+// ************************************************** //
+// ************** Organization related ************** //
+// ************************************************** //
+
+// listOrg returns organizations which user is a member of with ids of projects, projects would be embedded if expanded.
+func (con *Controller) listOrg(userId int, expand ...string) ([]*Org, error) {
+	// 1: list organizations of user
+	orgIds, err := con.listMemberOrgIds(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	orgListFromRepo, err := con.Repo.ListOrgByIds(orgIds)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list organizations of userId:%d", userId)
+	}
+
+	// 2: list projects of organizations with one query
+	return con.expandOrg(orgListFromRepo, expand)
+}
+
+// getOrg returns organization with ids of projects.
+func (con *Controller) getOrg(userId, orgId int) (*Org, error) {
+	if _, err := con.authorize(userId, orgId, PermOrgGet); err != nil {
+		return nil, err
+	}
+
+	return con.getOrgUnchecked(orgId)
+}
+
+// getOrgUnchecked returns organization with ids of projects without authorization.
+func (con *Controller) getOrgUnchecked(orgId int) (*Org, error) {
+	// 1: get organization from repo
+	orgFromRepo, err := con.findOrg(orgId)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2: list projects from repo
+	orgList, err := con.expandOrg([]*repository.Org{orgFromRepo}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return orgList[0], nil
+}
+
+// expandOrg converts organizations into API model with ids, counts or embedded projects,
+// projects of all organizations are fetched with one query.
+func (con *Controller) expandOrg(orgListFromRepo []*repository.Org, expand []string) ([]*Org, error) {
+	res := make([]*Org, 0)
+	if len(orgListFromRepo) < 1 {
+		return res, nil
+	}
+
+	orgIds := make([]int, 0)
+	for i := range orgListFromRepo {
+		orgIds = append(orgIds, orgListFromRepo[i].Id)
+	}
+
+	// 1: embed projects
+	if contains(expand, ExpandProjects) {
+		projMap, err := con.Repo.ListProjByOrg(orgIds, contains(expand, ExpandSource))
+		if err != nil {
+			return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list projects of organizations")
+		}
+
+		for i := range orgListFromRepo {
+			org := convertOrg(orgListFromRepo[i], projMap[orgListFromRepo[i].Id])
+			org.ProjList = make([]*Proj, 0)
+			for _, proj := range projMap[orgListFromRepo[i].Id] {
+				org.ProjList = append(org.ProjList, convertProj(proj))
+			}
+			if contains(expand, ExpandProjCount) {
+				count := len(org.ProjList)
+				org.ProjCount = &count
+			}
+			res = append(res, org)
+		}
+
+		return res, nil
+	}
+
+	// 2: counts of projects only, ids are not listed
+	if contains(expand, ExpandProjCount) {
+		countMap, err := con.Repo.ListProjCountByOrg(orgIds)
+		if err != nil {
+			return nil, repository.Wrapf(err, repository.CodeInternal, "failed to count projects of organizations")
+		}
+
+		for i := range orgListFromRepo {
+			org := convertOrg(orgListFromRepo[i], nil)
+			count := countMap[orgListFromRepo[i].Id]
+			org.ProjCount = &count
+			res = append(res, org)
+		}
+
+		return res, nil
+	}
+
+	// 3: ids of projects only
+	idMap, err := con.Repo.ListProjIdsByOrg(orgIds)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list ids of projects of organizations")
+	}
+
+	for i := range orgListFromRepo {
+		org := convertOrg(orgListFromRepo[i], nil)
+		org.ProjIds = append(org.ProjIds, idMap[orgListFromRepo[i].Id]...)
+		res = append(res, org)
+	}
+
+	return res, nil
+}
+
+// createOrg creates organization with name, a random name will be assigned if name is empty.
+// User who created the organization would be the owner of it.
+func (con *Controller) createOrg(userId int, name string) (*Org, error) {
+	// 1: create organization
+	orgForRepo := repository.NewOrg(name)
+	if _, err := con.Repo.CreateOrg(orgForRepo); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to create organization with name:%s", name)
+	}
+
+	// 2: add creator as owner
+	if _, err := con.Repo.UpsertMember(repository.NewMember(orgForRepo.Id, userId, repository.RoleOwner)); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to add owner of organization with orgId:%d", orgForRepo.Id)
+	}
+
+	return convertOrg(orgForRepo, nil), nil
+}
+
+// updateOrg replaces name of organization.
+func (con *Controller) updateOrg(userId, orgId int, name string) (*Org, error) {
+	if _, err := con.authorize(userId, orgId, PermOrgUpdate); err != nil {
+		return nil, err
+	}
+
+	// 1: get organization from repo
+	org, err := con.findOrg(orgId)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2: replace fields
+	org.Name = name
+
+	// 3: update in repo
+	if _, err := con.Repo.UpdateOrg(org); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to update organization with orgId:%d", orgId)
+	}
+
+	return con.getOrgUnchecked(orgId)
+}
+
+// deleteOrg removes organization together with members, organization with projects could not be removed.
+func (con *Controller) deleteOrg(userId, orgId int) error {
+	if _, err := con.authorize(userId, orgId, PermOrgDelete); err != nil {
+		return err
+	}
+
+	// 1: get organization first
+	if _, err := con.findOrg(orgId); err != nil {
+		return err
+	}
+
+	// 2: list projects from org
+	projListFromRepo, err := con.Repo.ListProj(orgId)
+	if err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to list projects with orgId:%d", orgId)
+	}
+	if len(projListFromRepo) > 0 {
+		return repository.NewConflictf("organization is not empty, please delete or migrate projects to another organization first.")
+	}
+
+	// 3: remove organization
+	if _, err := con.Repo.RemoveOrg(orgId); err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to delete organization with orgId:%d", orgId)
+	}
+
+	// 4: remove members of organization
+	if _, err := con.Repo.RemoveMemberByOrg(orgId); err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to delete members of organization with orgId:%d", orgId)
+	}
+
+	return nil
+}
+
+// ********************************************* //
+// ************** Project related ************** //
+// ********************************************* //
+
+// listProj returns projects in organization, projects in all organizations of user would be returned
+// if orgId is less than 1. Source of projects would be embedded if expanded.
+func (con *Controller) listProj(userId, orgId int, expand ...string) ([]*Proj, error) {
+	res := make([]*Proj, 0)
+
+	orgIds := make([]int, 0)
+	if orgId > 0 {
+		if _, err := con.authorize(userId, orgId, PermProjGet); err != nil {
+			return nil, err
+		}
+		orgIds = append(orgIds, orgId)
+	} else {
+		memberOrgIds, err := con.listMemberOrgIds(userId)
+		if err != nil {
+			return nil, err
+		}
+		orgIds = append(orgIds, memberOrgIds...)
+	}
+
+	// projects of all organizations would be returned with empty ids
+	if len(orgIds) < 1 {
+		return res, nil
+	}
+
+	projMap, err := con.Repo.ListProjByOrg(orgIds, contains(expand, ExpandSource))
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list projects with orgId:%d", orgId)
+	}
+
+	for _, projList := range projMap {
+		for i := range projList {
+			res = append(res, convertProj(projList[i]))
+		}
+	}
+
+	// keep the order of projects stable, since they are grouped by organization
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Meta.Id < res[j].Meta.Id
+	})
+
+	return res, nil
+}
+
+// getProj returns project with source.
+func (con *Controller) getProj(userId, projId int) (*Proj, error) {
+	projFromRepo, err := con.authorizeProj(userId, projId, PermProjGet)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertProj(projFromRepo), nil
+}
+
+// createProj creates project in organization.
+// Name of organization would be the same as organization in repository if orgName is empty.
+func (con *Controller) createProj(userId, orgId int, orgName, name string) (*Proj, error) {
+	if _, err := con.authorize(userId, orgId, PermProjCreate); err != nil {
+		return nil, err
+	}
+
+	// 1: get organization from repository
+	org, err := con.findOrg(orgId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(orgName) < 1 {
+		orgName = org.Name
+	}
+
+	// 2: create project
+	proj := repository.NewProj(name)
+	proj.OrgId = orgId
+	proj.OrgName = orgName
+	if _, err := con.Repo.CreateProj(proj); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to create project with orgId:%d", orgId)
+	}
+
+	return convertProj(proj), nil
+}
+
+// updateProj replaces name of project.
+func (con *Controller) updateProj(userId, projId int, name string) (*Proj, error) {
+	// 1: get project from repository
+	projFromRepo, err := con.authorizeProj(userId, projId, PermProjUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2: update values in project
+	projFromRepo.Name = name
+
+	// 3: update project to repository
+	if _, err := con.Repo.UpdateProj(projFromRepo); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to update project with projId:%d", projId)
+	}
+
+	return convertProj(projFromRepo), nil
+}
+
+// deleteProj removes project.
+func (con *Controller) deleteProj(userId, projId int) error {
+	if _, err := con.authorizeProj(userId, projId, PermProjDelete); err != nil {
+		return err
+	}
+
+	if _, err := con.Repo.RemoveProj(projId); err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, repository.ProjFailedToRemove, projId)
+	}
+
+	return nil
+}
+
+// ******************************************** //
+// ************** Source related ************** //
+// ******************************************** //
+
+// createSource creates source in project, one project could have one source only.
+// Github sources without host are created on primary host, so that they would not move if primary host changed.
+func (con *Controller) createSource(userId, projId int, srcType, repo, host string) (*Source, error) {
+	// types are validated case-insensitively, while sources, tokens and webhooks are looked up with lower-cased types
+	srcType = strings.ToLower(srcType)
+
+	// 1: get project from repository
+	proj, err := con.authorizeProj(userId, projId, PermSourceCreate)
+	if err != nil {
+		return nil, err
+	}
+	if proj.Source != nil {
+		return nil, repository.NewAlreadyExistf("source already exist in project with sourceId:%d", proj.Source.Id)
+	}
+
+	// 2: bind source to login of user in remote code repository, access token of user is used while accessing source
+	src := repository.NewSource(srcType, repo)
+	src.ProjId = projId
+	src.Host = host
+	if strings.EqualFold(srcType, repository.IdentityGithub) && len(host) < 1 {
+		src.Host = GetGithubHost("").Host
+	}
+
+	// local git repositories are accessed without user, others require access token of user
+	if !strings.EqualFold(srcType, SourceTypeGit) {
+		token, err := con.Repo.GetAccessToken(userId, srcType)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && token == nil) {
+			return nil, repository.NewUnauthenticatedf("connect your %s account first", srcType)
+		}
+		if err != nil {
+			return nil, repository.Wrapf(err, repository.CodeInternal, repository.AccessTokenFailedToGetMsg, srcType, userId)
+		}
+		src.User = token.User
+	}
+
+	// 3: verify repository is accessible by user, id of repository is kept so that renamed repository could be followed
+	provider, err := vcsProviderOf(srcType)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := provider.GetRepository(context.Background(), src)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, repository.NewInvalidArgumentf("repository %s of %s is not found or not accessible", repo, srcType)
+	}
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to verify repository %s of %s", repo, srcType)
+	}
+	if len(remote.FullName) > 0 {
+		src.Repository = remote.FullName
+	}
+	src.RepositoryId = remote.Id
+	src.DefaultBranch = remote.DefaultBranch
+
+	// 4: create source
+	if _, err := con.Repo.CreateSource(src); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to create source with projId:%d", projId)
+	}
+
+	return &Source{Meta: src}, nil
+}
+
+// getSource returns source.
+func (con *Controller) getSource(userId, sourceId int) (*Source, error) {
+	src, err := con.authorizeSource(userId, sourceId, PermSourceGet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Source{Meta: src}, nil
+}
+
+// deleteSource removes source.
+func (con *Controller) deleteSource(userId, sourceId int) error {
+	if _, err := con.authorizeSource(userId, sourceId, PermSourceDelete); err != nil {
+		return err
+	}
+
+	if _, err := con.Repo.RemoveSource(sourceId); err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, repository.SourceFailedToRemove, sourceId)
+	}
+
+	return nil
+}
+
+// listCommits returns page of commits of source from remote code repository.
+// Pages would be followed from page until there are no more pages or max pages of controller reached if all is true.
+func (con *Controller) listCommits(userId, sourceId int, branch string, perPage, page int, all bool) ([]*Commit, *Pagination, error) {
+	// 1: get source from repository
+	src, err := con.authorizeSource(userId, sourceId, PermSourceGet)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 2: get provider of source
+	provider, err := vcsProviderOf(src.Type)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 3: list commits
+	commits := make([]*Commit, 0)
+	pagination, err := listPages(con.perPageOf(perPage, all), page, con.maxPagesOf(all), func(perPage, page int) (*Pagination, error) {
+		res, pagination, err := provider.ListCommits(context.Background(), src, branch, perPage, page)
+		if err != nil {
+			return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list commits from %s", src.Type)
+		}
+		commits = append(commits, res...)
+
+		return pagination, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return commits, pagination, nil
+}
+
+// listBranchesAndTags returns pages of branches and tags of source from remote code repository, which are paged separately.
+// Pages would be followed from page until there are no more pages or max pages of controller reached if all is true.
+func (con *Controller) listBranchesAndTags(userId, sourceId int, perPage, branchPage, tagPage int, all bool) (*ListBranchesAndTagsResponse, error) {
+	// 1: get source from repository
+	src, err := con.authorizeSource(userId, sourceId, PermSourceGet)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2: get provider of source
+	provider, err := vcsProviderOf(src.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3: list branches and tags
+	res := &ListBranchesAndTagsResponse{
+		Branches: make([]string, 0),
+		Tags:     make([]string, 0),
+	}
+	res.BranchPagination, err = listPages(con.perPageOf(perPage, all), branchPage, con.maxPagesOf(all), func(perPage, page int) (*Pagination, error) {
+		branches, pagination, err := provider.ListBranches(context.Background(), src, perPage, page)
+		if err != nil {
+			return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list branches from %s", src.Type)
+		}
+		res.Branches = append(res.Branches, branches...)
+
+		return pagination, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res.TagPagination, err = listPages(con.perPageOf(perPage, all), tagPage, con.maxPagesOf(all), func(perPage, page int) (*Pagination, error) {
+		tags, pagination, err := provider.ListTags(context.Background(), src, perPage, page)
+		if err != nil {
+			return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list tags from %s", src.Type)
+		}
+		res.Tags = append(res.Tags, tags...)
+
+		return pagination, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Returns page size while listing from remote code repository, the largest page is used to list all if perPage is missing.
+func (con *Controller) perPageOf(perPage int, all bool) int {
+	if all && perPage < 1 {
+		return PerPageMax
+	}
+
+	return perPage
+}
+
+// Returns max number of pages followed while listing from remote code repository.
+func (con *Controller) maxPagesOf(all bool) int {
+	if all {
+		return con.VCSMaxPages
+	}
+
+	return 1
+}
+
+// listUserInstallations returns installations of user stored in repository, installations are synced from
+// remote code repository if refresh is true, or none of them stored and they were not synced within sync interval.
+func (con *Controller) listUserInstallations(source string, userId int, refresh bool) ([]*Installation, error) {
+	if _, err := vcsProviderOf(source); err != nil {
+		return nil, err
+	}
+
+	// 1: list installations stored in repository
+	if !refresh {
+		installations, err := con.Repo.ListInstallation(userId, source, true)
+		if err != nil {
+			return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list user installations of %s", source)
+		}
+
+		if len(installations) > 0 || con.isInstallationSyncedRecently(userId, source) {
+			res := make([]*Installation, 0)
+			for i := range installations {
+				res = append(res, toInstallation(installations[i]))
+			}
+			return res, nil
+		}
+	}
+
+	// 2: sync installations from remote code repository
+	return con.SyncInstallations(userId, source)
+}
+
+// ****************************************** //
+// ************** User related ************** //
+// ****************************************** //
+
+// getUser returns user with linked identities.
+func (con *Controller) getUser(userId int) (*User, error) {
+	user, err := con.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertUser(user), nil
+}
+
+// createUser creates user with profile of identity, identity would not be linked.
+func (con *Controller) createUser(identity *repository.Identity) (*repository.User, error) {
+	user := repository.NewUser(identity.Name)
+	if len(user.Name) < 1 {
+		user.Name = identity.Login
+	}
+	user.Email = identity.Email
+	user.AvatarUrl = identity.AvatarUrl
+
+	if _, err := con.Repo.CreateUser(user); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to create user with %s identity:%s", identity.Type, identity.Login)
+	}
+
+	return user, nil
+}
+
+// deleteUser removes user together with linked identities, access tokens and memberships.
+// The last owner of organization could not be removed, otherwise, nobody could manage the organization.
+func (con *Controller) deleteUser(userId int) error {
+	memberList, err := con.Repo.ListMemberByUser(userId)
+	if err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to list memberships of userId:%d", userId)
+	}
+
+	for i := range memberList {
+		if err := con.ensureOwnerRemains(memberList[i]); err != nil {
+			return err
+		}
+	}
+
+	if _, err := con.Repo.RemoveUser(userId); err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to remove user with userId:%d", userId)
+	}
+
+	return nil
+}
+
+// linkIdentity links identity to user, user could link at most one identity of each type.
+func (con *Controller) linkIdentity(user *repository.User, identity *repository.Identity) error {
+	if existing := user.GetIdentity(identity.Type); existing != nil && existing.Login != identity.Login {
+		return repository.NewConflictf("%s identity:%s was linked already, please unlink it first", identity.Type, existing.Login)
+	}
+
+	identity.UserId = user.Id
+	if _, err := con.Repo.UpsertIdentity(identity); err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to link %s identity:%s", identity.Type, identity.Login)
+	}
+
+	if user.GetIdentity(identity.Type) == nil {
+		user.Identities = append(user.Identities, identity)
+	}
+
+	return nil
+}
+
+// linkLocalIdentity links local identity with password to user, password of linked local identity would be updated
+// only if current password matches.
+func (con *Controller) linkLocalIdentity(userId int, login, password, currentPassword string) error {
+	// 1: login of local identity must be unique
+	linked, err := con.Repo.GetIdentity(repository.IdentityLocal, login)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return repository.Wrapf(err, repository.CodeInternal, repository.IdentityFailedToGetMsg, repository.IdentityLocal, login)
+	}
+	if linked != nil && linked.UserId != userId {
+		return repository.NewAlreadyExistf(repository.IdentityAlreadyLinkedMsg, repository.IdentityLocal, login)
+	}
+
+	// 2: get user
+	user, err := con.findUser(userId)
+	if err != nil {
+		return err
+	}
+
+	// 3: holder of session could not take over the user by replacing password
+	if existing := user.GetIdentity(repository.IdentityLocal); existing != nil &&
+		bcrypt.CompareHashAndPassword([]byte(existing.Password), []byte(currentPassword)) != nil {
+		return repository.NewPermissionDeniedf("current password is invalid")
+	}
+
+	// 4: store hash of password only
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to hash password")
+	}
+
+	identity := repository.NewIdentity(repository.IdentityLocal, login)
+	identity.Name = user.Name
+	identity.Email = user.Email
+	identity.Password = string(hash)
+
+	return con.linkIdentity(user, identity)
+}
+
+// verifyLocalIdentity returns local identity if password matches.
+func (con *Controller) verifyLocalIdentity(login, password string) (*repository.Identity, error) {
+	identity, err := con.Repo.GetIdentity(repository.IdentityLocal, login)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.IdentityFailedToGetMsg, repository.IdentityLocal, login)
+	}
+
+	// do not tell client whether login exists
+	if identity == nil || bcrypt.CompareHashAndPassword([]byte(identity.Password), []byte(password)) != nil {
+		return nil, repository.NewUnauthenticatedf("invalid login or password")
+	}
+
+	return identity, nil
+}
+
+// unlinkIdentity removes identity of type from user together with access token of the same type.
+// The last identity could not be unlinked, otherwise, user could not sign in anymore.
+func (con *Controller) unlinkIdentity(userId int, identityType string) error {
+	user, err := con.findUser(userId)
+	if err != nil {
+		return err
+	}
+
+	if user.GetIdentity(identityType) == nil {
+		return repository.NewNotFoundf(repository.IdentityOfUserNotFoundMsg, identityType, userId)
+	}
+
+	if len(user.Identities) < 2 {
+		return repository.NewConflictf("the last identity could not be unlinked, please delete user instead")
+	}
+
+	if _, err := con.Repo.RemoveIdentity(userId, identityType); err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to unlink %s identity of userId:%d", identityType, userId)
+	}
+
+	if _, err := con.Repo.RemoveAccessToken(userId, identityType); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to remove %s access token of userId:%d", identityType, userId)
+	}
+
+	return nil
+}
+
+// ******************************************** //
+// ************** Member related ************** //
+// ******************************************** //
+
+// listMember returns members of organization.
+func (con *Controller) listMember(userId, orgId int) ([]*Member, error) {
+	if _, err := con.authorize(userId, orgId, PermMemberList); err != nil {
+		return nil, err
+	}
+
+	memberListFromRepo, err := con.Repo.ListMember(orgId)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list members with orgId:%d", orgId)
+	}
+
+	res := make([]*Member, 0)
+	for i := range memberListFromRepo {
+		res = append(res, &Member{Meta: memberListFromRepo[i]})
+	}
+
+	return res, nil
+}
+
+// inviteMember adds user who linked identity with login into organization with role.
+// Role higher than the one of inviter could not be granted.
+func (con *Controller) inviteMember(userId, orgId int, identityType, login, role string) (*Member, error) {
+	// 1: check permission of inviter
+	inviter, err := con.authorize(userId, orgId, PermMemberManage)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageRole(inviter.Role, role) {
+		return nil, repository.NewPermissionDeniedf("%s could not grant role:%s", inviter.Role, role)
+	}
+
+	// 2: find user who linked the identity
+	identity, err := con.Repo.GetIdentity(identityType, login)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.IdentityFailedToGetMsg, identityType, login)
+	}
+	if identity == nil {
+		return nil, repository.NewNotFoundf(repository.IdentityNotFoundMsg, identityType, login)
+	}
+
+	// 3: user could be invited once
+	if _, err := con.Repo.GetMember(orgId, identity.UserId); err == nil {
+		return nil, repository.NewAlreadyExistf("user with userId:%d is a member of organization already", identity.UserId)
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.MemberFailedToGetMsg, orgId, identity.UserId)
+	}
+
+	// 4: add member
+	member := repository.NewMember(orgId, identity.UserId, role)
+	if _, err := con.Repo.UpsertMember(member); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to add member with orgId:%d userId:%d", orgId, identity.UserId)
+	}
+
+	return &Member{Meta: member}, nil
+}
+
+// updateMemberRole changes role of member, members with higher role than the operator could not be changed.
+func (con *Controller) updateMemberRole(userId, orgId, memberUserId int, role string) (*Member, error) {
+	// 1: check permission of operator
+	operator, err := con.authorize(userId, orgId, PermMemberManage)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := con.findMember(orgId, memberUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canManageRole(operator.Role, member.Role) || !canManageRole(operator.Role, role) {
+		return nil, repository.NewPermissionDeniedf("%s could not change role from %s to %s", operator.Role, member.Role, role)
+	}
+
+	// 2: organization must keep at least one owner
+	if role != repository.RoleOwner {
+		if err := con.ensureOwnerRemains(member); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3: update role
+	member.Role = role
+	if _, err := con.Repo.UpsertMember(member); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to update member with orgId:%d userId:%d", orgId, memberUserId)
+	}
+
+	return &Member{Meta: member}, nil
+}
+
+// GrantOrgRoles grants roles of organizations to user, which is used by identity providers mapping groups to roles.
+//
+// User would be added as member of organizations, roles of existing members would be raised only, so that roles
+// granted by other members would not be lowered and the last owner would never be demoted.
+func (con *Controller) GrantOrgRoles(userId int, roles map[int]string) error {
+	for orgId, role := range roles {
+		if _, ok := roleRanks[role]; !ok {
+			return repository.NewInvalidArgumentf("invalid role:%s of orgId:%d", role, orgId)
+		}
+
+		if _, err := con.findOrg(orgId); err != nil {
+			return err
+		}
+
+		member, err := con.Repo.GetMember(orgId, userId)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return repository.Wrapf(err, repository.CodeInternal, repository.MemberFailedToGetMsg, orgId, userId)
+		}
+
+		switch {
+		case member == nil:
+			member = repository.NewMember(orgId, userId, role)
+		case roleRanks[role] > roleRanks[member.Role]:
+			member.Role = role
+		default:
+			continue
+		}
+
+		if _, err := con.Repo.UpsertMember(member); err != nil {
+			return repository.Wrapf(err, repository.CodeInternal, "failed to grant role:%s with orgId:%d userId:%d", role, orgId, userId)
+		}
+	}
+
+	return nil
+}
+
+// removeMember removes member from organization, members could always leave organization by themselves.
+func (con *Controller) removeMember(userId, orgId, memberUserId int) error {
+	// 1: check permission of operator
+	perm := PermMemberManage
+	if userId == memberUserId {
+		perm = PermOrgGet
+	}
+
+	operator, err := con.authorize(userId, orgId, perm)
+	if err != nil {
+		return err
+	}
+
+	member, err := con.findMember(orgId, memberUserId)
+	if err != nil {
+		return err
+	}
+
+	if !canManageRole(operator.Role, member.Role) {
+		return repository.NewPermissionDeniedf("%s could not remove member with role:%s", operator.Role, member.Role)
+	}
+
+	// 2: organization must keep at least one owner
+	if err := con.ensureOwnerRemains(member); err != nil {
+		return err
+	}
+
+	// 3: remove member
+	if _, err := con.Repo.RemoveMember(orgId, memberUserId); err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to remove member with orgId:%d userId:%d", orgId, memberUserId)
+	}
+
+	return nil
+}
+
+// ensureOwnerRemains returns conflict if member is the last owner of organization.
+func (con *Controller) ensureOwnerRemains(member *repository.Member) error {
+	if member.Role != repository.RoleOwner {
+		return nil
+	}
+
+	memberList, err := con.Repo.ListMember(member.OrgId)
+	if err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to list members with orgId:%d", member.OrgId)
+	}
+
+	for i := range memberList {
+		if memberList[i].Role == repository.RoleOwner && memberList[i].UserId != member.UserId {
+			return nil
+		}
+	}
+
+	return repository.NewConflictf("user with userId:%d is the last owner of organization with orgId:%d, please transfer ownership first", member.UserId, member.OrgId)
+}
+
+// listMemberOrgIds returns sorted ids of organizations which user is a member of.
+func (con *Controller) listMemberOrgIds(userId int) ([]int, error) {
+	memberList, err := con.Repo.ListMemberByUser(userId)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list memberships of userId:%d", userId)
+	}
+
+	res := make([]int, 0)
+	for i := range memberList {
+		res = append(res, memberList[i].OrgId)
+	}
+	sort.Ints(res)
+
+	return res, nil
+}
+
+// ************************************************** //
+// ************ PipelineTemplate related ************ //
+// ************************************************** //
+
+// listPipelineTemplate returns all pipeline templates, templates are shared by all organizations.
+func (con *Controller) listPipelineTemplate(userId int) ([]*PipelineTemplate, error) {
+	if err := con.authorizeAny(userId, PermTemplateList); err != nil {
+		return nil, err
+	}
+
+	res := make([]*PipelineTemplate, 0)
+
+	templateListFromRepo, err := con.Repo.ListPipelineTemplate()
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to list pipeline templates")
+	}
+
+	for i := range templateListFromRepo {
+		// convert to API model
+		res = append(res, convertPipelineTemplate(templateListFromRepo[i]))
+	}
+
+	return res, nil
+}
+
+// ******************************************** //
+// ************** Lookup helpers ************** //
+// ******************************************** //
+
+func (con *Controller) findOrg(orgId int) (*repository.Org, error) {
+	org, err := con.Repo.GetOrg(orgId)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.OrgFailedToGetMsg, orgId)
+	}
+	if org == nil {
+		return nil, repository.NewNotFoundf(repository.OrgNotFoundMsg, orgId)
+	}
+
+	return org, nil
+}
+
+func (con *Controller) findProj(projId int) (*repository.Proj, error) {
+	proj, err := con.Repo.GetProj(projId)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.ProjFailedToGetMsg, projId)
+	}
+	if proj == nil {
+		return nil, repository.NewNotFoundf(repository.ProjNotFoundMsg, projId)
+	}
+
+	return proj, nil
+}
+
+func (con *Controller) findSource(sourceId int) (*repository.Source, error) {
+	src, err := con.Repo.GetSource(sourceId)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.SourceFailedToGetMsg, sourceId)
+	}
+	if src == nil {
+		return nil, repository.NewNotFoundf(repository.SourceNotFoundMsg, sourceId)
+	}
+
+	return src, nil
+}
+
+func (con *Controller) findUser(userId int) (*repository.User, error) {
+	user, err := con.Repo.GetUser(userId)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.UserFailedToGetMsg, userId)
+	}
+	if user == nil {
+		return nil, repository.NewNotFoundf(repository.UserNotFoundMsg, userId)
+	}
+
+	return user, nil
+}
+
+func (con *Controller) findMember(orgId, userId int) (*repository.Member, error) {
+	member, err := con.Repo.GetMember(orgId, userId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, repository.NewNotFoundf(repository.MemberNotFoundMsg, orgId, userId)
+	}
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.MemberFailedToGetMsg, orgId, userId)
+	}
+
+	return member, nil
+}
+
+// findUserAccessToken returns access token of user with type, token would be refreshed if expired.
+func (con *Controller) findUserAccessToken(userId int, tokenType string) (*repository.AccessToken, error) {
+	token, err := con.Repo.GetAccessToken(userId, tokenType)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && token == nil) {
+		return nil, repository.NewNotFoundf(repository.AccessTokenNotFoundMsg, tokenType, userId)
+	}
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.AccessTokenFailedToGetMsg, tokenType, userId)
+	}
+
+	return con.refreshAccessToken(token)
+}
+
+// findAccessToken returns access token of user who linked identity with login in VCS, token would be refreshed if expired.
+func (con *Controller) findAccessToken(repoType, repoUser string) (*repository.AccessToken, error) {
+	identity, err := con.Repo.GetIdentity(repoType, repoUser)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.IdentityFailedToGetMsg, repoType, repoUser)
+	}
+	if identity == nil {
+		return nil, repository.NewNotFoundf(repository.IdentityNotFoundMsg, repoType, repoUser)
+	}
+
+	token, err := con.Repo.GetAccessToken(identity.UserId, repoType)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.AccessTokenFailedToGetMsg, repoType, identity.UserId)
+	}
+	if token == nil {
+		return nil, repository.NewNotFoundf(repository.AccessTokenNotFoundMsg, repoType, identity.UserId)
+	}
+
+	return con.refreshAccessToken(token)
+}
@@ -0,0 +1,267 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"golang.org/x/oauth2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SessionCookieName is name of cookie which carries session issued after oauth login
+	SessionCookieName = "ws_session"
+	// SessionIssuer is issuer of session tokens
+	SessionIssuer = "workstation"
+	// SessionTtlDefault is default duration before session expired
+	SessionTtlDefault = 24 * time.Hour
+	// sessionKey is key of session in context
+	sessionKey = "ws-session"
+	// bearerPrefix is prefix of Authorization header
+	bearerPrefix = "Bearer "
+)
+
+var (
+	// AuthIgnorePrefixDefault contains paths which could be accessed without session,
+	// including health check, swagger, oauth and webhook APIs.
+	AuthIgnorePrefixDefault = []string{
+		"/rk/v1/healthy",
+		"/rk/v1/assets",
+		"/sw",
+		"/v1/oauth",
+		"/v1/session/login",
+		"/v1/webhook",
+	}
+)
+
+// Session is the identity of user who signed in with one of linked identities, it is signed as JWT.
+//
+// Subject of claims is id of user, Source and Login describe the identity used to sign in.
+// Sessions authenticated with personal tokens are limited by Scopes, sessions with nil Scopes are not limited.
+type Session struct {
+	jwt.StandardClaims
+	Source string   `json:"src"`
+	Login  string   `json:"login"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// UserId returns id of user in session.
+func (s *Session) UserId() int {
+	id, _ := strconv.Atoi(s.Subject)
+	return id
+}
+
+// StartSession issues a signed session for user signed in with identity of source and set it into cookie.
+// The same token could be used as bearer token in Authorization header.
+func (con *Controller) StartSession(ctx *gin.Context, userId int, source, login string) (string, error) {
+	token, session, err := con.IssueSession(userId, source, login)
+	if err != nil {
+		return "", err
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(SessionCookieName, token, int(time.Until(time.Unix(session.ExpiresAt, 0)).Seconds()),
+		"/", "", ctx.Request.TLS != nil, true)
+
+	return token, nil
+}
+
+// IssueSession signs a session for user signed in with identity of source.
+func (con *Controller) IssueSession(userId int, source, login string) (string, *Session, error) {
+	now := time.Now()
+
+	session := &Session{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    SessionIssuer,
+			Subject:   strconv.Itoa(userId),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(con.SessionTtl).Unix(),
+		},
+		Source: source,
+		Login:  login,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, session).SignedString(con.SessionSecret)
+	if err != nil {
+		return "", nil, repository.Wrapf(err, repository.CodeInternal, "failed to sign session")
+	}
+
+	return token, session, nil
+}
+
+// ParseSession verifies signature and expiration of token and returns session in it.
+func (con *Controller) ParseSession(token string) (*Session, error) {
+	session := &Session{}
+
+	_, err := jwt.ParseWithClaims(token, session, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method:%s", t.Header["alg"])
+		}
+		return con.SessionSecret, nil
+	})
+	if err != nil {
+		return nil, repository.NewUnauthenticatedf("invalid session, %v", err)
+	}
+
+	if !session.VerifyIssuer(SessionIssuer, true) || session.UserId() < 1 {
+		return nil, repository.NewUnauthenticatedf("invalid session")
+	}
+
+	return session, nil
+}
+
+// AuthInterceptor requires valid session on every request except paths with prefix in AuthIgnorePrefix of controller.
+//
+// Session is read from Authorization header with Bearer scheme first, and then cookie. Bearer token could be
+// personal token as well, which is limited by scopes granted to it. Handlers could get current session with GetSession().
+func AuthInterceptor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		controller := GetController()
+
+		for _, prefix := range controller.AuthIgnorePrefix {
+			if strings.HasPrefix(ctx.Request.URL.Path, prefix) {
+				ctx.Next()
+				return
+			}
+		}
+
+		session, err := controller.LookupSession(ctx)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		// routes which are not found would be reported by router
+		if route := ctx.FullPath(); len(route) > 0 {
+			if err := checkScope(session, routeScopeOf(ctx.Request.Method, route)); err != nil {
+				abortWithError(ctx, err)
+				return
+			}
+		}
+
+		ctx.Set(sessionKey, session)
+		ctx.Next()
+	}
+}
+
+// LookupSession reads session from Authorization header with Bearer scheme first, and then cookie.
+// It could be used by APIs which are not guarded by AuthInterceptor, like oauth callbacks.
+func (con *Controller) LookupSession(ctx *gin.Context) (*Session, error) {
+	token := ctx.GetHeader("Authorization")
+	if strings.HasPrefix(token, bearerPrefix) {
+		token = strings.TrimPrefix(token, bearerPrefix)
+	} else {
+		token, _ = ctx.Cookie(SessionCookieName)
+	}
+
+	if len(token) < 1 {
+		return nil, repository.NewUnauthenticatedf("missing session, please login with oauth first")
+	}
+
+	return con.sessionOf(token)
+}
+
+// SignIn signs in user with identity and starts session.
+//
+// Identity would be linked to user in current session if present, otherwise, user who linked the identity
+// would be signed in. A new user would be created with profile of identity if nobody linked it.
+// Access token of VCS would be stored for the user together with refresh token and expiration if not nil.
+func (con *Controller) SignIn(ctx *gin.Context, identity *repository.Identity, accessToken *oauth2.Token) (*repository.User, error) {
+	// 1: find user who linked the identity
+	linked, err := con.Repo.GetIdentity(identity.Type, identity.Login)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.IdentityFailedToGetMsg, identity.Type, identity.Login)
+	}
+
+	// 2: find user to sign in, link identity to user in current session if present
+	var user *repository.User
+	current, _ := con.LookupSession(ctx)
+	switch {
+	case current != nil:
+		if linked != nil && linked.UserId != current.UserId() {
+			return nil, repository.NewAlreadyExistf(repository.IdentityAlreadyLinkedMsg, identity.Type, identity.Login)
+		}
+		user, err = con.findUser(current.UserId())
+	case linked != nil:
+		user, err = con.findUser(linked.UserId)
+	default:
+		user, err = con.createUser(identity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 3: link identity
+	if err := con.linkIdentity(user, identity); err != nil {
+		return nil, err
+	}
+
+	// 4: store access token for user
+	if accessToken != nil {
+		token := NewAccessTokenFromOauth(user.Id, identity.Type, identity.Login, accessToken)
+		if _, err := con.Repo.UpsertAccessToken(token); err != nil {
+			return nil, repository.Wrapf(err, repository.CodeInternal, "failed to store access token of %s", identity.Type)
+		}
+	}
+
+	// 5: start session
+	if _, err := con.StartSession(ctx, user.Id, identity.Type, identity.Login); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Clear session cookie of current client.
+func clearSession(ctx *gin.Context) {
+	ctx.SetCookie(SessionCookieName, "", -1, "/", "", ctx.Request.TLS != nil, true)
+}
+
+// GetSession returns session of current request set by AuthInterceptor, nil would be returned if missing.
+func GetSession(ctx *gin.Context) *Session {
+	if raw, ok := ctx.Get(sessionKey); ok {
+		return raw.(*Session)
+	}
+
+	return nil
+}
+
+// Returns session of current request or attach an unauthenticated error to context.
+func mustGetSession(ctx *gin.Context) (*Session, bool) {
+	session := GetSession(ctx)
+	if session == nil {
+		ctx.Error(repository.NewUnauthenticatedf("missing session, please login with oauth first"))
+		return nil, false
+	}
+
+	return session, true
+}
+
+// Returns id of user in session of current request or attach an unauthenticated error to context.
+func mustGetUserId(ctx *gin.Context) (int, bool) {
+	session, ok := mustGetSession(ctx)
+	if !ok {
+		return 0, false
+	}
+
+	return session.UserId(), true
+}
+
+// Generate random secret while secret of session was not configured.
+func randomSessionSecret() []byte {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	return secret
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/rookie-ninja/rk-gin/boot"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestController_IssueAndParseSession(t *testing.T) {
+	controller := RegisterController(WithSessionSecret([]byte("ut-secret")), WithSessionTtl(time.Hour))
+
+	// happy case
+	token, session, err := controller.IssueSession(1, "github", "ut-user")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, session.UserId())
+
+	parsed, err := controller.ParseSession(token)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, parsed.UserId())
+	assert.Equal(t, "github", parsed.Source)
+	assert.Equal(t, "ut-user", parsed.Login)
+
+	// signed with another secret
+	other := RegisterController(WithSessionSecret([]byte("ut-other-secret")))
+	_, err = other.ParseSession(token)
+	assert.NotNil(t, err)
+
+	// expired
+	expired, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &Session{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    SessionIssuer,
+			Subject:   "1",
+			ExpiresAt: time.Now().Add(-time.Minute).Unix(),
+		},
+	}).SignedString([]byte("ut-secret"))
+	_, err = controller.ParseSession(expired)
+	assert.NotNil(t, err)
+
+	// none algorithm
+	none, _ := jwt.NewWithClaims(jwt.SigningMethodNone, &Session{
+		StandardClaims: jwt.StandardClaims{Issuer: SessionIssuer, Subject: "1"},
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	_, err = controller.ParseSession(none)
+	assert.NotNil(t, err)
+
+	// without user id
+	invalid, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &Session{
+		StandardClaims: jwt.StandardClaims{Issuer: SessionIssuer, Subject: "ut-user"},
+	}).SignedString([]byte("ut-secret"))
+	_, err = controller.ParseSession(invalid)
+	assert.NotNil(t, err)
+}
+
+func TestRegisterController_WithRandomSecret(t *testing.T) {
+	controller := RegisterController()
+	assert.Len(t, controller.SessionSecret, 32)
+	assert.Equal(t, SessionTtlDefault, controller.SessionTtl)
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+
+	// 1: without session
+	resp := doRequestWithHeaders(router, http.MethodGet, "/v1/org", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	// 2: with invalid bearer token
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/org", "", map[string]string{"Authorization": bearerPrefix + "invalid"})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	// 3: with bearer token
+	token := newSessionToken(1, "github", "ut-user")
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/org", "", map[string]string{"Authorization": bearerPrefix + token})
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// 4: with cookie
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/session", "", map[string]string{"Cookie": SessionCookieName + "=" + token})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	session := &GetSessionResponse{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), session))
+	assert.Equal(t, 1, session.UserId)
+	assert.Equal(t, "github", session.Source)
+	assert.Equal(t, "ut-user", session.Login)
+
+	// 5: ignored paths
+	router.GET("/v1/oauth/ut", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/oauth/ut", "", nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// 6: logout
+	resp = doRequestWithHeaders(router, http.MethodDelete, "/v1/session", "", map[string]string{"Authorization": bearerPrefix + token})
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Contains(t, resp.Header().Get("Set-Cookie"), SessionCookieName+"=;")
+}
+
+func TestAuthInterceptor_WithPrometheus(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	entry := rkgin.RegisterGinEntry(rkgin.WithNameGin("workstation"),
+		rkgin.WithPromEntryGin(rkgin.NewPromEntry(rkgin.WithPathProm("ut-metrics"))))
+	repository.RegisterMemory()
+	RegisterController()
+	initInterceptors()
+	entry.Router.GET("/ut-metrics", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	// path of prometheus is scraped without session, other paths still require it
+	assert.Equal(t, http.StatusOK, doRequestWithHeaders(entry.Router, http.MethodGet, "/ut-metrics", "", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, doRequestWithHeaders(entry.Router, http.MethodGet, "/v1/org", "", nil).Code)
+}
+
+func TestListUserInstallations_WithSession(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+	RegisterSourceType("github")
+	RegisterSourceType("gitlab")
+
+	// source without installations
+	token := newSessionToken(1, "github", "ut-user")
+	resp := doRequestWithHeaders(router, http.MethodGet, "/v1/user/installations?source=gitlab", "", map[string]string{"Authorization": bearerPrefix + token})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// user from query would be ignored, access token of user in session is missing
+	resp = doRequestWithHeaders(router, http.MethodGet, "/v1/user/installations?user=other", "", map[string]string{"Authorization": bearerPrefix + token})
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestController_SignIn(t *testing.T) {
+	repo := repository.RegisterMemory()
+	controller := RegisterController()
+
+	signIn := func(token string, identity *repository.Identity) (*repository.User, string, error) {
+		writer := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(writer)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/v1/oauth/callback/github", nil)
+		if len(token) > 0 {
+			ctx.Request.Header.Set("Authorization", bearerPrefix+token)
+		}
+		user, err := controller.SignIn(ctx, identity, (&oauth2.Token{
+			AccessToken:  "ut-token",
+			RefreshToken: "ut-refresh-token",
+			Expiry:       time.Now().Add(time.Hour),
+		}).WithExtra(map[string]interface{}{"scope": "repo,read:user"}))
+		return user, writer.Header().Get("Set-Cookie"), err
+	}
+
+	// 1: user would be created with profile of identity
+	identity := repository.NewIdentity(repository.IdentityGithub, "ut-login")
+	identity.Name = "ut-name"
+	user, cookie, err := signIn("", identity)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-name", user.Name)
+	assert.Contains(t, cookie, SessionCookieName)
+	token, err := repo.GetAccessToken(user.Id, repository.IdentityGithub)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-token", token.Token)
+	assert.Equal(t, "ut-refresh-token", token.RefreshToken)
+	assert.Equal(t, "repo read:user", token.Scopes)
+	assert.NotNil(t, token.ExpiredAt)
+
+	// 2: the same user would sign in with linked identity
+	again, _, err := signIn("", repository.NewIdentity(repository.IdentityGithub, "ut-login"))
+	assert.Nil(t, err)
+	assert.Equal(t, user.Id, again.Id)
+
+	// 3: identity would be linked to user in session
+	session := newSessionToken(user.Id, repository.IdentityGithub, "ut-login")
+	linked, _, err := signIn(session, repository.NewIdentity(repository.IdentityGitlab, "ut-login"))
+	assert.Nil(t, err)
+	assert.Equal(t, user.Id, linked.Id)
+	assert.Len(t, linked.Identities, 2)
+
+	// 4: another identity of the same type could not be linked
+	_, _, err = signIn(session, repository.NewIdentity(repository.IdentityGithub, "ut-other"))
+	assert.True(t, errors.Is(err, repository.ErrConflict))
+
+	// 5: identity linked to another user could not be linked
+	other, _, _ := signIn("", repository.NewIdentity(repository.IdentityGithub, "ut-other"))
+	assert.NotEqual(t, user.Id, other.Id)
+	_, _, err = signIn(newSessionToken(other.Id, repository.IdentityGithub, "ut-other"),
+		repository.NewIdentity(repository.IdentityGitlab, "ut-login"))
+	assert.True(t, errors.Is(err, repository.ErrAlreadyExist))
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"golang.org/x/oauth2"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenRefreshSkew is duration before expiration while access tokens would be refreshed,
+// so that tokens would not expire during upstream calls.
+const TokenRefreshSkew = time.Minute
+
+var (
+	// tokenProviders contains providers registered with RegisterTokenProvider()
+	tokenProviders      = make(map[string]TokenProvider)
+	tokenProvidersMutex = sync.RWMutex{}
+
+	// refreshLocks serializes refreshing of access tokens with the same user and type
+	refreshLocks = newKeyedMutex()
+)
+
+// TokenProvider refreshes and revokes access tokens issued by oauth provider, like github.
+// It is implemented by oauth entry which holds client secrets of providers.
+type TokenProvider interface {
+	// Refresh exchanges refresh token for a new token
+	Refresh(ctx context.Context, token *repository.AccessToken) (*oauth2.Token, error)
+	// Revoke revokes token upstream, tokens which are invalid already should not be reported as error
+	Revoke(ctx context.Context, token *repository.AccessToken) error
+}
+
+// RegisterTokenProvider registers provider of access tokens with type, like github.
+func RegisterTokenProvider(tokenType string, provider TokenProvider) {
+	tokenProvidersMutex.Lock()
+	defer tokenProvidersMutex.Unlock()
+
+	tokenProviders[strings.ToLower(tokenType)] = provider
+}
+
+// GetTokenProvider returns provider of access tokens with type, nil would be returned if missing.
+func GetTokenProvider(tokenType string) TokenProvider {
+	tokenProvidersMutex.RLock()
+	defer tokenProvidersMutex.RUnlock()
+
+	return tokenProviders[strings.ToLower(tokenType)]
+}
+
+// NewAccessTokenFromOauth converts token issued by oauth provider into access token of user.
+func NewAccessTokenFromOauth(userId int, tokenType, login string, token *oauth2.Token) *repository.AccessToken {
+	res := repository.NewAccessToken(userId, tokenType, login, token.AccessToken)
+	updateAccessToken(res, token)
+
+	return res
+}
+
+// Copy values of token issued by oauth provider into access token.
+func updateAccessToken(accessToken *repository.AccessToken, token *oauth2.Token) {
+	accessToken.Token = token.AccessToken
+
+	// refresh token would not be returned while refreshing with some providers, keep the old one
+	if len(token.RefreshToken) > 0 {
+		accessToken.RefreshToken = token.RefreshToken
+	}
+
+	accessToken.ExpiredAt = nil
+	if !token.Expiry.IsZero() {
+		expiredAt := token.Expiry
+		accessToken.ExpiredAt = &expiredAt
+	}
+
+	// github returns scopes with comma separated, while RFC 6749 uses space
+	if scope, ok := token.Extra("scope").(string); ok {
+		accessToken.Scopes = strings.Join(strings.FieldsFunc(scope, func(r rune) bool {
+			return r == ',' || r == ' '
+		}), " ")
+	}
+}
+
+// refreshAccessToken refreshes access token through token provider if it is about to expire.
+// Tokens which could not be refreshed are reported as unauthenticated, so that users could login again.
+//
+// Refreshing of the same user and type is serialized, since providers like gitlab rotate refresh tokens,
+// concurrent refreshing with the same refresh token would revoke tokens of each other.
+func (con *Controller) refreshAccessToken(token *repository.AccessToken) (*repository.AccessToken, error) {
+	if !token.ExpiresWithin(TokenRefreshSkew) {
+		return token, nil
+	}
+
+	// 1: refresh token is required
+	provider := GetTokenProvider(token.Type)
+	if len(token.RefreshToken) < 1 || provider == nil {
+		return nil, repository.NewUnauthenticatedf("access token of %s expired, please login with %s again", token.Type, token.Type)
+	}
+
+	unlock := refreshLocks.lock(fmt.Sprintf("%d:%s", token.UserId, strings.ToLower(token.Type)))
+	defer unlock()
+
+	// 2: read stored token again, it might be refreshed while waiting for the lock
+	stored, err := con.Repo.GetAccessToken(token.UserId, token.Type)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && stored == nil) {
+		return nil, repository.NewUnauthenticatedf("access token of %s was removed, please login with %s again", token.Type, token.Type)
+	}
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, repository.AccessTokenFailedToGetMsg, token.Type, token.UserId)
+	}
+	if !stored.ExpiresWithin(TokenRefreshSkew) {
+		return stored, nil
+	}
+	token = stored
+
+	// 3: refresh upstream
+	refreshed, err := provider.Refresh(context.Background(), token)
+	if err != nil {
+		retrieveErr := &oauth2.RetrieveError{}
+		if errors.As(err, &retrieveErr) {
+			return nil, repository.NewUnauthenticatedf("failed to refresh access token of %s, please login with %s again", token.Type, token.Type)
+		}
+		return nil, repository.NewUpstreamf(err, "failed to refresh access token of %s", token.Type)
+	}
+
+	// 4: store refreshed token
+	updateAccessToken(token, refreshed)
+	if _, err := con.Repo.UpsertAccessToken(token); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to store refreshed access token of %s", token.Type)
+	}
+
+	return token, nil
+}
+
+// keyedMutex is a set of mutexes identified by keys, mutexes are removed once nobody holds or waits for them.
+type keyedMutex struct {
+	mutex   sync.Mutex
+	entries map[string]*keyedMutexEntry
+}
+
+// keyedMutexEntry is a mutex with count of holders and waiters.
+type keyedMutexEntry struct {
+	sync.Mutex
+	refs int
+}
+
+// Create an empty keyedMutex.
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{entries: make(map[string]*keyedMutexEntry)}
+}
+
+// Lock mutex of key and returns function which unlocks it.
+func (m *keyedMutex) lock(key string) func() {
+	m.mutex.Lock()
+	entry, ok := m.entries[key]
+	if !ok {
+		entry = &keyedMutexEntry{}
+		m.entries[key] = entry
+	}
+	entry.refs++
+	m.mutex.Unlock()
+
+	entry.Lock()
+
+	return func() {
+		entry.Unlock()
+
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		if entry.refs--; entry.refs < 1 {
+			delete(m.entries, key)
+		}
+	}
+}
+
+// RevokeAccessToken revokes access token of user upstream and removes it from repository.
+// Token would be kept if it failed to revoke upstream, so that user could try again.
+func (con *Controller) RevokeAccessToken(userId int, tokenType string) error {
+	// 1: get token from repository
+	token, err := con.Repo.GetAccessToken(userId, tokenType)
+	if errors.Is(err, repository.ErrNotFound) {
+		return repository.NewNotFoundf(repository.AccessTokenNotFoundMsg, tokenType, userId)
+	}
+	if err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, repository.AccessTokenFailedToGetMsg, tokenType, userId)
+	}
+
+	// 2: revoke upstream
+	provider := GetTokenProvider(tokenType)
+	if provider == nil {
+		return repository.NewInvalidArgumentf("unsupported oauth provider:%s", tokenType)
+	}
+	if err := provider.Revoke(context.Background(), token); err != nil {
+		return repository.NewUpstreamf(err, "failed to revoke access token of %s", tokenType)
+	}
+
+	// 3: remove from repository
+	if _, err := con.Repo.RemoveAccessToken(userId, tokenType); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to remove %s access token of userId:%d", tokenType, userId)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeTokenProvider struct {
+	refreshErr error
+	revoked    []string
+	refreshed  int32
+	// refreshing would be closed by the first refreshing, which waits for release before returning if not nil
+	refreshing chan struct{}
+	release    chan struct{}
+}
+
+func (p *fakeTokenProvider) Refresh(ctx context.Context, token *repository.AccessToken) (*oauth2.Token, error) {
+	if atomic.AddInt32(&p.refreshed, 1) == 1 && p.release != nil {
+		close(p.refreshing)
+		<-p.release
+	}
+
+	if p.refreshErr != nil {
+		return nil, p.refreshErr
+	}
+
+	return &oauth2.Token{
+		AccessToken: "ut-refreshed-token",
+		Expiry:      time.Now().Add(time.Hour),
+	}, nil
+}
+
+func (p *fakeTokenProvider) Revoke(ctx context.Context, token *repository.AccessToken) error {
+	p.revoked = append(p.revoked, token.Token)
+	return nil
+}
+
+func TestController_RefreshAccessToken(t *testing.T) {
+	repo := repository.RegisterMemory()
+	controller := RegisterController()
+	provider := &fakeTokenProvider{}
+	RegisterTokenProvider("ut-vcs", provider)
+
+	// 1: tokens without expiration would not be refreshed
+	token := repository.NewAccessToken(1, "ut-vcs", "ut-login", "ut-token")
+	res, err := controller.refreshAccessToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-token", res.Token)
+
+	// 2: expired tokens without refresh token are unauthenticated
+	expiredAt := time.Now().Add(-time.Minute)
+	token.ExpiredAt = &expiredAt
+	_, err = controller.refreshAccessToken(token)
+	assert.True(t, errors.Is(err, repository.ErrUnauthenticated))
+
+	// 3: expired tokens would be refreshed and stored
+	token.RefreshToken = "ut-refresh-token"
+	repo.UpsertAccessToken(token)
+	res, err = controller.refreshAccessToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-refreshed-token", res.Token)
+	assert.Equal(t, "ut-refresh-token", res.RefreshToken)
+	assert.False(t, res.ExpiresWithin(TokenRefreshSkew))
+	stored, _ := repo.GetAccessToken(1, "ut-vcs")
+	assert.Equal(t, "ut-refreshed-token", stored.Token)
+
+	// 4: refresh token rejected by provider
+	token.ExpiredAt = &expiredAt
+	provider.refreshErr = &oauth2.RetrieveError{}
+	_, err = controller.refreshAccessToken(token)
+	assert.True(t, errors.Is(err, repository.ErrUnauthenticated))
+}
+
+func TestController_RefreshAccessToken_WithConcurrentRefreshing(t *testing.T) {
+	repo := repository.RegisterMemory()
+	controller := RegisterController()
+	provider := &fakeTokenProvider{refreshing: make(chan struct{}), release: make(chan struct{})}
+	RegisterTokenProvider("ut-concurrent-vcs", provider)
+
+	expiredAt := time.Now().Add(-time.Minute)
+	token := repository.NewAccessToken(1, "ut-concurrent-vcs", "ut-login", "ut-token")
+	token.RefreshToken = "ut-refresh-token"
+	token.ExpiredAt = &expiredAt
+	repo.UpsertAccessToken(token)
+
+	results := make(chan string, 2)
+	find := func() {
+		res, err := controller.findUserAccessToken(1, "ut-concurrent-vcs")
+		assert.Nil(t, err)
+		results <- res.Token
+	}
+
+	// 1: the second request waits for the first one which is refreshing upstream
+	go find()
+	<-provider.refreshing
+	go find()
+	time.Sleep(50 * time.Millisecond)
+	close(provider.release)
+
+	// 2: both of them get the refreshed token, while refresh token was used once
+	assert.Equal(t, "ut-refreshed-token", <-results)
+	assert.Equal(t, "ut-refreshed-token", <-results)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&provider.refreshed))
+}
+
+func TestController_RevokeAccessToken(t *testing.T) {
+	repo := repository.RegisterMemory()
+	controller := RegisterController()
+	provider := &fakeTokenProvider{}
+	RegisterTokenProvider("ut-vcs", provider)
+
+	// 1: token not exist
+	err := controller.RevokeAccessToken(1, "ut-vcs")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	// 2: token would be revoked upstream and removed
+	repo.UpsertAccessToken(repository.NewAccessToken(1, "ut-vcs", "ut-login", "ut-token"))
+	assert.Nil(t, controller.RevokeAccessToken(1, "ut-vcs"))
+	assert.Equal(t, []string{"ut-token"}, provider.revoked)
+	_, err = repo.GetAccessToken(1, "ut-vcs")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+}
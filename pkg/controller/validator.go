@@ -0,0 +1,260 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	// slugRegex matches names of organization and project, like my-org_1.0
+	slugRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_.-]*[a-zA-Z0-9])?$`)
+	// repositoryRegex matches repository with format of owner/name
+	repositoryRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+/[a-zA-Z0-9_.-]+$`)
+	// gitlabRepositoryRegex matches project of gitlab with format of group/subgroup/name, subgroups are optional
+	gitlabRepositoryRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+(/[a-zA-Z0-9_.-]+)+$`)
+
+	// sourceTypes contains types of source registered with RegisterSourceType()
+	sourceTypes      = make(map[string]bool)
+	sourceTypesMutex = sync.RWMutex{}
+
+	// identityTypes contains built-in types of identity and types registered with RegisterIdentityType()
+	identityTypes = map[string]bool{
+		repository.IdentityGithub: true,
+		repository.IdentityGitlab: true,
+		repository.IdentityGitea:  true,
+		repository.IdentityLocal:  true,
+	}
+	identityTypesMutex = sync.RWMutex{}
+)
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		// use names from tags in field errors, so that client could find them in request
+		v.RegisterTagNameFunc(fieldNameFromTag)
+
+		v.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+			return slugRegex.MatchString(fl.Field().String())
+		})
+		v.RegisterValidation("sourcetype", func(fl validator.FieldLevel) bool {
+			return IsSourceTypeRegistered(fl.Field().String())
+		})
+		v.RegisterValidation("identitytype", func(fl validator.FieldLevel) bool {
+			return IsIdentityTypeRegistered(fl.Field().String())
+		})
+		// repository of git source is local repository, gitlab projects might be in subgroups,
+		// others are in format of owner/name, host is allowed for github sources only
+		v.RegisterStructValidation(func(sl validator.StructLevel) {
+			req := sl.Current().Interface().(CreateSourceRequest)
+			if len(req.Host) > 0 && (!strings.EqualFold(req.Type, repository.IdentityGithub) || !IsGithubHostRegistered(req.Host)) {
+				sl.ReportError(req.Host, "host", "Host", "githubhost", "")
+			}
+			if len(req.Repository) < 1 {
+				return
+			}
+			if strings.EqualFold(req.Type, SourceTypeGit) {
+				if !isLocalGitRepository(req.Repository) {
+					sl.ReportError(req.Repository, "repository", "Repository", "gitrepository", "")
+				}
+			} else if strings.EqualFold(req.Type, repository.IdentityGitlab) {
+				if !isRepositoryOf(gitlabRepositoryRegex, req.Repository) {
+					sl.ReportError(req.Repository, "repository", "Repository", "repository", "")
+				}
+			} else if !isRepositoryOf(repositoryRegex, req.Repository) {
+				sl.ReportError(req.Repository, "repository", "Repository", "repository", "")
+			}
+		}, CreateSourceRequest{})
+		v.RegisterValidation("csvoneof", func(fl validator.FieldLevel) bool {
+			allowed := strings.Fields(fl.Param())
+			for _, v := range splitCsv(fl.Field().String()) {
+				if !contains(allowed, v) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// Checks whether repo matches regex without segments of . or .., which would escape paths of VCS APIs.
+func isRepositoryOf(regex *regexp.Regexp, repo string) bool {
+	if !regex.MatchString(repo) {
+		return false
+	}
+
+	for _, segment := range strings.Split(repo, "/") {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RegisterSourceType registers type of source, like github.
+// Sources with types not registered will be rejected while validating requests.
+func RegisterSourceType(srcType string) {
+	sourceTypesMutex.Lock()
+	defer sourceTypesMutex.Unlock()
+
+	sourceTypes[strings.ToLower(srcType)] = true
+}
+
+// IsSourceTypeRegistered checks whether type of source was registered.
+func IsSourceTypeRegistered(srcType string) bool {
+	sourceTypesMutex.RLock()
+	defer sourceTypesMutex.RUnlock()
+
+	return sourceTypes[strings.ToLower(srcType)]
+}
+
+// RegisterIdentityType registers type of identity, like name of OpenID Connect provider.
+// Identities with types not registered will be rejected while validating requests.
+func RegisterIdentityType(identityType string) {
+	identityTypesMutex.Lock()
+	defer identityTypesMutex.Unlock()
+
+	identityTypes[strings.ToLower(identityType)] = true
+}
+
+// IsIdentityTypeRegistered checks whether type of identity is built-in or registered.
+func IsIdentityTypeRegistered(identityType string) bool {
+	identityTypesMutex.RLock()
+	defer identityTypesMutex.RUnlock()
+
+	return identityTypes[strings.ToLower(identityType)]
+}
+
+// FieldViolation describes an invalid field in request, it will be returned as details of error.
+type FieldViolation struct {
+	Field   string `yaml:"field" json:"field"`
+	Rule    string `yaml:"rule" json:"rule"`
+	Message string `yaml:"message" json:"message"`
+}
+
+// bindUri binds path params into req and validate it.
+func bindUri(ctx *gin.Context, req interface{}) bool {
+	return handleBindError(ctx, ctx.ShouldBindUri(req))
+}
+
+// bindQuery binds query params into req and validate it.
+func bindQuery(ctx *gin.Context, req interface{}) bool {
+	return handleBindError(ctx, ctx.ShouldBindQuery(req))
+}
+
+// bindJson binds request body into req and validate it.
+func bindJson(ctx *gin.Context, req interface{}) bool {
+	return handleBindError(ctx, ctx.ShouldBindJSON(req))
+}
+
+// bindHeader binds request headers into req and validate it.
+func bindHeader(ctx *gin.Context, req interface{}) bool {
+	return handleBindError(ctx, ctx.ShouldBindHeader(req))
+}
+
+// Convert error returned from binding into InvalidArgument error attached to context.
+func handleBindError(ctx *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+
+	ctx.Error(toInvalidArgument(err))
+	return false
+}
+
+// validate validates req with rules declared in binding tags.
+func validate(req interface{}) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return toInvalidArgument(err)
+	}
+
+	return nil
+}
+
+// Convert error returned from binding or validation into InvalidArgument error with field violations as details.
+func toInvalidArgument(err error) *repository.Error {
+	details := make([]interface{}, 0)
+
+	var fieldErrs validator.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		for i := range fieldErrs {
+			details = append(details, &FieldViolation{
+				Field:   fieldErrs[i].Field(),
+				Rule:    fieldErrs[i].Tag(),
+				Message: violationMessage(fieldErrs[i]),
+			})
+		}
+	} else {
+		details = append(details, err.Error())
+	}
+
+	return repository.NewError(repository.CodeInvalidArgument, "invalid request", details...)
+}
+
+// Make a human readable message of field error.
+func violationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", fe.Field())
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", fe.Field(), fe.Param())
+	case "min":
+		return fmt.Sprintf("%s must not be less than %s", fe.Field(), fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("length of %s must not be greater than %s", fe.Field(), fe.Param())
+		}
+		return fmt.Sprintf("%s must not be greater than %s", fe.Field(), fe.Param())
+	case "slug":
+		return fmt.Sprintf("%s must start and end with letter or digit and contain only letters, digits, '-', '_' and '.'", fe.Field())
+	case "repository":
+		return fmt.Sprintf("%s must be in format of owner/name", fe.Field())
+	case "gitrepository":
+		return fmt.Sprintf("%s must be absolute path or file:// URL of local git repository", fe.Field())
+	case "githubhost":
+		return fmt.Sprintf("%s must be a registered github host and used by github sources only", fe.Field())
+	case "sourcetype":
+		return fmt.Sprintf("%s is not a registered source type", fe.Field())
+	case "identitytype":
+		return fmt.Sprintf("%s is not a registered identity type", fe.Field())
+	case "csvoneof":
+		return fmt.Sprintf("%s must be comma separated values of [%s]", fe.Field(), strings.Join(strings.Fields(fe.Param()), ","))
+	default:
+		return fmt.Sprintf("%s failed on rule %s", fe.Field(), fe.Tag())
+	}
+}
+
+// Returns name of field from tags of json, uri or form.
+func fieldNameFromTag(field reflect.StructField) string {
+	for _, tag := range []string{"json", "uri", "form"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if len(name) > 0 && name != "-" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+// Checks whether s is one of list.
+func contains(list []string, s string) bool {
+	for i := range list {
+		if list[i] == s {
+			return true
+		}
+	}
+
+	return false
+}
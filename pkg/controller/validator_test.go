@@ -0,0 +1,92 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRegisterSourceType(t *testing.T) {
+	assert.False(t, IsSourceTypeRegistered("ut-type"))
+
+	RegisterSourceType("UT-Type")
+	defer func() {
+		sourceTypesMutex.Lock()
+		delete(sourceTypes, "ut-type")
+		sourceTypesMutex.Unlock()
+	}()
+
+	assert.True(t, IsSourceTypeRegistered("ut-type"))
+}
+
+func TestRegisterIdentityType(t *testing.T) {
+	assert.True(t, IsIdentityTypeRegistered("github"))
+	assert.False(t, IsIdentityTypeRegistered("ut-idp"))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&IdentityTypeRequest{Type: "ut-idp"}))
+
+	RegisterIdentityType("UT-IdP")
+	defer func() {
+		identityTypesMutex.Lock()
+		delete(identityTypes, "ut-idp")
+		identityTypesMutex.Unlock()
+	}()
+
+	assert.True(t, IsIdentityTypeRegistered("ut-idp"))
+	assert.Nil(t, binding.Validator.ValidateStruct(&IdentityTypeRequest{Type: "ut-idp"}))
+}
+
+func TestSlugValidation(t *testing.T) {
+	assert.Nil(t, binding.Validator.ValidateStruct(&UpdateOrgRequest{Name: "ut-org_1.0"}))
+	assert.Nil(t, binding.Validator.ValidateStruct(&UpdateOrgRequest{Name: "a"}))
+
+	assert.NotNil(t, binding.Validator.ValidateStruct(&UpdateOrgRequest{Name: ""}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&UpdateOrgRequest{Name: "ut org"}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&UpdateOrgRequest{Name: "-ut-org"}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&UpdateOrgRequest{Name: "ut-org."}))
+
+	// names are required while creating with v1 APIs
+	assert.Nil(t, binding.Validator.ValidateStruct(&CreateOrgRequest{OrgName: "ut-org"}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&CreateOrgRequest{OrgName: ""}))
+	assert.Nil(t, binding.Validator.ValidateStruct(&CreateProjRequest{OrgId: 1, Name: "ut-proj"}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&CreateProjRequest{OrgId: 1, Name: ""}))
+}
+
+func TestRepositoryValidation(t *testing.T) {
+	RegisterSourceType("github")
+
+	assert.Nil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "github", Repository: "ut-owner/ut-repo"}))
+
+	assert.NotNil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "github", Repository: "ut-repo"}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "github", Repository: "ut-owner/ut-repo/ut"}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "ut-type", Repository: "ut-owner/ut-repo"}))
+
+	// segments of . or .. would escape paths of VCS APIs, dots inside names are still allowed
+	assert.Nil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "github", Repository: "ut-owner/.ut-repo"}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "github", Repository: "../.."}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "github", Repository: "./ut-repo"}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "github", Repository: "ut-owner/.."}))
+}
+
+func TestRepositoryValidation_WithGitlabSubgroups(t *testing.T) {
+	RegisterSourceType("gitlab")
+
+	assert.Nil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "gitlab", Repository: "ut-group/ut-repo"}))
+	assert.Nil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "gitlab", Repository: "ut-group/ut-sub/ut-repo"}))
+
+	assert.NotNil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "gitlab", Repository: "ut-repo"}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "gitlab", Repository: "ut-group//ut-repo"}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "gitlab", Repository: "ut-group/ut-repo/"}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "gitlab", Repository: "ut-group/../ut-repo"}))
+	assert.NotNil(t, binding.Validator.ValidateStruct(&CreateSourceRequest{Type: "gitlab", Repository: "ut-group/./ut-repo"}))
+}
+
+func TestFieldNameFromTag(t *testing.T) {
+	err := binding.Validator.ValidateStruct(&ListCommitsRequest{PerPage: 1000})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "perPage")
+}
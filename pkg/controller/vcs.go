@@ -0,0 +1,121 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// vcsClient is used by providers which call REST API of remote code repositories directly
+var vcsClient = &http.Client{Timeout: 30 * time.Second}
+
+var (
+	// vcsProviders contains providers registered with RegisterVCSProvider(), github is built in
+	vcsProviders = map[string]VCSProvider{
+		repository.IdentityGithub: &GithubProvider{},
+	}
+	vcsProvidersMutex = sync.RWMutex{}
+)
+
+// VCSProvider accesses remote code repositories of sources with type of provider, like github.
+// Credentials of source are resolved by provider itself, with access token of user or token of app.
+type VCSProvider interface {
+	// ListInstallations returns installations accessible by user, repositories of them are not listed
+	ListInstallations(ctx context.Context, userId int) ([]*Installation, error)
+	// ListRepos returns repositories of installation accessible by user
+	ListRepos(ctx context.Context, userId int, installation *Installation) ([]*Repository, error)
+	// GetRepository returns repository of source with id and default branch, NotFound would be returned if it is missing
+	GetRepository(ctx context.Context, src *repository.Source) (*Repository, error)
+	// ListCommits returns page of commits of branch, default branch would be used if branch is empty
+	ListCommits(ctx context.Context, src *repository.Source, branch string, perPage, page int) ([]*Commit, *Pagination, error)
+	// ListBranches returns page of names of branches
+	ListBranches(ctx context.Context, src *repository.Source, perPage, page int) ([]string, *Pagination, error)
+	// ListTags returns page of names of tags
+	ListTags(ctx context.Context, src *repository.Source, perPage, page int) ([]string, *Pagination, error)
+	// GetFileContent returns content of file at ref, NotFound would be returned if file is missing
+	GetFileContent(ctx context.Context, src *repository.Source, ref, path string) ([]byte, error)
+}
+
+// RegisterVCSProvider registers provider of remote code repositories with type of source, like github.
+func RegisterVCSProvider(srcType string, provider VCSProvider) {
+	vcsProvidersMutex.Lock()
+	defer vcsProvidersMutex.Unlock()
+
+	vcsProviders[strings.ToLower(srcType)] = provider
+}
+
+// GetVCSProvider returns provider of remote code repositories with type of source, nil would be returned if missing.
+func GetVCSProvider(srcType string) VCSProvider {
+	vcsProvidersMutex.RLock()
+	defer vcsProvidersMutex.RUnlock()
+
+	return vcsProviders[strings.ToLower(srcType)]
+}
+
+// Returns provider of source type, error would be returned if missing.
+func vcsProviderOf(srcType string) (VCSProvider, error) {
+	provider := GetVCSProvider(srcType)
+	if provider == nil {
+		return nil, repository.NewInvalidArgumentf("unrecognized source type:%s", srcType)
+	}
+
+	return provider, nil
+}
+
+// getVCSResource sends GET request to REST API of remote code repository with authorization header,
+// body of response would be decoded into out as JSON, or copied into out as it is if out is *[]byte.
+// NotFound would be returned if resource is missing, Upstream would be returned if request failed.
+func getVCSResource(ctx context.Context, rawUrl, authorization string, out interface{}) error {
+	_, err := getVCSResourceWithHeader(ctx, rawUrl, authorization, out)
+	return err
+}
+
+// getVCSResourceWithHeader is the same as getVCSResource, header of response would be returned as well,
+// which carries pagination of list like Link and X-Total.
+func getVCSResourceWithHeader(ctx context.Context, rawUrl, authorization string, out interface{}) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "invalid url:%s", rawUrl)
+	}
+	req.Header.Set("Accept", "application/json")
+	if len(authorization) > 0 {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := vcsClient.Do(req)
+	if err != nil {
+		return nil, repository.NewUpstreamf(err, "failed to get %s", req.URL.Path)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, repository.NewNotFoundf("%s not found", req.URL.Path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, repository.NewUpstreamf(fmt.Errorf("unexpected status %d", resp.StatusCode), "failed to get %s", req.URL.Path)
+	}
+
+	if bytes, ok := out.(*[]byte); ok {
+		if *bytes, err = ioutil.ReadAll(resp.Body); err != nil {
+			return nil, repository.NewUpstreamf(err, "failed to read %s", req.URL.Path)
+		}
+		return resp.Header, nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, repository.NewUpstreamf(err, "failed to decode %s", req.URL.Path)
+	}
+
+	return resp.Header, nil
+}
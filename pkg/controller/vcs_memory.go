@@ -0,0 +1,232 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MemoryVCSProvider is an in-memory provider of remote code repositories, which is used in unit tests.
+// Repositories are keyed with format of owner/repo, the first branch put into repository is the default branch.
+type MemoryVCSProvider struct {
+	installations map[int][]*Installation
+	repos         map[string]*memoryRepo
+	mutex         sync.RWMutex
+}
+
+type memoryRepo struct {
+	id            string
+	fullName      string
+	defaultBranch string
+	// commits of branches, latest commit comes first
+	branches map[string][]*Commit
+	// names of branches and tags in order of creation
+	branchNames []string
+	tagNames    []string
+	// contents of files, keyed by ref and path
+	files map[string]map[string][]byte
+}
+
+// NewMemoryVCSProvider creates an empty in-memory provider.
+func NewMemoryVCSProvider() *MemoryVCSProvider {
+	return &MemoryVCSProvider{
+		installations: make(map[int][]*Installation),
+		repos:         make(map[string]*memoryRepo),
+	}
+}
+
+// AddInstallation adds installation accessible by user, repositories of installation are kept as it is.
+func (p *MemoryVCSProvider) AddInstallation(userId int, installation *Installation) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.installations[userId] = append(p.installations[userId], installation)
+}
+
+// PutBranch puts branch into repository with commits, latest commit comes first.
+func (p *MemoryVCSProvider) PutBranch(repo, branch string, commits ...*Commit) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	r := p.repoOf(repo)
+	if _, ok := r.branches[branch]; !ok {
+		r.branchNames = append(r.branchNames, branch)
+	}
+	if len(r.defaultBranch) < 1 {
+		r.defaultBranch = branch
+	}
+	r.branches[branch] = commits
+}
+
+// PutTag puts tag into repository.
+func (p *MemoryVCSProvider) PutTag(repo, tag string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	r := p.repoOf(repo)
+	r.tagNames = append(r.tagNames, tag)
+}
+
+// PutFile puts file with content into repository at ref.
+func (p *MemoryVCSProvider) PutFile(repo, ref, path string, content []byte) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	r := p.repoOf(repo)
+	if _, ok := r.files[ref]; !ok {
+		r.files[ref] = make(map[string][]byte)
+	}
+	r.files[ref][strings.TrimPrefix(path, "/")] = content
+}
+
+// ListInstallations returns installations added for user.
+func (p *MemoryVCSProvider) ListInstallations(ctx context.Context, userId int) ([]*Installation, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	res := make([]*Installation, 0)
+	for _, installation := range p.installations[userId] {
+		copied := *installation
+		copied.Repos = make([]*Repository, 0)
+		res = append(res, &copied)
+	}
+
+	return res, nil
+}
+
+// ListRepos returns repositories of installation added for user.
+func (p *MemoryVCSProvider) ListRepos(ctx context.Context, userId int, installation *Installation) ([]*Repository, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	for _, added := range p.installations[userId] {
+		if added.Id == installation.Id {
+			return append(make([]*Repository, 0), added.Repos...), nil
+		}
+	}
+
+	return nil, repository.NewNotFoundf("installation:%d not found", installation.Id)
+}
+
+// GetRepository returns repository of source, ids of repositories are assigned in order of creation.
+func (p *MemoryVCSProvider) GetRepository(ctx context.Context, src *repository.Source) (*Repository, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	r, err := p.getRepo(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repository{
+		Id:            r.id,
+		FullName:      r.fullName,
+		Name:          r.fullName[strings.LastIndex(r.fullName, "/")+1:],
+		DefaultBranch: r.defaultBranch,
+	}, nil
+}
+
+// ListCommits returns commits of branch, commits of default branch would be returned if branch is empty.
+func (p *MemoryVCSProvider) ListCommits(ctx context.Context, src *repository.Source, branch string, perPage, page int) ([]*Commit, *Pagination, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	r, err := p.getRepo(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(branch) < 1 {
+		branch = r.defaultBranch
+	}
+
+	commits, ok := r.branches[branch]
+	if !ok {
+		return nil, nil, repository.NewNotFoundf("branch:%s not found in %s", branch, src.Repository)
+	}
+
+	start, end := pageOf(len(commits), perPage, page)
+	return append(make([]*Commit, 0), commits[start:end]...), paginationOf(len(commits), perPage, page), nil
+}
+
+// ListBranches returns names of branches in order of creation.
+func (p *MemoryVCSProvider) ListBranches(ctx context.Context, src *repository.Source, perPage, page int) ([]string, *Pagination, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	r, err := p.getRepo(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start, end := pageOf(len(r.branchNames), perPage, page)
+	return append(make([]string, 0), r.branchNames[start:end]...), paginationOf(len(r.branchNames), perPage, page), nil
+}
+
+// ListTags returns names of tags in order of creation.
+func (p *MemoryVCSProvider) ListTags(ctx context.Context, src *repository.Source, perPage, page int) ([]string, *Pagination, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	r, err := p.getRepo(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start, end := pageOf(len(r.tagNames), perPage, page)
+	return append(make([]string, 0), r.tagNames[start:end]...), paginationOf(len(r.tagNames), perPage, page), nil
+}
+
+// GetFileContent returns content of file put at ref, file at default branch would be returned if ref is empty.
+func (p *MemoryVCSProvider) GetFileContent(ctx context.Context, src *repository.Source, ref, path string) ([]byte, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	r, err := p.getRepo(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ref) < 1 {
+		ref = r.defaultBranch
+	}
+
+	content, ok := r.files[ref][strings.TrimPrefix(path, "/")]
+	if !ok {
+		return nil, repository.NewNotFoundf("file:%s not found in %s at ref:%s", path, src.Repository, ref)
+	}
+
+	return content, nil
+}
+
+// Returns repository and creates it if missing, caller must hold write lock.
+func (p *MemoryVCSProvider) repoOf(repo string) *memoryRepo {
+	key := strings.ToLower(repo)
+	if _, ok := p.repos[key]; !ok {
+		p.repos[key] = &memoryRepo{
+			id:       strconv.Itoa(len(p.repos) + 1),
+			fullName: repo,
+			branches: make(map[string][]*Commit),
+			files:    make(map[string]map[string][]byte),
+		}
+	}
+
+	return p.repos[key]
+}
+
+// Returns repository of source, caller must hold read lock.
+func (p *MemoryVCSProvider) getRepo(src *repository.Source) (*memoryRepo, error) {
+	r, ok := p.repos[strings.ToLower(src.Repository)]
+	if !ok {
+		return nil, repository.NewNotFoundf("repository:%s not found", src.Repository)
+	}
+
+	return r, nil
+}
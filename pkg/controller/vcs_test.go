@@ -0,0 +1,150 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestRegisterVCSProvider(t *testing.T) {
+	// github is built in
+	assert.NotNil(t, GetVCSProvider("GitHub"))
+	assert.Nil(t, GetVCSProvider("ut-vcs"))
+
+	provider := NewMemoryVCSProvider()
+	RegisterVCSProvider("UT-VCS", provider)
+	assert.Equal(t, provider, GetVCSProvider("ut-vcs"))
+
+	_, err := vcsProviderOf("ut-unknown")
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+}
+
+func TestMemoryVCSProvider(t *testing.T) {
+	provider := NewMemoryVCSProvider()
+	provider.PutBranch("ut-owner/ut-repo", "main", &Commit{Id: "ut-sha-2"}, &Commit{Id: "ut-sha-1"})
+	provider.PutBranch("ut-owner/ut-repo", "dev")
+	provider.PutFile("ut-owner/ut-repo", "main", "/ws.yaml", []byte("ut-content"))
+	src := repository.NewSource("ut-vcs", "ut-owner/ut-repo")
+	ctx := context.Background()
+
+	// 1: commits of default branch are paged
+	commits, pagination, err := provider.ListCommits(ctx, src, "", 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-sha-1", commits[0].Id)
+	assert.Equal(t, &Pagination{Page: 2, PerPage: 1, LastPage: 2, Total: 2}, pagination)
+	commits, _, err = provider.ListCommits(ctx, src, "", 1, 3)
+	assert.Nil(t, err)
+	assert.Empty(t, commits)
+	_, _, err = provider.ListCommits(ctx, src, "ut-missing", 1, 1)
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	// 2: file contents
+	content, err := provider.GetFileContent(ctx, src, "", "ws.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-content", string(content))
+	_, err = provider.GetFileContent(ctx, src, "dev", "ws.yaml")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	// 3: repository with default branch
+	remote, err := provider.GetRepository(ctx, src)
+	assert.Nil(t, err)
+	assert.Equal(t, &Repository{Id: "1", FullName: "ut-owner/ut-repo", Name: "ut-repo", DefaultBranch: "main"}, remote)
+
+	// 4: repository not exist
+	_, _, err = provider.ListBranches(ctx, repository.NewSource("ut-vcs", "ut-owner/ut-missing"), 10, 1)
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+}
+
+func TestSourceApi_WithVCSProvider(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+	repo := GetController().Repo
+
+	provider := NewMemoryVCSProvider()
+	RegisterVCSProvider("ut-vcs", provider)
+	RegisterSourceType("ut-vcs")
+	provider.AddInstallation(1, &Installation{
+		Id:           1,
+		RepoSource:   "ut-vcs",
+		Organization: "ut-owner",
+		Repos:        []*Repository{{FullName: "ut-owner/ut-repo", Name: "ut-repo"}},
+	})
+	provider.PutBranch("ut-owner/ut-repo", "main", &Commit{Id: "ut-sha"})
+	provider.PutBranch("ut-owner/ut-repo", "dev")
+	provider.PutTag("ut-owner/ut-repo", "v1.0.0")
+	provider.PutTag("ut-owner/ut-repo", "v1.1.0")
+
+	// prepare source of provider
+	org := repository.NewOrg("ut-org")
+	repo.CreateOrg(org)
+	repo.UpsertMember(repository.NewMember(org.Id, 1, repository.RoleViewer))
+	proj := repository.NewProj("ut-proj")
+	proj.OrgId = org.Id
+	repo.CreateProj(proj)
+	src := repository.NewSource("ut-vcs", "ut-owner/ut-repo")
+	src.ProjId = proj.Id
+	repo.CreateSource(src)
+
+	// 1: installations together with repositories
+	resp := doRequest(router, http.MethodGet, "/v1/user/installations?source=ut-vcs", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	installations := make([]*Installation, 0)
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &installations))
+	assert.Len(t, installations, 1)
+	assert.Equal(t, "ut-owner/ut-repo", installations[0].Repos[0].FullName)
+
+	// installations are served from repository until refreshed
+	provider.AddInstallation(1, &Installation{
+		Id:           2,
+		RepoSource:   "ut-vcs",
+		Organization: "ut-other",
+	})
+	resp = doRequest(router, http.MethodGet, "/v1/user/installations?source=ut-vcs", "", "")
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &installations))
+	assert.Len(t, installations, 1)
+	resp = doRequest(router, http.MethodGet, "/v1/user/installations?source=ut-vcs&refresh=true", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &installations))
+	assert.Len(t, installations, 2)
+	stored, _ := repo.ListInstallation(1, "ut-vcs", true)
+	assert.Len(t, stored, 2)
+
+	// 2: commits
+	resp = doRequest(router, http.MethodGet, "/v1/source/1/commits", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "ut-sha")
+
+	// 3: branches and tags
+	resp = doRequest(router, http.MethodGet, "/v1/source/1/branches", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"branches":["main","dev"],"tags":["v1.0.0","v1.1.0"],
+		"branchPagination":{"page":1,"perPage":10,"nextPage":0,"lastPage":1,"total":2},
+		"tagPagination":{"page":1,"perPage":10,"nextPage":0,"lastPage":1,"total":2}}`, resp.Body.String())
+
+	// 4: branches and tags are paged separately
+	resp = doRequest(router, http.MethodGet, "/v1/source/1/branches?perPage=1&tagPage=2", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"branches":["main"],"tags":["v1.1.0"],
+		"branchPagination":{"page":1,"perPage":1,"nextPage":2,"lastPage":2,"total":2},
+		"tagPagination":{"page":2,"perPage":1,"nextPage":0,"lastPage":2,"total":2}}`, resp.Body.String())
+
+	// 5: all pages are followed until max pages reached
+	resp = doRequest(router, http.MethodGet, "/v1/source/1/branches?perPage=1&all=true", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"branches":["main","dev"]`)
+	GetController().VCSMaxPages = 1
+	resp = doRequest(router, http.MethodGet, "/v1/source/1/branches?perPage=1&all=true", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"branches":["main"]`)
+	assert.Contains(t, resp.Body.String(), `"branchPagination":{"page":1,"perPage":1,"nextPage":2,"lastPage":2,"total":2}`)
+}
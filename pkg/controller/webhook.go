@@ -0,0 +1,328 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-github/v39/github"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// GithubEventHeader is request header which contains name of event delivered by github webhook
+	GithubEventHeader = "X-GitHub-Event"
+	// GithubDeliveryHeader is request header which identifies delivery, redeliveries share the same value
+	GithubDeliveryHeader = "X-GitHub-Delivery"
+	// GithubSignatureHeader is request header which contains HMAC-SHA256 of payload signed with webhook secret
+	GithubSignatureHeader = "X-Hub-Signature-256"
+	// GithubEnterpriseHostHeader is request header which contains host of GitHub Enterprise Server delivered event
+	GithubEnterpriseHostHeader = "X-GitHub-Enterprise-Host"
+	// webhookPayloadMaxSize is the max size of payload accepted, github caps payloads at 25MB
+	webhookPayloadMaxSize = 25 << 20
+)
+
+// GithubWebhookHandler handles payload of event delivered by github webhook on host.
+// Event would be marked as failed if error returned, it would be handled again if redelivered.
+type GithubWebhookHandler func(con *Controller, host *GithubHost, payload []byte) error
+
+var (
+	// githubWebhookHandlers contains handlers of github events registered with RegisterGithubWebhookHandler()
+	githubWebhookHandlers = map[string][]GithubWebhookHandler{
+		"repository":                {handleGithubRepositoryEvent},
+		"installation":              {handleGithubInstallationEvent, syncGithubInstallationsOfEvent},
+		"installation_repositories": {handleGithubInstallationRepositoriesEvent, syncGithubInstallationsOfEvent},
+	}
+	githubWebhookHandlersMutex = sync.RWMutex{}
+	// Deliveries which are in progress, redeliveries would be rejected until they finished.
+	inFlightWebhookDeliveries = sync.Map{}
+)
+
+// RegisterGithubWebhookHandler registers handler of github event, like push.
+// Handlers are called in order of registration.
+func RegisterGithubWebhookHandler(event string, handler GithubWebhookHandler) {
+	githubWebhookHandlersMutex.Lock()
+	defer githubWebhookHandlersMutex.Unlock()
+
+	githubWebhookHandlers[event] = append(githubWebhookHandlers[event], handler)
+}
+
+// Returns handlers of github event.
+func githubWebhookHandlersOf(event string) []GithubWebhookHandler {
+	githubWebhookHandlersMutex.RLock()
+	defer githubWebhookHandlersMutex.RUnlock()
+
+	return append([]GithubWebhookHandler{}, githubWebhookHandlers[event]...)
+}
+
+// Returns GitHub App on host which delivered webhook, github.com is used if X-GitHub-Enterprise-Host is missing.
+// NotFound would be returned if webhook secret of app is not configured.
+func githubWebhookAppOf(ctx *gin.Context) (*GithubApp, error) {
+	name := ctx.GetHeader(GithubEnterpriseHostHeader)
+	if len(name) < 1 {
+		name = GithubHostDefault
+	}
+
+	app := GetGithubApp(name)
+	if app == nil || len(app.WebhookSecret) < 1 {
+		return nil, repository.NewNotFoundf("github webhook is not configured on host:%s", name)
+	}
+
+	return app, nil
+}
+
+// Reads payload of github webhook and verifies X-Hub-Signature-256 with secret.
+// Payload of application/x-www-form-urlencoded request is in form parameter of payload.
+func readGithubWebhookPayload(ctx *gin.Context, secret string) ([]byte, error) {
+	signature := ctx.GetHeader(GithubSignatureHeader)
+	if !strings.HasPrefix(signature, "sha256=") {
+		return nil, repository.NewUnauthenticatedf("missing or invalid %s", GithubSignatureHeader)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(ctx.Request.Body, webhookPayloadMaxSize+1))
+	if err != nil {
+		return nil, repository.NewInvalidArgumentf("failed to read request body")
+	}
+	if len(body) > webhookPayloadMaxSize {
+		return nil, repository.NewInvalidArgumentf("size of payload must not be greater than %d", webhookPayloadMaxSize)
+	}
+
+	payload, err := github.ValidatePayloadFromBody(ctx.ContentType(), bytes.NewReader(body), signature, []byte(secret))
+	if err != nil {
+		return nil, repository.NewUnauthenticatedf("failed to verify %s", GithubSignatureHeader)
+	}
+
+	return payload, nil
+}
+
+// receiveGithubWebhook stores event delivered by github webhook on host and dispatches it to handlers.
+// Deliveries processed already would be ignored, failed ones would be processed again.
+func (con *Controller) receiveGithubWebhook(host *GithubHost, delivery, name string, payload []byte) (*WebhookResponse, error) {
+	// 1: reject concurrent redeliveries, otherwise, both of them would be processed
+	if _, loaded := inFlightWebhookDeliveries.LoadOrStore(delivery, true); loaded {
+		return nil, repository.NewConflictf("delivery:%s is in progress", delivery)
+	}
+	defer inFlightWebhookDeliveries.Delete(delivery)
+
+	// 2: ignore deliveries processed already
+	stored, err := con.Repo.GetWebhookEvent(delivery)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to get webhook event with delivery:%s", delivery)
+	}
+	if stored != nil && stored.IsProcessed() {
+		return &WebhookResponse{
+			Delivery:  delivery,
+			Event:     stored.Event,
+			Action:    stored.Action,
+			Status:    stored.Status,
+			Duplicate: true,
+		}, nil
+	}
+
+	// 3: store event
+	action := &struct {
+		Action string `json:"action"`
+	}{}
+	if err := json.Unmarshal(payload, action); err != nil {
+		return nil, repository.NewInvalidArgumentf("invalid payload of delivery:%s", delivery)
+	}
+
+	event := repository.NewWebhookEvent(repository.IdentityGithub, delivery, name)
+	event.Host = host.Host
+	event.Action = action.Action
+	event.Payload = payload
+	if _, err := con.Repo.UpsertWebhookEvent(event); err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInternal, "failed to store webhook event with delivery:%s", delivery)
+	}
+
+	// 4: dispatch event to handlers
+	var handleErr error
+	event.Status = repository.WebhookStatusProcessed
+	for _, handler := range githubWebhookHandlersOf(name) {
+		if handleErr = handler(con, host, payload); handleErr != nil {
+			event.Status = repository.WebhookStatusFailed
+			event.Error = handleErr.Error()
+			break
+		}
+	}
+
+	if _, err := con.Repo.UpsertWebhookEvent(event); err != nil {
+		con.ZapLoggerEntry.GetLogger().Warn("failed to update status of webhook event",
+			zap.String("delivery", delivery), zap.Error(err))
+	}
+
+	if handleErr != nil {
+		return nil, repository.Wrapf(handleErr, repository.CodeInternal, "failed to handle %s event with delivery:%s", name, delivery)
+	}
+
+	return &WebhookResponse{
+		Delivery: delivery,
+		Event:    name,
+		Action:   event.Action,
+		Status:   event.Status,
+	}, nil
+}
+
+// updateGithubSources updates github sources of repository on host with update, sources are matched by id of repository
+// as well so that sources of renamed repository are updated even if previous events were missed.
+func (con *Controller) updateGithubSources(host *GithubHost, repo string, repoId int64, update func(src *repository.Source)) error {
+	srcList, err := con.Repo.ListSourceByRepository(repository.IdentityGithub, repo)
+	if err != nil {
+		return repository.Wrapf(err, repository.CodeInternal, "failed to list sources of %s", repo)
+	}
+
+	if repoId > 0 {
+		srcListById, err := con.Repo.ListSourceByRepositoryId(repository.IdentityGithub, strconv.FormatInt(repoId, 10))
+		if err != nil {
+			return repository.Wrapf(err, repository.CodeInternal, "failed to list sources of repository:%d", repoId)
+		}
+		srcList = append(srcList, srcListById...)
+	}
+
+	updated := make(map[int]bool)
+	for _, src := range srcList {
+		// sources without host are on primary host
+		if srcHost, err := githubHostOf(src); err != nil || srcHost.Host != host.Host || updated[src.Id] {
+			continue
+		}
+		updated[src.Id] = true
+
+		update(src)
+		if _, err := con.Repo.UpdateSource(src); err != nil {
+			return repository.Wrapf(err, repository.CodeInternal, "failed to update source with sourceId:%d", src.Id)
+		}
+	}
+
+	return nil
+}
+
+// Updates status of github sources of repositories on host, cached installations of them would be forgotten.
+func (con *Controller) updateGithubSourcesStatus(host *GithubHost, repos []*github.Repository, status string) error {
+	app := GetGithubApp(host.Host)
+
+	for _, repo := range repos {
+		if app != nil {
+			app.forgetInstallation(repo.GetFullName())
+		}
+
+		err := con.updateGithubSources(host, repo.GetFullName(), repo.GetID(), func(src *repository.Source) {
+			src.Status = status
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// githubRepositoryEvent is repository event with changes of renamed or transferred repository,
+// which are missing in github.RepositoryEvent.
+type githubRepositoryEvent struct {
+	github.RepositoryEvent
+	Changes struct {
+		Repository struct {
+			Name struct {
+				From string `json:"from"`
+			} `json:"name"`
+		} `json:"repository"`
+		Owner struct {
+			From struct {
+				User         *github.User         `json:"user"`
+				Organization *github.Organization `json:"organization"`
+			} `json:"from"`
+		} `json:"owner"`
+	} `json:"changes"`
+}
+
+// Returns full name of repository before renamed or transferred, empty string would be returned if missing.
+func (e *githubRepositoryEvent) previousFullName() string {
+	switch e.GetAction() {
+	case "renamed":
+		if from := e.Changes.Repository.Name.From; len(from) > 0 {
+			return e.Repo.GetOwner().GetLogin() + "/" + from
+		}
+	case "transferred":
+		from := e.Changes.Owner.From
+		if owner := from.Organization.GetLogin(); len(owner) > 0 {
+			return owner + "/" + e.Repo.GetName()
+		}
+		if owner := from.User.GetLogin(); len(owner) > 0 {
+			return owner + "/" + e.Repo.GetName()
+		}
+	}
+
+	return ""
+}
+
+// Updates repository of sources if repository renamed or transferred, sources of deleted repository are marked as broken.
+func handleGithubRepositoryEvent(con *Controller, host *GithubHost, payload []byte) error {
+	event := &githubRepositoryEvent{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return repository.NewInvalidArgumentf("invalid payload of repository event")
+	}
+
+	switch event.GetAction() {
+	case "renamed", "transferred":
+		// sources are matched by id of repository as well, previous full name might be missing
+		from := event.previousFullName()
+		to := event.Repo.GetFullName()
+		if len(from) < 1 && event.Repo.GetID() < 1 {
+			return nil
+		}
+
+		if app := GetGithubApp(host.Host); app != nil {
+			app.forgetInstallation(from)
+		}
+
+		return con.updateGithubSources(host, from, event.Repo.GetID(), func(src *repository.Source) {
+			src.Repository = to
+		})
+	case "deleted":
+		return con.updateGithubSourcesStatus(host, []*github.Repository{event.Repo}, repository.SourceStatusBroken)
+	}
+
+	return nil
+}
+
+// Marks sources of repositories in installation as broken if app uninstalled or suspended, active if installed again.
+func handleGithubInstallationEvent(con *Controller, host *GithubHost, payload []byte) error {
+	event := &github.InstallationEvent{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return repository.NewInvalidArgumentf("invalid payload of installation event")
+	}
+
+	switch event.GetAction() {
+	case "deleted", "suspend":
+		return con.updateGithubSourcesStatus(host, event.Repositories, repository.SourceStatusBroken)
+	case "created", "unsuspend":
+		return con.updateGithubSourcesStatus(host, event.Repositories, repository.SourceStatusActive)
+	}
+
+	return nil
+}
+
+// Marks sources of repositories removed from installation as broken, and sources of added repositories as active.
+func handleGithubInstallationRepositoriesEvent(con *Controller, host *GithubHost, payload []byte) error {
+	event := &github.InstallationRepositoriesEvent{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return repository.NewInvalidArgumentf("invalid payload of installation_repositories event")
+	}
+
+	if err := con.updateGithubSourcesStatus(host, event.RepositoriesRemoved, repository.SourceStatusBroken); err != nil {
+		return err
+	}
+
+	return con.updateGithubSourcesStatus(host, event.RepositoriesAdded, repository.SourceStatusActive)
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package controller
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/stretchr/testify/assert"
+	httptest "github.com/stretchr/testify/http"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Deliver github event with payload signed by secret.
+func doGithubWebhook(router *gin.Engine, secret, event, delivery, payload string) *httptest.TestResponseWriter {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+
+	req, _ := http.NewRequest(http.MethodPost, "/v1/webhook/github", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(GithubEventHeader, event)
+	req.Header.Set(GithubDeliveryHeader, delivery)
+	req.Header.Set(GithubSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp := &httptest.TestResponseWriter{}
+	router.ServeHTTP(resp, req)
+
+	return resp
+}
+
+func TestReceiveGithubWebhook(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+	repo := GetController().Repo
+
+	// 1: webhook is not configured
+	resp := doGithubWebhook(router, "ut-secret", "ping", "ut-delivery-0", `{}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	api := newFakeGithubApi(t)
+	app := api.newApp(t, GithubHostDefault)
+	app.WebhookSecret = "ut-secret"
+	RegisterGithubApp(app)
+	defer unregisterGithubApp(GithubHostDefault)
+
+	org := repository.NewOrg("ut-org")
+	repo.CreateOrg(org)
+	proj := repository.NewProj("ut-proj")
+	proj.OrgId = org.Id
+	repo.CreateProj(proj)
+	src := repository.NewSource(repository.IdentityGithub, "ut-owner/ut-repo")
+	src.ProjId = proj.Id
+	src.Host = GithubHostDefault
+	repo.CreateSource(src)
+
+	// 2: invalid signature
+	resp = doGithubWebhook(router, "ut-invalid", "ping", "ut-delivery-1", `{}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// 3: missing delivery
+	resp = doGithubWebhook(router, "ut-secret", "ping", "", `{}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// 4: events without handlers are stored
+	resp = doGithubWebhook(router, "ut-secret", "ping", "ut-delivery-1", `{"zen":"ut-zen"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	event, err := repo.GetWebhookEvent("ut-delivery-1")
+	assert.Nil(t, err)
+	assert.Equal(t, GithubHostDefault, event.Host)
+	assert.True(t, event.IsProcessed())
+
+	// 5: repository renamed
+	renamed := `{"action":"renamed","changes":{"repository":{"name":{"from":"ut-repo"}}},` +
+		`"repository":{"name":"ut-renamed","full_name":"ut-owner/ut-renamed","owner":{"login":"ut-owner"}}}`
+	resp = doGithubWebhook(router, "ut-secret", "repository", "ut-delivery-2", renamed)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Output, `"action":"renamed","status":"processed","duplicate":false`)
+	srcFromRepo, _ := repo.GetSource(src.Id)
+	assert.Equal(t, "ut-owner/ut-renamed", srcFromRepo.Repository)
+
+	// sources are matched by id of repository even if previous renames were missed
+	srcFromRepo.Repository = "ut-owner/ut-stale"
+	srcFromRepo.RepositoryId = "1296269"
+	repo.UpdateSource(srcFromRepo)
+	renamedAgain := `{"action":"renamed","changes":{"repository":{"name":{"from":"ut-renamed"}}},` +
+		`"repository":{"id":1296269,"name":"ut-renamed-again","full_name":"ut-owner/ut-renamed-again","owner":{"login":"ut-owner"}}}`
+	resp = doGithubWebhook(router, "ut-secret", "repository", "ut-delivery-5", renamedAgain)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	srcFromRepo, _ = repo.GetSource(src.Id)
+	assert.Equal(t, "ut-owner/ut-renamed-again", srcFromRepo.Repository)
+
+	// repository transferred to another owner is followed as renamed, matched by previous owner or id of repository
+	transferred := `{"action":"transferred","changes":{"owner":{"from":{"user":{"login":"ut-owner"}}}},` +
+		`"repository":{"name":"ut-renamed-again","full_name":"ut-new-owner/ut-renamed-again","owner":{"login":"ut-new-owner"}}}`
+	resp = doGithubWebhook(router, "ut-secret", "repository", "ut-delivery-6", transferred)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	srcFromRepo, _ = repo.GetSource(src.Id)
+	assert.Equal(t, "ut-new-owner/ut-renamed-again", srcFromRepo.Repository)
+	transferredAgain := `{"action":"transferred","changes":{"owner":{"from":{"organization":{"login":"ut-missed-org"}}}},` +
+		`"repository":{"id":1296269,"name":"ut-renamed-again","full_name":"ut-org/ut-renamed-again","owner":{"login":"ut-org"}}}`
+	resp = doGithubWebhook(router, "ut-secret", "repository", "ut-delivery-7", transferredAgain)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	srcFromRepo, _ = repo.GetSource(src.Id)
+	assert.Equal(t, "ut-org/ut-renamed-again", srcFromRepo.Repository)
+
+	srcFromRepo.Repository = "ut-owner/ut-renamed"
+	repo.UpdateSource(srcFromRepo)
+
+	// 6: redelivery is ignored
+	resp = doGithubWebhook(router, "ut-secret", "repository", "ut-delivery-2", renamed)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Output, `"duplicate":true`)
+
+	// 7: app uninstalled
+	uninstalled := `{"action":"deleted","repositories":[{"full_name":"ut-owner/ut-renamed"}]}`
+	resp = doGithubWebhook(router, "ut-secret", "installation", "ut-delivery-3", uninstalled)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	srcFromRepo, _ = repo.GetSource(src.Id)
+	assert.True(t, srcFromRepo.IsBroken())
+
+	// 8: repository added to installation again
+	added := `{"action":"added","repositories_added":[{"full_name":"ut-owner/ut-renamed"}]}`
+	resp = doGithubWebhook(router, "ut-secret", "installation_repositories", "ut-delivery-4", added)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	srcFromRepo, _ = repo.GetSource(src.Id)
+	assert.False(t, srcFromRepo.IsBroken())
+}
+
+func TestReceiveGithubWebhook_WithFailedHandler(t *testing.T) {
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	router := newRouterV2()
+
+	api := newFakeGithubApi(t)
+	app := api.newApp(t, GithubHostDefault)
+	app.WebhookSecret = "ut-secret"
+	RegisterGithubApp(app)
+	defer unregisterGithubApp(GithubHostDefault)
+
+	calls := 0
+	RegisterGithubWebhookHandler("ut-event", func(con *Controller, host *GithubHost, payload []byte) error {
+		calls++
+		if calls < 2 {
+			return errors.New("ut-error")
+		}
+		return nil
+	})
+
+	// 1: failed event is stored with error
+	resp := doGithubWebhook(router, "ut-secret", "ut-event", "ut-delivery", `{}`)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	event, err := GetController().Repo.GetWebhookEvent("ut-delivery")
+	assert.Nil(t, err)
+	assert.Equal(t, repository.WebhookStatusFailed, event.Status)
+	assert.Equal(t, "ut-error", event.Error)
+
+	// 2: redelivery of failed event is handled again
+	resp = doGithubWebhook(router, "ut-secret", "ut-event", "ut-delivery", `{}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, calls)
+}
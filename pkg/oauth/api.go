@@ -8,16 +8,20 @@ import (
 	"github.com/pointgoal/workstation/pkg/controller"
 	"github.com/pointgoal/workstation/pkg/repository"
 	"github.com/rookie-ninja/rk-common/common"
-	"github.com/rookie-ninja/rk-common/error"
 	"github.com/rookie-ninja/rk-gin/boot"
+	"golang.org/x/oauth2"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 const (
-	CallbackPathGithub     = "/v1/oauth/callback/github"
-	SuccessPathGithub      = "/v1/oauth/success"
-	InstallNewGithubAppUrl = "https://github.com/apps/pg-workstation-test/installations/new"
+	CallbackPathGithub = "/v1/oauth/callback/github"
+	CallbackPathGitlab = "/v1/oauth/callback/gitlab"
+	CallbackPathGitea  = "/v1/oauth/callback/gitea"
+	CallbackPathOidc   = "/v1/oauth/oidc/:provider/callback"
+	SuccessPathGithub  = "/v1/oauth/success"
 )
 
 func initApi() {
@@ -28,7 +32,12 @@ func initApi() {
 	}
 
 	// Oauth
+	ginEntry.Router.GET("/v1/oauth/login/:provider", Login)
 	ginEntry.Router.GET(CallbackPathGithub, CallbackGithub)
+	ginEntry.Router.GET(CallbackPathGitlab, CallbackGitlab)
+	ginEntry.Router.GET(CallbackPathGitea, CallbackGitea)
+	ginEntry.Router.GET(CallbackPathOidc, CallbackOidc)
+	ginEntry.Router.DELETE("/v1/oauth/:provider", RevokeToken)
 
 	// For validation, could be removed after console was implemented!
 	ginEntry.Router.GET("/v1/oauth", Index)
@@ -40,6 +49,45 @@ func initApi() {
 // ************** Oauth related ************** //
 // ******************************************* //
 
+// Login
+// @Summary Start oauth flow
+// @Id 46
+// @version 1.0
+// @Tags oauth
+// @produce application/json
+// @Param provider path string true "Oauth provider"
+// @Success 302
+// @Router /v1/oauth/login/{provider} [get]
+func Login(ctx *gin.Context) {
+	entry := GetEntry()
+	provider := strings.ToLower(ctx.Param("provider"))
+	oidcProvider := entry.GetOidcProvider(provider)
+
+	// 1: get oauth config, endpoints of OpenID Connect provider would be discovered
+	oauthConfig, err := entry.oauthConfigOf(provider)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// 2: bind state, PKCE verifier and nonce of ID token to browser
+	flow, err := entry.StartFlow(ctx, provider)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// 3: redirect to provider, ID token of OpenID Connect provider is bound to the flow with nonce
+	params := []oauth2.AuthCodeOption{
+		oauth2.SetAuthURLParam("code_challenge", flow.Challenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	}
+	if oidcProvider != nil {
+		params = append(params, oauth2.SetAuthURLParam("nonce", flow.IdTokenNonce))
+	}
+	ctx.Redirect(http.StatusFound, oauthConfig.AuthCodeURL(flow.State, params...))
+}
+
 // CallbackGithub
 // @Summary Oauth callback
 // @Id 40
@@ -47,67 +95,321 @@ func initApi() {
 // @Tags oauth
 // @produce application/json
 // @Param code query string true "Code"
+// @Param state query string true "State issued by login API"
 // @Success 200
 // @Router /v1/oauth/callback/github [get]
 func CallbackGithub(ctx *gin.Context) {
 	entry := GetEntry()
 
+	signInWithCode(ctx, Github, func(accessToken string) (*repository.Identity, error) {
+		user, err := entry.GetGithubUser(accessToken)
+		if err != nil {
+			return nil, err
+		}
+
+		identity := repository.NewIdentity(repository.IdentityGithub, user.GetLogin())
+		identity.Name = user.GetName()
+		identity.Email = user.GetEmail()
+		identity.AvatarUrl = user.GetAvatarURL()
+		return identity, nil
+	}, func(user *repository.User, identity *repository.Identity) (string, error) {
+		con := controller.GetController()
+
+		// 1: refresh stored installations in background, access token already saved in repository
+		stored, err := con.Repo.ListInstallation(user.Id, Github, false)
+		if err != nil {
+			return "", repository.Wrapf(err, repository.CodeInternal, "failed to list installations of %s", Github)
+		}
+		if len(stored) > 0 {
+			con.EnqueueInstallationSync(user.Id, Github)
+			return successUrlOf(user, identity)
+		}
+
+		// 2: sync installations before redirecting if none stored, so that users who installed app already,
+		// like those signing in for the first time, would not be asked to install it again
+		installations, err := con.ListInstallations(user.Id, Github)
+		if err != nil {
+			return "", err
+		}
+		if len(installations) < 1 {
+			return entry.GithubAppInstallUrl, nil
+		}
+
+		return successUrlOf(user, identity)
+	})
+}
+
+// CallbackGitlab
+// @Summary Callback of gitlab
+// @Id 53
+// @version 1.0
+// @Tags oauth
+// @produce application/json
+// @Param code query string true "Code"
+// @Param state query string true "State issued by login API"
+// @Success 307
+// @Router /v1/oauth/callback/gitlab [get]
+func CallbackGitlab(ctx *gin.Context) {
+	entry := GetEntry()
+
+	signInWithCode(ctx, Gitlab, func(accessToken string) (*repository.Identity, error) {
+		user, err := entry.GetGitlabUser(context.Background(), accessToken)
+		if err != nil {
+			return nil, err
+		}
+
+		identity := repository.NewIdentity(repository.IdentityGitlab, user.Username)
+		identity.Name = user.Name
+		identity.Email = user.Email
+		identity.AvatarUrl = user.AvatarUrl
+		return identity, nil
+	}, successUrlOf)
+}
+
+// CallbackGitea
+// @Summary Callback of gitea or forgejo
+// @Id 54
+// @version 1.0
+// @Tags oauth
+// @produce application/json
+// @Param code query string true "Code"
+// @Param state query string true "State issued by login API"
+// @Success 307
+// @Router /v1/oauth/callback/gitea [get]
+func CallbackGitea(ctx *gin.Context) {
+	entry := GetEntry()
+
+	signInWithCode(ctx, Gitea, func(accessToken string) (*repository.Identity, error) {
+		user, err := entry.GetGiteaUser(context.Background(), accessToken)
+		if err != nil {
+			return nil, err
+		}
+
+		identity := repository.NewIdentity(repository.IdentityGitea, user.Login)
+		identity.Name = user.FullName
+		identity.Email = user.Email
+		identity.AvatarUrl = user.AvatarUrl
+		return identity, nil
+	}, successUrlOf)
+}
+
+// Finishes oauth flow of dest with code in callback, signs in with identity returned by profileOf
+// and redirects to url returned by redirectOf.
+func signInWithCode(ctx *gin.Context, dest string,
+	profileOf func(accessToken string) (*repository.Identity, error),
+	redirectOf func(user *repository.User, identity *repository.Identity) (string, error)) {
+	entry := GetEntry()
+
 	code := ctx.Query("code")
 
 	// 1: get oauth config
-	oauthConfig, err := entry.GetOauthConfig(Github)
+	oauthConfig, err := entry.GetOauthConfig(dest)
+	if err != nil {
+		ctx.Error(repository.Wrapf(err, repository.CodeInvalidArgument, "failed to process oauth request"))
+		return
+	}
+
+	if reason := ctx.Query("error"); len(reason) > 0 {
+		ctx.Error(repository.NewInvalidArgumentf("login was rejected by %s, %s", dest, reason))
+		return
+	}
+
+	// 2: validate state, callbacks without state issued for this browser or replayed would be rejected
+	flow, err := entry.FinishFlow(ctx, dest, ctx.Query("state"), code)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, rkerror.New(
-			rkerror.WithHttpCode(http.StatusBadRequest),
-			rkerror.WithMessage("failed to process oauth request"),
-			rkerror.WithDetails(err)))
+		ctx.Error(err)
 		return
 	}
 
-	// 2: get access token
-	accessToken, err := oauthConfig.Exchange(context.Background(), code)
+	// 3: get access token
+	accessToken, err := oauthConfig.Exchange(context.Background(), code,
+		oauth2.SetAuthURLParam("code_verifier", flow.Verifier))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, rkerror.New(
-			rkerror.WithHttpCode(http.StatusInternalServerError),
-			rkerror.WithMessage(fmt.Sprintf("failed to get access code from %s", Github)),
-			rkerror.WithDetails(err)))
+		ctx.Error(repository.NewUpstreamf(err, "failed to get access code from %s", dest))
 		return
 	}
 
-	// 3: get user info
-	user, err := entry.GetGithubUser(accessToken.AccessToken)
+	// 4: get user info
+	identity, err := profileOf(accessToken.AccessToken)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, rkerror.New(
-			rkerror.WithHttpCode(http.StatusInternalServerError),
-			rkerror.WithMessage(fmt.Sprintf("failed to get user info from %s", Github)),
-			rkerror.WithDetails(err)))
+		ctx.Error(repository.NewUpstreamf(err, "failed to get user info from %s", dest))
+		return
+	}
+
+	// 5: sign in with identity, access token would be stored for user
+	con := controller.GetController()
+	if con == nil {
+		ctx.Error(repository.NewErrorf(repository.CodeInternal, "controller is not enabled"))
 		return
 	}
 
-	// 4: save accessToken
-	repo := repository.GetRepository()
-	token := repository.NewAccessToken(Github, user.GetLogin(), accessToken.AccessToken)
-	repo.UpsertAccessToken(token)
+	user, err := con.SignIn(ctx, identity, accessToken)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
 
-	// 5: list repositories, access token already saved in repository
-	installations, err := controller.ListUserInstallationsFromGithub(user.GetLogin())
+	// 6: redirect
+	redirectUrl, err := redirectOf(user, identity)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, rkerror.New(
-			rkerror.WithHttpCode(http.StatusInternalServerError),
-			rkerror.WithMessage(fmt.Sprintf("failed to list installations from %s", Github)),
-			rkerror.WithDetails(err)))
+		ctx.Error(err)
+		return
+	}
+	ctx.Redirect(http.StatusTemporaryRedirect, redirectUrl)
+}
+
+// Returns url of success page with login of identity.
+func successUrlOf(user *repository.User, identity *repository.Identity) (string, error) {
+	return fmt.Sprintf("%s%s?user=%s", GetEntry().CallbackAddr, SuccessPathGithub, url.QueryEscape(identity.Login)), nil
+}
+
+// CallbackOidc
+// @Summary Callback of OpenID Connect provider
+// @Id 52
+// @version 1.0
+// @Tags oauth
+// @produce application/json
+// @Param provider path string true "Name of OpenID Connect provider"
+// @Param code query string true "Code"
+// @Param state query string true "State issued by login API"
+// @Success 307
+// @Router /v1/oauth/oidc/{provider}/callback [get]
+func CallbackOidc(ctx *gin.Context) {
+	entry := GetEntry()
+	name := strings.ToLower(ctx.Param("provider"))
+	code := ctx.Query("code")
+
+	// 1: get provider
+	provider := entry.GetOidcProvider(name)
+	if provider == nil {
+		ctx.Error(repository.NewInvalidArgumentf("unsupported oidc provider:%s", name))
+		return
+	}
+
+	if reason := ctx.Query("error"); len(reason) > 0 {
+		ctx.Error(repository.NewInvalidArgumentf("login was rejected by %s, %s", name, reason))
+		return
+	}
+
+	oauthConfig, err := provider.OauthConfig(context.Background())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// 2: validate state, callbacks without state issued for this browser or replayed would be rejected
+	flow, err := entry.FinishFlow(ctx, name, ctx.Query("state"), code)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// 3: get access token and ID token
+	accessToken, err := oauthConfig.Exchange(context.Background(), code,
+		oauth2.SetAuthURLParam("code_verifier", flow.Verifier))
+	if err != nil {
+		ctx.Error(repository.NewUpstreamf(err, "failed to get access code from %s", name))
+		return
+	}
+
+	// 4: verify ID token and map claims into identity
+	identity, groups, err := provider.Identity(context.Background(), accessToken, flow.IdTokenNonce)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// 5: sign in with identity and grant roles mapped from groups
+	con := controller.GetController()
+	if con == nil {
+		ctx.Error(repository.NewErrorf(repository.CodeInternal, "controller is not enabled"))
+		return
+	}
+
+	user, err := con.SignIn(ctx, identity, accessToken)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := con.GrantOrgRoles(user.Id, provider.RolesOf(groups)); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	successUrl := fmt.Sprintf("%s%s?user=%s", entry.CallbackAddr, SuccessPathGithub, url.QueryEscape(identity.Login))
+	ctx.Redirect(http.StatusTemporaryRedirect, successUrl)
+}
+
+// Returns oauth config of provider, endpoints of OpenID Connect provider would be discovered.
+func (entry *Entry) oauthConfigOf(provider string) (*oauth2.Config, error) {
+	if oidcProvider := entry.GetOidcProvider(provider); oidcProvider != nil {
+		return oidcProvider.OauthConfig(context.Background())
+	}
+
+	config, err := entry.GetOauthConfig(provider)
+	if err != nil {
+		return nil, repository.Wrapf(err, repository.CodeInvalidArgument, "failed to process oauth request")
+	}
+
+	return config, nil
+}
+
+// oidcCallbackPath returns callback path of OpenID Connect provider.
+func oidcCallbackPath(name string) string {
+	return strings.Replace(CallbackPathOidc, ":provider", name, 1)
+}
+
+// RevokeTokenResponse response of revoke token
+type RevokeTokenResponse struct {
+	Status bool `yaml:"status" json:"status"`
+}
+
+// RevokeToken
+// @Summary Revoke access token of oauth provider
+// @Id 47
+// @version 1.0
+// @Tags oauth
+// @produce application/json
+// @Param provider path string true "Oauth provider"
+// @Success 200 {object} RevokeTokenResponse
+// @Router /v1/oauth/{provider} [delete]
+func RevokeToken(ctx *gin.Context) {
+	entry := GetEntry()
+	provider := strings.ToLower(ctx.Param("provider"))
+
+	if !entry.IsValidOauthDest(provider) {
+		ctx.Error(repository.NewInvalidArgumentf("unsupported oauth provider:%s", provider))
+		return
+	}
+
+	// 1: oauth APIs are not guarded by auth interceptor, lookup session here
+	con := controller.GetController()
+	if con == nil {
+		ctx.Error(repository.NewErrorf(repository.CodeInternal, "controller is not enabled"))
+		return
+	}
+
+	session, err := con.LookupSession(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if !session.AllowScope(controller.ScopeWriteUser) {
+		ctx.Error(repository.NewPermissionDeniedf("personal token is not granted with scope:%s", controller.ScopeWriteUser))
 		return
 	}
 
-	// 6: if installations is not empty, then return success path
-	if len(installations) > 0 {
-		successUrl := fmt.Sprintf("%s%s?code=%s&user=%s", GithubCallbackHost, SuccessPathGithub, code, user.GetLogin())
-		ctx.Redirect(http.StatusTemporaryRedirect, successUrl)
+	// 2: revoke token upstream and remove it
+	if err := con.RevokeAccessToken(session.UserId(), provider); err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	// 7: if installation is empty, redirect to install app
-	ctx.Redirect(http.StatusTemporaryRedirect, InstallNewGithubAppUrl)
+	ctx.JSON(http.StatusOK, &RevokeTokenResponse{
+		Status: true,
+	})
 }
 
 // Index is temp API while developing.
@@ -0,0 +1,153 @@
+package oauth
+
+import (
+	"encoding/json"
+	"github.com/pointgoal/workstation/pkg/controller"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/rookie-ninja/rk-gin/boot"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// fakeVCSOauth describes oauth endpoints and user API of a stand-in VCS.
+type fakeVCSOauth struct {
+	name          string
+	identityType  string
+	authPath      string
+	tokenPath     string
+	userPath      string
+	authorization string
+	user          map[string]string
+	option        func(baseUrl string) EntryOption
+	callbackPath  string
+	baseUrlOf     func() string
+}
+
+// Stand-in of VCS which issues token for code exchanged with PKCE verifier, challenge is read while serving.
+func newFakeVCSOauthServer(t *testing.T, vcs *fakeVCSOauth, challenge *string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(vcs.tokenPath, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "ut-"+vcs.name+"-code" || codeChallenge(r.Form.Get("code_verifier")) != *challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "ut-access-token",
+			"refresh_token": "ut-refresh-token",
+			"token_type":    "bearer",
+			"expires_in":    3600,
+		})
+	})
+	mux.HandleFunc(vcs.userPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != vcs.authorization {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(vcs.user)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestLogin_WithVCS(t *testing.T) {
+	cases := []*fakeVCSOauth{
+		{
+			name:          Gitlab,
+			identityType:  repository.IdentityGitlab,
+			authPath:      "/oauth/authorize",
+			tokenPath:     "/oauth/token",
+			userPath:      "/api/v4/user",
+			authorization: "Bearer ut-access-token",
+			user:          map[string]string{"username": "ut-login", "name": "ut-name", "email": "ut@example.com"},
+			option:        WithGitlabBaseUrl,
+			callbackPath:  CallbackPathGitlab,
+			baseUrlOf: func() string {
+				return controller.GetVCSProvider(Gitlab).(*controller.GitlabProvider).BaseUrl
+			},
+		},
+		{
+			name:          Gitea,
+			identityType:  repository.IdentityGitea,
+			authPath:      "/login/oauth/authorize",
+			tokenPath:     "/login/oauth/access_token",
+			userPath:      "/api/v1/user",
+			authorization: "token ut-access-token",
+			user:          map[string]string{"login": "ut-login", "full_name": "ut-name", "email": "ut@example.com"},
+			option:        WithGiteaBaseUrl,
+			callbackPath:  CallbackPathGitea,
+			baseUrlOf: func() string {
+				return controller.GetVCSProvider(Gitea).(*controller.GiteaProvider).BaseUrl
+			},
+		},
+	}
+
+	for _, vcs := range cases {
+		t.Run(vcs.name, func(t *testing.T) {
+			challenge := ""
+			server := newFakeVCSOauthServer(t, vcs, &challenge)
+
+			// register entries
+			ginEntry := rkgin.RegisterGinEntry(rkgin.WithNameGin("workstation"))
+			repo := repository.RegisterMemory()
+			controller.RegisterController()
+			ginEntry.Router.Use(controller.ErrorInterceptor())
+			RegisterEntry(WithStateSecret([]byte("ut-secret")), WithCallbackAddr("http://ut-host"),
+				vcs.option(server.URL), WithOauthConfig(vcs.name, &oauth2.Config{
+					ClientID:    "ut-client",
+					RedirectURL: "http://ut-host" + vcs.callbackPath,
+					Endpoint: oauth2.Endpoint{
+						AuthURL:  server.URL + vcs.authPath,
+						TokenURL: server.URL + vcs.tokenPath,
+					},
+				}))
+			initApi()
+			defer rkentry.GlobalAppCtx.RemoveEntry(EntryName)
+			defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+
+			// sources are accessed with provider of configured base url
+			assert.True(t, controller.IsSourceTypeRegistered(vcs.name))
+			assert.Equal(t, server.URL, vcs.baseUrlOf())
+
+			// 1: login redirects to provider
+			resp := httptest.NewRecorder()
+			ginEntry.Router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1/oauth/login/"+vcs.name, nil))
+			assert.Equal(t, http.StatusFound, resp.Code)
+			location, _ := url.Parse(resp.Header().Get("Location"))
+			assert.Equal(t, server.URL+vcs.authPath, location.Scheme+"://"+location.Host+location.Path)
+			challenge = location.Query().Get("code_challenge")
+			cookie := resp.Header().Get("Set-Cookie")
+
+			// 2: login rejected by user
+			req := httptest.NewRequest(http.MethodGet, vcs.callbackPath+"?error=access_denied", nil)
+			resp = httptest.NewRecorder()
+			ginEntry.Router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+			// 3: callback signs in with identity and stores access token
+			req = httptest.NewRequest(http.MethodGet,
+				vcs.callbackPath+"?code=ut-"+vcs.name+"-code&state="+url.QueryEscape(location.Query().Get("state")), nil)
+			req.Header.Set("Cookie", cookie)
+			resp = httptest.NewRecorder()
+			ginEntry.Router.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusTemporaryRedirect, resp.Code)
+			assert.Equal(t, "http://ut-host/v1/oauth/success?user=ut-login", resp.Header().Get("Location"))
+
+			identity, err := repo.GetIdentity(vcs.identityType, "ut-login")
+			assert.Nil(t, err)
+			assert.Equal(t, "ut-name", identity.Name)
+			token, err := repo.GetAccessToken(identity.UserId, vcs.identityType)
+			assert.Nil(t, err)
+			assert.Equal(t, "ut-refresh-token", token.RefreshToken)
+		})
+	}
+}
@@ -1,21 +1,16 @@
 function githubOauth() {
-    // 1: oath login
-    const params = {
-        client_id: 'Iv1.27e4e24d5cf774cc',
-        redirect_uri: 'http://localhost:8080/v1/oauth/callback/github',
-    }
+    // 1: oath login, state and PKCE verifier are generated by server
+    const params = {}
     const options = { height: 800, width: 1200 }
-    const url = `https://github.com/login/oauth/authorize`
+    const url = `/v1/oauth/login/github`
     const id = "github-oauth"
 
-    var code, user, err
+    var user, err
 
     login(params, options, url, id).then(
         res => {
-            code = res.code;
             user = res.user;
             document.getElementById("user").innerHTML = res.user
-            document.getElementById("code").innerHTML = res.code
         })
 
 
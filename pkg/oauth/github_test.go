@@ -0,0 +1,124 @@
+package oauth
+
+import (
+	"encoding/json"
+	"github.com/pointgoal/workstation/pkg/controller"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/rookie-ninja/rk-gin/boot"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGithubEnterpriseLogin(t *testing.T) {
+	// stand-in of GitHub Enterprise Server which serves REST API under /api/v3
+	challenge := ""
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if !strings.HasPrefix(r.Form.Get("code"), "ut-ghe-code") || codeChallenge(r.Form.Get("code_verifier")) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "ut-access-token", "token_type": "bearer"})
+	})
+	// user signed in and whether app was installed by the user
+	login, installed := "ut-login", false
+	mux.HandleFunc("/api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ut-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"login": login, "name": "ut-name"})
+	})
+	mux.HandleFunc("/api/v3/user/installations", func(w http.ResponseWriter, r *http.Request) {
+		installations := []interface{}{}
+		if installed {
+			installations = append(installations, map[string]interface{}{"id": 1, "account": map[string]string{"login": "ut-org"}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"total_count": len(installations), "installations": installations})
+	})
+	mux.HandleFunc("/api/v3/user/installations/1/repositories", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"total_count": 1, "repositories": []interface{}{
+			map[string]string{"name": "ut-repo", "full_name": "ut-org/ut-repo"},
+		}})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	serverUrl, _ := url.Parse(server.URL)
+	host, err := controller.NewGithubHost(serverUrl.Host, server.URL+"/api/v3", "")
+	assert.Nil(t, err)
+
+	// register entries
+	ginEntry := rkgin.RegisterGinEntry(rkgin.WithNameGin("workstation"))
+	repo := repository.RegisterMemory()
+	controller.RegisterController()
+	ginEntry.Router.Use(controller.ErrorInterceptor())
+	RegisterEntry(WithStateSecret([]byte("ut-secret")), WithCallbackAddr("http://ut-host"),
+		WithGithubHost(host), WithOauthConfig(Github, &oauth2.Config{
+			ClientID:    "ut-client",
+			RedirectURL: "http://ut-host" + CallbackPathGithub,
+			Endpoint:    host.OauthEndpoint(),
+		}))
+	initApi()
+	defer rkentry.GlobalAppCtx.RemoveEntry(EntryName)
+	defer rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	defer controller.RegisterPrimaryGithubHost(controller.GetGithubHost(controller.GithubHostDefault))
+
+	// github sources without host are created on primary host
+	assert.Equal(t, serverUrl.Host, controller.GetGithubHost("").Host)
+
+	// 1: login redirects to GitHub Enterprise Server
+	resp := httptest.NewRecorder()
+	ginEntry.Router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1/oauth/login/github", nil))
+	assert.Equal(t, http.StatusFound, resp.Code)
+	location, _ := url.Parse(resp.Header().Get("Location"))
+	assert.True(t, strings.HasPrefix(location.String(), server.URL+"/login/oauth/authorize"))
+	challenge = location.Query().Get("code_challenge")
+
+	// 2: login rejected by user
+	rejected := httptest.NewRecorder()
+	ginEntry.Router.ServeHTTP(rejected, httptest.NewRequest(http.MethodGet, CallbackPathGithub+"?error=access_denied", nil))
+	assert.Equal(t, http.StatusBadRequest, rejected.Code)
+
+	// 3: callback gets user from API of host, and redirects to install app on host since no installation found
+	req := httptest.NewRequest(http.MethodGet,
+		CallbackPathGithub+"?code=ut-ghe-code&state="+url.QueryEscape(location.Query().Get("state")), nil)
+	req.Header.Set("Cookie", resp.Header().Get("Set-Cookie"))
+	resp = httptest.NewRecorder()
+	ginEntry.Router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.Code)
+	assert.Equal(t, server.URL+"/github-apps/"+controller.GithubAppSlugDefault+"/installations/new", resp.Header().Get("Location"))
+
+	identity, err := repo.GetIdentity(repository.IdentityGithub, "ut-login")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-name", identity.Name)
+
+	// 4: installations of user signing in for the first time are synced before redirecting,
+	// so that user who installed app already is not asked to install it again
+	login, installed = "ut-installed", true
+	resp = httptest.NewRecorder()
+	ginEntry.Router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1/oauth/login/github", nil))
+	location, _ = url.Parse(resp.Header().Get("Location"))
+	challenge = location.Query().Get("code_challenge")
+	req = httptest.NewRequest(http.MethodGet,
+		CallbackPathGithub+"?code=ut-ghe-code-2&state="+url.QueryEscape(location.Query().Get("state")), nil)
+	req.Header.Set("Cookie", resp.Header().Get("Set-Cookie"))
+	resp = httptest.NewRecorder()
+	ginEntry.Router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.Code)
+	assert.Equal(t, "http://ut-host"+SuccessPathGithub+"?user=ut-installed", resp.Header().Get("Location"))
+
+	identity, err = repo.GetIdentity(repository.IdentityGithub, "ut-installed")
+	assert.Nil(t, err)
+	installations, err := repo.ListInstallation(identity.UserId, repository.IdentityGithub, true)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 1)
+}
@@ -0,0 +1,401 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/pointgoal/workstation/pkg/controller"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"golang.org/x/oauth2"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// OidcWellKnownPath is path of discovery document relative to issuer
+	OidcWellKnownPath = "/.well-known/openid-configuration"
+	// oidcKeysRefetchInterval limits how often signing keys would be fetched while ID token was signed with unknown key
+	oidcKeysRefetchInterval = time.Minute
+	// oidcHttpTimeout is timeout of requests sent to provider
+	oidcHttpTimeout = 10 * time.Second
+)
+
+// OidcClaims maps claims of ID token or userinfo into profile of identity.
+// Empty fields fall back to standard claims, like preferred_username for login.
+type OidcClaims struct {
+	Login     string `yaml:"login" json:"login"`
+	Name      string `yaml:"name" json:"name"`
+	Email     string `yaml:"email" json:"email"`
+	AvatarUrl string `yaml:"avatarUrl" json:"avatarUrl"`
+	Groups    string `yaml:"groups" json:"groups"`
+}
+
+// OidcGroupRole grants role of organization to users in group of provider.
+type OidcGroupRole struct {
+	Group string `yaml:"group" json:"group"`
+	OrgId int    `yaml:"orgId" json:"orgId"`
+	Role  string `yaml:"role" json:"role"`
+}
+
+// OidcProvider is a generic OpenID Connect provider, like corporate IdP.
+//
+// Endpoints and signing keys are discovered from DiscoveryUrl on first use and cached.
+// Signing keys would be fetched again while ID token was signed with unknown key, so that keys could be rotated.
+type OidcProvider struct {
+	Name          string                 `json:"name" yaml:"name"`
+	DiscoveryUrl  string                 `json:"discoveryUrl" yaml:"discoveryUrl"`
+	Claims        *OidcClaims            `json:"claims" yaml:"claims"`
+	GroupRoles    []*OidcGroupRole       `json:"groupRoles" yaml:"groupRoles"`
+	config        *oauth2.Config         `json:"-" yaml:"-"`
+	client        *http.Client           `json:"-" yaml:"-"`
+	mutex         sync.Mutex             `json:"-" yaml:"-"`
+	discovery     *oidcDiscovery         `json:"-" yaml:"-"`
+	keys          map[string]interface{} `json:"-" yaml:"-"`
+	keysFetchedAt time.Time              `json:"-" yaml:"-"`
+}
+
+// oidcDiscovery is discovery document of provider.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+	RevocationEndpoint    string `json:"revocation_endpoint"`
+}
+
+// oidcJwk is signing key published by provider.
+type oidcJwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// NewOidcProvider creates provider with name, which is used as type of identities signed in with it.
+//
+// Discovery document would be read from issuer URL with OidcWellKnownPath appended if discoveryUrl does
+// not end with it. Endpoint of config would be filled after discovery.
+func NewOidcProvider(name, discoveryUrl string, config *oauth2.Config, claims *OidcClaims, groupRoles []*OidcGroupRole) *OidcProvider {
+	discoveryUrl = strings.TrimSuffix(discoveryUrl, "/")
+	if !strings.HasSuffix(discoveryUrl, OidcWellKnownPath) {
+		discoveryUrl += OidcWellKnownPath
+	}
+
+	res := &OidcProvider{
+		Name:         strings.ToLower(name),
+		DiscoveryUrl: discoveryUrl,
+		Claims: &OidcClaims{
+			Login:     "preferred_username",
+			Name:      "name",
+			Email:     "email",
+			AvatarUrl: "picture",
+			Groups:    "groups",
+		},
+		GroupRoles: groupRoles,
+		config:     config,
+		client:     &http.Client{Timeout: oidcHttpTimeout},
+		keys:       make(map[string]interface{}),
+	}
+
+	if claims != nil {
+		for dest, src := range map[*string]string{
+			&res.Claims.Login:     claims.Login,
+			&res.Claims.Name:      claims.Name,
+			&res.Claims.Email:     claims.Email,
+			&res.Claims.AvatarUrl: claims.AvatarUrl,
+			&res.Claims.Groups:    claims.Groups,
+		} {
+			if len(src) > 0 {
+				*dest = src
+			}
+		}
+	}
+
+	return res
+}
+
+// OauthConfig returns oauth config with endpoints discovered from provider.
+func (p *OidcProvider) OauthConfig(ctx context.Context) (*oauth2.Config, error) {
+	if _, err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	return p.config, nil
+}
+
+// Identity verifies ID token in token issued by provider and maps claims of it into identity.
+//
+// Claims missing in ID token would be read from userinfo endpoint if present. Groups of user are returned
+// together with identity, login falls back to subject if claim of login is missing.
+func (p *OidcProvider) Identity(ctx context.Context, token *oauth2.Token, nonce string) (*repository.Identity, []string, error) {
+	// 1: verify ID token
+	rawIdToken, _ := token.Extra("id_token").(string)
+	if len(rawIdToken) < 1 {
+		return nil, nil, repository.NewUpstreamf(fmt.Errorf("missing id_token"), "failed to get ID token from %s", p.Name)
+	}
+
+	claims, err := p.VerifyIdToken(ctx, rawIdToken, nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 2: merge claims from userinfo
+	userinfo, err := p.userinfo(ctx, token)
+	if err != nil {
+		return nil, nil, repository.NewUpstreamf(err, "failed to get user info from %s", p.Name)
+	}
+	if sub, _ := userinfo["sub"].(string); len(sub) > 0 && sub == claims["sub"] {
+		for k, v := range userinfo {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+
+	// 3: map claims
+	login := stringClaim(claims, p.Claims.Login)
+	if len(login) < 1 {
+		login = stringClaim(claims, "sub")
+	}
+	if len(login) < 1 {
+		return nil, nil, repository.NewUpstreamf(fmt.Errorf("missing claim:%s", p.Claims.Login), "failed to get login from %s", p.Name)
+	}
+
+	identity := repository.NewIdentity(p.Name, login)
+	identity.Name = stringClaim(claims, p.Claims.Name)
+	identity.Email = stringClaim(claims, p.Claims.Email)
+	identity.AvatarUrl = stringClaim(claims, p.Claims.AvatarUrl)
+
+	return identity, stringsClaim(claims, p.Claims.Groups), nil
+}
+
+// VerifyIdToken verifies signature, issuer, audience, expiration and nonce of ID token and returns claims in it.
+func (p *OidcProvider) VerifyIdToken(ctx context.Context, rawIdToken, nonce string) (jwt.MapClaims, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIdToken, claims, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA:
+		default:
+			return nil, fmt.Errorf("unexpected signing method:%s", t.Header["alg"])
+		}
+
+		kid, _ := t.Header["kid"].(string)
+		return p.keyOf(ctx, kid)
+	})
+	if err != nil {
+		return nil, repository.NewUnauthenticatedf("invalid ID token from %s, %v", p.Name, err)
+	}
+
+	switch {
+	case !claims.VerifyIssuer(discovery.Issuer, true):
+		return nil, repository.NewUnauthenticatedf("ID token was not issued by %s", p.Name)
+	case !claims.VerifyAudience(p.config.ClientID, true):
+		return nil, repository.NewUnauthenticatedf("ID token was not issued for client of %s", p.Name)
+	case !claims.VerifyExpiresAt(time.Now().Unix(), true):
+		return nil, repository.NewUnauthenticatedf("ID token from %s expired", p.Name)
+	case stringClaim(claims, "nonce") != nonce:
+		return nil, repository.NewUnauthenticatedf("ID token from %s was not issued for this login", p.Name)
+	}
+
+	return claims, nil
+}
+
+// RolesOf returns roles of organizations granted to groups, the highest role would be kept if user is in
+// multiple groups mapped to the same organization.
+func (p *OidcProvider) RolesOf(groups []string) map[int]string {
+	res := make(map[int]string)
+	for _, groupRole := range p.GroupRoles {
+		for _, group := range groups {
+			if group == groupRole.Group && controller.RoleRank(groupRole.Role) > controller.RoleRank(res[groupRole.OrgId]) {
+				res[groupRole.OrgId] = groupRole.Role
+			}
+		}
+	}
+
+	return res
+}
+
+// Read discovery document of provider and fill endpoints into oauth config, discovery would be retried
+// on next call if it failed.
+func (p *OidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	discovery := &oidcDiscovery{}
+	if err := p.getJson(ctx, p.DiscoveryUrl, "", discovery); err != nil {
+		return nil, repository.NewUpstreamf(err, "failed to discover OpenID Connect provider %s", p.Name)
+	}
+
+	// issuer must match the one discovery document was read from
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(p.DiscoveryUrl, OidcWellKnownPath) {
+		return nil, repository.NewUpstreamf(fmt.Errorf("issuer %s does not match discovery url", discovery.Issuer),
+			"failed to discover OpenID Connect provider %s", p.Name)
+	}
+	if len(discovery.AuthorizationEndpoint) < 1 || len(discovery.TokenEndpoint) < 1 || len(discovery.JwksUri) < 1 {
+		return nil, repository.NewUpstreamf(fmt.Errorf("missing endpoints in discovery document"),
+			"failed to discover OpenID Connect provider %s", p.Name)
+	}
+
+	p.config.Endpoint = oauth2.Endpoint{
+		AuthURL:  discovery.AuthorizationEndpoint,
+		TokenURL: discovery.TokenEndpoint,
+	}
+	p.discovery = discovery
+
+	return discovery, nil
+}
+
+// Returns signing key with id, keys would be fetched again if missing.
+func (p *OidcProvider) keyOf(ctx context.Context, kid string) (interface{}, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	if time.Since(p.keysFetchedAt) < oidcKeysRefetchInterval {
+		return nil, fmt.Errorf("unknown signing key:%s", kid)
+	}
+
+	// 1: fetch keys
+	jwks := &struct {
+		Keys []*oidcJwk `json:"keys"`
+	}{}
+	if err := p.getJson(ctx, p.discovery.JwksUri, "", jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys, %v", err)
+	}
+	p.keysFetchedAt = time.Now()
+
+	// 2: parse keys, keys for encryption and unsupported keys are skipped
+	keys := make(map[string]interface{})
+	for _, jwk := range jwks.Keys {
+		if jwk.Use == "enc" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	p.keys = keys
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key:%s", kid)
+}
+
+// Read claims from userinfo endpoint, nothing would be returned if provider has no userinfo endpoint.
+func (p *OidcProvider) userinfo(ctx context.Context, token *oauth2.Token) (map[string]interface{}, error) {
+	res := make(map[string]interface{})
+	if len(p.discovery.UserinfoEndpoint) < 1 {
+		return res, nil
+	}
+
+	err := p.getJson(ctx, p.discovery.UserinfoEndpoint, token.AccessToken, &res)
+	return res, err
+}
+
+// Send GET request to url and decode JSON response into res, access token would be sent as bearer token if present.
+func (p *OidcProvider) getJson(ctx context.Context, url, accessToken string, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if len(accessToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(res)
+}
+
+// Convert JSON web key into RSA or ECDSA public key.
+func (jwk *oidcJwk) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[jwk.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve:%s", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type:%s", jwk.Kty)
+	}
+}
+
+// Read string claim, empty string would be returned if missing.
+func stringClaim(claims map[string]interface{}, name string) string {
+	v, _ := claims[name].(string)
+	return v
+}
+
+// Read claim as list of strings, claim with single string is supported as well.
+func stringsClaim(claims map[string]interface{}, name string) []string {
+	res := make([]string, 0)
+
+	switch v := claims[name].(type) {
+	case string:
+		res = append(res, v)
+	case []interface{}:
+		for i := range v {
+			if s, ok := v[i].(string); ok {
+				res = append(res, s)
+			}
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,270 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/pointgoal/workstation/pkg/controller"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/rookie-ninja/rk-entry/entry"
+	"github.com/rookie-ninja/rk-gin/boot"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// fakeOidcServer is a stand-in OpenID Connect provider which issues ID tokens signed with RSA key.
+type fakeOidcServer struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newFakeOidcServer(t *testing.T) *fakeOidcServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	server := &fakeOidcServer{key: key}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(OidcWellKnownPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"userinfo_endpoint":      server.URL + "/userinfo",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "ut-kid",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	// code is exchanged only with verifier of PKCE challenge sent while login
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "ut-code" || codeChallenge(r.Form.Get("code_verifier")) != server.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "ut-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     server.sign(t, "ut-kid", server.idTokenClaims()),
+		})
+	})
+
+	// groups are published by userinfo only
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ut-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"sub":    "ut-sub",
+			"groups": []string{"ut-admins", "ut-devs"},
+		})
+	})
+
+	server.Server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// Returns claims of ID token issued for client with nonce of login.
+func (s *fakeOidcServer) idTokenClaims() jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"iss":                s.URL,
+		"sub":                "ut-sub",
+		"aud":                "ut-client",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              s.nonce,
+		"preferred_username": "ut-login",
+		"name":               "ut-name",
+		"email":              "ut@example.com",
+	}
+	for k, v := range s.claims {
+		claims[k] = v
+	}
+
+	return claims
+}
+
+// Sign claims with RSA key of server.
+func (s *fakeOidcServer) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	res, err := token.SignedString(s.key)
+	assert.Nil(t, err)
+
+	return res
+}
+
+// Register gin, repository, controller and oauth entry with OpenID Connect provider of server.
+func newOidcRouter(t *testing.T, server *fakeOidcServer) (*gin.Engine, *OidcProvider) {
+	ginEntry := rkgin.RegisterGinEntry(rkgin.WithNameGin("workstation"))
+	repository.RegisterMemory()
+	controller.RegisterController()
+	ginEntry.Router.Use(controller.ErrorInterceptor())
+
+	provider := NewOidcProvider("ut-idp", server.URL, &oauth2.Config{
+		ClientID:     "ut-client",
+		ClientSecret: "ut-secret",
+		RedirectURL:  "http://ut-host" + oidcCallbackPath("ut-idp"),
+		Scopes:       []string{"openid", "profile"},
+	}, nil, []*OidcGroupRole{
+		{Group: "ut-devs", OrgId: 1, Role: repository.RoleDeveloper},
+		{Group: "ut-admins", OrgId: 1, Role: repository.RoleMaintainer},
+	})
+	RegisterEntry(WithStateSecret([]byte("ut-secret")), WithCallbackAddr("http://ut-host"), WithOidcProvider(provider))
+	initApi()
+
+	t.Cleanup(func() {
+		rkentry.GlobalAppCtx.RemoveEntry(EntryName)
+		rkentry.GlobalAppCtx.RemoveEntry("workstation")
+	})
+
+	return ginEntry.Router, provider
+}
+
+func TestOidcLogin(t *testing.T) {
+	server := newFakeOidcServer(t)
+	router, _ := newOidcRouter(t, server)
+	repo := controller.GetController().Repo
+	repo.CreateOrg(repository.NewOrg("ut-org"))
+	assert.True(t, controller.IsIdentityTypeRegistered("ut-idp"))
+
+	// 1: login redirects to discovered authorization endpoint with PKCE and nonce
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1/oauth/login/ut-idp", nil))
+	assert.Equal(t, http.StatusFound, resp.Code)
+	location, _ := url.Parse(resp.Header().Get("Location"))
+	assert.Equal(t, server.URL+"/authorize", location.Scheme+"://"+location.Host+location.Path)
+	assert.Equal(t, "ut-client", location.Query().Get("client_id"))
+	server.challenge = location.Query().Get("code_challenge")
+	server.nonce = location.Query().Get("nonce")
+	assert.NotEmpty(t, server.nonce)
+	cookie := resp.Header().Get("Set-Cookie")
+
+	callback := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet,
+			oidcCallbackPath("ut-idp")+"?code=ut-code&state="+url.QueryEscape(location.Query().Get("state")), nil)
+		req.Header.Set("Cookie", cookie)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	// 2: callback signs in with identity mapped from claims and grants roles mapped from groups
+	resp = callback()
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.Code)
+	assert.Equal(t, "http://ut-host/v1/oauth/success?user=ut-login", resp.Header().Get("Location"))
+
+	identity, err := repo.GetIdentity("ut-idp", "ut-login")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-name", identity.Name)
+	assert.Equal(t, "ut@example.com", identity.Email)
+	member, err := repo.GetMember(1, identity.UserId)
+	assert.Nil(t, err)
+	assert.Equal(t, repository.RoleMaintainer, member.Role)
+	token, err := repo.GetAccessToken(identity.UserId, "ut-idp")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-access-token", token.Token)
+
+	// 3: callback could not be replayed
+	resp = callback()
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// 4: unknown provider
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1/oauth/login/ut-unknown", nil))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestOidcProvider_VerifyIdToken(t *testing.T) {
+	server := newFakeOidcServer(t)
+	_, provider := newOidcRouter(t, server)
+	server.nonce = "ut-nonce"
+	ctx := context.Background()
+
+	// 1: happy case
+	claims, err := provider.VerifyIdToken(ctx, server.sign(t, "ut-kid", server.idTokenClaims()), "ut-nonce")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-sub", claims["sub"])
+
+	// 2: nonce of another login
+	_, err = provider.VerifyIdToken(ctx, server.sign(t, "ut-kid", server.idTokenClaims()), "ut-other-nonce")
+	assert.Equal(t, repository.CodeUnauthenticated, repository.CodeOf(err))
+
+	// 3: issued for another client, by another issuer or expired
+	for k, v := range map[string]interface{}{
+		"aud": "ut-other-client",
+		"iss": "https://ut-other-issuer",
+		"exp": time.Now().Add(-time.Minute).Unix(),
+	} {
+		server.claims = jwt.MapClaims{k: v}
+		_, err = provider.VerifyIdToken(ctx, server.sign(t, "ut-kid", server.idTokenClaims()), "ut-nonce")
+		assert.Equal(t, repository.CodeUnauthenticated, repository.CodeOf(err), k)
+	}
+	server.claims = nil
+
+	// 4: signed with unknown key or without signature
+	_, err = provider.VerifyIdToken(ctx, server.sign(t, "ut-other-kid", server.idTokenClaims()), "ut-nonce")
+	assert.Equal(t, repository.CodeUnauthenticated, repository.CodeOf(err))
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, server.idTokenClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	_, err = provider.VerifyIdToken(ctx, unsigned, "ut-nonce")
+	assert.Equal(t, repository.CodeUnauthenticated, repository.CodeOf(err))
+}
+
+func TestOidcProvider_Discover(t *testing.T) {
+	server := newFakeOidcServer(t)
+
+	// issuer must match discovery url
+	provider := NewOidcProvider("ut-idp", server.URL+"/ut-tenant", &oauth2.Config{}, nil, nil)
+	_, err := provider.OauthConfig(context.Background())
+	assert.Equal(t, repository.CodeUpstream, repository.CodeOf(err))
+
+	provider = NewOidcProvider("ut-idp", server.URL+OidcWellKnownPath, &oauth2.Config{}, &OidcClaims{Login: "email"}, nil)
+	config, err := provider.OauthConfig(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, server.URL+"/token", config.Endpoint.TokenURL)
+	assert.Equal(t, "email", provider.Claims.Login)
+	assert.Equal(t, "name", provider.Claims.Name)
+}
+
+func TestOidcProvider_RolesOf(t *testing.T) {
+	provider := NewOidcProvider("ut-idp", "https://ut-issuer", &oauth2.Config{}, nil, []*OidcGroupRole{
+		{Group: "ut-admins", OrgId: 1, Role: repository.RoleOwner},
+		{Group: "ut-devs", OrgId: 1, Role: repository.RoleDeveloper},
+		{Group: "ut-devs", OrgId: 2, Role: repository.RoleDeveloper},
+	})
+
+	assert.Equal(t, map[int]string{1: repository.RoleOwner, 2: repository.RoleDeveloper}, provider.RolesOf([]string{"ut-devs", "ut-admins"}))
+	assert.Empty(t, provider.RolesOf([]string{"ut-others"}))
+}
@@ -0,0 +1,203 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// StateTtlDefault is default duration before state of oauth flow expired
+	StateTtlDefault = 10 * time.Minute
+	// StateCookieName is name of cookie which binds oauth flow to browser
+	StateCookieName = "ws_oauth"
+	// stateIssuer is issuer of signed states
+	stateIssuer = "workstation-oauth"
+	// stateCookiePath limits state cookie to oauth APIs
+	stateCookiePath = "/v1/oauth"
+)
+
+// State is signed as value of state parameter sent to oauth provider.
+//
+// Nonce of state must match the one in state cookie of browser, so that callbacks started from
+// another browser would be rejected.
+type State struct {
+	jwt.StandardClaims
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+}
+
+// StateCookie is signed into state cookie, PKCE verifier is kept in browser only and never sent to provider.
+//
+// IdTokenNonce is generated independently of verifier, ID token of OpenID Connect provider must carry it.
+type StateCookie struct {
+	jwt.StandardClaims
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	Verifier     string `json:"verifier"`
+	IdTokenNonce string `json:"idTokenNonce"`
+}
+
+// Flow is started by StartFlow, state, PKCE challenge and nonce of ID token should be sent to provider.
+type Flow struct {
+	State        string
+	Challenge    string
+	IdTokenNonce string
+}
+
+// usedFlows records nonces of states and hashes of codes which were consumed, with expiration as value.
+// Callbacks replayed with the same state or code would be rejected.
+//
+// Records are kept in memory of current process, so replay protection does not hold across replicas,
+// callbacks load balanced over replicas could be replayed once on each of them until state expired.
+var usedFlows = sync.Map{}
+
+// StartFlow generates state, PKCE verifier and nonce of ID token of provider and set them into state cookie.
+// State, challenge of verifier and nonce of ID token should be sent to provider.
+func (entry *Entry) StartFlow(ctx *gin.Context, provider string) (*Flow, error) {
+	now := time.Now()
+	expiresAt := now.Add(entry.StateTtl).Unix()
+	nonce := randomString(16)
+	verifier := randomString(32)
+	idTokenNonce := randomString(16)
+
+	// 1: sign state
+	state, err := entry.sign(&State{
+		StandardClaims: jwt.StandardClaims{Issuer: stateIssuer, IssuedAt: now.Unix(), ExpiresAt: expiresAt},
+		Provider:       provider,
+		Nonce:          nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 2: sign cookie with verifier
+	cookie, err := entry.sign(&StateCookie{
+		StandardClaims: jwt.StandardClaims{Issuer: stateIssuer, IssuedAt: now.Unix(), ExpiresAt: expiresAt},
+		Provider:       provider,
+		Nonce:          nonce,
+		Verifier:       verifier,
+		IdTokenNonce:   idTokenNonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(StateCookieName, cookie, int(entry.StateTtl.Seconds()), stateCookiePath, "", ctx.Request.TLS != nil, true)
+
+	return &Flow{State: state, Challenge: codeChallenge(verifier), IdTokenNonce: idTokenNonce}, nil
+}
+
+// FinishFlow validates state and code received by callback of provider and returns claims of state cookie
+// with PKCE verifier and nonce of ID token.
+//
+// State must be signed by us, not expired, issued for the provider and bound to state cookie of browser.
+// Every state and code could be used once.
+func (entry *Entry) FinishFlow(ctx *gin.Context, provider, state, code string) (*StateCookie, error) {
+	// state cookie could be used once
+	ctx.SetCookie(StateCookieName, "", -1, stateCookiePath, "", ctx.Request.TLS != nil, true)
+
+	if len(state) < 1 || len(code) < 1 {
+		return nil, repository.NewInvalidArgumentf("missing state or code, please login with /v1/oauth/login/%s", provider)
+	}
+
+	// 1: verify state
+	stateClaims := &State{}
+	if err := entry.parse(state, stateClaims); err != nil || stateClaims.Provider != provider {
+		return nil, repository.NewInvalidArgumentf("invalid oauth state, please login again")
+	}
+
+	// 2: verify state cookie of browser
+	raw, _ := ctx.Cookie(StateCookieName)
+	cookieClaims := &StateCookie{}
+	if err := entry.parse(raw, cookieClaims); err != nil || cookieClaims.Nonce != stateClaims.Nonce {
+		return nil, repository.NewInvalidArgumentf("oauth state was not issued for this browser, please login again")
+	}
+
+	// 3: reject replayed state and code
+	expiresAt := time.Unix(stateClaims.ExpiresAt, 0)
+	if !markUsed("state:"+stateClaims.Nonce, expiresAt) || !markUsed("code:"+hashOf(code), expiresAt) {
+		return nil, repository.NewInvalidArgumentf("oauth state or code was used already, please login again")
+	}
+
+	return cookieClaims, nil
+}
+
+// Sign claims with HMAC.
+func (entry *Entry) sign(claims jwt.Claims) (string, error) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(entry.StateSecret)
+	if err != nil {
+		return "", repository.Wrapf(err, repository.CodeInternal, "failed to sign oauth state")
+	}
+
+	return token, nil
+}
+
+// signedClaims are claims with standard issuer.
+type signedClaims interface {
+	jwt.Claims
+	VerifyIssuer(cmp string, req bool) bool
+}
+
+// Verify signature, expiration and issuer of token and parse claims.
+func (entry *Entry) parse(token string, claims signedClaims) error {
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method:%s", t.Header["alg"])
+		}
+		return entry.StateSecret, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !claims.VerifyIssuer(stateIssuer, true) {
+		return fmt.Errorf("unexpected issuer")
+	}
+
+	return nil
+}
+
+// Mark key as used in current process until expiresAt, false would be returned if it was used already.
+// Expired keys are purged on the way, since nobody could pass verification with them anymore.
+func markUsed(key string, expiresAt time.Time) bool {
+	now := time.Now()
+	usedFlows.Range(func(k, v interface{}) bool {
+		if v.(time.Time).Before(now) {
+			usedFlows.Delete(k)
+		}
+		return true
+	})
+
+	_, loaded := usedFlows.LoadOrStore(key, expiresAt)
+	return !loaded
+}
+
+// Calculate S256 challenge of PKCE verifier.
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Calculate hex encoded sha256 of value, so that raw codes would not be kept in memory.
+func hashOf(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Generate URL safe random string with n bytes of entropy.
+func randomString(n int) string {
+	bytes := make([]byte, n)
+	rand.Read(bytes)
+
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
@@ -0,0 +1,140 @@
+package oauth
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Start flow of provider and returns flow and state cookie set into browser.
+func startFlow(t *testing.T, entry *Entry, provider string) (*Flow, *http.Cookie) {
+	resp := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(resp)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v1/oauth/login/"+provider, nil)
+
+	flow, err := entry.StartFlow(ctx, provider)
+	assert.Nil(t, err)
+
+	cookies := resp.Result().Cookies()
+	assert.Len(t, cookies, 1)
+
+	return flow, cookies[0]
+}
+
+// Finish flow of provider in browser with state cookie.
+func finishFlow(entry *Entry, provider, state, code string, cookie *http.Cookie) (*StateCookie, error) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v1/oauth/callback/"+provider, nil)
+	if cookie != nil {
+		ctx.Request.AddCookie(cookie)
+	}
+
+	return entry.FinishFlow(ctx, provider, state, code)
+}
+
+func TestStateFlow(t *testing.T) {
+	entry := &Entry{StateSecret: []byte("ut-secret"), StateTtl: time.Minute}
+
+	// 1: verifier in state cookie matches challenge sent to provider, nonce of ID token is kept as well
+	flow, cookie := startFlow(t, entry, Github)
+	state := flow.State
+	assert.Equal(t, StateCookieName, cookie.Name)
+	assert.True(t, cookie.HttpOnly)
+	claims, err := finishFlow(entry, Github, state, "ut-code-1", cookie)
+	assert.Nil(t, err)
+	assert.Equal(t, flow.Challenge, codeChallenge(claims.Verifier))
+	assert.NotEmpty(t, flow.IdTokenNonce)
+	assert.Equal(t, flow.IdTokenNonce, claims.IdTokenNonce)
+
+	// 2: missing state or code
+	_, err = finishFlow(entry, Github, "", "ut-code", cookie)
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+	_, err = finishFlow(entry, Github, state, "", cookie)
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+
+	// 3: state issued for another provider
+	flow, cookie = startFlow(t, entry, Github)
+	_, err = finishFlow(entry, "gitlab", flow.State, "ut-code-2", cookie)
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+}
+
+func TestStateFlow_WithTamperedSignature(t *testing.T) {
+	entry := &Entry{StateSecret: []byte("ut-secret"), StateTtl: time.Minute}
+	flow, cookie := startFlow(t, entry, Github)
+	state := flow.State
+
+	// 1: state signed with another secret
+	other := &Entry{StateSecret: []byte("ut-other-secret"), StateTtl: time.Minute}
+	otherFlow, _ := startFlow(t, other, Github)
+	_, err := finishFlow(entry, Github, otherFlow.State, "ut-code", cookie)
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+
+	// 2: signature of state modified
+	tampered := state[:len(state)-2] + "xx"
+	_, err = finishFlow(entry, Github, tampered, "ut-code", cookie)
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+
+	// 3: state cookie modified
+	cookie.Value = cookie.Value[:len(cookie.Value)-2] + "xx"
+	_, err = finishFlow(entry, Github, state, "ut-code", cookie)
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+}
+
+func TestStateFlow_WithExpiredState(t *testing.T) {
+	entry := &Entry{StateSecret: []byte("ut-secret"), StateTtl: time.Minute}
+	claims := jwt.StandardClaims{Issuer: stateIssuer, IssuedAt: time.Now().Add(-time.Hour).Unix(), ExpiresAt: time.Now().Add(-time.Minute).Unix()}
+
+	state, err := entry.sign(&State{StandardClaims: claims, Provider: Github, Nonce: "ut-nonce"})
+	assert.Nil(t, err)
+	raw, err := entry.sign(&StateCookie{StandardClaims: claims, Provider: Github, Nonce: "ut-nonce", Verifier: "ut-verifier"})
+	assert.Nil(t, err)
+
+	_, err = finishFlow(entry, Github, state, "ut-code", &http.Cookie{Name: StateCookieName, Value: raw})
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+}
+
+func TestStateFlow_WithReplayedState(t *testing.T) {
+	entry := &Entry{StateSecret: []byte("ut-secret"), StateTtl: time.Minute}
+	flow, cookie := startFlow(t, entry, Github)
+	state := flow.State
+
+	// 1: first callback succeeds
+	_, err := finishFlow(entry, Github, state, "ut-replayed-code", cookie)
+	assert.Nil(t, err)
+
+	// 2: the same state is rejected, even with another code
+	_, err = finishFlow(entry, Github, state, "ut-another-code", cookie)
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+
+	// 3: the same code is rejected, even with a new state
+	flow, cookie = startFlow(t, entry, Github)
+	_, err = finishFlow(entry, Github, flow.State, "ut-replayed-code", cookie)
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+}
+
+func TestStateFlow_WithMismatchedVerifier(t *testing.T) {
+	entry := &Entry{StateSecret: []byte("ut-secret"), StateTtl: time.Minute}
+	flow, _ := startFlow(t, entry, Github)
+	state := flow.State
+	_, otherCookie := startFlow(t, entry, Github)
+
+	// 1: state cookie of another flow is rejected, so that its verifier would never be used
+	_, err := finishFlow(entry, Github, state, "ut-mismatched-code", otherCookie)
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+
+	// 2: flow without state cookie is rejected
+	_, err = finishFlow(entry, Github, state, "ut-mismatched-code", nil)
+	assert.Equal(t, repository.CodeInvalidArgument, repository.CodeOf(err))
+
+	// 3: verifiers and nonces of ID token of different flows never match each other
+	otherFlow, otherCookie := startFlow(t, entry, Github)
+	claims, err := finishFlow(entry, Github, otherFlow.State, "ut-other-code", otherCookie)
+	assert.Nil(t, err)
+	assert.NotEqual(t, flow.Challenge, codeChallenge(claims.Verifier))
+	assert.NotEqual(t, flow.IdTokenNonce, claims.IdTokenNonce)
+}
@@ -0,0 +1,155 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	githubClient "github.com/google/go-github/v39/github"
+	"github.com/pointgoal/workstation/pkg/controller"
+	"github.com/pointgoal/workstation/pkg/repository"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Returns token provider of oauth destination, nil would be returned if tokens of destination could not be managed.
+func (entry *Entry) newTokenProvider(dest string, config *oauth2.Config) controller.TokenProvider {
+	switch dest {
+	case Github:
+		return &githubTokenProvider{config: config, host: entry.GithubHost}
+	case Gitlab:
+		return &gitlabTokenProvider{config: config}
+	case Gitea:
+		return &giteaTokenProvider{config: config}
+	default:
+		return nil
+	}
+}
+
+// githubTokenProvider refreshes and revokes user tokens of github app or oauth app on primary host.
+type githubTokenProvider struct {
+	config *oauth2.Config
+	host   *controller.GithubHost
+}
+
+// Refresh exchanges refresh token for a new token, github returns a new refresh token as well.
+func (p *githubTokenProvider) Refresh(ctx context.Context, token *repository.AccessToken) (*oauth2.Token, error) {
+	return p.config.TokenSource(ctx, &oauth2.Token{RefreshToken: token.RefreshToken}).Token()
+}
+
+// Revoke revokes token with credentials of app, tokens which are invalid already are ignored.
+func (p *githubTokenProvider) Revoke(ctx context.Context, token *repository.AccessToken) error {
+	transport := &githubClient.BasicAuthTransport{
+		Username: p.config.ClientID,
+		Password: p.config.ClientSecret,
+	}
+	client := p.host.NewClient(transport.Client())
+
+	resp, err := client.Authorizations.Revoke(ctx, p.config.ClientID, token.Token)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	return err
+}
+
+// gitlabTokenProvider refreshes and revokes user tokens of gitlab application.
+type gitlabTokenProvider struct {
+	config *oauth2.Config
+}
+
+// Refresh exchanges refresh token for a new token, gitlab returns a new refresh token as well.
+func (p *gitlabTokenProvider) Refresh(ctx context.Context, token *repository.AccessToken) (*oauth2.Token, error) {
+	return p.config.TokenSource(ctx, &oauth2.Token{RefreshToken: token.RefreshToken}).Token()
+}
+
+// Revoke revokes token with revocation endpoint of gitlab which is next to token endpoint.
+func (p *gitlabTokenProvider) Revoke(ctx context.Context, token *repository.AccessToken) error {
+	endpoint := strings.TrimSuffix(p.config.Endpoint.TokenURL, "/token") + "/revoke"
+	form := url.Values{
+		"token":         {token.Token},
+		"client_id":     {p.config.ClientID},
+		"client_secret": {p.config.ClientSecret},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, endpoint)
+	}
+
+	return nil
+}
+
+// giteaTokenProvider refreshes user tokens of gitea or forgejo application.
+type giteaTokenProvider struct {
+	config *oauth2.Config
+}
+
+// Refresh exchanges refresh token for a new token, gitea returns a new refresh token as well.
+func (p *giteaTokenProvider) Refresh(ctx context.Context, token *repository.AccessToken) (*oauth2.Token, error) {
+	return p.config.TokenSource(ctx, &oauth2.Token{RefreshToken: token.RefreshToken}).Token()
+}
+
+// Revoke does nothing since gitea does not provide revocation endpoint, tokens would expire instead.
+func (p *giteaTokenProvider) Revoke(ctx context.Context, token *repository.AccessToken) error {
+	return nil
+}
+
+// oidcTokenProvider refreshes tokens of OpenID Connect provider and revokes them with revocation endpoint (RFC 7009).
+type oidcTokenProvider struct {
+	provider *OidcProvider
+}
+
+// Refresh exchanges refresh token for a new token with token endpoint discovered from provider.
+func (p *oidcTokenProvider) Refresh(ctx context.Context, token *repository.AccessToken) (*oauth2.Token, error) {
+	config, err := p.provider.OauthConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.TokenSource(ctx, &oauth2.Token{RefreshToken: token.RefreshToken}).Token()
+}
+
+// Revoke revokes token with revocation endpoint, nothing would be done if provider does not publish one.
+func (p *oidcTokenProvider) Revoke(ctx context.Context, token *repository.AccessToken) error {
+	config, err := p.provider.OauthConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	endpoint := p.provider.discovery.RevocationEndpoint
+	if len(endpoint) < 1 {
+		return nil
+	}
+
+	form := url.Values{"token": {token.Token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+
+	resp, err := p.provider.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, endpoint)
+	}
+
+	return nil
+}
@@ -1,54 +1,245 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
 package repository
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	OrgNotFoundMsg              = "organization not found with orgId:%d"
+	OrgFailedToGetMsg           = "failed to get organization with orgId:%d"
+	ProjNotFoundMsg             = "project not found with projId:%d"
+	ProjFailedToGetMsg          = "failed to get project with projId:%d"
+	ProjFailedToRemove          = "failed to remove project with projId:%d"
+	SourceNotFoundMsg           = "source not found with sourceId:%d"
+	SourceFailedToGetMsg        = "failed to get source with sourceId:%d"
+	SourceFailedToRemove        = "failed to remove source with sourceId:%d"
+	OauthSourceNotFoundMsg      = "oauth source not found with source:%s"
+	AccessTokenAlreadyExistMsg  = "access token already exist with type:%s userId:%d"
+	AccessTokenNotFoundMsg      = "access token not found with type:%s userId:%d"
+	AccessTokenFailedToGetMsg   = "failed to get access token with type:%s userId:%d"
+	UserNotFoundMsg             = "user not found with userId:%d"
+	UserFailedToGetMsg          = "failed to get user with userId:%d"
+	IdentityNotFoundMsg         = "identity not found with type:%s login:%s"
+	IdentityOfUserNotFoundMsg   = "identity not found with type:%s userId:%d"
+	IdentityFailedToGetMsg      = "failed to get identity with type:%s login:%s"
+	IdentityAlreadyLinkedMsg    = "identity with type:%s login:%s is linked to another user"
+	MemberNotFoundMsg           = "member not found with orgId:%d userId:%d"
+	MemberFailedToGetMsg        = "failed to get member with orgId:%d userId:%d"
+	PersonalTokenNotFoundMsg    = "personal token not found with tokenId:%d"
+	PersonalTokenFailedToGetMsg = "failed to get personal token with tokenId:%d"
+	IdempotencyKeyNotFoundMsg   = "idempotency key not found with key:%s"
+	WebhookEventNotFoundMsg     = "webhook event not found with delivery:%s"
+)
+
+// Code is a stable and machine-readable identifier of error category.
+// Clients could rely on it instead of parsing error messages.
+type Code string
 
 const (
-	OrgNotFoundMsg             = "organization not found with orgId:%d"
-	OrgFailedToGetMsg          = "failed to get organization with orgId:%d"
-	ProjNotFoundMsg            = "project not found with projId:%d"
-	ProjFailedToGetMsg         = "failed to get project with projId:%d"
-	ProjFailedToRemove         = "failed to remove project with projId:%d"
-	SourceNotFoundMsg          = "source not found with sourceId:%d"
-	SourceFailedToGetMsg       = "failed to get source with sourceId:%d"
-	SourceFailedToRemove       = "failed to remove source with sourceId:%d"
-	OauthSourceNotFoundMsg     = "oauth source not found with source:%s"
-	AccessTokenAlreadyExistMsg = "access token already exist with type:%s user:%s"
-	AccessTokenNotFoundMsg     = "access token not found with type:%s user:%s"
-	AccessTokenFailedToGetMsg  = "failed to get access token with type:%s user:%s"
+	// CodeInternal describes unexpected errors
+	CodeInternal Code = "INTERNAL"
+	// CodeInvalidArgument describes malformed or invalid input
+	CodeInvalidArgument Code = "INVALID_ARGUMENT"
+	// CodeNotFound describes missing resources
+	CodeNotFound Code = "NOT_FOUND"
+	// CodeAlreadyExist describes resources which exist already
+	CodeAlreadyExist Code = "ALREADY_EXIST"
+	// CodeConflict describes requests conflict with current state of resources
+	CodeConflict Code = "CONFLICT"
+	// CodePermissionDenied describes caller without permission
+	CodePermissionDenied Code = "PERMISSION_DENIED"
+	// CodeUpstream describes failures from upstream VCS like github
+	CodeUpstream Code = "UPSTREAM_VCS"
+	// CodeUnauthenticated describes requests without valid session
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	// CodeUnprocessable describes well-formed requests which could not be processed, like reused idempotency key
+	CodeUnprocessable Code = "UNPROCESSABLE"
+	// CodeRateLimited describes requests rejected since rate limit of upstream VCS like github exhausted
+	CodeRateLimited Code = "RATE_LIMITED"
 )
 
-type NotFound struct {
-	msg string `yaml:"err" json:"err"`
+var (
+	// ErrInternal could be used with errors.Is()
+	ErrInternal = &Error{Code: CodeInternal}
+	// ErrInvalidArgument could be used with errors.Is()
+	ErrInvalidArgument = &Error{Code: CodeInvalidArgument}
+	// ErrNotFound could be used with errors.Is()
+	ErrNotFound = &Error{Code: CodeNotFound}
+	// ErrAlreadyExist could be used with errors.Is()
+	ErrAlreadyExist = &Error{Code: CodeAlreadyExist}
+	// ErrConflict could be used with errors.Is()
+	ErrConflict = &Error{Code: CodeConflict}
+	// ErrPermissionDenied could be used with errors.Is()
+	ErrPermissionDenied = &Error{Code: CodePermissionDenied}
+	// ErrUpstream could be used with errors.Is()
+	ErrUpstream = &Error{Code: CodeUpstream}
+	// ErrUnauthenticated could be used with errors.Is()
+	ErrUnauthenticated = &Error{Code: CodeUnauthenticated}
+	// ErrUnprocessable could be used with errors.Is()
+	ErrUnprocessable = &Error{Code: CodeUnprocessable}
+	// ErrRateLimited could be used with errors.Is()
+	ErrRateLimited = &Error{Code: CodeRateLimited}
+)
+
+// Error is the unified error of workstation.
+//
+// Errors with the same Code are treated as the same with errors.Is(),
+// and the original error is still reachable with errors.As() via Unwrap().
+type Error struct {
+	Code    Code          `yaml:"code" json:"code"`
+	Message string        `yaml:"message" json:"message"`
+	Details []interface{} `yaml:"details" json:"details"`
+	// RetryAfter is duration which callers should wait before retrying, it is used by CodeRateLimited
+	RetryAfter time.Duration `yaml:"-" json:"-"`
+	cause      error
 }
 
-func NewNotFound(msg string) *NotFound {
-	return &NotFound{
-		msg: msg,
+// NewError creates an error with code and message.
+func NewError(code Code, msg string, details ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: msg,
+		Details: details,
 	}
 }
 
-func NewNotFoundf(format string, a ...interface{}) *NotFound {
-	return NewNotFound(fmt.Sprintf(format, a...))
+// NewErrorf creates an error with code and formatted message.
+func NewErrorf(code Code, format string, a ...interface{}) *Error {
+	return NewError(code, fmt.Sprintf(format, a...))
+}
+
+// Wrapf annotates err with formatted message.
+// The code of err would be kept if err is an *Error already, otherwise, code provided will be used.
+func Wrapf(err error, code Code, format string, a ...interface{}) *Error {
+	var inner *Error
+	if errors.As(err, &inner) {
+		code = inner.Code
+	}
+
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, a...),
+		cause:   err,
+	}
+}
+
+// CodeOf returns code of err, CodeInternal will be returned if err is not an *Error.
+func CodeOf(err error) Code {
+	var inner *Error
+	if errors.As(err, &inner) {
+		return inner.Code
+	}
+
+	return CodeInternal
 }
 
-func (e *NotFound) Error() string {
-	return e.msg
+// Error returns message of error including cause.
+func (e *Error) Error() string {
+	msg := e.Message
+	if len(msg) < 1 {
+		msg = string(e.Code)
+	}
+
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s", msg, e.cause.Error())
+	}
+
+	return msg
 }
 
-type AlreadyExist struct {
-	msg string `yaml:"err" json:"err"`
+// Unwrap returns the cause of error.
+func (e *Error) Unwrap() error {
+	return e.cause
 }
 
-func NewAlreadyExist(msg string) *NotFound {
-	return &NotFound{
-		msg: msg,
+// Is compares codes of errors.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
 	}
+
+	return e.Code == t.Code
+}
+
+// NewNotFound creates an error with CodeNotFound.
+func NewNotFound(msg string) *Error {
+	return NewError(CodeNotFound, msg)
+}
+
+// NewNotFoundf creates an error with CodeNotFound and formatted message.
+func NewNotFoundf(format string, a ...interface{}) *Error {
+	return NewNotFound(fmt.Sprintf(format, a...))
+}
+
+// NewAlreadyExist creates an error with CodeAlreadyExist.
+func NewAlreadyExist(msg string) *Error {
+	return NewError(CodeAlreadyExist, msg)
 }
 
-func NewAlreadyExistf(format string, a ...interface{}) *NotFound {
+// NewAlreadyExistf creates an error with CodeAlreadyExist and formatted message.
+func NewAlreadyExistf(format string, a ...interface{}) *Error {
 	return NewAlreadyExist(fmt.Sprintf(format, a...))
 }
 
-func (e *AlreadyExist) Error() string {
-	return e.msg
+// NewInvalidArgumentf creates an error with CodeInvalidArgument and formatted message.
+func NewInvalidArgumentf(format string, a ...interface{}) *Error {
+	return NewErrorf(CodeInvalidArgument, format, a...)
+}
+
+// NewConflictf creates an error with CodeConflict and formatted message.
+func NewConflictf(format string, a ...interface{}) *Error {
+	return NewErrorf(CodeConflict, format, a...)
+}
+
+// NewPermissionDeniedf creates an error with CodePermissionDenied and formatted message.
+func NewPermissionDeniedf(format string, a ...interface{}) *Error {
+	return NewErrorf(CodePermissionDenied, format, a...)
+}
+
+// NewUnauthenticatedf creates an error with CodeUnauthenticated and formatted message.
+func NewUnauthenticatedf(format string, a ...interface{}) *Error {
+	return NewErrorf(CodeUnauthenticated, format, a...)
+}
+
+// NewUnprocessablef creates an error with CodeUnprocessable and formatted message.
+func NewUnprocessablef(format string, a ...interface{}) *Error {
+	return NewErrorf(CodeUnprocessable, format, a...)
+}
+
+// NewUpstreamf wraps error returned from upstream VCS with CodeUpstream.
+func NewUpstreamf(err error, format string, a ...interface{}) *Error {
+	return &Error{
+		Code:    CodeUpstream,
+		Message: fmt.Sprintf(format, a...),
+		cause:   err,
+	}
+}
+
+// NewRateLimitedf wraps error returned from upstream VCS with CodeRateLimited, callers should retry after retryAfter.
+func NewRateLimitedf(err error, retryAfter time.Duration, format string, a ...interface{}) *Error {
+	return &Error{
+		Code:       CodeRateLimited,
+		Message:    fmt.Sprintf(format, a...),
+		RetryAfter: retryAfter,
+		cause:      err,
+	}
+}
+
+// RetryAfterOf returns the first RetryAfter in chain of err, 0 will be returned if missing.
+func RetryAfterOf(err error) time.Duration {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if inner, ok := err.(*Error); ok && inner.RetryAfter > 0 {
+			return inner.RetryAfter
+		}
+	}
+
+	return 0
 }
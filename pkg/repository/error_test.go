@@ -0,0 +1,82 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewNotFoundf(t *testing.T) {
+	err := NewNotFoundf(OrgNotFoundMsg, 1)
+
+	assert.Equal(t, CodeNotFound, err.Code)
+	assert.Equal(t, fmt.Sprintf(OrgNotFoundMsg, 1), err.Error())
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.False(t, errors.Is(err, ErrAlreadyExist))
+}
+
+func TestNewAlreadyExistf(t *testing.T) {
+	err := NewAlreadyExistf(AccessTokenAlreadyExistMsg, "github", 1)
+
+	assert.Equal(t, CodeAlreadyExist, err.Code)
+	assert.True(t, errors.Is(err, ErrAlreadyExist))
+	assert.False(t, errors.Is(err, ErrNotFound))
+}
+
+func TestWrapf_WithCode(t *testing.T) {
+	inner := NewNotFoundf(ProjNotFoundMsg, 1)
+	err := Wrapf(fmt.Errorf("ut-wrap: %w", inner), CodeInternal, "ut-message")
+
+	// code of inner error should be kept
+	assert.Equal(t, CodeNotFound, err.Code)
+	assert.Equal(t, CodeNotFound, CodeOf(err))
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.Contains(t, err.Error(), "ut-message")
+	assert.Contains(t, err.Error(), fmt.Sprintf(ProjNotFoundMsg, 1))
+}
+
+func TestWrapf_WithoutCode(t *testing.T) {
+	inner := errors.New("ut-error")
+	err := Wrapf(inner, CodeInternal, "ut-message")
+
+	assert.Equal(t, CodeInternal, err.Code)
+	assert.True(t, errors.Is(err, ErrInternal))
+	assert.True(t, errors.Is(err, inner))
+	assert.Equal(t, inner, errors.Unwrap(err))
+}
+
+func TestNewUpstreamf(t *testing.T) {
+	inner := errors.New("ut-error")
+	err := NewUpstreamf(inner, "ut-message")
+
+	var target *Error
+	assert.True(t, errors.As(fmt.Errorf("ut-wrap: %w", err), &target))
+	assert.Equal(t, CodeUpstream, target.Code)
+	assert.True(t, errors.Is(err, inner))
+}
+
+func TestNewRateLimitedf(t *testing.T) {
+	inner := errors.New("ut-error")
+	err := Wrapf(NewRateLimitedf(inner, time.Minute, "ut-message"), CodeInternal, "ut-wrap")
+
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	assert.True(t, errors.Is(err, inner))
+	assert.Equal(t, time.Minute, RetryAfterOf(err))
+	assert.Equal(t, time.Duration(0), RetryAfterOf(inner))
+}
+
+func TestCodeOf(t *testing.T) {
+	assert.Equal(t, CodeInternal, CodeOf(errors.New("ut-error")))
+	assert.Equal(t, CodeInvalidArgument, CodeOf(NewInvalidArgumentf("ut-error")))
+	assert.Equal(t, CodeConflict, CodeOf(NewConflictf("ut-error")))
+	assert.Equal(t, CodePermissionDenied, CodeOf(NewPermissionDeniedf("ut-error")))
+	assert.Equal(t, CodeUnauthenticated, CodeOf(NewUnauthenticatedf("ut-error")))
+	assert.Equal(t, CodeUnprocessable, CodeOf(NewUnprocessablef("ut-error")))
+}
@@ -9,11 +9,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"github.com/rookie-ninja/rk-common/common"
 	"github.com/rookie-ninja/rk-entry/entry"
 	"github.com/rookie-ninja/rk-query"
 	"go.uber.org/zap"
+	"strings"
 	"time"
 )
 
@@ -26,6 +26,9 @@ func RegisterMemory() *Memory {
 		ZapLoggerEntry:   rkentry.GlobalAppCtx.GetZapLoggerEntryDefault(),
 		EventLoggerEntry: rkentry.GlobalAppCtx.GetEventLoggerEntryDefault(),
 		orgMap:           make(map[int]*Org, 0),
+		userMap:          make(map[int]*User, 0),
+		idempotencyKeys:  make(map[string]*IdempotencyKey, 0),
+		webhookEvents:    make(map[string]*WebhookEvent, 0),
 		lastIndex:        make(map[interface{}]int, 0),
 	}
 
@@ -36,14 +39,21 @@ func RegisterMemory() *Memory {
 
 // Memory implements interface of DataStore whose underlying storage is memory
 type Memory struct {
-	EntryName        string                    `json:"entryName" yaml:"entryName"`
-	EntryType        string                    `json:"entryType" yaml:"entryType"`
-	EntryDescription string                    `json:"entryDescription" yaml:"entryDescription"`
-	ZapLoggerEntry   *rkentry.ZapLoggerEntry   `json:"zapLoggerEntry" yaml:"zapLoggerEntry"`
-	EventLoggerEntry *rkentry.EventLoggerEntry `json:"eventLoggerEntry" yaml:"eventLoggerEntry"`
-	orgMap           map[int]*Org              `json:"-" yaml:"-"`
-	AccessTokenList  []*AccessToken            `json:"-" yaml:"-"`
-	lastIndex        map[interface{}]int       `json:"-" yaml:"-"`
+	EntryName        string                     `json:"entryName" yaml:"entryName"`
+	EntryType        string                     `json:"entryType" yaml:"entryType"`
+	EntryDescription string                     `json:"entryDescription" yaml:"entryDescription"`
+	ZapLoggerEntry   *rkentry.ZapLoggerEntry    `json:"zapLoggerEntry" yaml:"zapLoggerEntry"`
+	EventLoggerEntry *rkentry.EventLoggerEntry  `json:"eventLoggerEntry" yaml:"eventLoggerEntry"`
+	orgMap           map[int]*Org               `json:"-" yaml:"-"`
+	userMap          map[int]*User              `json:"-" yaml:"-"`
+	identityList     []*Identity                `json:"-" yaml:"-"`
+	memberList       []*Member                  `json:"-" yaml:"-"`
+	AccessTokenList  []*AccessToken             `json:"-" yaml:"-"`
+	personalList     []*PersonalToken           `json:"-" yaml:"-"`
+	installationList []*Installation            `json:"-" yaml:"-"`
+	idempotencyKeys  map[string]*IdempotencyKey `json:"-" yaml:"-"`
+	webhookEvents    map[string]*WebhookEvent   `json:"-" yaml:"-"`
+	lastIndex        map[interface{}]int        `json:"-" yaml:"-"`
 }
 
 // Connect to to remote/local provider
@@ -128,10 +138,23 @@ func (m *Memory) ListOrg() ([]*Org, error) {
 	return res, nil
 }
 
+// ListOrgByIds as function name described
+func (m *Memory) ListOrgByIds(orgIds []int) ([]*Org, error) {
+	res := make([]*Org, 0)
+
+	for _, orgId := range orgIds {
+		if org, ok := m.orgMap[orgId]; ok && org != nil {
+			res = append(res, org)
+		}
+	}
+
+	return res, nil
+}
+
 // CreateOrg as function name described
 func (m *Memory) CreateOrg(org *Org) (bool, error) {
 	if org == nil {
-		return false, fmt.Errorf("nil organization")
+		return false, NewInvalidArgumentf("nil organization")
 	}
 
 	m.assignRequiredFields(org)
@@ -162,7 +185,7 @@ func (m *Memory) RemoveOrg(orgId int) (bool, error) {
 // UpdateOrg as function name described
 func (m *Memory) UpdateOrg(org *Org) (bool, error) {
 	if org == nil {
-		return false, errors.New("nil organization")
+		return false, NewInvalidArgumentf("nil organization")
 	}
 
 	old, ok := m.orgMap[org.Id]
@@ -176,6 +199,103 @@ func (m *Memory) UpdateOrg(org *Org) (bool, error) {
 	return true, nil
 }
 
+// ******************************************** //
+// ************** Member related ************** //
+// ******************************************** //
+
+// ListMember as function name described
+func (m *Memory) ListMember(orgId int) ([]*Member, error) {
+	res := make([]*Member, 0)
+
+	for i := range m.memberList {
+		if m.memberList[i].OrgId == orgId {
+			res = append(res, m.memberList[i])
+		}
+	}
+
+	return res, nil
+}
+
+// ListMemberByUser as function name described
+func (m *Memory) ListMemberByUser(userId int) ([]*Member, error) {
+	res := make([]*Member, 0)
+
+	for i := range m.memberList {
+		if m.memberList[i].UserId == userId {
+			res = append(res, m.memberList[i])
+		}
+	}
+
+	return res, nil
+}
+
+// GetMember as function name described
+func (m *Memory) GetMember(orgId, userId int) (*Member, error) {
+	for i := range m.memberList {
+		member := m.memberList[i]
+		if member.OrgId == orgId && member.UserId == userId {
+			return member, nil
+		}
+	}
+
+	return nil, NewNotFoundf(MemberNotFoundMsg, orgId, userId)
+}
+
+// UpsertMember as function name described
+func (m *Memory) UpsertMember(member *Member) (bool, error) {
+	if member == nil {
+		return false, NewInvalidArgumentf("nil member")
+	}
+
+	// update role if exist
+	memberFromRepo, _ := m.GetMember(member.OrgId, member.UserId)
+	if memberFromRepo == nil {
+		m.assignRequiredFields(member)
+		m.memberList = append(m.memberList, member)
+	} else {
+		memberFromRepo.Role = member.Role
+		memberFromRepo.UpdatedAt = time.Now()
+	}
+
+	return true, nil
+}
+
+// RemoveMember as function name described
+func (m *Memory) RemoveMember(orgId, userId int) (bool, error) {
+	index := -1
+
+	for i := range m.memberList {
+		member := m.memberList[i]
+		if member.OrgId == orgId && member.UserId == userId {
+			index = i
+			break
+		}
+	}
+
+	if index < 0 {
+		return false, NewNotFoundf(MemberNotFoundMsg, orgId, userId)
+	}
+
+	m.memberList = append(m.memberList[:index], m.memberList[index+1:]...)
+
+	return true, nil
+}
+
+// RemoveMemberByOrg as function name described
+func (m *Memory) RemoveMemberByOrg(orgId int) (int, error) {
+	members := make([]*Member, 0)
+	for i := range m.memberList {
+		if m.memberList[i].OrgId != orgId {
+			members = append(members, m.memberList[i])
+		}
+	}
+
+	count := len(m.memberList) - len(members)
+	m.memberList = members
+
+	return count, nil
+}
+
 // ********************************************* //
 // ************** Project related ************** //
 // ********************************************* //
@@ -208,7 +328,7 @@ func (m *Memory) ListProj(orgId int) ([]*Proj, error) {
 // CreateProj as function name described
 func (m *Memory) CreateProj(proj *Proj) (bool, error) {
 	if proj == nil {
-		return false, errors.New("nil project")
+		return false, NewInvalidArgumentf("nil project")
 	}
 
 	m.assignRequiredFields(proj)
@@ -272,7 +392,7 @@ func (m *Memory) RemoveProj(projId int) (bool, error) {
 // UpdateProj as function name described
 func (m *Memory) UpdateProj(proj *Proj) (bool, error) {
 	if proj == nil {
-		return false, fmt.Errorf("nil project")
+		return false, NewInvalidArgumentf("nil project")
 	}
 
 	org, ok := m.orgMap[proj.OrgId]
@@ -298,6 +418,74 @@ func (m *Memory) UpdateProj(proj *Proj) (bool, error) {
 	return true, nil
 }
 
+// ListProjIdsByOrg as function name described
+func (m *Memory) ListProjIdsByOrg(orgIds []int) (map[int][]int, error) {
+	res := make(map[int][]int)
+
+	for _, org := range m.filterOrg(orgIds) {
+		ids := make([]int, 0)
+		for i := range org.ProjList {
+			ids = append(ids, org.ProjList[i].Id)
+		}
+		res[org.Id] = ids
+	}
+
+	return res, nil
+}
+
+// ListProjCountByOrg as function name described
+func (m *Memory) ListProjCountByOrg(orgIds []int) (map[int]int, error) {
+	res := make(map[int]int)
+
+	for _, org := range m.filterOrg(orgIds) {
+		res[org.Id] = len(org.ProjList)
+	}
+
+	return res, nil
+}
+
+// ListProjByOrg as function name described
+func (m *Memory) ListProjByOrg(orgIds []int, withSource bool) (map[int][]*Proj, error) {
+	res := make(map[int][]*Proj)
+
+	for _, org := range m.filterOrg(orgIds) {
+		projList := make([]*Proj, 0)
+		for i := range org.ProjList {
+			proj := org.ProjList[i]
+			if !withSource {
+				// copy project, source should stay in memory
+				cp := *proj
+				cp.Source = nil
+				proj = &cp
+			}
+			projList = append(projList, proj)
+		}
+		res[org.Id] = projList
+	}
+
+	return res, nil
+}
+
+// Returns organizations with ids, all organizations would be returned if orgIds is empty.
+func (m *Memory) filterOrg(orgIds []int) []*Org {
+	res := make([]*Org, 0)
+
+	if len(orgIds) < 1 {
+		for _, org := range m.orgMap {
+			res = append(res, org)
+		}
+		return res
+	}
+
+	for _, orgId := range orgIds {
+		if org, ok := m.orgMap[orgId]; ok && org != nil {
+			res = append(res, org)
+		}
+	}
+
+	return res
+}
+
 // Get max ID of Organization
 func (m *Memory) maxOrgId() int {
 	orgList, err := m.ListOrg()
@@ -367,6 +555,27 @@ func (m *Memory) assignRequiredFields(in interface{}) {
 		now := time.Now()
 		v.CreatedAt = now
 		v.UpdatedAt = now
+	case *User:
+		id := m.lastIndex[userKey] + 1
+		m.lastIndex[userKey] = id
+		v.Id = id
+		now := time.Now()
+		v.CreatedAt = now
+		v.UpdatedAt = now
+	case *Identity:
+		id := m.lastIndex[identityKey] + 1
+		m.lastIndex[identityKey] = id
+		v.Id = id
+		now := time.Now()
+		v.CreatedAt = now
+		v.UpdatedAt = now
+	case *Member:
+		id := m.lastIndex[memberKey] + 1
+		m.lastIndex[memberKey] = id
+		v.Id = id
+		now := time.Now()
+		v.CreatedAt = now
+		v.UpdatedAt = now
 	case *AccessToken:
 		id := m.lastIndex[accessTokenKey] + 1
 		m.lastIndex[accessTokenKey] = id
@@ -374,6 +583,41 @@ func (m *Memory) assignRequiredFields(in interface{}) {
 		now := time.Now()
 		v.CreatedAt = now
 		v.UpdatedAt = now
+	case *PersonalToken:
+		id := m.lastIndex[personalKey] + 1
+		m.lastIndex[personalKey] = id
+		v.Id = id
+		now := time.Now()
+		v.CreatedAt = now
+		v.UpdatedAt = now
+	case *IdempotencyKey:
+		id := m.lastIndex[idempotencyKey] + 1
+		m.lastIndex[idempotencyKey] = id
+		v.Id = id
+		now := time.Now()
+		v.CreatedAt = now
+		v.UpdatedAt = now
+	case *Installation:
+		id := m.lastIndex[installKey] + 1
+		m.lastIndex[installKey] = id
+		v.Id = id
+		now := time.Now()
+		v.CreatedAt = now
+		v.UpdatedAt = now
+	case *InstallationRepo:
+		id := m.lastIndex[installRepoKey] + 1
+		m.lastIndex[installRepoKey] = id
+		v.Id = id
+		now := time.Now()
+		v.CreatedAt = now
+		v.UpdatedAt = now
+	case *WebhookEvent:
+		id := m.lastIndex[webhookKey] + 1
+		m.lastIndex[webhookKey] = id
+		v.Id = id
+		now := time.Now()
+		v.CreatedAt = now
+		v.UpdatedAt = now
 	}
 }
 
@@ -384,7 +628,7 @@ func (m *Memory) assignRequiredFields(in interface{}) {
 // CreateSource as function name described
 func (m *Memory) CreateSource(src *Source) (bool, error) {
 	if src == nil {
-		return false, errors.New("nil source")
+		return false, NewInvalidArgumentf("nil source")
 	}
 
 	m.assignRequiredFields(src)
@@ -455,10 +699,237 @@ func (m *Memory) GetSource(sourceId int) (*Source, error) {
 	return res, nil
 }
 
+// UpdateSource as function name described
+func (m *Memory) UpdateSource(src *Source) (bool, error) {
+	if src == nil {
+		return false, NewInvalidArgumentf("nil source")
+	}
+
+	srcFromRepo, err := m.GetSource(src.Id)
+	if err != nil {
+		return false, err
+	}
+
+	srcFromRepo.Repository = src.Repository
+	srcFromRepo.Host = src.Host
+	srcFromRepo.User = src.User
+	srcFromRepo.Status = src.Status
+	srcFromRepo.RepositoryId = src.RepositoryId
+	srcFromRepo.DefaultBranch = src.DefaultBranch
+	srcFromRepo.UpdatedAt = time.Now()
+
+	return true, nil
+}
+
+// ListSourceByRepository as function name described
+func (m *Memory) ListSourceByRepository(repoType, repository string) ([]*Source, error) {
+	res := make([]*Source, 0)
+
+	for _, org := range m.orgMap {
+		for _, proj := range org.ProjList {
+			src := proj.Source
+			if src != nil && src.Type == repoType && strings.EqualFold(src.Repository, repository) {
+				res = append(res, src)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// ListSourceByRepositoryId as function name described
+func (m *Memory) ListSourceByRepositoryId(repoType, repositoryId string) ([]*Source, error) {
+	res := make([]*Source, 0)
+	if len(repositoryId) < 1 {
+		return res, nil
+	}
+
+	for _, org := range m.orgMap {
+		for _, proj := range org.ProjList {
+			src := proj.Source
+			if src != nil && src.Type == repoType && src.RepositoryId == repositoryId {
+				res = append(res, src)
+			}
+		}
+	}
+
+	return res, nil
+}
+
 func (m *Memory) ListPipelineTemplate() ([]*PipelineTemplate, error) {
 	panic("implement me")
 }
 
+// ****************************************** //
+// ************** User related ************** //
+// ****************************************** //
+
+// CreateUser as function name described
+func (m *Memory) CreateUser(user *User) (bool, error) {
+	if user == nil {
+		return false, NewInvalidArgumentf("nil user")
+	}
+
+	m.assignRequiredFields(user)
+	m.userMap[user.Id] = user
+
+	for i := range user.Identities {
+		user.Identities[i].UserId = user.Id
+		if _, err := m.UpsertIdentity(user.Identities[i]); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// GetUser as function name described
+func (m *Memory) GetUser(userId int) (*User, error) {
+	user, ok := m.userMap[userId]
+	if !ok {
+		return nil, NewNotFoundf(UserNotFoundMsg, userId)
+	}
+
+	user.Identities = make([]*Identity, 0)
+	for i := range m.identityList {
+		if m.identityList[i].UserId == userId {
+			user.Identities = append(user.Identities, m.identityList[i])
+		}
+	}
+
+	return user, nil
+}
+
+// UpdateUser as function name described
+func (m *Memory) UpdateUser(user *User) (bool, error) {
+	if user == nil {
+		return false, NewInvalidArgumentf("nil user")
+	}
+
+	userFromRepo, ok := m.userMap[user.Id]
+	if !ok {
+		return false, NewNotFoundf(UserNotFoundMsg, user.Id)
+	}
+
+	userFromRepo.Name = user.Name
+	userFromRepo.Email = user.Email
+	userFromRepo.AvatarUrl = user.AvatarUrl
+	userFromRepo.UpdatedAt = time.Now()
+
+	return true, nil
+}
+
+// RemoveUser as function name described
+func (m *Memory) RemoveUser(userId int) (bool, error) {
+	if _, ok := m.userMap[userId]; !ok {
+		return false, NewNotFoundf(UserNotFoundMsg, userId)
+	}
+
+	delete(m.userMap, userId)
+
+	identities := make([]*Identity, 0)
+	for i := range m.identityList {
+		if m.identityList[i].UserId != userId {
+			identities = append(identities, m.identityList[i])
+		}
+	}
+	m.identityList = identities
+
+	tokens := make([]*AccessToken, 0)
+	for i := range m.AccessTokenList {
+		if m.AccessTokenList[i].UserId != userId {
+			tokens = append(tokens, m.AccessTokenList[i])
+		}
+	}
+	m.AccessTokenList = tokens
+
+	personalTokens := make([]*PersonalToken, 0)
+	for i := range m.personalList {
+		if m.personalList[i].UserId != userId {
+			personalTokens = append(personalTokens, m.personalList[i])
+		}
+	}
+	m.personalList = personalTokens
+
+	members := make([]*Member, 0)
+	for i := range m.memberList {
+		if m.memberList[i].UserId != userId {
+			members = append(members, m.memberList[i])
+		}
+	}
+	m.memberList = members
+
+	m.removeInstallationIf(func(installation *Installation) bool {
+		return installation.UserId == userId
+	})
+
+	return true, nil
+}
+
+// ********************************************** //
+// ************** Identity related ************** //
+// ********************************************** //
+
+// UpsertIdentity as function name described
+func (m *Memory) UpsertIdentity(identity *Identity) (bool, error) {
+	if identity == nil {
+		return false, NewInvalidArgumentf("nil identity")
+	}
+
+	if _, ok := m.userMap[identity.UserId]; !ok {
+		return false, NewNotFoundf(UserNotFoundMsg, identity.UserId)
+	}
+
+	// update identity if exist
+	identityFromRepo, _ := m.GetIdentity(identity.Type, identity.Login)
+	if identityFromRepo == nil {
+		m.assignRequiredFields(identity)
+		m.identityList = append(m.identityList, identity)
+	} else {
+		identityFromRepo.UserId = identity.UserId
+		identityFromRepo.Name = identity.Name
+		identityFromRepo.Email = identity.Email
+		identityFromRepo.AvatarUrl = identity.AvatarUrl
+		identityFromRepo.Password = identity.Password
+		identityFromRepo.UpdatedAt = time.Now()
+	}
+
+	return true, nil
+}
+
+// GetIdentity as function name described
+func (m *Memory) GetIdentity(identityType, login string) (*Identity, error) {
+	for i := range m.identityList {
+		identity := m.identityList[i]
+		if identity.Type == identityType && identity.Login == login {
+			return identity, nil
+		}
+	}
+
+	return nil, NewNotFoundf(IdentityNotFoundMsg, identityType, login)
+}
+
+// RemoveIdentity as function name described
+func (m *Memory) RemoveIdentity(userId int, identityType string) (bool, error) {
+	index := -1
+
+	for i := range m.identityList {
+		identity := m.identityList[i]
+		if identity.UserId == userId && identity.Type == identityType {
+			index = i
+			break
+		}
+	}
+
+	if index < 0 {
+		return false, NewNotFoundf(IdentityOfUserNotFoundMsg, identityType, userId)
+	}
+
+	m.identityList = append(m.identityList[:index], m.identityList[index+1:]...)
+
+	return true, nil
+}
+
 // ************************************************* //
 // ************** AccessToken related ************** //
 // ************************************************* //
@@ -466,51 +937,308 @@ func (m *Memory) ListPipelineTemplate() ([]*PipelineTemplate, error) {
 // UpsertAccessToken as function name described
 func (m *Memory) UpsertAccessToken(token *AccessToken) (bool, error) {
 	if token == nil {
-		return false, errors.New("nil access token")
+		return false, NewInvalidArgumentf("nil access token")
 	}
 
-	m.assignRequiredFields(token)
-
-	// return error if token exist
-	tokenFromRepo, _ := m.GetAccessToken(token.Type, token.User)
+	// update token if exist
+	tokenFromRepo, _ := m.GetAccessToken(token.UserId, token.Type)
 	if tokenFromRepo == nil {
+		m.assignRequiredFields(token)
 		m.AccessTokenList = append(m.AccessTokenList, token)
 	} else {
+		tokenFromRepo.User = token.User
 		tokenFromRepo.Token = token.Token
+		tokenFromRepo.RefreshToken = token.RefreshToken
+		tokenFromRepo.ExpiredAt = token.ExpiredAt
+		tokenFromRepo.Scopes = token.Scopes
+		tokenFromRepo.UpdatedAt = time.Now()
 	}
 
 	return true, nil
 }
 
 // GetAccessToken as function name described
-func (m *Memory) GetAccessToken(repoType, repoUser string) (*AccessToken, error) {
+func (m *Memory) GetAccessToken(userId int, tokenType string) (*AccessToken, error) {
 	for i := range m.AccessTokenList {
 		token := m.AccessTokenList[i]
-		if token.Type == repoType && token.User == repoUser {
+		if token.UserId == userId && token.Type == tokenType {
 			return token, nil
 		}
 	}
 
-	return nil, NewNotFoundf(AccessTokenNotFoundMsg, repoType, repoUser)
+	return nil, NewNotFoundf(AccessTokenNotFoundMsg, tokenType, userId)
+}
+
+// ListAccessToken as function name described
+func (m *Memory) ListAccessToken(tokenType string) ([]*AccessToken, error) {
+	res := make([]*AccessToken, 0)
+
+	for i := range m.AccessTokenList {
+		if len(tokenType) < 1 || m.AccessTokenList[i].Type == tokenType {
+			res = append(res, m.AccessTokenList[i])
+		}
+	}
+
+	return res, nil
 }
 
 // RemoveAccessToken as function name described
-func (m *Memory) RemoveAccessToken(repoType, repoUser string) (bool, error) {
+func (m *Memory) RemoveAccessToken(userId int, tokenType string) (bool, error) {
 	index := -1
 
 	for i := range m.AccessTokenList {
 		token := m.AccessTokenList[i]
-		if token.Type == repoType && token.User == repoUser {
+		if token.UserId == userId && token.Type == tokenType {
 			index = i
 			break
 		}
 	}
 
 	if index < 0 {
-		return false, NewNotFoundf(AccessTokenNotFoundMsg, repoType, repoUser)
+		return false, NewNotFoundf(AccessTokenNotFoundMsg, tokenType, userId)
 	}
 
 	m.AccessTokenList = append(m.AccessTokenList[:index], m.AccessTokenList[index+1:]...)
 
 	return true, nil
 }
+
+// *************************************************** //
+// ************** PersonalToken related ************** //
+// *************************************************** //
+
+// ListPersonalToken as function name described
+func (m *Memory) ListPersonalToken(userId int) ([]*PersonalToken, error) {
+	res := make([]*PersonalToken, 0)
+	for i := range m.personalList {
+		if m.personalList[i].UserId == userId {
+			res = append(res, m.personalList[i])
+		}
+	}
+
+	return res, nil
+}
+
+// CreatePersonalToken as function name described
+func (m *Memory) CreatePersonalToken(token *PersonalToken) (bool, error) {
+	if token == nil {
+		return false, NewInvalidArgumentf("nil personal token")
+	}
+
+	if _, ok := m.userMap[token.UserId]; !ok {
+		return false, NewNotFoundf(UserNotFoundMsg, token.UserId)
+	}
+
+	m.assignRequiredFields(token)
+	m.personalList = append(m.personalList, token)
+
+	return true, nil
+}
+
+// GetPersonalToken as function name described
+func (m *Memory) GetPersonalToken(tokenId int) (*PersonalToken, error) {
+	for i := range m.personalList {
+		if m.personalList[i].Id == tokenId {
+			return m.personalList[i], nil
+		}
+	}
+
+	return nil, NewNotFoundf(PersonalTokenNotFoundMsg, tokenId)
+}
+
+// GetPersonalTokenByHash as function name described
+func (m *Memory) GetPersonalTokenByHash(hash string) (*PersonalToken, error) {
+	for i := range m.personalList {
+		if m.personalList[i].Hash == hash {
+			return m.personalList[i], nil
+		}
+	}
+
+	return nil, NewNotFoundf("personal token not found")
+}
+
+// UpdatePersonalToken as function name described
+func (m *Memory) UpdatePersonalToken(token *PersonalToken) (bool, error) {
+	if token == nil {
+		return false, NewInvalidArgumentf("nil personal token")
+	}
+
+	tokenFromRepo, err := m.GetPersonalToken(token.Id)
+	if err != nil {
+		return false, err
+	}
+
+	tokenFromRepo.Name = token.Name
+	tokenFromRepo.Prefix = token.Prefix
+	tokenFromRepo.Hash = token.Hash
+	tokenFromRepo.Scopes = token.Scopes
+	tokenFromRepo.ExpiredAt = token.ExpiredAt
+	tokenFromRepo.LastUsedAt = token.LastUsedAt
+	tokenFromRepo.UpdatedAt = time.Now()
+
+	return true, nil
+}
+
+// RemovePersonalToken as function name described
+func (m *Memory) RemovePersonalToken(tokenId int) (bool, error) {
+	for i := range m.personalList {
+		if m.personalList[i].Id == tokenId {
+			m.personalList = append(m.personalList[:i], m.personalList[i+1:]...)
+			return true, nil
+		}
+	}
+
+	return false, NewNotFoundf(PersonalTokenNotFoundMsg, tokenId)
+}
+
+// ************************************************* //
+// ************ IdempotencyKey related ************* //
+// ************************************************* //
+
+// UpsertIdempotencyKey as function name described
+func (m *Memory) UpsertIdempotencyKey(key *IdempotencyKey) (bool, error) {
+	if key == nil {
+		return false, NewInvalidArgumentf("nil idempotency key")
+	}
+
+	m.assignRequiredFields(key)
+	m.idempotencyKeys[key.Key] = key
+
+	return true, nil
+}
+
+// GetIdempotencyKey as function name described
+func (m *Memory) GetIdempotencyKey(key string) (*IdempotencyKey, error) {
+	res, ok := m.idempotencyKeys[key]
+	if !ok {
+		return nil, NewNotFoundf(IdempotencyKeyNotFoundMsg, key)
+	}
+
+	return res, nil
+}
+
+// RemoveExpiredIdempotencyKey as function name described
+func (m *Memory) RemoveExpiredIdempotencyKey(t time.Time) (int, error) {
+	var count int
+
+	for k, v := range m.idempotencyKeys {
+		if v.ExpiredAt.Before(t) {
+			delete(m.idempotencyKeys, k)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// ************************************************* //
+// ************** Installation related ************* //
+// ************************************************* //
+
+// ListInstallation as function name described
+func (m *Memory) ListInstallation(userId int, installationType string, withRepos bool) ([]*Installation, error) {
+	res := make([]*Installation, 0)
+
+	for _, installation := range m.installationList {
+		if (userId > 0 && installation.UserId != userId) || (len(installationType) > 0 && installation.Type != installationType) {
+			continue
+		}
+
+		if !withRepos {
+			// copy installation, repositories should stay in memory
+			cp := *installation
+			cp.Repos = nil
+			installation = &cp
+		}
+		res = append(res, installation)
+	}
+
+	return res, nil
+}
+
+// ListInstallationByRemoteId as function name described
+func (m *Memory) ListInstallationByRemoteId(installationType, host string, remoteId int64) ([]*Installation, error) {
+	res := make([]*Installation, 0)
+
+	for _, installation := range m.installationList {
+		if installation.Type == installationType && installation.Host == host && installation.RemoteId == remoteId {
+			res = append(res, installation)
+		}
+	}
+
+	return res, nil
+}
+
+// ReplaceInstallation as function name described
+func (m *Memory) ReplaceInstallation(userId int, installationType string, installations []*Installation) (bool, error) {
+	m.removeInstallationIf(func(installation *Installation) bool {
+		return installation.UserId == userId && installation.Type == installationType
+	})
+
+	for _, installation := range installations {
+		installation.UserId = userId
+		installation.Type = installationType
+		m.assignRequiredFields(installation)
+		for _, repo := range installation.Repos {
+			m.assignRequiredFields(repo)
+			repo.InstallationId = installation.Id
+		}
+		m.installationList = append(m.installationList, installation)
+	}
+
+	return true, nil
+}
+
+// RemoveInstallationByRemoteId as function name described
+func (m *Memory) RemoveInstallationByRemoteId(installationType, host string, remoteId int64) (int, error) {
+	return m.removeInstallationIf(func(installation *Installation) bool {
+		return installation.Type == installationType && installation.Host == host && installation.RemoteId == remoteId
+	}), nil
+}
+
+// Remove installations matched and returns count of removed installations.
+func (m *Memory) removeInstallationIf(match func(installation *Installation) bool) int {
+	installations := make([]*Installation, 0)
+	for i := range m.installationList {
+		if !match(m.installationList[i]) {
+			installations = append(installations, m.installationList[i])
+		}
+	}
+
+	count := len(m.installationList) - len(installations)
+	m.installationList = installations
+
+	return count
+}
+
+// ************************************************* //
+// ************** WebhookEvent related ************* //
+// ************************************************* //
+
+// UpsertWebhookEvent as function name described
+func (m *Memory) UpsertWebhookEvent(event *WebhookEvent) (bool, error) {
+	if event == nil {
+		return false, NewInvalidArgumentf("nil webhook event")
+	}
+
+	if stored, ok := m.webhookEvents[event.Delivery]; ok {
+		event.Id = stored.Id
+		event.CreatedAt = stored.CreatedAt
+		event.UpdatedAt = time.Now()
+	} else {
+		m.assignRequiredFields(event)
+	}
+	m.webhookEvents[event.Delivery] = event
+
+	return true, nil
+}
+
+// GetWebhookEvent as function name described
+func (m *Memory) GetWebhookEvent(delivery string) (*WebhookEvent, error) {
+	res, ok := m.webhookEvents[delivery]
+	if !ok {
+		return nil, NewNotFoundf(WebhookEventNotFoundMsg, delivery)
+	}
+
+	return res, nil
+}
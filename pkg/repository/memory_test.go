@@ -7,8 +7,10 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestRegisterMemory_HappyCase(t *testing.T) {
@@ -83,6 +85,11 @@ func TestMemory_Organization_Operations(t *testing.T) {
 	assert.True(t, succ)
 	assert.Nil(t, err)
 
+	// list orgs by ids, missing ids are ignored
+	orgList, err = repo.ListOrgByIds([]int{org.Id, org.Id + 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []*Org{org}, orgList)
+
 	// Update org
 	org.Name = "ut-org-new"
 	succ, err = repo.UpdateOrg(org)
@@ -116,6 +123,29 @@ func TestMemory_Project_Operations(t *testing.T) {
 	assert.True(t, succ)
 	assert.Nil(t, err)
 
+	// list ids of projects by org
+	idMap, err := repo.ListProjIdsByOrg([]int{org.Id})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{proj.Id}, idMap[org.Id])
+
+	// list counts of projects by org
+	countMap, err := repo.ListProjCountByOrg([]int{org.Id})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, countMap[org.Id])
+
+	// list projects by org without source
+	proj.Source = NewSource("github", "ut-user/ut-repo")
+	projMap, err := repo.ListProjByOrg(nil, false)
+	assert.Nil(t, err)
+	assert.Len(t, projMap[org.Id], 1)
+	assert.Nil(t, projMap[org.Id][0].Source)
+	assert.NotNil(t, proj.Source)
+
+	// list projects by org with source
+	projMap, err = repo.ListProjByOrg([]int{org.Id}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, proj.Source, projMap[org.Id][0].Source)
+
 	// update proj
 	proj.Name = "ut-proj-new"
 	succ, err = repo.UpdateProj(proj)
@@ -147,12 +177,317 @@ func TestMemory_Source_Operations(t *testing.T) {
 	// create source
 	src := NewSource("ut-repo-type", "ut-repo")
 	src.ProjId = proj.Id
+	src.RepositoryId = "ut-repo-id"
 	succ, err = repo.CreateSource(src)
 	assert.True(t, succ)
 	assert.Nil(t, err)
 
+	// list sources by id of repository
+	srcList, err := repo.ListSourceByRepositoryId("ut-repo-type", "ut-repo-id")
+	assert.Nil(t, err)
+	assert.Len(t, srcList, 1)
+	srcList, err = repo.ListSourceByRepositoryId("ut-repo-type", "")
+	assert.Nil(t, err)
+	assert.Empty(t, srcList)
+
+	// list sources by repository case-insensitively
+	srcList, err = repo.ListSourceByRepository("ut-repo-type", "UT-REPO")
+	assert.Nil(t, err)
+	assert.Len(t, srcList, 1)
+	srcList, err = repo.ListSourceByRepository("ut-other-type", "ut-repo")
+	assert.Nil(t, err)
+	assert.Empty(t, srcList)
+
+	// update source
+	update := *src
+	update.Repository = "ut-renamed"
+	update.Status = SourceStatusBroken
+	succ, err = repo.UpdateSource(&update)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	srcFromRepo, err := repo.GetSource(src.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-renamed", srcFromRepo.Repository)
+	assert.True(t, srcFromRepo.IsBroken())
+
 	// remove source
 	succ, err = repo.RemoveSource(src.Id)
 	assert.True(t, succ)
 	assert.Nil(t, err)
 }
+
+func TestMemory_IdempotencyKey_Operations(t *testing.T) {
+	repo := RegisterMemory()
+
+	// get missing key
+	key, err := repo.GetIdempotencyKey("ut-key")
+	assert.Nil(t, key)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// upsert key
+	key = NewIdempotencyKey("ut-key", "ut-fingerprint")
+	key.ExpiredAt = time.Now().Add(time.Minute)
+	succ, err := repo.UpsertIdempotencyKey(key)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+
+	// get key
+	keyFromRepo, err := repo.GetIdempotencyKey("ut-key")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-fingerprint", keyFromRepo.Fingerprint)
+
+	// remove expired keys
+	count, err := repo.RemoveExpiredIdempotencyKey(time.Now())
+	assert.Zero(t, count)
+	assert.Nil(t, err)
+	count, err = repo.RemoveExpiredIdempotencyKey(time.Now().Add(time.Hour))
+	assert.Equal(t, 1, count)
+	assert.Nil(t, err)
+}
+
+func TestMemory_Installation_Operations(t *testing.T) {
+	repo := RegisterMemory()
+
+	// replace installations of user
+	installation := NewInstallation(1, "github", 7)
+	installation.Host = "github.com"
+	installation.Repos = append(installation.Repos, NewInstallationRepo("ut-owner/ut-repo", "ut-repo"))
+	succ, err := repo.ReplaceInstallation(1, "github", []*Installation{installation})
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	succ, err = repo.ReplaceInstallation(2, "github", []*Installation{NewInstallation(2, "github", 7)})
+	assert.True(t, succ)
+	assert.Nil(t, err)
+
+	// list installations of user with repositories
+	installations, err := repo.ListInstallation(1, "github", true)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 1)
+	assert.Equal(t, installation.Id, installations[0].Repos[0].InstallationId)
+
+	// list installations of all users without repositories
+	installations, err = repo.ListInstallation(0, "", false)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 2)
+	assert.Nil(t, installations[0].Repos)
+	assert.Len(t, installation.Repos, 1)
+
+	// list installations by remote id
+	installations, err = repo.ListInstallationByRemoteId("github", "github.com", 7)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 1)
+
+	// replace installations of user with nothing
+	succ, err = repo.ReplaceInstallation(2, "github", nil)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+
+	// remove installations by remote id
+	count, err := repo.RemoveInstallationByRemoteId("github", "github.com", 7)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	installations, err = repo.ListInstallation(0, "", false)
+	assert.Nil(t, err)
+	assert.Empty(t, installations)
+}
+
+func TestMemory_WebhookEvent_Operations(t *testing.T) {
+	repo := RegisterMemory()
+
+	// get missing event
+	event, err := repo.GetWebhookEvent("ut-delivery")
+	assert.Nil(t, event)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// upsert event
+	event = NewWebhookEvent("github", "ut-delivery", "ping")
+	succ, err := repo.UpsertWebhookEvent(event)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+
+	// upsert event with the same delivery
+	processed := NewWebhookEvent("github", "ut-delivery", "ping")
+	processed.Status = WebhookStatusProcessed
+	succ, err = repo.UpsertWebhookEvent(processed)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	assert.Equal(t, event.Id, processed.Id)
+
+	// get event
+	eventFromRepo, err := repo.GetWebhookEvent("ut-delivery")
+	assert.Nil(t, err)
+	assert.True(t, eventFromRepo.IsProcessed())
+}
+
+func TestMemory_User_Operations(t *testing.T) {
+	repo := RegisterMemory()
+
+	// create user with identity
+	user := NewUser("ut-user")
+	user.Identities = append(user.Identities, NewIdentity(IdentityGithub, "ut-login"))
+	succ, err := repo.CreateUser(user)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+
+	// get user with identities
+	userFromRepo, err := repo.GetUser(user.Id)
+	assert.Nil(t, err)
+	assert.Len(t, userFromRepo.Identities, 1)
+	assert.NotNil(t, userFromRepo.GetIdentity(IdentityGithub))
+	assert.Nil(t, userFromRepo.GetIdentity(IdentityGitlab))
+
+	// update user
+	user.Email = "ut@pointgoal.io"
+	succ, err = repo.UpdateUser(user)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+
+	// link identity to missing user
+	identity := NewIdentity(IdentityGitlab, "ut-login")
+	identity.UserId = 100
+	succ, err = repo.UpsertIdentity(identity)
+	assert.False(t, succ)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// link identity
+	identity.UserId = user.Id
+	succ, err = repo.UpsertIdentity(identity)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	identityFromRepo, err := repo.GetIdentity(IdentityGitlab, "ut-login")
+	assert.Nil(t, err)
+	assert.Equal(t, user.Id, identityFromRepo.UserId)
+
+	// upsert access token
+	succ, err = repo.UpsertAccessToken(NewAccessToken(user.Id, IdentityGithub, "ut-login", "ut-token"))
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	succ, err = repo.UpsertAccessToken(NewAccessToken(user.Id, IdentityGithub, "ut-login", "ut-new-token"))
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	token, err := repo.GetAccessToken(user.Id, IdentityGithub)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-new-token", token.Token)
+	assert.Len(t, repo.AccessTokenList, 1)
+
+	// list access tokens by type
+	tokenList, err := repo.ListAccessToken(IdentityGithub)
+	assert.Nil(t, err)
+	assert.Len(t, tokenList, 1)
+	tokenList, err = repo.ListAccessToken(IdentityGitlab)
+	assert.Nil(t, err)
+	assert.Empty(t, tokenList)
+
+	// create personal token
+	succ, err = repo.CreatePersonalToken(NewPersonalToken(user.Id, "ut-name", "wsp_ut", "ut-hash"))
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	personal, err := repo.GetPersonalTokenByHash("ut-hash")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-name", personal.Name)
+	personalList, _ := repo.ListPersonalToken(user.Id)
+	assert.Len(t, personalList, 1)
+
+	// unlink identity
+	succ, err = repo.RemoveIdentity(user.Id, IdentityGitlab)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	succ, err = repo.RemoveIdentity(user.Id, IdentityGitlab)
+	assert.False(t, succ)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// remove user together with identities and tokens
+	succ, err = repo.RemoveUser(user.Id)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	_, err = repo.GetIdentity(IdentityGithub, "ut-login")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = repo.GetAccessToken(user.Id, IdentityGithub)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = repo.GetPersonalToken(personal.Id)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = repo.GetUser(user.Id)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestMemory_Member_Operations(t *testing.T) {
+	repo := RegisterMemory()
+
+	// add members
+	succ, err := repo.UpsertMember(NewMember(1, 1, RoleOwner))
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	succ, err = repo.UpsertMember(NewMember(1, 2, RoleViewer))
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	succ, err = repo.UpsertMember(NewMember(2, 2, RoleOwner))
+	assert.True(t, succ)
+	assert.Nil(t, err)
+
+	// change role
+	succ, err = repo.UpsertMember(NewMember(1, 2, RoleDeveloper))
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	member, err := repo.GetMember(1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, RoleDeveloper, member.Role)
+
+	// list members
+	memberList, err := repo.ListMember(1)
+	assert.Nil(t, err)
+	assert.Len(t, memberList, 2)
+	memberList, err = repo.ListMemberByUser(2)
+	assert.Nil(t, err)
+	assert.Len(t, memberList, 2)
+
+	// remove member
+	succ, err = repo.RemoveMember(1, 2)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	_, err = repo.GetMember(1, 2)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	succ, err = repo.RemoveMember(1, 2)
+	assert.False(t, succ)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// remove members of organization
+	count, err := repo.RemoveMemberByOrg(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestMemory_PersonalToken_Operations(t *testing.T) {
+	repo := RegisterMemory()
+
+	// token of missing user
+	succ, err := repo.CreatePersonalToken(NewPersonalToken(1, "ut-name", "wsp_ut", "ut-hash"))
+	assert.False(t, succ)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// create token
+	user := NewUser("ut-user")
+	repo.CreateUser(user)
+	token := NewPersonalToken(user.Id, "ut-name", "wsp_ut", "ut-hash")
+	succ, err = repo.CreatePersonalToken(token)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+
+	// update hash of token
+	token.Hash = "ut-new-hash"
+	succ, err = repo.UpdatePersonalToken(token)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	_, err = repo.GetPersonalTokenByHash("ut-hash")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	tokenFromRepo, err := repo.GetPersonalTokenByHash("ut-new-hash")
+	assert.Nil(t, err)
+	assert.Equal(t, token.Id, tokenFromRepo.Id)
+
+	// remove token
+	succ, err = repo.RemovePersonalToken(token.Id)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+	succ, err = repo.RemovePersonalToken(token.Id)
+	assert.False(t, succ)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
@@ -17,6 +17,14 @@ var (
 	projKey        = &Proj{}
 	sourceKey      = &Source{}
 	accessTokenKey = &AccessToken{}
+	personalKey    = &PersonalToken{}
+	userKey        = &User{}
+	identityKey    = &Identity{}
+	memberKey      = &Member{}
+	idempotencyKey = &IdempotencyKey{}
+	webhookKey     = &WebhookEvent{}
+	installKey     = &Installation{}
+	installRepoKey = &InstallationRepo{}
 )
 
 // ************************************************ //
@@ -60,6 +68,45 @@ func (org *Org) String() string {
 	return string(bytes)
 }
 
+// ******************************************** //
+// ************** Member related ************** //
+// ******************************************** //
+
+const (
+	// RoleOwner could do everything in organization including deleting it
+	RoleOwner = "owner"
+	// RoleMaintainer could manage organization, members and projects
+	RoleMaintainer = "maintainer"
+	// RoleDeveloper could work on projects and sources
+	RoleDeveloper = "developer"
+	// RoleViewer could read organization and projects only
+	RoleViewer = "viewer"
+)
+
+// Member defines membership of user in organization with role.
+type Member struct {
+	Base
+	Id     int    `yaml:"id" json:"id" gorm:"primaryKey"`
+	OrgId  int    `yaml:"orgId" json:"orgId" gorm:"index"`
+	UserId int    `yaml:"userId" json:"userId" gorm:"index"`
+	Role   string `yaml:"role" json:"role"`
+}
+
+// NewMember create a member of organization with role.
+func NewMember(orgId, userId int, role string) *Member {
+	return &Member{
+		OrgId:  orgId,
+		UserId: userId,
+		Role:   role,
+	}
+}
+
+// String will marshal member into json format.
+func (member *Member) String() string {
+	bytes, _ := json.Marshal(member)
+	return string(bytes)
+}
+
 // ********************************************* //
 // ************** Project related ************** //
 // ********************************************* //
@@ -96,13 +143,25 @@ func (proj *Proj) String() string {
 // ************** Source related ************** //
 // ******************************************** //
 
+const (
+	// SourceStatusActive is status of source which could be accessed
+	SourceStatusActive = "active"
+	// SourceStatusBroken is status of source which could not be accessed anymore, like app uninstalled from repository
+	SourceStatusBroken = "broken"
+)
+
 type Source struct {
 	Base
 	Id         int    `yaml:"id" json:"id" gorm:"primaryKey"`
 	ProjId     int    `yaml:"projId" json:"projId" gorm:"index"`
 	Type       string `yaml:"type" json:"type" gorm:"index"`
 	Repository string `yaml:"repository" json:"repository"`
+	Host       string `yaml:"host" json:"host"`
 	User       string `yaml:"user" json:"user"`
+	Status     string `yaml:"status" json:"status"`
+	// id of repository in remote code repository, which is kept after repository renamed
+	RepositoryId  string `yaml:"repositoryId" json:"repositoryId" gorm:"index;size:255"`
+	DefaultBranch string `yaml:"defaultBranch" json:"defaultBranch"`
 }
 
 // NewSource create a project with params.
@@ -111,43 +170,323 @@ func NewSource(repoType, repository string) *Source {
 	return &Source{
 		Type:       repoType,
 		Repository: repository,
+		Status:     SourceStatusActive,
 	}
 }
 
+// IsBroken checks whether source is marked as broken, sources created before status introduced are active.
+func (src *Source) IsBroken() bool {
+	return src.Status == SourceStatusBroken
+}
+
 // String will marshal source into json format.
 func (src *Source) String() string {
 	bytes, _ := json.Marshal(src)
 	return string(bytes)
 }
 
+// ****************************************** //
+// ************** User related ************** //
+// ****************************************** //
+
+const (
+	// IdentityGithub is type of identity signed in with github
+	IdentityGithub = "github"
+	// IdentityGitlab is type of identity signed in with gitlab
+	IdentityGitlab = "gitlab"
+	// IdentityGitea is type of identity signed in with gitea or forgejo
+	IdentityGitea = "gitea"
+	// IdentityLocal is type of identity signed in with password managed by workstation
+	IdentityLocal = "local"
+)
+
+// User defines users of workstation.
+// User could sign in with any of linked identities, access tokens of VCS belong to user.
+type User struct {
+	Base
+	Id         int         `yaml:"id" json:"id" gorm:"primaryKey"`
+	Name       string      `yaml:"name" json:"name"`
+	Email      string      `yaml:"email" json:"email"`
+	AvatarUrl  string      `yaml:"avatarUrl" json:"avatarUrl"`
+	Identities []*Identity `yaml:"identities" json:"identities"`
+}
+
+// NewUser create a user with display name.
+func NewUser(name string) *User {
+	return &User{
+		Name:       name,
+		Identities: make([]*Identity, 0),
+	}
+}
+
+// GetIdentity returns linked identity with type, nil would be returned if missing.
+func (user *User) GetIdentity(identityType string) *Identity {
+	for i := range user.Identities {
+		if user.Identities[i].Type == identityType {
+			return user.Identities[i]
+		}
+	}
+
+	return nil
+}
+
+// String will marshal user into json format.
+func (user *User) String() string {
+	bytes, _ := json.Marshal(user)
+	return string(bytes)
+}
+
+// Identity defines account of user in VCS like github, or local account managed by workstation.
+// A user could link at most one identity of each type.
+type Identity struct {
+	Base
+	Id        int    `yaml:"id" json:"id" gorm:"primaryKey"`
+	UserId    int    `yaml:"userId" json:"userId" gorm:"index"`
+	Type      string `yaml:"type" json:"type" gorm:"index;size:64"`
+	Login     string `yaml:"login" json:"login" gorm:"index;size:255"`
+	Name      string `yaml:"name" json:"name"`
+	Email     string `yaml:"email" json:"email"`
+	AvatarUrl string `yaml:"avatarUrl" json:"avatarUrl"`
+	Password  string `yaml:"-" json:"-"`
+}
+
+// NewIdentity create an identity with type and login.
+func NewIdentity(identityType, login string) *Identity {
+	return &Identity{
+		Type:  identityType,
+		Login: login,
+	}
+}
+
+// String will marshal identity into json format.
+func (identity *Identity) String() string {
+	bytes, _ := json.Marshal(identity)
+	return string(bytes)
+}
+
 // ************************************************* //
 // ************** AccessToken related ************** //
 // ************************************************* //
 
+// AccessToken defines token of VCS which belongs to user, a user has at most one token of each type.
+//
+// Tokens without ExpiredAt never expire, expired tokens could be refreshed with RefreshToken if present.
+// Scopes are space separated scopes granted by VCS.
 type AccessToken struct {
 	Base
-	Id    int    `yaml:"id" json:"id" gorm:"primaryKey"`
-	Type  string `yaml:"type" json:"type" gorm:"index"`
-	User  string `yaml:"user" json:"user"`
-	Token string `yaml:"-" json:"-"`
+	Id           int        `yaml:"id" json:"id" gorm:"primaryKey"`
+	UserId       int        `yaml:"userId" json:"userId" gorm:"index"`
+	Type         string     `yaml:"type" json:"type" gorm:"index"`
+	User         string     `yaml:"user" json:"user"`
+	Token        string     `yaml:"-" json:"-"`
+	RefreshToken string     `yaml:"-" json:"-"`
+	ExpiredAt    *time.Time `yaml:"expiredAt" json:"expiredAt,omitempty"`
+	Scopes       string     `yaml:"scopes" json:"scopes"`
 }
 
-// NewAccessToken create a project with params.
-// A new name will be assigned with random Id if name is empty.
-func NewAccessToken(repoType, repoUser, repoToken string) *AccessToken {
+// NewAccessToken create an access token of user with login in VCS.
+func NewAccessToken(userId int, repoType, repoUser, repoToken string) *AccessToken {
 	return &AccessToken{
-		Type:  repoType,
-		User:  repoUser,
-		Token: repoToken,
+		UserId: userId,
+		Type:   repoType,
+		User:   repoUser,
+		Token:  repoToken,
 	}
 }
 
+// ExpiresWithin checks whether token expires within duration from now, tokens without expiration never expire.
+func (token *AccessToken) ExpiresWithin(d time.Duration) bool {
+	if token.ExpiredAt == nil {
+		return false
+	}
+
+	return !time.Now().Add(d).Before(*token.ExpiredAt)
+}
+
 // String will marshal token into json format.
 func (token *AccessToken) String() string {
 	bytes, _ := json.Marshal(token)
 	return string(bytes)
 }
 
+// *************************************************** //
+// ************** PersonalToken related ************** //
+// *************************************************** //
+
+// PersonalToken defines personal access token minted by user for CLI and automation.
+//
+// Only sha256 hash of token is stored, Prefix keeps the first characters of token so that users could tell
+// tokens apart. Scopes are space separated scopes granted to token, tokens without ExpiredAt never expire.
+type PersonalToken struct {
+	Base
+	Id         int        `yaml:"id" json:"id" gorm:"primaryKey"`
+	UserId     int        `yaml:"userId" json:"userId" gorm:"index"`
+	Name       string     `yaml:"name" json:"name" gorm:"size:64"`
+	Prefix     string     `yaml:"prefix" json:"prefix"`
+	Hash       string     `yaml:"-" json:"-" gorm:"uniqueIndex;size:64"`
+	Scopes     string     `yaml:"scopes" json:"scopes"`
+	ExpiredAt  *time.Time `yaml:"expiredAt" json:"expiredAt,omitempty"`
+	LastUsedAt *time.Time `yaml:"lastUsedAt" json:"lastUsedAt,omitempty"`
+}
+
+// NewPersonalToken create a personal token of user with hash of token.
+func NewPersonalToken(userId int, name, prefix, hash string) *PersonalToken {
+	return &PersonalToken{
+		UserId: userId,
+		Name:   name,
+		Prefix: prefix,
+		Hash:   hash,
+	}
+}
+
+// IsExpired checks whether token expired already, tokens without expiration never expire.
+func (token *PersonalToken) IsExpired() bool {
+	return token.ExpiredAt != nil && !time.Now().Before(*token.ExpiredAt)
+}
+
+// String will marshal token into json format.
+func (token *PersonalToken) String() string {
+	bytes, _ := json.Marshal(token)
+	return string(bytes)
+}
+
+// ************************************************* //
+// ************ IdempotencyKey related ************* //
+// ************************************************* //
+
+// IdempotencyKey stores response of create request sent with Idempotency-Key header,
+// retries with the same key would be replayed with stored response until it expired.
+type IdempotencyKey struct {
+	Base
+	Id          int       `yaml:"id" json:"id" gorm:"primaryKey"`
+	Key         string    `yaml:"key" json:"key" gorm:"uniqueIndex;size:280"`
+	Fingerprint string    `yaml:"fingerprint" json:"fingerprint"`
+	StatusCode  int       `yaml:"statusCode" json:"statusCode"`
+	ContentType string    `yaml:"contentType" json:"contentType"`
+	Location    string    `yaml:"location" json:"location"`
+	Response    []byte    `yaml:"-" json:"-"`
+	ExpiredAt   time.Time `yaml:"expiredAt" json:"expiredAt" gorm:"index"`
+}
+
+// NewIdempotencyKey create an idempotency key with fingerprint of request.
+func NewIdempotencyKey(key, fingerprint string) *IdempotencyKey {
+	return &IdempotencyKey{
+		Key:         key,
+		Fingerprint: fingerprint,
+	}
+}
+
+// IsExpired checks whether key is expired at now.
+func (key *IdempotencyKey) IsExpired() bool {
+	return !time.Now().Before(key.ExpiredAt)
+}
+
+// String will marshal idempotency key into json format.
+func (key *IdempotencyKey) String() string {
+	bytes, _ := json.Marshal(key)
+	return string(bytes)
+}
+
+// ************************************************* //
+// ************** Installation related ************* //
+// ************************************************* //
+
+// Installation is an installation of app, or an owner of repositories, in remote code repository which user could access.
+// Installations are synced from remote code repository, so that they could be listed without calling it.
+type Installation struct {
+	Base
+	Id           int                 `yaml:"id" json:"id" gorm:"primaryKey"`
+	UserId       int                 `yaml:"userId" json:"userId" gorm:"index"`
+	Type         string              `yaml:"type" json:"type" gorm:"index;size:64"`
+	Host         string              `yaml:"host" json:"host"`
+	RemoteId     int64               `yaml:"remoteId" json:"remoteId" gorm:"index"`
+	Organization string              `yaml:"organization" json:"organization"`
+	AvatarUrl    string              `yaml:"avatarUrl" json:"avatarUrl"`
+	Repos        []*InstallationRepo `yaml:"repos" json:"repos"`
+}
+
+// NewInstallation create an installation of user with type and id of it in remote code repository.
+func NewInstallation(userId int, installationType string, remoteId int64) *Installation {
+	return &Installation{
+		UserId:   userId,
+		Type:     installationType,
+		RemoteId: remoteId,
+		Repos:    make([]*InstallationRepo, 0),
+	}
+}
+
+// String will marshal installation into json format.
+func (installation *Installation) String() string {
+	bytes, _ := json.Marshal(installation)
+	return string(bytes)
+}
+
+// InstallationRepo is a repository of installation with format of owner/repo.
+type InstallationRepo struct {
+	Base
+	Id             int    `yaml:"id" json:"id" gorm:"primaryKey"`
+	InstallationId int    `yaml:"installationId" json:"installationId" gorm:"index"`
+	FullName       string `yaml:"fullName" json:"fullName"`
+	Name           string `yaml:"name" json:"name"`
+}
+
+// NewInstallationRepo create a repository of installation with full name and name.
+func NewInstallationRepo(fullName, name string) *InstallationRepo {
+	return &InstallationRepo{
+		FullName: fullName,
+		Name:     name,
+	}
+}
+
+// ************************************************* //
+// ************** WebhookEvent related ************* //
+// ************************************************* //
+
+const (
+	// WebhookStatusReceived is status of event which is stored but not processed yet
+	WebhookStatusReceived = "received"
+	// WebhookStatusProcessed is status of event processed by handlers, redeliveries of it would be ignored
+	WebhookStatusProcessed = "processed"
+	// WebhookStatusFailed is status of event failed to be processed, it would be processed again if redelivered
+	WebhookStatusFailed = "failed"
+)
+
+// WebhookEvent stores event delivered by webhook of remote code repository, like github.
+// Deliveries are identified by Delivery, so that redeliveries of the same event could be deduplicated.
+type WebhookEvent struct {
+	Base
+	Id       int    `yaml:"id" json:"id" gorm:"primaryKey"`
+	Type     string `yaml:"type" json:"type" gorm:"index"`
+	Host     string `yaml:"host" json:"host"`
+	Delivery string `yaml:"delivery" json:"delivery" gorm:"uniqueIndex;size:255"`
+	Event    string `yaml:"event" json:"event"`
+	Action   string `yaml:"action" json:"action"`
+	Status   string `yaml:"status" json:"status"`
+	Error    string `yaml:"error" json:"error"`
+	Payload  []byte `yaml:"-" json:"-"`
+}
+
+// NewWebhookEvent create a received event with type of source, delivery and name of event.
+func NewWebhookEvent(repoType, delivery, event string) *WebhookEvent {
+	return &WebhookEvent{
+		Type:     repoType,
+		Delivery: delivery,
+		Event:    event,
+		Status:   WebhookStatusReceived,
+	}
+}
+
+// IsProcessed checks whether event was processed by handlers successfully.
+func (event *WebhookEvent) IsProcessed() bool {
+	return event.Status == WebhookStatusProcessed
+}
+
+// String will marshal webhook event into json format.
+func (event *WebhookEvent) String() string {
+	bytes, _ := json.Marshal(event)
+	return string(bytes)
+}
+
 // ************************************************* //
 // ************** PipelineTemplate related ************** //
 // ************************************************* //
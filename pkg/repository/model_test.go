@@ -8,6 +8,7 @@ package repository
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestNewOrg_WithEmptyName(t *testing.T) {
@@ -61,3 +62,22 @@ func TestSource_String(t *testing.T) {
 	src := NewSource("github", "ut/ut-repo")
 	assert.NotEmpty(t, src.String())
 }
+
+func TestIdempotencyKey_IsExpired(t *testing.T) {
+	key := NewIdempotencyKey("ut-key", "ut-fingerprint")
+	assert.True(t, key.IsExpired())
+
+	key.ExpiredAt = time.Now().Add(time.Minute)
+	assert.False(t, key.IsExpired())
+	assert.NotEmpty(t, key.String())
+}
+
+func TestAccessToken_ExpiresWithin(t *testing.T) {
+	token := NewAccessToken(1, IdentityGithub, "ut-login", "ut-token")
+	assert.False(t, token.ExpiresWithin(time.Hour))
+
+	expiredAt := time.Now().Add(time.Minute)
+	token.ExpiredAt = &expiredAt
+	assert.False(t, token.ExpiresWithin(0))
+	assert.True(t, token.ExpiresWithin(time.Hour))
+}
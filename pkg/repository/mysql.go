@@ -19,6 +19,7 @@ import (
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"strings"
 	"time"
 )
@@ -126,12 +127,12 @@ type MySql struct {
 	EntryDescription string                    `json:"entryDescription" yaml:"entryDescription"`
 	ZapLoggerEntry   *rkentry.ZapLoggerEntry   `json:"zapLoggerEntry" yaml:"zapLoggerEntry"`
 	EventLoggerEntry *rkentry.EventLoggerEntry `json:"eventLoggerEntry" yaml:"eventLoggerEntry"`
-	user             string                    `yaml:"user" json:"user"`
-	pass             string                    `yaml:"pass" json:"pass"`
-	protocol         string                    `yaml:"protocol" json:"protocol"`
-	addr             string                    `yaml:"addr" json:"addr"`
-	database         string                    `yaml:"database" json:"database"`
-	params           []string                  `yaml:"params" json:"params"`
+	user             string                    `yaml:"-" json:"-"`
+	pass             string                    `yaml:"-" json:"-"`
+	protocol         string                    `yaml:"-" json:"-"`
+	addr             string                    `yaml:"-" json:"-"`
+	database         string                    `yaml:"-" json:"-"`
+	params           []string                  `yaml:"-" json:"-"`
 	db               *gorm.DB                  `yaml:"-" json:"-"`
 	// For unit test
 	enableMockDb bool             `yaml:"-" json:"-"`
@@ -262,8 +263,16 @@ func (m *MySql) Bootstrap(ctx context.Context) {
 	m.db.AutoMigrate(&Org{})
 	m.db.AutoMigrate(&Proj{})
 	m.db.AutoMigrate(&Source{})
+	m.db.AutoMigrate(&User{})
+	m.db.AutoMigrate(&Identity{})
+	m.db.AutoMigrate(&Member{})
 	m.db.AutoMigrate(&AccessToken{})
+	m.db.AutoMigrate(&PersonalToken{})
 	m.db.AutoMigrate(&PipelineTemplate{})
+	m.db.AutoMigrate(&IdempotencyKey{})
+	m.db.AutoMigrate(&WebhookEvent{})
+	m.db.AutoMigrate(&Installation{})
+	m.db.AutoMigrate(&InstallationRepo{})
 
 	m.EventLoggerEntry.GetEventHelper().Finish(event)
 	logger.Info("Bootstrapping repository.", event.ListPayloads()...)
@@ -323,10 +332,26 @@ func (m *MySql) ListOrg() ([]*Org, error) {
 	return orgList, nil
 }
 
+// ListOrgByIds as function name described
+func (m *MySql) ListOrgByIds(orgIds []int) ([]*Org, error) {
+	orgList := make([]*Org, 0)
+	if len(orgIds) < 1 {
+		return orgList, nil
+	}
+
+	res := m.db.Where("id IN ?", orgIds).Find(&orgList)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to list organizations by ids from DB", zap.Error(res.Error))
+		return orgList, res.Error
+	}
+
+	return orgList, nil
+}
+
 // CreateOrg as function name described
 func (m *MySql) CreateOrg(org *Org) (bool, error) {
 	if org == nil {
-		return false, errors.New("nil organization")
+		return false, NewInvalidArgumentf("nil organization")
 	}
 
 	res := m.db.Create(org)
@@ -376,7 +401,7 @@ func (m *MySql) RemoveOrg(orgId int) (bool, error) {
 // UpdateOrg as function name described
 func (m *MySql) UpdateOrg(org *Org) (bool, error) {
 	if org == nil {
-		return false, errors.New("nil organization")
+		return false, NewInvalidArgumentf("nil organization")
 	}
 
 	res := m.db.Save(org)
@@ -392,6 +417,102 @@ func (m *MySql) UpdateOrg(org *Org) (bool, error) {
 	return true, nil
 }
 
+// ******************************************** //
+// ************** Member related ************** //
+// ******************************************** //
+
+// ListMember as function name described
+func (m *MySql) ListMember(orgId int) ([]*Member, error) {
+	memberList := make([]*Member, 0)
+	res := m.db.Where("org_id = ?", orgId).Find(&memberList)
+
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to list members from DB", zap.Error(res.Error))
+		return memberList, res.Error
+	}
+
+	return memberList, nil
+}
+
+// ListMemberByUser as function name described
+func (m *MySql) ListMemberByUser(userId int) ([]*Member, error) {
+	memberList := make([]*Member, 0)
+	res := m.db.Where("user_id = ?", userId).Find(&memberList)
+
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to list memberships of user from DB", zap.Error(res.Error))
+		return memberList, res.Error
+	}
+
+	return memberList, nil
+}
+
+// GetMember as function name described
+func (m *MySql) GetMember(orgId, userId int) (*Member, error) {
+	member := &Member{}
+	res := m.db.Where("org_id = ? AND user_id = ?", orgId, userId).Find(member)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to get member from DB", zap.Error(res.Error))
+		return nil, res.Error
+	}
+
+	if res.RowsAffected < 1 {
+		return nil, NewNotFoundf(MemberNotFoundMsg, orgId, userId)
+	}
+
+	return member, nil
+}
+
+// UpsertMember as function name described
+func (m *MySql) UpsertMember(member *Member) (bool, error) {
+	if member == nil {
+		return false, NewInvalidArgumentf("nil member")
+	}
+
+	memberFromRepo, err := m.GetMember(member.OrgId, member.UserId)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return false, Wrapf(err, CodeInternal, MemberFailedToGetMsg, member.OrgId, member.UserId)
+	}
+	if memberFromRepo != nil {
+		member.Id = memberFromRepo.Id
+		member.CreatedAt = memberFromRepo.CreatedAt
+	}
+
+	res := m.db.Save(member)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to upsert member to DB", zap.Error(res.Error))
+		return false, res.Error
+	}
+
+	return true, nil
+}
+
+// RemoveMember as function name described
+func (m *MySql) RemoveMember(orgId, userId int) (bool, error) {
+	res := m.db.Delete(&Member{}, "org_id = ? AND user_id = ?", orgId, userId)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to delete member from DB", zap.Error(res.Error))
+		return false, res.Error
+	}
+
+	if res.RowsAffected < 1 {
+		return false, NewNotFoundf(MemberNotFoundMsg, orgId, userId)
+	}
+
+	return true, nil
+}
+
+// RemoveMemberByOrg as function name described
+func (m *MySql) RemoveMemberByOrg(orgId int) (int, error) {
+	res := m.db.Where("org_id = ?", orgId).Delete(&Member{})
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to delete members of organization from DB", zap.Error(res.Error))
+		return 0, res.Error
+	}
+
+	return int(res.RowsAffected), nil
+}
+
 // ********************************************* //
 // ************** Project related ************** //
 // ********************************************* //
@@ -421,7 +542,7 @@ func (m *MySql) ListProj(orgId int) ([]*Proj, error) {
 // CreateProj as function name described
 func (m *MySql) CreateProj(proj *Proj) (bool, error) {
 	if proj == nil {
-		return false, errors.New("nil project")
+		return false, NewInvalidArgumentf("nil project")
 	}
 
 	err := m.db.Model(&Org{Id: proj.OrgId}).Association("ProjList").Append(proj)
@@ -469,7 +590,7 @@ func (m *MySql) RemoveProj(projId int) (bool, error) {
 // UpdateProj as function name described
 func (m *MySql) UpdateProj(proj *Proj) (bool, error) {
 	if proj == nil {
-		return false, errors.New("nil project")
+		return false, NewInvalidArgumentf("nil project")
 	}
 
 	err := m.db.Model(&Org{Id: proj.OrgId}).Association("ProjList").Replace(proj)
@@ -482,6 +603,87 @@ func (m *MySql) UpdateProj(proj *Proj) (bool, error) {
 	return true, nil
 }
 
+// ListProjIdsByOrg as function name described
+func (m *MySql) ListProjIdsByOrg(orgIds []int) (map[int][]int, error) {
+	projList := make([]*Proj, 0)
+
+	tx := m.db.Model(&Proj{}).Select("id", "org_id")
+	if len(orgIds) > 0 {
+		tx = tx.Where("org_id IN ?", orgIds)
+	}
+
+	if res := tx.Find(&projList); res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to list ids of projects from DB", zap.Error(res.Error))
+		return nil, res.Error
+	}
+
+	res := make(map[int][]int)
+	for i := range orgIds {
+		res[orgIds[i]] = make([]int, 0)
+	}
+	for i := range projList {
+		res[projList[i].OrgId] = append(res[projList[i].OrgId], projList[i].Id)
+	}
+
+	return res, nil
+}
+
+// ListProjCountByOrg as function name described
+func (m *MySql) ListProjCountByOrg(orgIds []int) (map[int]int, error) {
+	rows := make([]*struct {
+		OrgId int
+		Count int
+	}, 0)
+
+	tx := m.db.Model(&Proj{}).Select("org_id", "COUNT(*) AS count").Group("org_id")
+	if len(orgIds) > 0 {
+		tx = tx.Where("org_id IN ?", orgIds)
+	}
+
+	if res := tx.Scan(&rows); res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to count projects from DB", zap.Error(res.Error))
+		return nil, res.Error
+	}
+
+	res := make(map[int]int)
+	for i := range orgIds {
+		res[orgIds[i]] = 0
+	}
+	for i := range rows {
+		res[rows[i].OrgId] = rows[i].Count
+	}
+
+	return res, nil
+}
+
+// ListProjByOrg as function name described
+func (m *MySql) ListProjByOrg(orgIds []int, withSource bool) (map[int][]*Proj, error) {
+	projList := make([]*Proj, 0)
+
+	tx := m.db.Model(&Proj{})
+	if len(orgIds) > 0 {
+		tx = tx.Where("org_id IN ?", orgIds)
+	}
+	if withSource {
+		tx = tx.Preload("Source")
+	}
+
+	if res := tx.Find(&projList); res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to list projects from DB", zap.Error(res.Error))
+		return nil, res.Error
+	}
+
+	res := make(map[int][]*Proj)
+	for i := range orgIds {
+		res[orgIds[i]] = make([]*Proj, 0)
+	}
+	for i := range projList {
+		res[projList[i].OrgId] = append(res[projList[i].OrgId], projList[i])
+	}
+
+	return res, nil
+}
+
 // ******************************************** //
 // ************** Source related ************** //
 // ******************************************** //
@@ -489,7 +691,7 @@ func (m *MySql) UpdateProj(proj *Proj) (bool, error) {
 // CreateSource as function name described
 func (m *MySql) CreateSource(src *Source) (bool, error) {
 	if src == nil {
-		return false, errors.New("nil source")
+		return false, NewInvalidArgumentf("nil source")
 	}
 
 	err := m.db.Model(&Proj{Id: src.ProjId}).Association("Source").Append(src)
@@ -532,6 +734,210 @@ func (m *MySql) GetSource(sourceId int) (*Source, error) {
 	return src, nil
 }
 
+// UpdateSource as function name described
+func (m *MySql) UpdateSource(src *Source) (bool, error) {
+	if src == nil {
+		return false, NewInvalidArgumentf("nil source")
+	}
+
+	res := m.db.Model(&Source{}).Where("id = ?", src.Id).Select("repository", "host", "user", "status", "repository_id", "default_branch").Updates(src)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to update source", zap.Error(res.Error))
+		return false, res.Error
+	}
+
+	if res.RowsAffected < 1 {
+		return false, NewNotFoundf(SourceNotFoundMsg, src.Id)
+	}
+
+	return true, nil
+}
+
+// ListSourceByRepository as function name described, collation of column is case-insensitive
+func (m *MySql) ListSourceByRepository(repoType, repository string) ([]*Source, error) {
+	res := make([]*Source, 0)
+	tx := m.db.Where("type = ? AND repository = ?", repoType, repository).Find(&res)
+	if tx.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to list sources by repository", zap.Error(tx.Error))
+		return nil, tx.Error
+	}
+
+	return res, nil
+}
+
+// ListSourceByRepositoryId as function name described
+func (m *MySql) ListSourceByRepositoryId(repoType, repositoryId string) ([]*Source, error) {
+	res := make([]*Source, 0)
+	if len(repositoryId) < 1 {
+		return res, nil
+	}
+
+	tx := m.db.Where("type = ? AND repository_id = ?", repoType, repositoryId).Find(&res)
+	if tx.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to list sources by repository id", zap.Error(tx.Error))
+		return nil, tx.Error
+	}
+
+	return res, nil
+}
+
+// ****************************************** //
+// ************** User related ************** //
+// ****************************************** //
+
+// CreateUser as function name described
+func (m *MySql) CreateUser(user *User) (bool, error) {
+	if user == nil {
+		return false, NewInvalidArgumentf("nil user")
+	}
+
+	// identities would be created together with user
+	res := m.db.Create(user)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to create user in DB", zap.Error(res.Error))
+		return false, res.Error
+	}
+
+	if res.RowsAffected < 1 {
+		return false, fmt.Errorf("failed to create user with name:%s", user.Name)
+	}
+
+	return true, nil
+}
+
+// GetUser as function name described
+func (m *MySql) GetUser(userId int) (*User, error) {
+	user := &User{}
+	res := m.db.Preload("Identities").Where("id = ?", userId).Find(user)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to get user from DB", zap.Error(res.Error))
+		return nil, res.Error
+	}
+
+	if res.RowsAffected < 1 {
+		return nil, NewNotFoundf(UserNotFoundMsg, userId)
+	}
+
+	return user, nil
+}
+
+// UpdateUser as function name described
+func (m *MySql) UpdateUser(user *User) (bool, error) {
+	if user == nil {
+		return false, NewInvalidArgumentf("nil user")
+	}
+
+	res := m.db.Omit(clause.Associations).Save(user)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to update user to DB", zap.Error(res.Error))
+		return false, res.Error
+	}
+
+	if res.RowsAffected < 1 {
+		return false, NewNotFoundf(UserNotFoundMsg, user.Id)
+	}
+
+	return true, nil
+}
+
+// RemoveUser as function name described
+func (m *MySql) RemoveUser(userId int) (bool, error) {
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&User{}, userId)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected < 1 {
+			return NewNotFoundf(UserNotFoundMsg, userId)
+		}
+
+		if err := tx.Where("user_id = ?", userId).Delete(&Identity{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userId).Delete(&Member{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userId).Delete(&PersonalToken{}).Error; err != nil {
+			return err
+		}
+
+		if err := removeInstallationWhere(tx, "user_id = ?", userId); err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", userId).Delete(&AccessToken{}).Error
+	})
+
+	if err != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to delete user from DB", zap.Error(err))
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ********************************************** //
+// ************** Identity related ************** //
+// ********************************************** //
+
+// UpsertIdentity as function name described
+func (m *MySql) UpsertIdentity(identity *Identity) (bool, error) {
+	if identity == nil {
+		return false, NewInvalidArgumentf("nil identity")
+	}
+
+	identityFromRepo, err := m.GetIdentity(identity.Type, identity.Login)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return false, Wrapf(err, CodeInternal, IdentityFailedToGetMsg, identity.Type, identity.Login)
+	}
+	if identityFromRepo != nil {
+		identity.Id = identityFromRepo.Id
+		identity.CreatedAt = identityFromRepo.CreatedAt
+	}
+
+	res := m.db.Save(identity)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to upsert identity to DB", zap.Error(res.Error))
+		return false, res.Error
+	}
+
+	return true, nil
+}
+
+// GetIdentity as function name described
+func (m *MySql) GetIdentity(identityType, login string) (*Identity, error) {
+	identity := &Identity{}
+	res := m.db.Where("type = ? AND login = ?", identityType, login).Find(identity)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to get identity from DB", zap.Error(res.Error))
+		return nil, res.Error
+	}
+
+	if res.RowsAffected < 1 {
+		return nil, NewNotFoundf(IdentityNotFoundMsg, identityType, login)
+	}
+
+	return identity, nil
+}
+
+// RemoveIdentity as function name described
+func (m *MySql) RemoveIdentity(userId int, identityType string) (bool, error) {
+	res := m.db.Delete(&Identity{}, "user_id = ? AND type = ?", userId, identityType)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to delete identity from DB", zap.Error(res.Error))
+		return false, res.Error
+	}
+
+	if res.RowsAffected < 1 {
+		return false, NewNotFoundf(IdentityOfUserNotFoundMsg, identityType, userId)
+	}
+
+	return true, nil
+}
+
 // ************************************************* //
 // ************** AccessToken related ************** //
 // ************************************************* //
@@ -539,63 +945,379 @@ func (m *MySql) GetSource(sourceId int) (*Source, error) {
 // UpsertAccessToken as function name described
 func (m *MySql) UpsertAccessToken(token *AccessToken) (bool, error) {
 	if token == nil {
-		return false, errors.New("nil access token")
+		return false, NewInvalidArgumentf("nil access token")
 	}
 
-	okToUpsert := false
-	tokenFromRepo, err := m.GetAccessToken(token.Type, token.User)
-	switch err.(type) {
-	case *NotFound:
-		okToUpsert = true
+	tokenFromRepo, err := m.GetAccessToken(token.UserId, token.Type)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return false, Wrapf(err, CodeInternal, AccessTokenFailedToGetMsg, token.Type, token.UserId)
 	}
-
-	if tokenFromRepo == nil {
-		okToUpsert = true
-	}
-
-	if !okToUpsert {
-		return false, fmt.Errorf("failed to get access token with type:%s user:%s", token.Type, token.User)
+	if tokenFromRepo != nil {
+		token.Id = tokenFromRepo.Id
+		token.CreatedAt = tokenFromRepo.CreatedAt
 	}
 
 	res := m.db.Save(token)
-	if res.Error != nil || res.RowsAffected < 1 {
-		return false, fmt.Errorf("failed to upsert access token with type:%s user:%s", token.Type, token.User)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to upsert access token to DB", zap.Error(res.Error))
+		return false, res.Error
 	}
 
 	return true, nil
 }
 
 // GetAccessToken as function name described
-func (m *MySql) GetAccessToken(repoType, repoUser string) (*AccessToken, error) {
+func (m *MySql) GetAccessToken(userId int, tokenType string) (*AccessToken, error) {
 	token := &AccessToken{}
-	res := m.db.Where("type = ? AND user = ?", repoType, repoUser).Find(token)
+	res := m.db.Where("user_id = ? AND type = ?", userId, tokenType).Find(token)
 	if res.Error != nil {
 		m.ZapLoggerEntry.GetLogger().Warn("failed to get access token from DB", zap.Error(res.Error))
 		return nil, res.Error
 	}
 
 	if res.RowsAffected < 1 {
-		return nil, NewNotFoundf(AccessTokenNotFoundMsg, repoType, repoUser)
+		return nil, NewNotFoundf(AccessTokenNotFoundMsg, tokenType, userId)
 	}
 
 	return token, nil
 }
 
+// ListAccessToken as function name described
+func (m *MySql) ListAccessToken(tokenType string) ([]*AccessToken, error) {
+	tokenList := make([]*AccessToken, 0)
+
+	tx := m.db
+	if len(tokenType) > 0 {
+		tx = tx.Where("type = ?", tokenType)
+	}
+
+	if res := tx.Find(&tokenList); res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to list access tokens from DB", zap.Error(res.Error))
+		return tokenList, res.Error
+	}
+
+	return tokenList, nil
+}
+
 // RemoveAccessToken as function name described
-func (m *MySql) RemoveAccessToken(repoType, repoUser string) (bool, error) {
-	res := m.db.Delete(&AccessToken{}, "type = ? AND user = ?", repoType, repoUser)
+func (m *MySql) RemoveAccessToken(userId int, tokenType string) (bool, error) {
+	res := m.db.Delete(&AccessToken{}, "user_id = ? AND type = ?", userId, tokenType)
 	if res.Error != nil {
 		m.ZapLoggerEntry.GetLogger().Warn("failed to delete access token from DB", zap.Error(res.Error))
 		return false, res.Error
 	}
 
 	if res.RowsAffected < 1 {
-		return false, NewNotFoundf(AccessTokenNotFoundMsg, repoType, repoUser)
+		return false, NewNotFoundf(AccessTokenNotFoundMsg, tokenType, userId)
 	}
 
 	return true, nil
 }
 
+// *************************************************** //
+// ************** PersonalToken related ************** //
+// *************************************************** //
+
+// ListPersonalToken as function name described
+func (m *MySql) ListPersonalToken(userId int) ([]*PersonalToken, error) {
+	tokenList := make([]*PersonalToken, 0)
+	res := m.db.Where("user_id = ?", userId).Find(&tokenList)
+
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to list personal tokens from DB", zap.Error(res.Error))
+		return tokenList, res.Error
+	}
+
+	return tokenList, nil
+}
+
+// CreatePersonalToken as function name described
+func (m *MySql) CreatePersonalToken(token *PersonalToken) (bool, error) {
+	if token == nil {
+		return false, NewInvalidArgumentf("nil personal token")
+	}
+
+	res := m.db.Create(token)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to create personal token in DB", zap.Error(res.Error))
+		return false, res.Error
+	}
+
+	if res.RowsAffected < 1 {
+		return false, fmt.Errorf("failed to create personal token with name:%s", token.Name)
+	}
+
+	return true, nil
+}
+
+// GetPersonalToken as function name described
+func (m *MySql) GetPersonalToken(tokenId int) (*PersonalToken, error) {
+	token := &PersonalToken{}
+	res := m.db.Where("id = ?", tokenId).Find(token)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to get personal token from DB", zap.Error(res.Error))
+		return nil, res.Error
+	}
+
+	if res.RowsAffected < 1 {
+		return nil, NewNotFoundf(PersonalTokenNotFoundMsg, tokenId)
+	}
+
+	return token, nil
+}
+
+// GetPersonalTokenByHash as function name described
+func (m *MySql) GetPersonalTokenByHash(hash string) (*PersonalToken, error) {
+	token := &PersonalToken{}
+	res := m.db.Where("hash = ?", hash).Find(token)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to get personal token from DB", zap.Error(res.Error))
+		return nil, res.Error
+	}
+
+	if res.RowsAffected < 1 {
+		return nil, NewNotFoundf("personal token not found")
+	}
+
+	return token, nil
+}
+
+// UpdatePersonalToken as function name described
+func (m *MySql) UpdatePersonalToken(token *PersonalToken) (bool, error) {
+	if token == nil {
+		return false, NewInvalidArgumentf("nil personal token")
+	}
+
+	res := m.db.Save(token)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to update personal token to DB", zap.Error(res.Error))
+		return false, res.Error
+	}
+
+	if res.RowsAffected < 1 {
+		return false, NewNotFoundf(PersonalTokenNotFoundMsg, token.Id)
+	}
+
+	return true, nil
+}
+
+// RemovePersonalToken as function name described
+func (m *MySql) RemovePersonalToken(tokenId int) (bool, error) {
+	res := m.db.Delete(&PersonalToken{}, tokenId)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to delete personal token from DB", zap.Error(res.Error))
+		return false, res.Error
+	}
+
+	if res.RowsAffected < 1 {
+		return false, NewNotFoundf(PersonalTokenNotFoundMsg, tokenId)
+	}
+
+	return true, nil
+}
+
+// ************************************************* //
+// ************ IdempotencyKey related ************* //
+// ************************************************* //
+
+// UpsertIdempotencyKey as function name described
+func (m *MySql) UpsertIdempotencyKey(key *IdempotencyKey) (bool, error) {
+	if key == nil {
+		return false, NewInvalidArgumentf("nil idempotency key")
+	}
+
+	// replace expired key with the same value
+	keyFromRepo, err := m.GetIdempotencyKey(key.Key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+	if keyFromRepo != nil {
+		key.Id = keyFromRepo.Id
+		key.CreatedAt = keyFromRepo.CreatedAt
+	}
+
+	res := m.db.Save(key)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to upsert idempotency key to DB", zap.Error(res.Error))
+		return false, res.Error
+	}
+
+	return true, nil
+}
+
+// GetIdempotencyKey as function name described
+func (m *MySql) GetIdempotencyKey(key string) (*IdempotencyKey, error) {
+	res := &IdempotencyKey{}
+	tx := m.db.Where("`key` = ?", key).Find(res)
+	if tx.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to get idempotency key from DB", zap.Error(tx.Error))
+		return nil, tx.Error
+	}
+
+	if tx.RowsAffected < 1 {
+		return nil, NewNotFoundf(IdempotencyKeyNotFoundMsg, key)
+	}
+
+	return res, nil
+}
+
+// RemoveExpiredIdempotencyKey as function name described
+func (m *MySql) RemoveExpiredIdempotencyKey(t time.Time) (int, error) {
+	// expired keys are useless, delete them permanently
+	res := m.db.Unscoped().Where("expired_at < ?", t).Delete(&IdempotencyKey{})
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to delete expired idempotency keys from DB", zap.Error(res.Error))
+		return 0, res.Error
+	}
+
+	return int(res.RowsAffected), nil
+}
+
+// ************************************************* //
+// ************** Installation related ************* //
+// ************************************************* //
+
+// ListInstallation as function name described
+func (m *MySql) ListInstallation(userId int, installationType string, withRepos bool) ([]*Installation, error) {
+	res := make([]*Installation, 0)
+
+	tx := m.db
+	if withRepos {
+		tx = tx.Preload("Repos")
+	}
+	if userId > 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if len(installationType) > 0 {
+		tx = tx.Where("type = ?", installationType)
+	}
+
+	if err := tx.Find(&res).Error; err != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to list installations from DB", zap.Error(err))
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ListInstallationByRemoteId as function name described
+func (m *MySql) ListInstallationByRemoteId(installationType, host string, remoteId int64) ([]*Installation, error) {
+	res := make([]*Installation, 0)
+
+	err := m.db.Where("type = ? AND host = ? AND remote_id = ?", installationType, host, remoteId).Find(&res).Error
+	if err != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to list installations by remote id from DB", zap.Error(err))
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ReplaceInstallation as function name described
+func (m *MySql) ReplaceInstallation(userId int, installationType string, installations []*Installation) (bool, error) {
+	for _, installation := range installations {
+		installation.UserId = userId
+		installation.Type = installationType
+	}
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := removeInstallationWhere(tx, "user_id = ? AND type = ?", userId, installationType); err != nil {
+			return err
+		}
+
+		if len(installations) < 1 {
+			return nil
+		}
+
+		// repositories would be created together with installations
+		return tx.Create(&installations).Error
+	})
+
+	if err != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to replace installations in DB", zap.Error(err))
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RemoveInstallationByRemoteId as function name described
+func (m *MySql) RemoveInstallationByRemoteId(installationType, host string, remoteId int64) (int, error) {
+	var count int64
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		query := "type = ? AND host = ? AND remote_id = ?"
+		if err := tx.Model(&Installation{}).Where(query, installationType, host, remoteId).Count(&count).Error; err != nil {
+			return err
+		}
+
+		return removeInstallationWhere(tx, query, installationType, host, remoteId)
+	})
+
+	if err != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to delete installations by remote id from DB", zap.Error(err))
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
+// Removes installations matched with query together with repositories of them.
+// Installations are synced from remote code repository, delete them permanently.
+func removeInstallationWhere(tx *gorm.DB, query string, args ...interface{}) error {
+	ids := tx.Unscoped().Model(&Installation{}).Select("id").Where(query, args...)
+	if err := tx.Unscoped().Where("installation_id IN (?)", ids).Delete(&InstallationRepo{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Where(query, args...).Delete(&Installation{}).Error
+}
+
+// ************************************************* //
+// ************** WebhookEvent related ************* //
+// ************************************************* //
+
+// UpsertWebhookEvent as function name described
+func (m *MySql) UpsertWebhookEvent(event *WebhookEvent) (bool, error) {
+	if event == nil {
+		return false, NewInvalidArgumentf("nil webhook event")
+	}
+
+	// update status of event delivered again
+	eventFromRepo, err := m.GetWebhookEvent(event.Delivery)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+	if eventFromRepo != nil {
+		event.Id = eventFromRepo.Id
+		event.CreatedAt = eventFromRepo.CreatedAt
+	}
+
+	res := m.db.Save(event)
+	if res.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to upsert webhook event to DB", zap.Error(res.Error))
+		return false, res.Error
+	}
+
+	return true, nil
+}
+
+// GetWebhookEvent as function name described
+func (m *MySql) GetWebhookEvent(delivery string) (*WebhookEvent, error) {
+	res := &WebhookEvent{}
+	tx := m.db.Where("delivery = ?", delivery).Find(res)
+	if tx.Error != nil {
+		m.ZapLoggerEntry.GetLogger().Warn("failed to get webhook event from DB", zap.Error(tx.Error))
+		return nil, tx.Error
+	}
+
+	if tx.RowsAffected < 1 {
+		return nil, NewNotFoundf(WebhookEventNotFoundMsg, delivery)
+	}
+
+	return res, nil
+}
+
 // ************************************************** //
 // ************** PipelineTemplate related ************** //
 // ************************************************** //
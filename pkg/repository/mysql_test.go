@@ -10,6 +10,34 @@ import (
 	"time"
 )
 
+func TestMySql_ListOrgByIds(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `orgs` WHERE id IN (?,?) AND `orgs`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: without ids, nothing is queried
+	orgList, err := repo.ListOrgByIds(nil)
+	assert.Empty(t, orgList)
+	assert.Nil(t, err)
+
+	// 3: with organizations
+	repo.sqlMock.ExpectQuery(query).WithArgs(1, 2).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "created_at", "updated_at", "deleted_at", "name"}).
+			AddRow(1, time.Now(), time.Now(), nil, "ut-org"))
+	orgList, err = repo.ListOrgByIds([]int{1, 2})
+	assert.Len(t, orgList, 1)
+	assert.Nil(t, err)
+
+	// 4: with error
+	repo.sqlMock.ExpectQuery(query).WithArgs(1, 2).
+		WillReturnError(errors.New("ut-error"))
+	orgList, err = repo.ListOrgByIds([]int{1, 2})
+	assert.Empty(t, orgList)
+	assert.NotNil(t, err)
+}
+
 func TestMySql_ListOrg(t *testing.T) {
 	query := regexp.QuoteMeta("SELECT * FROM `orgs` WHERE `orgs`.`deleted_at` IS NULL")
 
@@ -215,6 +243,82 @@ func TestMySql_ListProj(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestMySql_ListProjIdsByOrg(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT `id`,`org_id` FROM `projs` WHERE (org_id IN (?,?)) AND `projs`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: with error
+	repo.sqlMock.ExpectQuery(query).WithArgs(1, 2).WillReturnError(errors.New("ut-error"))
+	idMap, err := repo.ListProjIdsByOrg([]int{1, 2})
+	assert.Nil(t, idMap)
+	assert.NotNil(t, err)
+
+	// 3: with projects
+	repo.sqlMock.ExpectQuery(query).WithArgs(1, 2).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "org_id"}).
+			AddRow(1, 1).
+			AddRow(2, 1))
+	idMap, err = repo.ListProjIdsByOrg([]int{1, 2})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, idMap[1])
+	assert.Empty(t, idMap[2])
+}
+
+func TestMySql_ListProjCountByOrg(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT `org_id`,COUNT(*) AS count FROM `projs` WHERE (org_id IN (?,?)) AND `projs`.`deleted_at` IS NULL GROUP BY `org_id`")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: with error
+	repo.sqlMock.ExpectQuery(query).WithArgs(1, 2).WillReturnError(errors.New("ut-error"))
+	countMap, err := repo.ListProjCountByOrg([]int{1, 2})
+	assert.Nil(t, countMap)
+	assert.NotNil(t, err)
+
+	// 3: with projects
+	repo.sqlMock.ExpectQuery(query).WithArgs(1, 2).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"org_id", "count"}).
+			AddRow(1, 2))
+	countMap, err = repo.ListProjCountByOrg([]int{1, 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, countMap[1])
+	assert.Equal(t, 0, countMap[2])
+}
+
+func TestMySql_ListProjByOrg(t *testing.T) {
+	queryProj := regexp.QuoteMeta("SELECT * FROM `projs` WHERE (org_id IN (?)) AND `projs`.`deleted_at` IS NULL")
+	querySource := regexp.QuoteMeta("SELECT * FROM `sources` WHERE `sources`.`proj_id` IN (?,?) AND `sources`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: with error
+	repo.sqlMock.ExpectQuery(queryProj).WithArgs(1).WillReturnError(errors.New("ut-error"))
+	projMap, err := repo.ListProjByOrg([]int{1}, false)
+	assert.Nil(t, projMap)
+	assert.NotNil(t, err)
+
+	// 3: with sources preloaded in one query
+	repo.sqlMock.ExpectQuery(queryProj).WithArgs(1).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "org_id", "created_at", "updated_at", "deleted_at", "name"}).
+			AddRow(1, 1, time.Now(), time.Now(), nil, "ut-proj-1").
+			AddRow(2, 1, time.Now(), time.Now(), nil, "ut-proj-2"))
+	repo.sqlMock.ExpectQuery(querySource).WithArgs(1, 2).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "proj_id", "created_at", "updated_at", "deleted_at", "type"}).
+			AddRow(1, 2, time.Now(), time.Now(), nil, "github"))
+	projMap, err = repo.ListProjByOrg([]int{1}, true)
+	assert.Nil(t, err)
+	assert.Len(t, projMap[1], 2)
+	assert.Nil(t, projMap[1][0].Source)
+	assert.NotNil(t, projMap[1][1].Source)
+}
+
 //func TestMySql_CreateProj(t *testing.T) {
 //	queryOrg := regexp.QuoteMeta("UPDATE `orgs` SET `updated_at`=? WHERE `id` = ?")
 //	queryProj := regexp.QuoteMeta("INSERT INTO `projs` (`created_at`,`updated_at`,`deleted_at`,`org_id`,`name`) VALUES (?,?,?,?,?) ON DUPLICATE KEY UPDATE `org_id`=VALUES(`org_id`)")
@@ -485,6 +589,422 @@ func TestMySql_RemoveSource(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestMySql_GetIdempotencyKey(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `idempotency_keys` WHERE `key` = ? AND `idempotency_keys`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case
+	repo.sqlMock.ExpectQuery(query).WithArgs("ut-key").
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "key", "fingerprint", "status_code"}).
+			AddRow(1, "ut-key", "ut-fingerprint", 201))
+	key, err := repo.GetIdempotencyKey("ut-key")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-fingerprint", key.Fingerprint)
+
+	// 3: not found
+	repo.sqlMock.ExpectQuery(query).WithArgs("ut-key").
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "key", "fingerprint", "status_code"}))
+	key, err = repo.GetIdempotencyKey("ut-key")
+	assert.Nil(t, key)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// 4: with error
+	repo.sqlMock.ExpectQuery(query).WithArgs("ut-key").WillReturnError(errors.New("ut-error"))
+	key, err = repo.GetIdempotencyKey("ut-key")
+	assert.Nil(t, key)
+	assert.NotNil(t, err)
+}
+
+func TestMySql_UpdateSource(t *testing.T) {
+	query := regexp.QuoteMeta("UPDATE `sources` SET `updated_at`=?,`repository`=?,`host`=?,`user`=?,`status`=?,`repository_id`=?,`default_branch`=? WHERE id = ? AND `sources`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	src := NewSource("github", "ut-owner/ut-renamed")
+	src.Id = 1
+
+	// 2: happy case
+	repo.sqlMock.ExpectBegin()
+	repo.sqlMock.ExpectExec(query).
+		WithArgs(sqlmock.AnyArg(), src.Repository, "", "", SourceStatusActive, "", "", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	repo.sqlMock.ExpectCommit()
+	succ, err := repo.UpdateSource(src)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+
+	// 3: not found
+	repo.sqlMock.ExpectBegin()
+	repo.sqlMock.ExpectExec(query).
+		WithArgs(sqlmock.AnyArg(), src.Repository, "", "", SourceStatusActive, "", "", 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	repo.sqlMock.ExpectCommit()
+	succ, err = repo.UpdateSource(src)
+	assert.False(t, succ)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestMySql_ListSourceByRepository(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `sources` WHERE (type = ? AND repository = ?) AND `sources`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case
+	repo.sqlMock.ExpectQuery(query).WithArgs("github", "ut-owner/ut-repo").
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "type", "repository"}).
+			AddRow(1, "github", "ut-owner/ut-repo"))
+	srcList, err := repo.ListSourceByRepository("github", "ut-owner/ut-repo")
+	assert.Nil(t, err)
+	assert.Len(t, srcList, 1)
+
+	// 3: with error
+	repo.sqlMock.ExpectQuery(query).WithArgs("github", "ut-owner/ut-repo").WillReturnError(errors.New("ut-error"))
+	srcList, err = repo.ListSourceByRepository("github", "ut-owner/ut-repo")
+	assert.Nil(t, srcList)
+	assert.NotNil(t, err)
+}
+
+func TestMySql_ListSourceByRepositoryId(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `sources` WHERE (type = ? AND repository_id = ?) AND `sources`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case
+	repo.sqlMock.ExpectQuery(query).WithArgs("github", "1296269").
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "type", "repository", "repository_id"}).
+			AddRow(1, "github", "ut-owner/ut-repo", "1296269"))
+	srcList, err := repo.ListSourceByRepositoryId("github", "1296269")
+	assert.Nil(t, err)
+	assert.Len(t, srcList, 1)
+
+	// 3: sources without id of repository are not listed
+	srcList, err = repo.ListSourceByRepositoryId("github", "")
+	assert.Nil(t, err)
+	assert.Empty(t, srcList)
+
+	// 4: with error
+	repo.sqlMock.ExpectQuery(query).WithArgs("github", "1296269").WillReturnError(errors.New("ut-error"))
+	srcList, err = repo.ListSourceByRepositoryId("github", "1296269")
+	assert.Nil(t, srcList)
+	assert.NotNil(t, err)
+}
+
+func TestMySql_GetWebhookEvent(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `webhook_events` WHERE delivery = ? AND `webhook_events`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case
+	repo.sqlMock.ExpectQuery(query).WithArgs("ut-delivery").
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "type", "delivery", "event", "status"}).
+			AddRow(1, "github", "ut-delivery", "ping", WebhookStatusProcessed))
+	event, err := repo.GetWebhookEvent("ut-delivery")
+	assert.Nil(t, err)
+	assert.True(t, event.IsProcessed())
+
+	// 3: not found
+	repo.sqlMock.ExpectQuery(query).WithArgs("ut-delivery").
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "type", "delivery", "event", "status"}))
+	event, err = repo.GetWebhookEvent("ut-delivery")
+	assert.Nil(t, event)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestMySql_RemoveExpiredIdempotencyKey(t *testing.T) {
+	query := regexp.QuoteMeta("DELETE FROM `idempotency_keys` WHERE expired_at < ?")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case
+	now := time.Now()
+	repo.sqlMock.ExpectBegin()
+	repo.sqlMock.ExpectExec(query).WithArgs(now).WillReturnResult(sqlmock.NewResult(0, 2))
+	repo.sqlMock.ExpectCommit()
+	count, err := repo.RemoveExpiredIdempotencyKey(now)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	// 3: with error
+	repo.sqlMock.ExpectBegin()
+	repo.sqlMock.ExpectExec(query).WithArgs(now).WillReturnError(errors.New("ut-error"))
+	repo.sqlMock.ExpectRollback()
+	count, err = repo.RemoveExpiredIdempotencyKey(now)
+	assert.NotNil(t, err)
+	assert.Zero(t, count)
+}
+
+func TestMySql_GetUser(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `users` WHERE id = ? AND `users`.`deleted_at` IS NULL")
+	preload := regexp.QuoteMeta("SELECT * FROM `identities` WHERE `identities`.`user_id` = ? AND `identities`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case
+	repo.sqlMock.ExpectQuery(query).WithArgs(1).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "name"}).AddRow(1, "ut-user"))
+	repo.sqlMock.ExpectQuery(preload).WithArgs(1).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "user_id", "type", "login"}).AddRow(1, 1, IdentityGithub, "ut-login"))
+	user, err := repo.GetUser(1)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-user", user.Name)
+	assert.NotNil(t, user.GetIdentity(IdentityGithub))
+
+	// 3: not found
+	repo.sqlMock.ExpectQuery(query).WithArgs(1).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "name"}))
+	user, err = repo.GetUser(1)
+	assert.Nil(t, user)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// 4: with error
+	repo.sqlMock.ExpectQuery(query).WithArgs(1).WillReturnError(errors.New("ut-error"))
+	user, err = repo.GetUser(1)
+	assert.Nil(t, user)
+	assert.NotNil(t, err)
+}
+
+func TestMySql_RemoveUser(t *testing.T) {
+	deleteUser := regexp.QuoteMeta("UPDATE `users` SET `deleted_at`=? WHERE `users`.`id` = ? AND `users`.`deleted_at` IS NULL")
+	deleteIdentity := regexp.QuoteMeta("UPDATE `identities` SET `deleted_at`=? WHERE user_id = ? AND `identities`.`deleted_at` IS NULL")
+	deleteMember := regexp.QuoteMeta("UPDATE `members` SET `deleted_at`=? WHERE user_id = ? AND `members`.`deleted_at` IS NULL")
+	deletePersonal := regexp.QuoteMeta("UPDATE `personal_tokens` SET `deleted_at`=? WHERE user_id = ? AND `personal_tokens`.`deleted_at` IS NULL")
+	deleteToken := regexp.QuoteMeta("UPDATE `access_tokens` SET `deleted_at`=? WHERE user_id = ? AND `access_tokens`.`deleted_at` IS NULL")
+	deleteInstallRepo := regexp.QuoteMeta("DELETE FROM `installation_repos` WHERE installation_id IN (SELECT `id` FROM `installations` WHERE user_id = ?)")
+	deleteInstall := regexp.QuoteMeta("DELETE FROM `installations` WHERE user_id = ?")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case
+	repo.sqlMock.ExpectBegin()
+	repo.sqlMock.ExpectExec(deleteUser).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	repo.sqlMock.ExpectExec(deleteIdentity).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 2))
+	repo.sqlMock.ExpectExec(deleteMember).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	repo.sqlMock.ExpectExec(deletePersonal).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 0))
+	repo.sqlMock.ExpectExec(deleteInstallRepo).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 3))
+	repo.sqlMock.ExpectExec(deleteInstall).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	repo.sqlMock.ExpectExec(deleteToken).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	repo.sqlMock.ExpectCommit()
+	succ, err := repo.RemoveUser(1)
+	assert.True(t, succ)
+	assert.Nil(t, err)
+
+	// 3: not found
+	repo.sqlMock.ExpectBegin()
+	repo.sqlMock.ExpectExec(deleteUser).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 0))
+	repo.sqlMock.ExpectRollback()
+	succ, err = repo.RemoveUser(1)
+	assert.False(t, succ)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestMySql_ListInstallation(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `installations` WHERE user_id = ? AND type = ? AND `installations`.`deleted_at` IS NULL")
+	preload := regexp.QuoteMeta("SELECT * FROM `installation_repos` WHERE `installation_repos`.`installation_id` = ? AND `installation_repos`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case with repositories
+	repo.sqlMock.ExpectQuery(query).WithArgs(1, "github").
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "user_id", "type", "remote_id", "organization"}).
+			AddRow(1, 1, "github", 7, "ut-owner"))
+	repo.sqlMock.ExpectQuery(preload).WithArgs(1).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "installation_id", "full_name", "name"}).
+			AddRow(1, 1, "ut-owner/ut-repo", "ut-repo"))
+	installations, err := repo.ListInstallation(1, "github", true)
+	assert.Nil(t, err)
+	assert.Len(t, installations, 1)
+	assert.Equal(t, "ut-owner/ut-repo", installations[0].Repos[0].FullName)
+
+	// 3: with error
+	repo.sqlMock.ExpectQuery(query).WithArgs(1, "github").WillReturnError(errors.New("ut-error"))
+	installations, err = repo.ListInstallation(1, "github", false)
+	assert.Nil(t, installations)
+	assert.NotNil(t, err)
+}
+
+func TestMySql_ReplaceInstallation(t *testing.T) {
+	deleteInstallRepo := regexp.QuoteMeta("DELETE FROM `installation_repos` WHERE installation_id IN (SELECT `id` FROM `installations` WHERE user_id = ? AND type = ?)")
+	deleteInstall := regexp.QuoteMeta("DELETE FROM `installations` WHERE user_id = ? AND type = ?")
+	insertInstall := regexp.QuoteMeta("INSERT INTO `installations`")
+	insertInstallRepo := regexp.QuoteMeta("INSERT INTO `installation_repos`")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	installation := NewInstallation(1, "github", 7)
+	installation.Repos = append(installation.Repos, NewInstallationRepo("ut-owner/ut-repo", "ut-repo"))
+
+	// 2: happy case
+	repo.sqlMock.ExpectBegin()
+	repo.sqlMock.ExpectExec(deleteInstallRepo).WithArgs(1, "github").WillReturnResult(sqlmock.NewResult(0, 1))
+	repo.sqlMock.ExpectExec(deleteInstall).WithArgs(1, "github").WillReturnResult(sqlmock.NewResult(0, 1))
+	repo.sqlMock.ExpectExec(insertInstall).WillReturnResult(sqlmock.NewResult(1, 1))
+	repo.sqlMock.ExpectExec(insertInstallRepo).WillReturnResult(sqlmock.NewResult(1, 1))
+	repo.sqlMock.ExpectCommit()
+	succ, err := repo.ReplaceInstallation(1, "github", []*Installation{installation})
+	assert.True(t, succ)
+	assert.Nil(t, err)
+
+	// 3: with error
+	repo.sqlMock.ExpectBegin()
+	repo.sqlMock.ExpectExec(deleteInstallRepo).WithArgs(1, "github").WillReturnError(errors.New("ut-error"))
+	repo.sqlMock.ExpectRollback()
+	succ, err = repo.ReplaceInstallation(1, "github", nil)
+	assert.False(t, succ)
+	assert.NotNil(t, err)
+}
+
+func TestMySql_GetMember(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `members` WHERE (org_id = ? AND user_id = ?) AND `members`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case
+	repo.sqlMock.ExpectQuery(query).WithArgs(1, 2).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "org_id", "user_id", "role"}).AddRow(1, 1, 2, RoleOwner))
+	member, err := repo.GetMember(1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, RoleOwner, member.Role)
+
+	// 3: not found
+	repo.sqlMock.ExpectQuery(query).WithArgs(1, 2).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "org_id", "user_id", "role"}))
+	member, err = repo.GetMember(1, 2)
+	assert.Nil(t, member)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// 4: with error
+	repo.sqlMock.ExpectQuery(query).WithArgs(1, 2).WillReturnError(errors.New("ut-error"))
+	member, err = repo.GetMember(1, 2)
+	assert.Nil(t, member)
+	assert.NotNil(t, err)
+}
+
+func TestMySql_RemoveMemberByOrg(t *testing.T) {
+	query := regexp.QuoteMeta("UPDATE `members` SET `deleted_at`=? WHERE (org_id = ?) AND `members`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case
+	repo.sqlMock.ExpectBegin()
+	repo.sqlMock.ExpectExec(query).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 3))
+	repo.sqlMock.ExpectCommit()
+	count, err := repo.RemoveMemberByOrg(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestMySql_GetIdentity(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `identities` WHERE (type = ? AND login = ?) AND `identities`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case
+	repo.sqlMock.ExpectQuery(query).WithArgs(IdentityGithub, "ut-login").
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "user_id", "type", "login"}).AddRow(1, 2, IdentityGithub, "ut-login"))
+	identity, err := repo.GetIdentity(IdentityGithub, "ut-login")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, identity.UserId)
+
+	// 3: not found
+	repo.sqlMock.ExpectQuery(query).WithArgs(IdentityGithub, "ut-login").
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "user_id", "type", "login"}))
+	identity, err = repo.GetIdentity(IdentityGithub, "ut-login")
+	assert.Nil(t, identity)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestMySql_GetAccessToken(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `access_tokens` WHERE (user_id = ? AND type = ?) AND `access_tokens`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case
+	repo.sqlMock.ExpectQuery(query).WithArgs(1, IdentityGithub).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "user_id", "type", "user", "token"}).AddRow(1, 1, IdentityGithub, "ut-login", "ut-token"))
+	token, err := repo.GetAccessToken(1, IdentityGithub)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-token", token.Token)
+
+	// 3: not found
+	repo.sqlMock.ExpectQuery(query).WithArgs(1, IdentityGithub).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "user_id", "type", "user", "token"}))
+	token, err = repo.GetAccessToken(1, IdentityGithub)
+	assert.Nil(t, token)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestMySql_ListAccessToken(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `access_tokens` WHERE type = ? AND `access_tokens`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case
+	repo.sqlMock.ExpectQuery(query).WithArgs(IdentityGithub).
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "user_id", "type", "user", "token"}).AddRow(1, 1, IdentityGithub, "ut-login", "ut-token"))
+	tokenList, err := repo.ListAccessToken(IdentityGithub)
+	assert.Nil(t, err)
+	assert.Len(t, tokenList, 1)
+
+	// 3: with error
+	repo.sqlMock.ExpectQuery(query).WithArgs(IdentityGithub).WillReturnError(errors.New("ut-error"))
+	tokenList, err = repo.ListAccessToken(IdentityGithub)
+	assert.Empty(t, tokenList)
+	assert.NotNil(t, err)
+}
+
+func TestMySql_GetPersonalTokenByHash(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `personal_tokens` WHERE hash = ? AND `personal_tokens`.`deleted_at` IS NULL")
+
+	// 1: init repo as MySQL
+	repo := RegisterMySql(WithEnableMockDb())
+	repo.Bootstrap(context.TODO())
+
+	// 2: happy case
+	repo.sqlMock.ExpectQuery(query).WithArgs("ut-hash").
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "user_id", "name", "hash"}).AddRow(1, 2, "ut-name", "ut-hash"))
+	token, err := repo.GetPersonalTokenByHash("ut-hash")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, token.UserId)
+
+	// 3: not found
+	repo.sqlMock.ExpectQuery(query).WithArgs("ut-hash").
+		WillReturnRows(repo.sqlMock.NewRows([]string{"id", "user_id", "name", "hash"}))
+	token, err = repo.GetPersonalTokenByHash("ut-hash")
+	assert.Nil(t, token)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
 func TestMySql_Options(t *testing.T) {
 	sql := RegisterMySql(
 		WithUser("ut-user"),
@@ -6,8 +6,10 @@
 package repository
 
 import (
+	"github.com/pointgoal/workstation/pkg/utils"
 	"github.com/rookie-ninja/rk-common/common"
 	"github.com/rookie-ninja/rk-entry/entry"
+	"time"
 )
 
 const (
@@ -63,8 +65,8 @@ func RegisterRepositoryFromConfig(configFilePath string) map[string]rkentry.Entr
 		switch config.Repository.Provider {
 		case "mySql":
 			repo := RegisterMySql(
-				WithUser(config.Repository.MySql.User),
-				WithPass(config.Repository.MySql.Pass),
+				WithUser(utils.MustResolveSecret("repository.mySql.user", config.Repository.MySql.User)),
+				WithPass(utils.MustResolveSecret("repository.mySql.pass", config.Repository.MySql.Pass)),
 				WithProtocol(config.Repository.MySql.Protocol),
 				WithAddr(config.Repository.MySql.Addr),
 				WithDatabase(config.Repository.MySql.Database),
@@ -95,6 +97,9 @@ type Repository interface {
 	// ListOrg as function name described
 	ListOrg() ([]*Org, error)
 
+	// ListOrgByIds returns organizations with ids in orgIds, missing ids are ignored.
+	ListOrgByIds(orgIds []int) ([]*Org, error)
+
 	// CreateOrg as function name described
 	CreateOrg(org *Org) (bool, error)
 
@@ -107,6 +112,28 @@ type Repository interface {
 	// UpdateOrg as function name described
 	UpdateOrg(org *Org) (bool, error)
 
+	// ******************************************** //
+	// ************** Member related ************** //
+	// ******************************************** //
+
+	// ListMember returns members of organization
+	ListMember(orgId int) ([]*Member, error)
+
+	// ListMemberByUser returns memberships of user in all organizations
+	ListMemberByUser(userId int) ([]*Member, error)
+
+	// GetMember as function name described
+	GetMember(orgId, userId int) (*Member, error)
+
+	// UpsertMember creates or updates role of member with the same organization and user
+	UpsertMember(*Member) (bool, error)
+
+	// RemoveMember as function name described
+	RemoveMember(orgId, userId int) (bool, error)
+
+	// RemoveMemberByOrg removes all members of organization and returns count of removed members
+	RemoveMemberByOrg(orgId int) (int, error)
+
 	// ********************************************* //
 	// ************** Project related ************** //
 	// ********************************************* //
@@ -126,6 +153,19 @@ type Repository interface {
 	// UpdateProj as function name described
 	UpdateProj(org *Proj) (bool, error)
 
+	// ListProjIdsByOrg returns ids of projects grouped by organization ids with one query.
+	// Projects of all organizations would be returned if orgIds is empty.
+	ListProjIdsByOrg(orgIds []int) (map[int][]int, error)
+
+	// ListProjCountByOrg returns counts of projects grouped by organization ids with one query.
+	// Projects of all organizations would be counted if orgIds is empty.
+	ListProjCountByOrg(orgIds []int) (map[int]int, error)
+
+	// ListProjByOrg returns projects grouped by organization ids with one query.
+	// Projects of all organizations would be returned if orgIds is empty,
+	// source of projects would be returned only if withSource is true.
+	ListProjByOrg(orgIds []int, withSource bool) (map[int][]*Proj, error)
+
 	// ******************************************** //
 	// ************** Source related ************** //
 	// ******************************************** //
@@ -139,18 +179,123 @@ type Repository interface {
 	// GetSource as function name described
 	GetSource(int) (*Source, error)
 
+	// UpdateSource updates repository, host, user and status of source
+	UpdateSource(src *Source) (bool, error)
+
+	// ListSourceByRepository returns sources with type and repository, repository is matched case-insensitively
+	ListSourceByRepository(repoType, repository string) ([]*Source, error)
+
+	// ListSourceByRepositoryId returns sources with type and id of repository in remote code repository
+	ListSourceByRepositoryId(repoType, repositoryId string) ([]*Source, error)
+
+	// ****************************************** //
+	// ************** User related ************** //
+	// ****************************************** //
+
+	// CreateUser creates user together with identities in it
+	CreateUser(user *User) (bool, error)
+
+	// GetUser returns user with linked identities
+	GetUser(int) (*User, error)
+
+	// UpdateUser updates profile of user, identities would not be updated
+	UpdateUser(user *User) (bool, error)
+
+	// RemoveUser removes user together with identities, access tokens, personal tokens, memberships and installations of user
+	RemoveUser(int) (bool, error)
+
+	// ********************************************** //
+	// ************** Identity related ************** //
+	// ********************************************** //
+
+	// UpsertIdentity creates or updates identity with the same type and login
+	UpsertIdentity(*Identity) (bool, error)
+
+	// GetIdentity as function name described
+	GetIdentity(identityType, login string) (*Identity, error)
+
+	// RemoveIdentity removes identity with type of user
+	RemoveIdentity(userId int, identityType string) (bool, error)
+
 	// ************************************************* //
 	// ************** AccessToken related ************** //
 	// ************************************************* //
 
-	// UpsertAccessToken as function name described
+	// UpsertAccessToken creates or updates token with the same user and type
 	UpsertAccessToken(*AccessToken) (bool, error)
 
 	// GetAccessToken as function name described
-	GetAccessToken(repoType, repoUser string) (*AccessToken, error)
+	GetAccessToken(userId int, tokenType string) (*AccessToken, error)
+
+	// ListAccessToken returns access tokens of all users with type, tokens of all types would be returned if type is empty.
+	ListAccessToken(tokenType string) ([]*AccessToken, error)
 
 	// RemoveAccessToken as function name described
-	RemoveAccessToken(string, string) (bool, error)
+	RemoveAccessToken(userId int, tokenType string) (bool, error)
+
+	// *************************************************** //
+	// ************** PersonalToken related ************** //
+	// *************************************************** //
+
+	// ListPersonalToken returns personal tokens of user
+	ListPersonalToken(userId int) ([]*PersonalToken, error)
+
+	// CreatePersonalToken as function name described
+	CreatePersonalToken(*PersonalToken) (bool, error)
+
+	// GetPersonalToken as function name described
+	GetPersonalToken(tokenId int) (*PersonalToken, error)
+
+	// GetPersonalTokenByHash returns personal token with hash of token
+	GetPersonalTokenByHash(hash string) (*PersonalToken, error)
+
+	// UpdatePersonalToken updates hash, expiration and last used time of token
+	UpdatePersonalToken(*PersonalToken) (bool, error)
+
+	// RemovePersonalToken as function name described
+	RemovePersonalToken(tokenId int) (bool, error)
+
+	// ************************************************* //
+	// ************ IdempotencyKey related ************* //
+	// ************************************************* //
+
+	// UpsertIdempotencyKey as function name described
+	UpsertIdempotencyKey(*IdempotencyKey) (bool, error)
+
+	// GetIdempotencyKey as function name described
+	GetIdempotencyKey(key string) (*IdempotencyKey, error)
+
+	// RemoveExpiredIdempotencyKey removes keys expired before t and returns count of removed keys
+	RemoveExpiredIdempotencyKey(t time.Time) (int, error)
+
+	// ************************************************* //
+	// ************** Installation related ************* //
+	// ************************************************* //
+
+	// ListInstallation returns installations of user with type. Installations of all users would be returned if userId
+	// is not positive, and installations of all types would be returned if type is empty.
+	// Repositories of installations would be returned only if withRepos is true.
+	ListInstallation(userId int, installationType string, withRepos bool) ([]*Installation, error)
+
+	// ListInstallationByRemoteId returns installations of all users with type, host and id in remote code repository
+	ListInstallationByRemoteId(installationType, host string, remoteId int64) ([]*Installation, error)
+
+	// ReplaceInstallation replaces installations of user with type together with repositories of them
+	ReplaceInstallation(userId int, installationType string, installations []*Installation) (bool, error)
+
+	// RemoveInstallationByRemoteId removes installations of all users with type, host and id in remote code repository,
+	// count of removed installations would be returned
+	RemoveInstallationByRemoteId(installationType, host string, remoteId int64) (int, error)
+
+	// ************************************************* //
+	// ************** WebhookEvent related ************* //
+	// ************************************************* //
+
+	// UpsertWebhookEvent creates or updates event with the same delivery
+	UpsertWebhookEvent(*WebhookEvent) (bool, error)
+
+	// GetWebhookEvent as function name described
+	GetWebhookEvent(delivery string) (*WebhookEvent, error)
 
 	// ************************************************* //
 	// ************** PipelineTemplate related ************** //
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rookie-ninja/rk-common/common"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// SecretPrefixEnv refers to secret in environment variable, like env:GITHUB_CLIENT_SECRET
+	SecretPrefixEnv = "env:"
+	// SecretPrefixFile refers to secret in file, like file:/run/secrets/key.pem
+	SecretPrefixFile = "file:"
+	// SecretPrefixVault refers to secret in key/value engine of Vault, like vault:secret/data/workstation#clientSecret
+	SecretPrefixVault = "vault:"
+	// VaultAddrEnv is environment variable of Vault address
+	VaultAddrEnv = "VAULT_ADDR"
+	// VaultTokenEnv is environment variable of Vault token
+	VaultTokenEnv = "VAULT_TOKEN"
+)
+
+// vaultClient is used to read secrets from Vault
+var vaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// ResolveSecret returns value of secret reference in config.
+//
+// Supported references:
+// env:NAME reads environment variable which must be set.
+// file:/path reads file, trailing newline is trimmed.
+// vault:path#field reads field from Vault at VAULT_ADDR with VAULT_TOKEN, both KV v1 and v2 engines are supported.
+//
+// Values without known prefix are returned as it is.
+func ResolveSecret(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, SecretPrefixEnv):
+		name := strings.TrimPrefix(ref, SecretPrefixEnv)
+		res, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return res, nil
+	case strings.HasPrefix(ref, SecretPrefixFile):
+		path := strings.TrimPrefix(ref, SecretPrefixFile)
+		bytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file %s: %v", path, err)
+		}
+		return strings.TrimRight(string(bytes), "\r\n"), nil
+	case strings.HasPrefix(ref, SecretPrefixVault):
+		return readVaultSecret(strings.TrimPrefix(ref, SecretPrefixVault))
+	default:
+		return ref, nil
+	}
+}
+
+// MustResolveSecret resolves secret reference of config key, shutdown if failed.
+func MustResolveSecret(key, ref string) string {
+	res, err := ResolveSecret(ref)
+	if err != nil {
+		rkcommon.ShutdownWithError(fmt.Errorf("failed to resolve secret of %s: %v", key, err))
+	}
+
+	return res
+}
+
+// Read field of secret from Vault, path is like secret/data/workstation#clientSecret.
+func readVaultSecret(ref string) (string, error) {
+	tokens := strings.SplitN(ref, "#", 2)
+	if len(tokens) != 2 || len(tokens[0]) < 1 || len(tokens[1]) < 1 {
+		return "", fmt.Errorf("invalid vault secret reference %s, expect path#field", ref)
+	}
+	path, field := strings.Trim(tokens[0], "/"), tokens[1]
+
+	addr := strings.TrimSuffix(os.Getenv(VaultAddrEnv), "/")
+	if len(addr) < 1 {
+		return "", fmt.Errorf("environment variable %s is not set", VaultAddrEnv)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, addr+"/v1/"+path, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid vault secret path %s: %v", path, err)
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv(VaultTokenEnv))
+
+	resp, err := vaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault secret %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to read vault secret %s: status %d", path, resp.StatusCode)
+	}
+
+	body := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault secret %s: %v", path, err)
+	}
+
+	// fields are nested in data of KV v2 engine
+	data := body.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+
+	res, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("field %s is missing in vault secret %s", field, path)
+	}
+
+	return res, nil
+}
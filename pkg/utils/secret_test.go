@@ -0,0 +1,108 @@
+// Copyright (c) 2021 PointGoal
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package utils
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSecret_Plain(t *testing.T) {
+	res, err := ResolveSecret("ut-secret")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-secret", res)
+
+	res, err = ResolveSecret("")
+	assert.Nil(t, err)
+	assert.Empty(t, res)
+}
+
+func TestResolveSecret_Env(t *testing.T) {
+	os.Setenv("UT_WS_SECRET", "ut-secret")
+	defer os.Unsetenv("UT_WS_SECRET")
+
+	res, err := ResolveSecret("env:UT_WS_SECRET")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-secret", res)
+
+	// variable not set
+	_, err = ResolveSecret("env:UT_WS_MISSING")
+	assert.NotNil(t, err)
+}
+
+func TestResolveSecret_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("ut-line-1\nut-line-2\n"), 0600))
+
+	// only trailing newline is trimmed
+	res, err := ResolveSecret("file:" + path)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-line-1\nut-line-2", res)
+
+	// file not exist
+	_, err = ResolveSecret("file:" + path + ".missing")
+	assert.NotNil(t, err)
+}
+
+func TestResolveSecret_Vault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "ut-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/workstation":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"data":     map[string]string{"clientSecret": "ut-kv2-secret"},
+					"metadata": map[string]interface{}{"version": 1},
+				},
+			})
+		case "/v1/kv/workstation":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"clientSecret": "ut-kv1-secret"},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	os.Setenv(VaultAddrEnv, server.URL)
+	os.Setenv(VaultTokenEnv, "ut-token")
+	defer os.Unsetenv(VaultAddrEnv)
+	defer os.Unsetenv(VaultTokenEnv)
+
+	// 1: KV v2 engine
+	res, err := ResolveSecret("vault:secret/data/workstation#clientSecret")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-kv2-secret", res)
+
+	// 2: KV v1 engine
+	res, err = ResolveSecret("vault:/kv/workstation#clientSecret")
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-kv1-secret", res)
+
+	// 3: missing field, missing path and invalid reference
+	_, err = ResolveSecret("vault:secret/data/workstation#privateKey")
+	assert.NotNil(t, err)
+	_, err = ResolveSecret("vault:secret/data/missing#clientSecret")
+	assert.NotNil(t, err)
+	_, err = ResolveSecret("vault:secret/data/workstation")
+	assert.NotNil(t, err)
+
+	// 4: token rejected
+	os.Setenv(VaultTokenEnv, "ut-other-token")
+	_, err = ResolveSecret("vault:secret/data/workstation#clientSecret")
+	assert.NotNil(t, err)
+}